@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// diagnostics is the environment summary shown by the about overlay and
+// `mailnotify -version -verbose`, meant to be pasted into bug reports.
+type diagnostics struct {
+	version    string
+	goVersion  string
+	platform   string
+	backend    string
+	configPath string
+	configNote string
+	cacheDirs  []string
+	cacheSize  int64
+	mailState  string
+}
+
+type diagnosticsMsg diagnostics
+
+func versionString() string {
+	if commit != "" {
+		return fmt.Sprintf("%s (%s)", version, commit)
+	}
+	return version
+}
+
+func collectDiagnostics(cfg config) diagnostics {
+	d := diagnostics{
+		version:    versionString(),
+		goVersion:  runtime.Version(),
+		platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		configPath: cfg.path,
+		cacheDirs:  daemonStateDirs(),
+		mailState:  mailPermissionStatus(),
+	}
+	if p, err := newProvider(cfg); err != nil {
+		d.backend = err.Error()
+	} else {
+		d.backend = p.name()
+	}
+	switch _, err := os.Stat(cfg.path); {
+	case cfg.path == "":
+		d.configNote = "none"
+	case err == nil:
+		d.configNote = "loaded"
+	case os.IsNotExist(err):
+		d.configNote = "not found, using defaults"
+	default:
+		d.configNote = err.Error()
+	}
+	for _, dir := range d.cacheDirs {
+		d.cacheSize += dirSize(dir)
+	}
+	return d
+}
+
+func (d diagnostics) rows() [][2]string {
+	return [][2]string{
+		{"Version", d.version},
+		{"Go", d.goVersion},
+		{"Platform", d.platform},
+		{"Backend", d.backend},
+		{"Config", fmt.Sprintf("%s (%s)", d.configPath, d.configNote)},
+		{"Cache", fmt.Sprintf("%s in %s", formatBytes(d.cacheSize), strings.Join(d.cacheDirs, ", "))},
+		{"Mail.app", d.mailState},
+	}
+}
+
+func (d diagnostics) String() string {
+	var b strings.Builder
+	for _, r := range d.rows() {
+		fmt.Fprintf(&b, "%-10s %s\n", r[0]+":", r[1])
+	}
+	return b.String()
+}
+
+func fetchDiagnostics(cfg config) tea.Cmd {
+	return func() tea.Msg {
+		return diagnosticsMsg(collectDiagnostics(cfg))
+	}
+}
+
+// mailPermissionStatus reports whether Mail.app is running and whether this
+// process may automate it, without launching Mail.app.
+func mailPermissionStatus() string {
+	if runtime.GOOS != "darwin" {
+		return "unavailable (not macOS)"
+	}
+	running, err := mailRunning()
+	if err != nil {
+		return "unknown: " + err.Error()
+	}
+	if !running {
+		return "not running"
+	}
+	if _, err := mailAccounts(); err != nil {
+		if errors.Is(err, errAutomationDenied) {
+			return "running, automation permission denied"
+		}
+		return "running, error: " + err.Error()
+	}
+	return "running, automation permitted"
+}
+
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+// aboutView renders the about overlay.
+func (m model) aboutView() string {
+	title := lipgloss.NewStyle().Bold(true).Foreground(accentColor).Render("mailnotify")
+
+	var body string
+	if m.about == nil {
+		body = m.spinner.View() + " Collecting diagnostics..."
+	} else {
+		var lines []string
+		for _, r := range m.about.rows() {
+			lines = append(lines, metaStyle.Render(fmt.Sprintf("%-10s", r[0]))+bodyStyle.Render(r[1]))
+		}
+		body = strings.Join(lines, "\n")
+	}
+	hint := statusStyle.Render("Include this in bug reports • any key to close")
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(accentColor).
+		Padding(1, 2).
+		Render(title + "\n\n" + body + "\n\n" + hint)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// messageActionMsg reports the result of acting on a single message. verb
+// and done describe the action before and after, for the status line. The
+// list was already updated when the action started.
+type messageActionMsg struct {
+	verb string
+	done string
+	err  error
+}
+
+func actOnMessage(verb, done string, act func() error) tea.Cmd {
+	return func() tea.Msg {
+		return messageActionMsg{verb: verb, done: done, err: act()}
+	}
+}
+
+// actionTarget is the message a per-message action applies to: the open
+// one in the detail view, or the selected one in the list.
+func (m model) actionTarget() (email, bool) {
+	switch m.mode {
+	case detailView:
+		if m.currentEmail != nil {
+			return *m.currentEmail, true
+		}
+	case listView:
+		if e, ok := m.list.SelectedItem().(email); ok {
+			return e, true
+		}
+	}
+	return email{}, false
+}
+
+// dropEmail removes e from the list, and from search results, straight away
+// rather than waiting for the next poll.
+func (m *model) dropEmail(e email) tea.Cmd {
+	return m.dropEmails([]email{e})
+}
+
+// dropEmails is dropEmail for several messages.
+func (m *model) dropEmails(emails []email) tea.Cmd {
+	keys := map[string]bool{}
+	for _, e := range emails {
+		keys[emailKey(e)] = true
+	}
+	m.readHere = slices.DeleteFunc(m.readHere, func(other email) bool { return keys[emailKey(other)] })
+	kept := make([]email, 0, len(m.emails))
+	for _, other := range m.emails {
+		if !keys[emailKey(other)] {
+			kept = append(kept, other)
+		}
+	}
+	for i := len(m.search.results.Items()) - 1; i >= 0; i-- {
+		if other, ok := m.search.results.Items()[i].(email); ok && keys[emailKey(other)] {
+			m.search.results.RemoveItem(i)
+		}
+	}
+	return m.applyEmails(emailsMsg{emails: kept, err: m.err, total: m.total - (len(m.emails) - len(kept))})
+}
+
+// maxReadHere caps how many messages opened this session stay listed as
+// read.
+const maxReadHere = 20
+
+// keepAsRead moves e, which opening just marked read, from the unread
+// messages to the read ones still listed below them.
+func (m *model) keepAsRead(e email) tea.Cmd {
+	key := emailKey(e)
+	if !slices.ContainsFunc(m.emails, func(other email) bool { return emailKey(other) == key }) {
+		return nil
+	}
+	m.lastRead = []email{e}
+	m.readHere = append([]email{e}, m.readHere[:min(len(m.readHere), maxReadHere-1)]...)
+	kept := slices.DeleteFunc(slices.Clone(m.emails), func(other email) bool { return emailKey(other) == key })
+	return m.applyEmails(emailsMsg{emails: kept, err: m.err, total: max(m.total-1, 0)})
+}
+
+// listedRead are the messages of readHere to list as read under polled,
+// the ones it doesn't include as unread.
+func (m model) listedRead(polled []email) []email {
+	var out []email
+	for _, e := range m.readHere {
+		key := emailKey(e)
+		if !slices.ContainsFunc(polled, func(other email) bool { return emailKey(other) == key }) {
+			e.read = true
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// markUnread flags emails unread again and lists them as unread straight
+// away, rather than at the next poll.
+func (m *model) markUnread(emails []email) tea.Cmd {
+	editor, ok := m.mail().(messageEditor)
+	if !ok {
+		m.setNotice("Messages can't be marked unread in " + m.provider.name())
+		return nil
+	}
+	restored := slices.Clone(m.emails)
+	for _, e := range emails {
+		key := emailKey(e)
+		m.readHere = slices.DeleteFunc(m.readHere, func(other email) bool { return emailKey(other) == key })
+		if !slices.ContainsFunc(restored, func(other email) bool { return emailKey(other) == key }) {
+			e.read = false
+			restored = append([]email{e}, restored...)
+		}
+	}
+	m.arrived.known(emails)
+	done := "Marked unread"
+	if len(emails) > 1 {
+		done = fmt.Sprintf("Marked %d unread", len(emails))
+	}
+	emails = slices.Clone(emails)
+	return tea.Batch(
+		m.applyEmails(emailsMsg{emails: restored, err: m.err, total: m.total + len(restored) - len(m.emails)}),
+		m.track(actOnMessage("mark unread", done, func() error {
+			for _, e := range emails {
+				if err := editor.markUnread(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		})),
+	)
+}
+
+// leaveDetail returns from the detail view to the view it was opened from.
+func (m *model) leaveDetail() {
+	if m.mode == detailView {
+		m.stopSpeech()
+		m.mode = m.detailFrom
+		m.currentEmail = nil
+		m.emailBody = ""
+	}
+}
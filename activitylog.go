@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// sessionLogSize is how many entries the log keeps; older ones drop off.
+const sessionLogSize = 500
+
+// logEntry is one line of the session log.
+type logEntry struct {
+	at   time.Time
+	text string
+}
+
+// logRing keeps the newest sessionLogSize entries logged to it.
+type logRing struct {
+	mu      sync.Mutex
+	entries []logEntry
+	// added counts every entry ever logged, so the log screen can tell
+	// when there are new ones.
+	added int
+}
+
+func (r *logRing) snapshot() ([]logEntry, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.entries), r.added
+}
+
+func (r *logRing) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// ringHandler is a slog.Handler writing to a logRing, each record as its
+// message followed by its attributes as key=value.
+type ringHandler struct {
+	ring  *logRing
+	attrs []slog.Attr
+}
+
+func (h ringHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+func (h ringHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Message)
+	attr := func(a slog.Attr) bool {
+		if !a.Equal(slog.Attr{}) {
+			fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		attr(a)
+	}
+	r.Attrs(attr)
+	at := r.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	h.ring.mu.Lock()
+	defer h.ring.mu.Unlock()
+	h.ring.entries = append(h.ring.entries, logEntry{at: at, text: b.String()})
+	if len(h.ring.entries) > sessionLogSize {
+		h.ring.entries = h.ring.entries[len(h.ring.entries)-sessionLogSize:]
+	}
+	h.ring.added++
+	return nil
+}
+
+func (h ringHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h.attrs = append(slices.Clip(h.attrs), attrs...)
+	return h
+}
+
+// WithGroup leaves the attributes unqualified; the log screen is read by
+// people, not parsed.
+func (h ringHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// sessionLog is what mailnotify did this session, its actions and what the
+// backend said, for the log screen. Its logger is the TUI's default, so
+// what's logged anywhere, from Update or a command's goroutine, lands here
+// rather than over the screen. It's a pointer in the model so the log
+// screen's state survives the copies Update makes.
+type sessionLog struct {
+	logger *slog.Logger
+	ring   *logRing
+	// shown is how many entries had been logged when the screen last
+	// drew them.
+	shown int
+	// vp scrolls the log screen; back is the view it returns to.
+	vp   viewport.Model
+	back viewMode
+}
+
+func newSessionLog() *sessionLog {
+	ring := &logRing{}
+	return &sessionLog{logger: slog.New(ringHandler{ring: ring}), ring: ring, vp: viewport.New(0, 0)}
+}
+
+// add logs an entry, formatted as with fmt.Sprintf.
+func (l *sessionLog) add(format string, args ...any) {
+	l.logger.Info(fmt.Sprintf(format, args...))
+	l.refresh()
+}
+
+// refresh shows what's been logged since the screen last drew, following
+// new entries unless the user has scrolled up to read.
+func (l *sessionLog) refresh() {
+	entries, added := l.ring.snapshot()
+	if added == l.shown {
+		return
+	}
+	l.shown = added
+	follow := l.vp.AtBottom()
+	l.vp.SetContent(renderLog(entries))
+	if follow {
+		l.vp.GotoBottom()
+	}
+}
+
+func (l *sessionLog) render() string {
+	entries, added := l.ring.snapshot()
+	l.shown = added
+	return renderLog(entries)
+}
+
+func renderLog(entries []logEntry) string {
+	if len(entries) == 0 {
+		return metaStyle.Render("Nothing yet")
+	}
+	var b strings.Builder
+	for i, e := range entries {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(dateStyle.Render(e.at.Format("15:04")) + "  " + bodyStyle.Render(e.text))
+	}
+	return b.String()
+}
+
+func (l *sessionLog) setSize(width, height int) {
+	l.vp.Width = max(width-4, 10)
+	l.vp.Height = max(height-7, 1)
+}
+
+// logPoll records a poll of the default inbox or a mailbox.
+func (l *sessionLog) logPoll(msg emailsMsg) {
+	switch {
+	case msg.network != netOnline:
+		l.add("poll failed, %s", strings.ToLower(msg.network.String()))
+	case msg.err != nil:
+		l.add("poll failed: %v", msg.err)
+	default:
+		took := msg.timings.fetch + msg.timings.parse
+		l.add("poll ok %dms, %d unread", took.Milliseconds(), max(msg.total, len(msg.emails)))
+	}
+	for _, name := range slices.Sorted(maps.Keys(msg.failed)) {
+		l.add("%s: poll failed: %v", name, msg.failed[name])
+	}
+}
+
+// logAction records the result of an action on messages.
+func (l *sessionLog) logAction(msg messageActionMsg) {
+	if msg.err != nil {
+		l.add("couldn't %s: %v", msg.verb, msg.err)
+		return
+	}
+	if msg.done == "" {
+		l.add("%s ok", msg.verb)
+		return
+	}
+	l.add("%s", strings.ToLower(msg.done[:1])+msg.done[1:])
+}
+
+// openLog shows the session log, scrolled to the newest entry.
+func (m *model) openLog() {
+	m.log.back = m.mode
+	m.log.setSize(m.width, m.height)
+	m.log.vp.SetContent(m.log.render())
+	m.log.vp.GotoBottom()
+	m.mode = logView
+}
+
+func (m *model) logKeys(key string) (tea.Cmd, bool) {
+	switch key {
+	case "E", "esc", "q":
+		m.mode = m.log.back
+		return nil, true
+	}
+	return nil, false
+}
+
+func (m *model) updateLog(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	m.log.vp, cmd = m.log.vp.Update(msg)
+	return cmd
+}
+
+func (m model) viewLog(status string) string {
+	title := headerStyle.Render("Session log") + "  " + metaStyle.Render(fmt.Sprintf("%d entries", m.log.ring.len()))
+	body := lipgloss.NewStyle().Padding(1, 2).Render(title + "\n\n" + m.log.vp.View())
+	gap := max(m.height-lipgloss.Height(body)-lipgloss.Height(status)-1, 0)
+	return body + strings.Repeat("\n", gap) + "\n" + status + renderHelpBar(m.width, [][]string{{"↑/↓", "scroll"}, {"esc", "back"}})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSessionLog(t *testing.T) {
+	l := newSessionLog()
+	l.setSize(80, 20)
+	l.add("marked %d read", 3)
+	done := make(chan struct{})
+	go func() {
+		// As a command's goroutine logs, through the log package.
+		slog.NewLogLogger(l.logger.Handler(), slog.LevelInfo).Printf("mail bridge exited")
+		l.logger.With("account", "Work").Info("reconnecting")
+		close(done)
+	}()
+	<-done
+	l.logger.Debug("not shown")
+
+	l.refresh()
+	got := l.vp.View()
+	for _, want := range []string{"marked 3 read", "mail bridge exited", "reconnecting account=Work"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log screen is missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "not shown") {
+		t.Error("a debug record was kept")
+	}
+
+	for i := range sessionLogSize + 10 {
+		l.add("entry %d", i)
+	}
+	entries, _ := l.ring.snapshot()
+	if len(entries) != sessionLogSize || entries[0].text != fmt.Sprintf("entry %d", 10) {
+		t.Errorf("log keeps %d entries from %q, want the newest %d", len(entries), entries[0].text, sessionLogSize)
+	}
+}
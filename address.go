@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/mail"
+	"slices"
+	"strings"
+)
+
+// addressing describes how a message reached the user.
+type addressing int
+
+const (
+	addressedUnknown  addressing = iota
+	addressedDirect              // one of my addresses is in To
+	addressedCC                  // one of my addresses is in Cc only
+	addressedIndirect            // neither: a mailing list, alias or Bcc
+)
+
+// addressingBadges are mutt-style index flags drawn before the subject.
+var addressingBadges = map[addressing]string{
+	addressedUnknown:  " ",
+	addressedDirect:   "»",
+	addressedCC:       "›",
+	addressedIndirect: "L",
+}
+
+// addressSet is the user's own addresses, lower-cased.
+type addressSet map[string]bool
+
+func newAddressSet(addrs []string) addressSet {
+	set := make(addressSet, len(addrs))
+	for _, a := range addrs {
+		if a = normalizeAddress(a); a != "" {
+			set[a] = true
+		}
+	}
+	return set
+}
+
+// has reports whether addr is in s, or is a plus address of one in s, as
+// me+shop@example.com is of me@example.com.
+func (s addressSet) has(addr string) bool {
+	addr = normalizeAddress(addr)
+	if s[addr] {
+		return true
+	}
+	base, ok := plusBase(addr)
+	return ok && s[base]
+}
+
+func (s addressSet) containsAny(addrs []string) bool {
+	for _, a := range addrs {
+		if s.has(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// plusBase strips the tag from a plus address, "me+shop@example.com" to
+// "me@example.com". It reports false for an address without one.
+func plusBase(addr string) (string, bool) {
+	local, domain, ok := strings.Cut(addr, "@")
+	if !ok {
+		return "", false
+	}
+	local, _, ok = strings.Cut(local, "+")
+	if !ok || local == "" {
+		return "", false
+	}
+	return local + "@" + domain, true
+}
+
+// via returns the address e was delivered to when it's one of aliases, or
+// a plus address of one in s, rather than a main address: the address the
+// sender was given, which says who passed it on. It's "" otherwise.
+func (s addressSet) via(e email, aliases addressSet) string {
+	for _, a := range slices.Concat(e.to, e.cc) {
+		a = normalizeAddress(a)
+		if aliases[a] || !s[a] && s.has(a) {
+			return a
+		}
+	}
+	return ""
+}
+
+// classify reports how e was addressed. It is addressedUnknown when no
+// addresses are configured.
+func (s addressSet) classify(e email) addressing {
+	switch {
+	case len(s) == 0:
+		return addressedUnknown
+	case s.containsAny(e.to):
+		return addressedDirect
+	case s.containsAny(e.cc):
+		return addressedCC
+	default:
+		return addressedIndirect
+	}
+}
+
+// normalizeAddress reduces "Name <addr>" or a bare address to the lower-cased
+// address. Invalid UTF-8, as in raw 8-bit headers, is replaced first so
+// the result normalizes to itself.
+func normalizeAddress(a string) string {
+	a = strings.ToValidUTF8(strings.TrimSpace(a), "\uFFFD")
+	if parsed, err := mail.ParseAddress(a); err == nil {
+		a = parsed.Address
+	}
+	return strings.ToLower(a)
+}
+
+// splitAddresses splits the comma-joined recipient list the AppleScript
+// layer returns.
+func splitAddresses(s string) []string {
+	var out []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const agentLabel = "com.github.darkdenlion.mailnotify"
+
+// agentPlist's values are escaped, so a path with & or < in it still makes
+// a plist launchd accepts.
+var agentPlist = template.Must(template.New("plist").Funcs(template.FuncMap{"xml": xmlEscape}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{xml .Label}}</string>
+	<key>ProgramArguments</key>
+	<array>
+{{- range .Args}}
+		<string>{{xml .}}</string>
+{{- end}}
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>ThrottleInterval</key>
+	<integer>30</integer>
+	<key>ProcessType</key>
+	<string>Background</string>
+	<key>StandardOutPath</key>
+	<string>{{xml .Log}}</string>
+	<key>StandardErrorPath</key>
+	<string>{{xml .Log}}</string>
+</dict>
+</plist>
+`))
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func agentPaths() (plist, logFile string, err error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", err
+	}
+	plist = filepath.Join(home, "Library", "LaunchAgents", agentLabel+".plist")
+	logFile = filepath.Join(home, "Library", "Logs", "mailnotify", "daemon.log")
+	return plist, logFile, nil
+}
+
+// installAgent writes a launchd agent that runs the daemon at login and
+// restarts it if it crashes, then loads it.
+func installAgent(configPath string) error {
+	plistPath, logPath, err := agentPaths()
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = agentPlist.Execute(&buf, struct {
+		Label string
+		Args  []string
+		Log   string
+	}{
+		Label: agentLabel,
+		Args:  []string{exe, "-daemon", "-config", configPath},
+		Log:   logPath,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(plistPath), 0o755); err != nil {
+		return err
+	}
+	// Reinstalling replaces the running agent with the new definition.
+	_ = launchctl("bootout", launchdTarget()+"/"+agentLabel)
+	if err := os.WriteFile(plistPath, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := launchctl("bootstrap", launchdTarget(), plistPath); err != nil {
+		return err
+	}
+	fmt.Printf("Installed %s\nLogs: %s\n", plistPath, logPath)
+	return nil
+}
+
+// uninstallAgent stops the agent and removes its plist.
+func uninstallAgent() error {
+	plistPath, _, err := agentPaths()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(plistPath); os.IsNotExist(err) {
+		return fmt.Errorf("no agent installed at %s", plistPath)
+	}
+	_ = launchctl("bootout", launchdTarget()+"/"+agentLabel)
+	if err := os.Remove(plistPath); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s\n", plistPath)
+	return nil
+}
+
+func launchdTarget() string {
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
+
+func launchctl(args ...string) error {
+	out, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("launchctl %s: %v: %s", args[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
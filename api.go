@@ -0,0 +1,297 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiServer serves the daemon's cached unread list over HTTP for launchers
+// and shortcuts. Every request needs the configured bearer token.
+//
+//	GET  /v1/unread                 the cached unread list
+//	GET  /v1/search?q=<query>       the cached list narrowed by a filter query
+//	GET  /v1/messages/{id}          a message's body and attachments
+//	POST /v1/messages/{id}/read     mark a message read
+//	POST /v1/messages/{id}/archive  archive a message
+//
+// A message id can be ambiguous across accounts; ?account= and ?mailbox=
+// pick one.
+type apiServer struct {
+	token string
+
+	mu       sync.Mutex
+	provider mailProvider
+	emails   []email
+	updated  time.Time
+	err      error
+}
+
+// apiEmail is an email as the API returns it.
+type apiEmail struct {
+	ID        string   `json:"id"`
+	Sender    string   `json:"sender"`
+	Subject   string   `json:"subject"`
+	Date      string   `json:"date"`
+	Account   string   `json:"account,omitempty"`
+	Mailbox   string   `json:"mailbox,omitempty"`
+	To        []string `json:"to,omitempty"`
+	Cc        []string `json:"cc,omitempty"`
+	MessageID string   `json:"message_id,omitempty"`
+	Priority  string   `json:"priority"`
+}
+
+type apiList struct {
+	Emails  []apiEmail `json:"emails"`
+	Updated time.Time  `json:"updated"`
+	// Error is the last poll's failure, if it failed. Emails are then from
+	// the last poll that worked.
+	Error string `json:"error,omitempty"`
+}
+
+type apiMessage struct {
+	apiEmail
+	Body        string          `json:"body"`
+	HTML        string          `json:"html,omitempty"`
+	Attachments []apiAttachment `json:"attachments,omitempty"`
+}
+
+type apiAttachment struct {
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+}
+
+func toAPIEmail(e email) apiEmail {
+	return apiEmail{
+		ID:        e.id,
+		Sender:    e.sender,
+		Subject:   e.subject,
+		Date:      e.date,
+		Account:   e.account,
+		Mailbox:   e.mailbox,
+		To:        e.to,
+		Cc:        e.cc,
+		MessageID: e.messageID,
+		Priority:  e.priority.String(),
+	}
+}
+
+// email is the message e describes, as far as it says, for matching
+// rules against.
+func (e apiEmail) email() email {
+	return email{
+		id:        e.ID,
+		sender:    e.Sender,
+		subject:   e.Subject,
+		date:      e.Date,
+		account:   e.Account,
+		mailbox:   e.Mailbox,
+		to:        e.To,
+		cc:        e.Cc,
+		messageID: e.MessageID,
+		priority:  parsePriority(e.Priority),
+	}
+}
+
+// startAPI listens on cfg.Listen and serves the API in the background. It
+// refuses to start without a token.
+func startAPI(cfg apiConfig, provider mailProvider) (*apiServer, error) {
+	token, err := resolveSecret(cfg.Token)
+	if err != nil {
+		return nil, fmt.Errorf("api: %w", err)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("api: a token is required")
+	}
+	ln, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("api: %w", err)
+	}
+	s := &apiServer{token: token, provider: provider}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/unread", s.handleUnread)
+	mux.HandleFunc("GET /v1/search", s.handleSearch)
+	mux.HandleFunc("GET /v1/messages/{id}", s.handleMessage)
+	mux.HandleFunc("POST /v1/messages/{id}/{action}", s.handleAction)
+	srv := &http.Server{Handler: s.authorize(mux), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("api stopped: %v", err)
+		}
+	}()
+	log.Printf("api listening on %s", ln.Addr())
+	return s, nil
+}
+
+// update records a poll's result. A failed poll keeps the last list.
+func (s *apiServer) update(emails []email, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+	if err == nil {
+		s.emails = emails
+		s.updated = time.Now()
+	}
+}
+
+// setProvider switches the backend after a config reload.
+func (s *apiServer) setProvider(p mailProvider) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.provider = p
+	s.mu.Unlock()
+}
+
+func (s *apiServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mailnotify"`)
+			apiError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *apiServer) handleUnread(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.list(nil))
+}
+
+func (s *apiServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	terms := parseFilterQuery(r.URL.Query().Get("q"))
+	writeJSON(w, s.list(func(e email) bool { return matchesQuery(terms, e) }))
+}
+
+func (s *apiServer) list(keep func(email) bool) apiList {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := apiList{Emails: []apiEmail{}, Updated: s.updated}
+	if s.err != nil {
+		out.Error = s.err.Error()
+	}
+	for _, e := range s.emails {
+		if keep == nil || keep(e) {
+			out.Emails = append(out.Emails, toAPIEmail(e))
+		}
+	}
+	return out
+}
+
+// handleMessage fetches a message's content from the backend. As when it's
+// opened in the TUI, that marks it read.
+func (s *apiServer) handleMessage(w http.ResponseWriter, r *http.Request) {
+	e, provider, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	c, err := provider.content(e)
+	if err != nil {
+		apiError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	s.drop(e)
+	msg := apiMessage{apiEmail: toAPIEmail(e), Body: c.body, HTML: c.html}
+	for _, a := range c.attachments {
+		msg.Attachments = append(msg.Attachments, apiAttachment{Name: a.name, MIMEType: a.mimeType, Size: a.size})
+	}
+	writeJSON(w, msg)
+}
+
+func (s *apiServer) handleAction(w http.ResponseWriter, r *http.Request) {
+	e, provider, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	act, err := messageAction(provider, r.PathValue("action"))
+	if err != nil {
+		apiError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err := act(e); err != nil {
+		apiError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	s.drop(e)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// messageAction returns the backend call for an action verb a launcher can
+// apply to one message.
+func messageAction(p mailProvider, action string) (func(email) error, error) {
+	switch action {
+	case "read":
+		return func(e email) error { return p.markRead([]email{e}) }, nil
+	case "archive":
+		editor, ok := p.(messageEditor)
+		if !ok {
+			return nil, fmt.Errorf("%s can't archive messages", p.name())
+		}
+		return editor.archive, nil
+	}
+	return nil, fmt.Errorf("unknown action %q", action)
+}
+
+// lookup finds the cached email a request names, writing an error response
+// when there isn't exactly one.
+func (s *apiServer) lookup(w http.ResponseWriter, r *http.Request) (email, mailProvider, bool) {
+	id := r.PathValue("id")
+	account, mailbox := r.URL.Query().Get("account"), r.URL.Query().Get("mailbox")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found []email
+	for _, e := range s.emails {
+		if e.id == id && (account == "" || e.account == account) && (mailbox == "" || e.mailbox == mailbox) {
+			found = append(found, e)
+		}
+	}
+	switch len(found) {
+	case 0:
+		apiError(w, http.StatusNotFound, "no unread message "+id)
+		return email{}, nil, false
+	case 1:
+		return found[0], s.provider, true
+	default:
+		apiError(w, http.StatusConflict, "message id "+id+" is ambiguous; add account or mailbox")
+		return email{}, nil, false
+	}
+}
+
+// drop removes a message that's no longer unread from the cache, ahead of
+// the next poll.
+func (s *apiServer) drop(e email) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := emailKey(e)
+	kept := make([]email, 0, len(s.emails))
+	for _, other := range s.emails {
+		if emailKey(other) != key {
+			kept = append(kept, other)
+		}
+	}
+	s.emails = kept
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func apiError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
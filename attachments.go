@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// attachment is a file attached to a message, as Mail.app describes it.
+// text holds the contents of a small text attachment once it's loaded.
+type attachment struct {
+	name     string
+	mimeType string
+	size     int64
+	text     string
+}
+
+// maxInlineSize is the largest text attachment shown inline in the detail
+// view.
+const maxInlineSize = 32 << 10
+
+var inlineExtensions = map[string]bool{
+	".txt": true, ".log": true, ".csv": true, ".tsv": true, ".md": true,
+	".json": true, ".yaml": true, ".yml": true, ".xml": true, ".ini": true,
+}
+
+// inlineable reports whether a is small plain text worth showing inline.
+func (a attachment) inlineable() bool {
+	if a.size > maxInlineSize {
+		return false
+	}
+	return strings.HasPrefix(a.mimeType, "text/") || inlineExtensions[strings.ToLower(filepath.Ext(a.name))]
+}
+
+func (a attachment) String() string {
+	return fmt.Sprintf("%s (%s)", a.name, formatBytes(a.size))
+}
+
+// saveAttachment writes a from e to dest.
+func (mailAppProvider) saveAttachment(e email, a attachment, dest string) error {
+	if e.id == "" {
+		return fmt.Errorf("message has no id")
+	}
+	script := `
+on run argv
+	tell application "Mail"
+` + messageByIDScript + `
+		save (first mail attachment of msg whose name is (item 4 of argv)) in POSIX file (item 5 of argv)
+	end tell
+end run
+`
+	args := append([]string{"-e", script}, messageArgs(e)...)
+	return exec.Command("osascript", append(args, a.name, dest)...).Run()
+}
+
+// inlineAttachmentsMsg carries the loaded text of the inlineable
+// attachments of message id, keyed by attachment index.
+type inlineAttachmentsMsg struct {
+	id    string
+	texts map[int]string
+}
+
+// loadInlineAttachments reads the inlineable attachments in atts that the
+// backend didn't already load, by saving each to a temporary file.
+func loadInlineAttachments(s attachmentSaver, e email, atts []attachment) tea.Cmd {
+	return func() tea.Msg {
+		msg := inlineAttachmentsMsg{id: e.id, texts: map[int]string{}}
+		dir, err := os.MkdirTemp("", "mailnotify-inline-")
+		if err != nil {
+			return msg
+		}
+		defer os.RemoveAll(dir)
+
+		for i, a := range atts {
+			if !a.inlineable() || a.text != "" {
+				continue
+			}
+			path := filepath.Join(dir, fmt.Sprintf("%d-%s", i, filepath.Base(a.name)))
+			if err := s.saveAttachment(e, a, path); err != nil {
+				continue
+			}
+			if data, err := os.ReadFile(path); err == nil {
+				msg.texts[i] = string(data)
+			}
+		}
+		return msg
+	}
+}
+
+// renderInline formats a loaded text attachment for the viewport. CSV and
+// TSV are laid out in aligned columns.
+func renderInline(a attachment) string {
+	text := strings.TrimRight(strings.ReplaceAll(a.text, "\r\n", "\n"), "\n")
+	comma := rune(0)
+	switch strings.ToLower(filepath.Ext(a.name)) {
+	case ".csv":
+		comma = ','
+	case ".tsv":
+		comma = '\t'
+	}
+	if a.mimeType == "text/csv" {
+		comma = ','
+	}
+	if comma == 0 {
+		return text
+	}
+
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return text
+	}
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	return strings.TrimRight(b.String(), "\n")
+}
+
+type quickLookMsg struct {
+	err error
+}
+
+// quickLook saves a to a temporary file and previews it with Quick Look.
+// The file is removed once the preview window is closed.
+func quickLook(s attachmentSaver, e email, a attachment) tea.Cmd {
+	return func() tea.Msg {
+		dir, err := os.MkdirTemp("", "mailnotify-preview-")
+		if err != nil {
+			return quickLookMsg{err: err}
+		}
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, filepath.Base(a.name))
+		if err := s.saveAttachment(e, a, path); err != nil {
+			return quickLookMsg{err: err}
+		}
+		return quickLookMsg{err: exec.Command("qlmanage", "-p", path).Run()}
+	}
+}
+
+type attachmentSavedMsg struct {
+	path string
+	err  error
+}
+
+// saveToDisk saves a into the configured directory under a name that
+// doesn't clobber an existing file. With a scanner configured the
+// attachment is first fetched into a private temporary directory and only
+// copied out once the scanner accepts it.
+func saveToDisk(s attachmentSaver, cfg attachmentsConfig, e email, a attachment) tea.Cmd {
+	return func() tea.Msg {
+		dir, err := os.MkdirTemp("", "mailnotify-save-")
+		if err != nil {
+			return attachmentSavedMsg{err: err}
+		}
+		defer os.RemoveAll(dir)
+
+		tmp := filepath.Join(dir, filepath.Base(a.name))
+		if err := s.saveAttachment(e, a, tmp); err != nil {
+			return attachmentSavedMsg{err: err}
+		}
+		if cfg.Scanner != "" {
+			if err := scanAttachment(cfg.Scanner, tmp); err != nil {
+				return attachmentSavedMsg{err: err}
+			}
+		}
+
+		dest, err := uniquePath(attachmentDir(cfg), filepath.Base(a.name))
+		if err != nil {
+			return attachmentSavedMsg{err: err}
+		}
+		data, err := os.ReadFile(tmp)
+		if err != nil {
+			return attachmentSavedMsg{err: err}
+		}
+		return attachmentSavedMsg{path: dest, err: os.WriteFile(dest, data, 0o644)}
+	}
+}
+
+// scanAttachment pipes the file at path into the scanner command on stdin,
+// with its path also in $MAILNOTIFY_ATTACHMENT for scanners that want a
+// file. A nonzero exit rejects it; the scanner's first line of output says
+// why.
+func scanAttachment(scanner, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cmd := exec.Command("sh", "-c", scanner)
+	cmd.Stdin = f
+	cmd.Env = append(os.Environ(), "MAILNOTIFY_ATTACHMENT="+path)
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		reason, _, _ := strings.Cut(string(bytes.TrimSpace(out)), "\n")
+		if reason == "" {
+			reason = fmt.Sprintf("exit status %d", exit.ExitCode())
+		}
+		return fmt.Errorf("blocked by scanner: %s", reason)
+	}
+	if err != nil {
+		return fmt.Errorf("scanner: %w", err)
+	}
+	return nil
+}
+
+// attachmentDir is the configured save directory, or ~/Downloads.
+func attachmentDir(cfg attachmentsConfig) string {
+	if cfg.Dir != "" {
+		return expandHome(cfg.Dir)
+	}
+	return expandHome("~/Downloads")
+}
+
+// uniquePath returns dir/name, or "name (2).ext" and so on when that's
+// taken, as Finder does.
+func uniquePath(dir, name string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	path := filepath.Join(dir, name)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return path, nil
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, n, ext))
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// autoReplyDir holds a claim file for each sender sent a [[rules]] reply
+// today. It's in the state directory, so the daemon and the TUI, which both
+// send them, go by the same claims.
+const autoReplyDir = "autoreplies"
+
+// autoReplyClaims are the senders answered on day, one file each in dir.
+// Creating a sender's file is what claims them, and only one process can
+// create it, so two answering the same mail at once can't both reply.
+type autoReplyClaims struct {
+	dir string
+	day string
+}
+
+// openAutoReplyClaims opens now's claims in stateDir, removing the claims
+// of earlier days.
+func openAutoReplyClaims(stateDir string, now time.Time) (autoReplyClaims, error) {
+	if stateDir == "" {
+		return autoReplyClaims{}, fmt.Errorf("no state directory")
+	}
+	c := autoReplyClaims{dir: filepath.Join(stateDir, autoReplyDir), day: now.Format(time.DateOnly)}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return autoReplyClaims{}, err
+	}
+	entries, _ := os.ReadDir(c.dir)
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), c.day+"_") {
+			os.Remove(filepath.Join(c.dir, e.Name()))
+		}
+	}
+	return c, nil
+}
+
+func (c autoReplyClaims) path(addr string) string {
+	return filepath.Join(c.dir, c.day+"_"+url.PathEscape(addr))
+}
+
+// claimed reports whether addr was claimed today.
+func (c autoReplyClaims) claimed(addr string) bool {
+	_, err := os.Stat(c.path(addr))
+	return err == nil
+}
+
+// claim claims addr for today, reporting false when someone already has.
+func (c autoReplyClaims) claim(addr string) (bool, error) {
+	f, err := os.OpenFile(c.path(addr), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, f.Close()
+}
+
+// release gives up a claim whose reply couldn't be sent, so a later poll
+// tries again.
+func (c autoReplyClaims) release(addr string) {
+	os.Remove(c.path(addr))
+}
+
+// machineSender matches the local parts of addresses that send mail
+// automatically and that no one reads replies to.
+var machineSender = regexp.MustCompile(`(?i)^(mailer-daemon|postmaster|no-?reply|do-?not-?reply|bounces?|listserv|majordomo|owner-.*|.*-request|.*-bounces?)$`)
+
+// automatedHeaders reports whether the headers get reads say the message
+// was sent automatically or through a list: an Auto-Submitted other than
+// "no", a bulk, list or junk Precedence, or any List-Id.
+func automatedHeaders(get func(string) string) bool {
+	if v := strings.ToLower(strings.TrimSpace(get("Auto-Submitted"))); v != "" && v != "no" {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(get("Precedence"))) {
+	case "bulk", "list", "junk":
+		return true
+	}
+	return strings.TrimSpace(get("List-Id")) != ""
+}
+
+// autoRepliedHeaders reports whether the headers get reads mark the message
+// as an auto-responder's reply: "Auto-Submitted: auto-replied", or the
+// X-Autoreply or X-Autorespond some responders set instead.
+func autoRepliedHeaders(get func(string) string) bool {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(get("Auto-Submitted"))), "auto-replied") {
+		return true
+	}
+	return strings.TrimSpace(get("X-Autoreply")) != "" || strings.TrimSpace(get("X-Autorespond")) != ""
+}
+
+// unanswerable reports whether e, from addr, mustn't be auto-replied to:
+// it's an auto-reply itself, it says it was automated, or its sender is a
+// machine.
+func unanswerable(e email, addr string) bool {
+	local, _, _ := strings.Cut(addr, "@")
+	return isAutoReply(e) || e.automated || machineSender.MatchString(local)
+}
+
+// autoReplyData is what a reply template is executed against.
+type autoReplyData struct {
+	// Name is the sender's display name, or their address without one.
+	Name    string
+	Sender  string
+	Address string
+	Subject string
+	Date    string
+}
+
+// sendAutoReplies answers the messages in arrived that a reply rule
+// matches, through p's sending account, and returns who was answered. It
+// leaves out other auto-replies, the user's own mail and senders already
+// answered today, so two mailboxes replying to each other can't loop, and
+// claims each sender before answering, so the daemon and the TUI don't
+// both answer. As
+// RFC 3834 asks, it also leaves out mail that says it was sent by a machine
+// or through a list, and mail from addresses like mailer-daemon that no
+// one reads.
+func sendAutoReplies(cfg config, p mailProvider, arrived []email, now time.Time) ([]string, error) {
+	store, ok := p.(draftStore)
+	if !ok {
+		return nil, fmt.Errorf("%s can't send auto-replies", p.name())
+	}
+	claims, err := openAutoReplyClaims(cfg.State.dir(), now)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the auto-replies sent: %w", err)
+	}
+	me := cfg.Identity.me()
+	answered := map[string]bool{}
+	var sent []string
+	var errs []error
+	for _, e := range arrived {
+		r, ok := cfg.rules.first(e)
+		addr := normalizeAddress(e.sender)
+		if !ok || r.action != ruleReply || addr == "" || unanswerable(e, addr) || me.has(addr) || answered[addr] || claims.claimed(addr) {
+			continue
+		}
+		answered[addr] = true
+		// A dry run only logs, so it leaves the sender for a real run.
+		if !cfg.dryRun {
+			if ok, err := claims.claim(addr); err != nil || !ok {
+				if err != nil {
+					errs = append(errs, fmt.Errorf("auto-reply to %s: %w", addr, err))
+				}
+				continue
+			}
+		}
+		var body strings.Builder
+		err := r.reply.Execute(&body, autoReplyData{
+			Name:    displayName(e.sender),
+			Sender:  e.sender,
+			Address: addr,
+			Subject: e.subject,
+			Date:    e.date,
+		})
+		if err == nil {
+			err = store.compose(outgoingMessage{to: []string{e.sender}, subject: replySubject(e.subject), body: body.String(), inReplyTo: e, autoSubmitted: true}, true)
+		}
+		if err != nil {
+			if !cfg.dryRun {
+				claims.release(addr)
+			}
+			errs = append(errs, fmt.Errorf("auto-reply to %s: %w", addr, err))
+			continue
+		}
+		sent = append(sent, addr)
+	}
+	return sent, errors.Join(errs...)
+}
+
+// hasReplyRules reports whether any rule sends auto-replies, so polls
+// without them skip the work.
+func (rs rules) hasReplyRules() bool {
+	for _, r := range rs {
+		if r.action == ruleReply {
+			return true
+		}
+	}
+	return false
+}
+
+type autoRepliedMsg struct {
+	to  []string
+	err error
+}
+
+// autoReply sends the TUI's auto-replies for the messages in arrived.
+func autoReply(cfg config, p mailProvider, arrived []email) tea.Cmd {
+	if len(arrived) == 0 || !cfg.rules.hasReplyRules() {
+		return nil
+	}
+	return func() tea.Msg {
+		to, err := sendAutoReplies(cfg, p, arrived, time.Now())
+		return autoRepliedMsg{to: to, err: err}
+	}
+}
+
+func (m *model) applyAutoReplied(msg autoRepliedMsg) {
+	for _, addr := range msg.to {
+		m.log.add("auto-replied to %s", addr)
+	}
+	switch {
+	case msg.err != nil:
+		m.log.add("%v", msg.err)
+		m.setNotice(fmt.Sprintf("Auto-reply failed: %v", msg.err))
+	case len(msg.to) == 1:
+		m.setNotice("Auto-replied to " + msg.to[0])
+	case len(msg.to) > 1:
+		m.setNotice(fmt.Sprintf("Auto-replied to %d senders", len(msg.to)))
+	}
+}
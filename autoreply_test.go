@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUnanswerable(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers string
+		want    bool
+	}{
+		{"person", "From: Ann <ann@example.com>\r\nSubject: Lunch?\r\n", false},
+		{"auto-submitted no", "From: ann@example.com\r\nSubject: Lunch?\r\nAuto-Submitted: no\r\n", false},
+		{"auto-submitted", "From: ann@example.com\r\nSubject: Lunch?\r\nAuto-Submitted: auto-generated\r\n", true},
+		{"auto-replied", "From: ann@example.com\r\nSubject: Lunch?\r\nAuto-Submitted: Auto-Replied\r\n", true},
+		{"precedence bulk", "From: news@example.com\r\nSubject: This week\r\nPrecedence: bulk\r\n", true},
+		{"precedence list", "From: ann@example.com\r\nSubject: Re: patch\r\nPrecedence: list\r\n", true},
+		{"list-id", "From: ann@example.com\r\nSubject: Re: patch\r\nList-Id: <dev.lists.example.com>\r\n", true},
+		{"out of office", "From: ann@example.com\r\nSubject: Out of Office: Lunch?\r\n", true},
+		{"mailer-daemon", "From: MAILER-DAEMON@example.com\r\nSubject: Undelivered Mail\r\n", true},
+		{"noreply", "From: Shop <no-reply@shop.example>\r\nSubject: Your order\r\n", true},
+		{"list request", "From: dev-request@lists.example.com\r\nSubject: confirm\r\n", true},
+		{"name containing noreply", "From: noreplyann@example.com\r\nSubject: Lunch?\r\n", false},
+	}
+	for _, tt := range tests {
+		e := parseHeaderEmail([]byte(tt.headers + "\r\n"))
+		if got := unanswerable(e, normalizeAddress(e.sender)); got != tt.want {
+			t.Errorf("%s: unanswerable = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAutoReplyClaims(t *testing.T) {
+	dir := t.TempDir()
+	day := time.Date(2026, 10, 14, 9, 0, 0, 0, time.Local)
+	c, err := openAutoReplyClaims(dir, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var won atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, err := c.claim("ann@example.com"); err != nil {
+				t.Error(err)
+			} else if ok {
+				won.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if won.Load() != 1 {
+		t.Errorf("%d claims on one sender won, want 1", won.Load())
+	}
+	c.release("ann@example.com")
+	if ok, _ := c.claim("ann@example.com"); !ok {
+		t.Error("a released sender can't be claimed again")
+	}
+	next, err := openAutoReplyClaims(dir, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.claimed("ann@example.com") {
+		t.Error("yesterday's claim still counts today")
+	}
+	if entries, _ := os.ReadDir(next.dir); len(entries) != 0 {
+		t.Errorf("yesterday's claims weren't removed: %v", entries)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// autoReplySubject matches the prefixes auto-responders give their replies'
+// subjects, in the common languages, colon included so that "OOO planning"
+// or "Absence policy" isn't taken for one.
+var autoReplySubject = regexp.MustCompile(`(?i)^\s*(automatic reply|auto[- ]?reply|auto[- ]?response|out of (the )?office|ooo|abwesend|abwesenheitsnotiz|réponse automatique|absence|respuesta automática|risposta automatica|automatisch antwoord|afwezig)\s*:`)
+
+// awayUntil finds when an auto-reply says its sender is back, as the phrase
+// after "until", "back on" and the like.
+var awayUntil = regexp.MustCompile(`(?i)\b(?:until|till|through|returning(?: on)?|return(?:ing)? to the office on|back (?:in the office )?on|back in the office)\s+([^\n]{3,60})`)
+
+// awayEnd cuts the phrase awayUntil found at the end of its clause.
+var awayEnd = regexp.MustCompile(`(?i)(\.\s|[!;(]| and | with | at which | when | during |, (?:but|and|so|please|i)\b)`)
+
+// isAutoReply reports whether e is an auto-responder's reply: its headers
+// say so, or, for responders that don't set them, its subject does.
+func isAutoReply(e email) bool {
+	return e.autoReplied || autoReplySubject.MatchString(e.subject)
+}
+
+// parseAwayUntil returns when an auto-reply body says its sender is back,
+// as written, or "" when it doesn't say.
+func parseAwayUntil(body string) string {
+	m := awayUntil.FindStringSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	until := m[1]
+	if loc := awayEnd.FindStringIndex(until); loc != nil {
+		until = until[:loc[0]]
+	}
+	return strings.TrimRight(strings.TrimSpace(until), ".,:")
+}
+
+// autoReplyFrom returns the newest auto-reply in the list from the sender
+// of e.
+func (m model) autoReplyFrom(e email) (email, bool) {
+	addr := normalizeAddress(e.sender)
+	for _, list := range [][]email{m.emails, m.readHere} {
+		for _, r := range list {
+			if isAutoReply(r) && normalizeAddress(r.sender) == addr {
+				return r, true
+			}
+		}
+	}
+	return email{}, false
+}
+
+// awayMsg carries what an auto-reply says about when its sender is back.
+type awayMsg struct {
+	sender string
+	until  string
+}
+
+// fetchAway reads the auto-reply r from sender without marking it read, to
+// find the date in it.
+func fetchAway(p peeker, r email, sender string) tea.Cmd {
+	return func() tea.Msg {
+		content, err := p.peek(r)
+		if err != nil {
+			return awayMsg{sender: sender}
+		}
+		return awayMsg{sender: sender, until: parseAwayUntil(content.body)}
+	}
+}
+
+// warnIfAway notes in the composer that the sender of e, being replied to,
+// has sent an auto-reply, and starts finding out until when.
+func (m *model) warnIfAway(e email) tea.Cmd {
+	r, ok := m.autoReplyFrom(e)
+	if !ok {
+		return nil
+	}
+	m.composer.away = &awayMsg{sender: e.sender}
+	m.composer.setSize(m.width, m.height)
+	if cached, ok := m.cache.body(r); ok {
+		m.composer.away.until = parseAwayUntil(cached.body)
+		return nil
+	}
+	// The date isn't worth a download in headers-only mode.
+	if p, ok := m.mail().(peeker); ok && !m.metered {
+		return fetchAway(p, r, e.sender)
+	}
+	return nil
+}
+
+// warning is the composer's line about the auto-reply.
+func (a awayMsg) warning() string {
+	name := displayName(a.sender)
+	if a.until == "" {
+		return name + " has an out-of-office auto-reply on"
+	}
+	return name + " is out of office until " + a.until
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestIsAutoReply(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		headers string
+		want    bool
+	}{
+		{"automatic reply", "Subject: Automatic reply: Offsite\r\n", true},
+		{"out of office", "Subject: Out of Office: Offsite\r\n", true},
+		{"ooo", "Subject: OOO: back Monday\r\n", true},
+		{"absence", "Subject: Absence : Offsite\r\n", true},
+		{"ooo planning", "Subject: OOO planning for December\r\n", false},
+		{"absence policy", "Subject: Absence policy\r\n", false},
+		{"auto-submitted", "Subject: Re: Offsite\r\nAuto-Submitted: auto-replied\r\n", true},
+		{"x-autoreply", "Subject: Re: Offsite\r\nX-Autoreply: yes\r\n", true},
+		{"auto-generated", "Subject: Your receipt\r\nAuto-Submitted: auto-generated\r\n", false},
+	} {
+		e := parseHeaderEmail([]byte("From: ann@example.com\r\n" + tt.headers + "\r\n"))
+		if got := isAutoReply(e); got != tt.want {
+			t.Errorf("%s: isAutoReply = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseAwayUntil(t *testing.T) {
+	for body, want := range map[string]string{
+		"I'm out of the office until Monday, 20 October. For urgent matters, call Bob.": "Monday, 20 October",
+		"I am back in the office on 3 November and will reply then.":                    "3 November",
+		"Returning on Friday!":                      "Friday",
+		"I'll get back to you as soon as I can.":    "",
+		"Thanks for your mail, I'm away. Back soon": "",
+	} {
+		if got := parseAwayUntil(body); got != want {
+			t.Errorf("parseAwayUntil(%q) = %q, want %q", body, got, want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// batteryStatus is a snapshot of the power source. known is false on
+// desktops and wherever the battery can't be read.
+type batteryStatus struct {
+	known     bool
+	onBattery bool
+	percent   int
+}
+
+type batteryMsg batteryStatus
+
+// batteryCheckInterval is how often the power source is re-read. It's kept
+// well above the poll interval so the check doesn't cost what it saves.
+const batteryCheckInterval = time.Minute
+
+func checkBattery() tea.Cmd {
+	return func() tea.Msg {
+		return batteryMsg(readBattery())
+	}
+}
+
+func readBattery() batteryStatus {
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := exec.Command("pmset", "-g", "batt").Output()
+		if err != nil {
+			return batteryStatus{}
+		}
+		return parsePmset(string(out))
+	case "linux":
+		return readSysfsBattery("/sys/class/power_supply")
+	}
+	return batteryStatus{}
+}
+
+var pmsetPercent = regexp.MustCompile(`(\d+)%`)
+
+// parsePmset reads `pmset -g batt` output such as:
+//
+//	Now drawing from 'Battery Power'
+//	 -InternalBattery-0 (id=1234)	45%; discharging; 3:12 remaining
+func parsePmset(out string) batteryStatus {
+	m := pmsetPercent.FindStringSubmatch(out)
+	if m == nil {
+		return batteryStatus{}
+	}
+	pct, _ := strconv.Atoi(m[1])
+	return batteryStatus{
+		known:     true,
+		onBattery: strings.Contains(out, "'Battery Power'"),
+		percent:   pct,
+	}
+}
+
+func readSysfsBattery(dir string) batteryStatus {
+	bats, _ := filepath.Glob(filepath.Join(dir, "BAT*"))
+	if len(bats) == 0 {
+		return batteryStatus{}
+	}
+	read := func(name string) string {
+		b, _ := os.ReadFile(filepath.Join(bats[0], name))
+		return strings.TrimSpace(string(b))
+	}
+	pct, err := strconv.Atoi(read("capacity"))
+	if err != nil {
+		return batteryStatus{}
+	}
+	return batteryStatus{
+		known:     true,
+		onBattery: read("status") == "Discharging",
+		percent:   pct,
+	}
+}
+
+const (
+	defaultBatteryThreshold = 20
+	defaultBatteryInterval  = 5 * time.Minute
+)
+
+func (b batteryConfig) threshold() int {
+	if b.Threshold == nil {
+		return defaultBatteryThreshold
+	}
+	return *b.Threshold
+}
+
+// low reports whether s calls for saving power.
+func (b batteryConfig) low(s batteryStatus) bool {
+	return s.known && s.onBattery && s.percent < b.threshold()
+}
+
+// pollInterval stretches interval to the low-battery interval when s is low.
+func (b batteryConfig) pollInterval(interval time.Duration, s batteryStatus) time.Duration {
+	if !b.low(s) {
+		return interval
+	}
+	slow := b.Interval
+	if slow <= 0 {
+		slow = defaultBatteryInterval
+	}
+	return max(interval, slow)
+}
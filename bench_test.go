@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Performance budgets for the parts of a poll cycle that don't wait on a
+// backend. With MAILNOTIFY_PERF_BUDGET=1 set, TestPerfBudget fails when a
+// path exceeds its budget, so a regression in parsing or rendering shows up
+// before it's felt. It's off otherwise, since wall-clock budgets depend on
+// the machine and fail under -race.
+const (
+	budgetDecodeList  = 2 * time.Millisecond   // 20 messages from the Mail.app bridge
+	budgetParseHeader = 200 * time.Microsecond // one IMAP header block
+	budgetHTML        = 5 * time.Millisecond   // a newsletter-sized HTML body
+	budgetRender      = 20 * time.Millisecond  // the list view with 20 messages
+)
+
+func TestPerfBudget(t *testing.T) {
+	if os.Getenv("MAILNOTIFY_PERF_BUDGET") == "" {
+		t.Skip("set MAILNOTIFY_PERF_BUDGET=1 to check the timing budgets")
+	}
+	for _, c := range []struct {
+		name   string
+		bench  func(*testing.B)
+		budget time.Duration
+	}{
+		{"decodeBridgeMessages", BenchmarkDecodeBridgeMessages, budgetDecodeList},
+		{"parseHeaderEmail", BenchmarkParseHeaderEmail, budgetParseHeader},
+		{"htmlToText", BenchmarkHTMLToText, budgetHTML},
+		{"render", BenchmarkListRender, budgetRender},
+	} {
+		r := testing.Benchmark(c.bench)
+		if got := time.Duration(r.NsPerOp()); got > c.budget {
+			t.Errorf("%s: %s per op, over its %s budget", c.name, got, c.budget)
+		}
+	}
+}
+
+// BenchmarkPollIMAP measures a whole IMAP poll against a local server:
+// connecting, listing, searching, fetching 20 header blocks and parsing
+// them.
+func BenchmarkPollIMAP(b *testing.B) {
+	addr := fakeIMAPServer(b, 20)
+	host, port, _ := net.SplitHostPort(addr)
+	portNum, _ := strconv.Atoi(port)
+	p, err := newIMAPProvider(backendConfig{Host: host, Port: portNum, TLS: "none", Username: "u", Password: "p"}, mailboxConfig{}, defaultMaxUnread)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for b.Loop() {
+		emails, err := p.unread()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(emails) != 20 {
+			b.Fatalf("got %d emails, want 20", len(emails))
+		}
+	}
+}
+
+// BenchmarkPollMailApp measures a real Mail.app poll through the JXA
+// bridge. The first poll starts the bridge; later ones show what a poll
+// costs once it's running. It needs Mail.app running and
+// MAILNOTIFY_BENCH_MAILAPP=1, since it reads the actual inbox.
+func BenchmarkPollMailApp(b *testing.B) {
+	if runtime.GOOS != "darwin" || os.Getenv("MAILNOTIFY_BENCH_MAILAPP") == "" {
+		b.Skip("set MAILNOTIFY_BENCH_MAILAPP=1 on macOS to poll Mail.app")
+	}
+	p := mailAppProvider{limit: defaultMaxUnread}
+	for b.Loop() {
+		if _, err := p.unread(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeBridgeMessages(b *testing.B) {
+	msgs := make([]bridgeMessage, 20)
+	for i := range msgs {
+		msgs[i] = bridgeMessage{
+			ID:        strconv.Itoa(1000 + i),
+			Sender:    fmt.Sprintf("Jörg Example <jorg%d@example.com>", i),
+			Subject:   fmt.Sprintf("Quarterly report %d", i),
+			Date:      fmt.Sprintf("2026-10-13T10:%02d:00.000Z", i),
+			Account:   "Work",
+			Mailbox:   "INBOX",
+			To:        []string{"me@example.com", "team@example.com"},
+			Priority:  "1",
+			MessageID: fmt.Sprintf("msg%d@example.com", i),
+		}
+	}
+	data, _ := json.Marshal(msgs)
+	for b.Loop() {
+		emails, err := decodeBridgeMessages(data)
+		if err != nil || len(emails) != 20 {
+			b.Fatalf("got %d emails, want 20 (%v)", len(emails), err)
+		}
+	}
+}
+
+func BenchmarkParseHeaderEmail(b *testing.B) {
+	raw := []byte(imapHeaderBlock(1))
+	for b.Loop() {
+		parseHeaderEmail(raw)
+	}
+}
+
+func BenchmarkParseMessage(b *testing.B) {
+	raw := []byte("From: a@example.com\r\nSubject: Report\r\nContent-Type: multipart/mixed; boundary=XX\r\n\r\n" +
+		"--XX\r\nContent-Type: multipart/alternative; boundary=YY\r\n\r\n" +
+		"--YY\r\nContent-Type: text/plain\r\n\r\n" + strings.Repeat("Plain text body line.\r\n", 200) +
+		"--YY\r\nContent-Type: text/html\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n" + strings.Repeat("<p>HTML body <b>line</b>.</p>\r\n", 200) +
+		"--YY--\r\n" +
+		"--XX\r\nContent-Type: text/csv; name=\"d.csv\"\r\nContent-Disposition: attachment; filename=\"d.csv\"\r\nContent-Transfer-Encoding: base64\r\n\r\nYSxiCjEsMgo=\r\n" +
+		"--XX--\r\n")
+	for b.Loop() {
+		if _, err := parseMessage(raw); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHTMLToText(b *testing.B) {
+	var page strings.Builder
+	page.WriteString("<html><head><style>p { color: red }</style></head><body>")
+	for i := range 50 {
+		fmt.Fprintf(&page, `<h2>Story %d</h2><p>Some <b>bold</b> and <i>italic</i> text with <a href="https://example.com/%d">a link</a>.</p><ul><li>one</li><li>two</li></ul>`, i, i)
+	}
+	page.WriteString("<table><tr><td>a</td><td>b</td></tr></table></body></html>")
+	doc := page.String()
+	for b.Loop() {
+		htmlToText(doc)
+	}
+}
+
+// BenchmarkListRender measures rendering the list view with 20 messages.
+func BenchmarkListRender(b *testing.B) {
+	m := initialModel(config{State: stateConfig{Dir: b.TempDir(), CacheDir: b.TempDir()}}, mailAppProvider{})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	m = updated.(model)
+	emails := make([]email, 20)
+	for i := range emails {
+		emails[i] = email{
+			id:       strconv.Itoa(i),
+			sender:   fmt.Sprintf("Sender %d <sender%d@example.com>", i, i),
+			subject:  fmt.Sprintf("Subject line number %d", i),
+			date:     time.Now().Add(-time.Duration(i) * time.Hour).Format(time.RFC1123Z),
+			account:  "Work",
+			priority: priority(i % 3),
+		}
+	}
+	updated, _ = m.Update(emailsMsg{emails: emails})
+	m = updated.(model)
+	if !strings.Contains(m.View(), "Subject line number 0") {
+		b.Fatal("the list isn't showing")
+	}
+	for b.Loop() {
+		m.View()
+	}
+}
+
+func imapHeaderBlock(i int) string {
+	return fmt.Sprintf("From: =?UTF-8?Q?J=C3=B6rg?= <jorg%d@example.com>\r\nSubject: Quarterly report %d\r\n"+
+		"Date: Tue, 13 Oct 2026 10:00:00 +0000\r\nTo: me@example.com\r\nCc: team@example.com\r\n"+
+		"X-Priority: 3\r\nMessage-ID: <msg%d@example.com>\r\n\r\n", i, i, i)
+}
+
+// fakeIMAPServer serves an INBOX holding n unread messages on a loopback
+// port and returns its address.
+func fakeIMAPServer(tb testing.TB, n int) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeIMAP(conn, n)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveFakeIMAP(conn net.Conn, n int) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	fmt.Fprint(w, "* OK ready\r\n")
+	w.Flush()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := append(strings.Fields(line), "", "")
+		tag, cmd := fields[0], strings.ToUpper(fields[1]+" "+fields[2])
+		switch {
+		case strings.HasPrefix(cmd, "LIST"):
+			fmt.Fprint(w, "* LIST (\\HasNoChildren) \".\" \"INBOX\"\r\n")
+		case cmd == "UID SEARCH":
+			uids := make([]string, n)
+			for i := range uids {
+				uids[i] = strconv.Itoa(i + 1)
+			}
+			fmt.Fprintf(w, "* SEARCH %s\r\n", strings.Join(uids, " "))
+		case cmd == "UID FETCH":
+			for i := 1; i <= n; i++ {
+				hdr := imapHeaderBlock(i)
+				fmt.Fprintf(w, "* %d FETCH (UID %d BODY[HEADER.FIELDS (FROM)] {%d}\r\n%s)\r\n", i, i, len(hdr), hdr)
+			}
+		case cmd == "LOGOUT ":
+			fmt.Fprintf(w, "* BYE\r\n%s OK done\r\n", tag)
+			w.Flush()
+			return
+		}
+		fmt.Fprintf(w, "%s OK done\r\n", tag)
+		w.Flush()
+	}
+}
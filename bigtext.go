@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// bigFont is a 5-row block font. Each glyph is drawn with '#' for filled
+// cells; rows of a glyph share a width but glyphs may differ.
+var bigFont = map[rune][5]string{
+	'A':  {".##.", "#..#", "####", "#..#", "#..#"},
+	'B':  {"###.", "#..#", "###.", "#..#", "###."},
+	'C':  {".###", "#...", "#...", "#...", ".###"},
+	'D':  {"###.", "#..#", "#..#", "#..#", "###."},
+	'E':  {"####", "#...", "###.", "#...", "####"},
+	'F':  {"####", "#...", "###.", "#...", "#..."},
+	'G':  {".###", "#...", "#.##", "#..#", ".###"},
+	'H':  {"#..#", "#..#", "####", "#..#", "#..#"},
+	'I':  {"###", ".#.", ".#.", ".#.", "###"},
+	'J':  {"..##", "...#", "...#", "#..#", ".##."},
+	'K':  {"#..#", "#.#.", "##..", "#.#.", "#..#"},
+	'L':  {"#...", "#...", "#...", "#...", "####"},
+	'M':  {"#...#", "##.##", "#.#.#", "#...#", "#...#"},
+	'N':  {"#..#", "##.#", "#.##", "#..#", "#..#"},
+	'O':  {".##.", "#..#", "#..#", "#..#", ".##."},
+	'P':  {"###.", "#..#", "###.", "#...", "#..."},
+	'Q':  {".##.", "#..#", "#..#", "#.##", ".###"},
+	'R':  {"###.", "#..#", "###.", "#.#.", "#..#"},
+	'S':  {".###", "#...", ".##.", "...#", "###."},
+	'T':  {"#####", "..#..", "..#..", "..#..", "..#.."},
+	'U':  {"#..#", "#..#", "#..#", "#..#", ".##."},
+	'V':  {"#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W':  {"#...#", "#...#", "#.#.#", "##.##", "#...#"},
+	'X':  {"#..#", "#..#", ".##.", "#..#", "#..#"},
+	'Y':  {"#...#", ".#.#.", "..#..", "..#..", "..#.."},
+	'Z':  {"####", "...#", ".##.", "#...", "####"},
+	'0':  {".##.", "#.##", "#..#", "##.#", ".##."},
+	'1':  {".#.", "##.", ".#.", ".#.", "###"},
+	'2':  {"###.", "...#", ".##.", "#...", "####"},
+	'3':  {"###.", "...#", ".##.", "...#", "###."},
+	'4':  {"#..#", "#..#", "####", "...#", "...#"},
+	'5':  {"####", "#...", "###.", "...#", "###."},
+	'6':  {".##.", "#...", "###.", "#..#", ".##."},
+	'7':  {"####", "...#", "..#.", ".#..", ".#.."},
+	'8':  {".##.", "#..#", ".##.", "#..#", ".##."},
+	'9':  {".##.", "#..#", ".###", "...#", ".##."},
+	' ':  {"..", "..", "..", "..", ".."},
+	'.':  {".", ".", ".", ".", "#"},
+	',':  {"..", "..", "..", ".#", "#."},
+	'!':  {"#", "#", "#", ".", "#"},
+	'?':  {"###.", "...#", ".##.", "....", ".#.."},
+	'-':  {"...", "...", "###", "...", "..."},
+	'\'': {"#", "#", ".", ".", "."},
+	':':  {".", "#", ".", "#", "."},
+	'/':  {"...#", "..#.", ".#..", "#...", "...."},
+	'+':  {"...", ".#.", "###", ".#.", "..."},
+}
+
+var bigGlyphReplacer = strings.NewReplacer("#", "█", ".", " ")
+
+// bigTextWidth returns the number of columns renderBigText needs for s.
+func bigTextWidth(s string) int {
+	w := 0
+	for i, r := range bigRunes(s) {
+		if i > 0 {
+			w++
+		}
+		w += len(bigFont[r][0])
+	}
+	return w
+}
+
+// renderBigText draws s in the block font. Letters are upper-cased and
+// characters without a glyph are drawn as '?'.
+func renderBigText(s string) string {
+	var rows [5]strings.Builder
+	for i, r := range bigRunes(s) {
+		g := bigFont[r]
+		for row := range rows {
+			if i > 0 {
+				rows[row].WriteByte(' ')
+			}
+			rows[row].WriteString(bigGlyphReplacer.Replace(g[row]))
+		}
+	}
+	lines := make([]string, len(rows))
+	for i := range rows {
+		lines[i] = strings.TrimRight(rows[i].String(), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fitBigText shortens s until its big rendering fits in width columns,
+// marking the cut with an ellipsis.
+func fitBigText(s string, width int) string {
+	if bigTextWidth(s) <= width {
+		return s
+	}
+	runes := []rune(strings.TrimSpace(s))
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := strings.TrimSpace(string(runes)) + "..."
+		if bigTextWidth(candidate) <= width {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func bigRunes(s string) []rune {
+	var out []rune
+	for _, r := range s {
+		r = unicode.ToUpper(r)
+		if _, ok := bigFont[r]; !ok {
+			r = '?'
+		}
+		out = append(out, r)
+	}
+	return out
+}
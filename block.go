@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// senderBlocker is implemented by backends that can install a server- or
+// client-side rule so future mail from a sender is dealt with even when
+// mailnotify isn't running.
+type senderBlocker interface {
+	blockSender(addr string, cfg blockConfig) error
+}
+
+type senderBlockedMsg struct {
+	addr string
+	// rule reports whether a backend rule was created, as opposed to the
+	// sender only being hidden locally.
+	rule bool
+	err  error
+}
+
+// blockSender creates the backend rule for addr, when the backend has one.
+// The sender is already hidden locally by the time this runs.
+func blockSender(b senderBlocker, cfg blockConfig, addr string) tea.Cmd {
+	return func() tea.Msg {
+		if b == nil {
+			return senderBlockedMsg{addr: addr}
+		}
+		return senderBlockedMsg{addr: addr, rule: true, err: b.blockSender(addr, cfg)}
+	}
+}
+
+// blockSender adds a Mail.app rule that deletes, or moves to cfg.Mailbox,
+// every message from addr.
+func (mailAppProvider) blockSender(addr string, cfg blockConfig) error {
+	if cfg.Mailbox != "" && cfg.Account == "" {
+		return fmt.Errorf("block: mailbox %q needs an account", cfg.Mailbox)
+	}
+	script := `
+on run argv
+	set addr to item 1 of argv
+	set mbName to item 2 of argv
+	set acctName to item 3 of argv
+	tell application "Mail"
+		if mbName is "" then
+			set r to make new rule at end of rules with properties {name:"mailnotify: block " & addr, enabled:true, delete message:true, stop evaluating rules:true}
+		else
+			set r to make new rule at end of rules with properties {name:"mailnotify: block " & addr, enabled:true, should move message:true, move message:(mailbox mbName of account acctName), stop evaluating rules:true}
+		end if
+		tell r
+			make new rule condition at end of rule conditions with properties {rule type:from header, qualifier:equal to value, expression:addr}
+		end tell
+	end tell
+end run
+`
+	return exec.Command("osascript", "-e", script, addr, cfg.Mailbox, cfg.Account).Run()
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxBodyBytes is how much of a message body the detail view shows until L
+// loads the rest. Newsletters can run to megabytes, and rendering them whole
+// would stall opening the message.
+const maxBodyBytes = 256 << 10
+
+// maxLineBytes caps a line in the viewport. The viewport only draws the
+// lines in view, but it draws each of those whole, so a minified HTML part
+// on a single line would be re-cut on every frame; longer lines are broken.
+const maxLineBytes = 4 << 10
+
+// truncateBody returns the first limit bytes of s, ending at a line break
+// when there is one in the last quarter, and whether anything was cut.
+func truncateBody(s string, limit int) (string, bool) {
+	if len(s) <= limit {
+		return s, false
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if nl := strings.LastIndexByte(s[:cut], '\n'); nl > limit*3/4 {
+		cut = nl
+	}
+	return s[:cut], true
+}
+
+// tabStop is the column tabs line up on in a preformatted message, as in
+// terminals and most editors; lipgloss would draw each as four spaces.
+const tabStop = 8
+
+// expandTabs replaces the tabs in s with spaces to the next tab stop.
+func expandTabs(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range s {
+		switch r {
+		case '\t':
+			n := tabStop - col%tabStop
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		case '\n':
+			b.WriteRune(r)
+			col = 0
+		default:
+			b.WriteRune(r)
+			col++
+		}
+	}
+	return b.String()
+}
+
+var patchLine = regexp.MustCompile(`(?m)^(diff --git |@@ -\d+(,\d+)? \+\d+(,\d+)? @@)`)
+
+// looksLikePatch reports whether body carries a diff, which opens
+// preformatted so its lines stay as they were sent.
+func looksLikePatch(body string) bool {
+	return patchLine.MatchString(body)
+}
+
+// setPreformatted turns preformatted display on or off for the open
+// message. Only preformatted text is wider than the viewport, so only it
+// scrolls sideways.
+func (m *model) setPreformatted(on bool) {
+	m.preformatted = on
+	m.viewport.SetXOffset(0)
+	if on {
+		m.viewport.SetHorizontalStep(tabStop)
+	} else {
+		m.viewport.SetHorizontalStep(0)
+	}
+}
+
+// breakLongLines splits lines longer than limit bytes at rune boundaries.
+func breakLongLines(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) + len(s)/limit)
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		for len(line) > limit {
+			cut := limit
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			b.WriteString(line[:cut])
+			b.WriteByte('\n')
+			line = line[cut:]
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// bodyText is the open message's text as the detail view shows it: the
+// plain body, rendered HTML, raw markup or the raw source, cut to
+// maxBodyBytes unless the whole message was asked for. It also reports the
+// full size when it was cut.
+func (m model) bodyText() (string, int, bool) {
+	src := m.emailBody
+	switch {
+	case m.showSource:
+		src = m.emailSource
+	case m.emailHTML != "":
+		src = m.emailHTML
+	}
+	text, cut := src, false
+	if !m.fullBody {
+		text, cut = truncateBody(src, maxBodyBytes)
+	}
+	switch {
+	case m.showSource:
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	case m.emailHTML == "":
+	case m.rawHTML:
+		text = strings.TrimSpace(text)
+	default:
+		text = htmlToText(text)
+	}
+	return text, len(src), cut
+}
+
+// binarySample is how much of a body looksBinary inspects.
+const binarySample = 8 << 10
+
+// looksBinary reports whether text would show as garbage: mostly invalid
+// UTF-8, control characters or replacement characters, as from a wrong
+// charset label, or a block of base64 that was never decoded.
+func looksBinary(text string) bool {
+	sample, _ := truncateBody(text, binarySample)
+	sample = strings.TrimSpace(sample)
+	if sample == "" {
+		return false
+	}
+	total, bad := 0, 0
+	for _, r := range sample {
+		if r == utf8.RuneError || unicode.IsControl(r) && r != '\n' && r != '\t' && r != '\r' {
+			bad++
+		}
+		total++
+	}
+	if bad*10 > total {
+		return true
+	}
+	return looksBase64(sample)
+}
+
+// looksBase64 reports whether most of text's lines are long runs of base64
+// characters with no spaces, as an undecoded attachment or body is.
+func looksBase64(text string) bool {
+	lines := strings.Split(text, "\n")
+	if len(lines) < 4 {
+		return false
+	}
+	encoded := 0
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if len(line) >= 60 && strings.Trim(line, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/=") == "" {
+			encoded++
+		}
+	}
+	return encoded*10 >= len(lines)*8
+}
+
+// unreadableNotice stands in for a body looksBinary rejected, offering the
+// ways left to read the message.
+func (m model) unreadableNotice() string {
+	lines := []string{
+		headerStyle.Render("This message can't be displayed"),
+		"",
+		"Its text is mostly binary or unprintable, which usually means a wrong",
+		"charset label or an attachment that was never decoded.",
+		"",
+	}
+	keys := m.cfg.keys
+	if m.emailSource != "" {
+		lines = append(lines, "  "+keys.label(detailView, "S")+"  view the raw source")
+	}
+	if m.currentEmail != nil && canOpenInMail(*m.currentEmail) {
+		lines = append(lines, "  "+keys.label(detailView, "O")+"  open it in Mail.app")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// canOpenInMail reports whether e can be shown in Mail.app, which finds
+// messages by Message-ID.
+func canOpenInMail(e email) bool {
+	return runtime.GOOS == "darwin" && e.messageID != ""
+}
+
+type openedInMailMsg struct {
+	err error
+}
+
+// openInMail shows e in a Mail.app window through its message: URL, which
+// activates Mail.app.
+func openInMail(e email) tea.Cmd {
+	return func() tea.Msg {
+		return openedInMailMsg{err: exec.Command("open", messageURL(e.messageID)).Run()}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// cacheFile holds the last unread list and the bodies of messages opened
+// since, so the list shows instantly on startup and messages already read
+// can be reopened offline. It's a cache, so it lives per machine rather
+// than in the state directory.
+const cacheFile = "cache.json"
+
+// maxCachedBodies bounds how many opened messages are kept.
+const maxCachedBodies = 50
+
+// mailCache is the on-disk cache.
+type mailCache struct {
+	Saved  time.Time     `json:"saved"`
+	Emails []cachedEmail `json:"emails"`
+	// Total is how much unread mail there was; the list holds less when
+	// the poll left some out.
+	Total  int                   `json:"total,omitempty"`
+	Bodies map[string]cachedBody `json:"bodies,omitempty"`
+
+	path string
+}
+
+type cachedEmail struct {
+	ID          string    `json:"id"`
+	Sender      string    `json:"sender"`
+	Subject     string    `json:"subject"`
+	Date        string    `json:"date"`
+	Account     string    `json:"account,omitempty"`
+	To          []string  `json:"to,omitempty"`
+	Cc          []string  `json:"cc,omitempty"`
+	Priority    priority  `json:"priority,omitempty"`
+	MessageID   string    `json:"message_id,omitempty"`
+	Flag        flagColor `json:"flag,omitempty"`
+	Size        int       `json:"size,omitempty"`
+	Mailbox     string    `json:"mailbox,omitempty"`
+	Automated   bool      `json:"automated,omitempty"`
+	AutoReplied bool      `json:"auto_replied,omitempty"`
+	ThreadRoot  string    `json:"thread_root,omitempty"`
+}
+
+type cachedBody struct {
+	Body        string             `json:"body"`
+	HTML        string             `json:"html,omitempty"`
+	Attachments []cachedAttachment `json:"attachments,omitempty"`
+	Viewed      time.Time          `json:"viewed"`
+}
+
+type cachedAttachment struct {
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+	Text     string `json:"text,omitempty"`
+}
+
+// defaultCacheDir is where mailnotify keeps data it can rebuild, such as
+// the message cache, unless [state] cache_dir moves it.
+func defaultCacheDir() string {
+	dirs := daemonStateDirs()
+	if len(dirs) == 0 {
+		return ""
+	}
+	return dirs[0]
+}
+
+// loadMailCache reads the cache in dir. A missing or unreadable cache
+// starts empty.
+func loadMailCache(dir string) *mailCache {
+	c := &mailCache{Bodies: map[string]cachedBody{}}
+	if dir == "" {
+		return c
+	}
+	c.path = filepath.Join(dir, cacheFile)
+	if data, err := os.ReadFile(c.path); err == nil {
+		json.Unmarshal(data, c)
+	}
+	if c.Bodies == nil {
+		c.Bodies = map[string]cachedBody{}
+	}
+	return c
+}
+
+// emails returns the cached unread list.
+func (c *mailCache) emails() []email {
+	out := make([]email, len(c.Emails))
+	for i, e := range c.Emails {
+		out[i] = email{
+			id:          e.ID,
+			sender:      e.Sender,
+			subject:     e.Subject,
+			date:        e.Date,
+			account:     e.Account,
+			to:          e.To,
+			cc:          e.Cc,
+			priority:    e.Priority,
+			messageID:   e.MessageID,
+			flag:        e.Flag,
+			size:        e.Size,
+			mailbox:     e.Mailbox,
+			automated:   e.Automated,
+			autoReplied: e.AutoReplied,
+			threadRoot:  e.ThreadRoot,
+		}
+	}
+	return out
+}
+
+// setEmails replaces the cached list with a poll's result, out of total
+// unread, and saves it.
+func (c *mailCache) setEmails(emails []email, total int) error {
+	c.Total = max(total, len(emails))
+	c.Emails = make([]cachedEmail, len(emails))
+	for i, e := range emails {
+		c.Emails[i] = cachedEmail{
+			ID:          e.id,
+			Sender:      e.sender,
+			Subject:     e.subject,
+			Date:        e.date,
+			Account:     e.account,
+			To:          e.to,
+			Cc:          e.cc,
+			Priority:    e.priority,
+			MessageID:   e.messageID,
+			Flag:        e.flag,
+			Size:        e.size,
+			Mailbox:     e.mailbox,
+			Automated:   e.automated,
+			AutoReplied: e.autoReplied,
+			ThreadRoot:  e.threadRoot,
+		}
+	}
+	c.Saved = time.Now()
+	return c.save()
+}
+
+// complete returns the cached list when a poll within maxAge listed all the
+// unread mail.
+func (c *mailCache) complete(maxAge time.Duration) ([]email, bool) {
+	if c.Saved.IsZero() || time.Since(c.Saved) > maxAge || len(c.Emails) < c.Total {
+		return nil, false
+	}
+	return c.emails(), true
+}
+
+// body returns the cached content of e, if it was opened before.
+func (c *mailCache) body(e email) (messageContent, bool) {
+	b, ok := c.Bodies[emailKey(e)]
+	if !ok {
+		return messageContent{}, false
+	}
+	content := messageContent{id: e.id, body: b.Body, html: b.HTML}
+	for _, a := range b.Attachments {
+		content.attachments = append(content.attachments, attachment{name: a.Name, mimeType: a.MIMEType, size: a.Size, text: a.Text})
+	}
+	return content, true
+}
+
+// setBody remembers an opened message's content and saves the cache,
+// dropping the longest-unviewed bodies past maxCachedBodies.
+func (c *mailCache) setBody(e email, content messageContent) error {
+	b := cachedBody{Body: content.body, HTML: content.html, Viewed: time.Now()}
+	for _, a := range content.attachments {
+		b.Attachments = append(b.Attachments, cachedAttachment{Name: a.name, MIMEType: a.mimeType, Size: a.size, Text: a.text})
+	}
+	c.Bodies[emailKey(e)] = b
+	if len(c.Bodies) > maxCachedBodies {
+		keys := make([]string, 0, len(c.Bodies))
+		for k := range c.Bodies {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return c.Bodies[keys[i]].Viewed.After(c.Bodies[keys[j]].Viewed) })
+		for _, k := range keys[maxCachedBodies:] {
+			delete(c.Bodies, k)
+		}
+	}
+	return c.save()
+}
+
+// save writes the cache through a temporary file, so a crash mid-write
+// leaves the previous cache intact.
+func (c *mailCache) save() error {
+	if c.path == "" {
+		return nil
+	}
+	return writeStateFile(filepath.Dir(c.path), filepath.Base(c.path), c)
+}
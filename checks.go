@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// errAutomationDenied is osascript's error -1743: this terminal hasn't been
+// allowed to control Mail.app.
+var errAutomationDenied = errors.New("automation permission not granted")
+
+// automationPane is the Automation page of System Settings' Privacy &
+// Security section.
+const automationPane = "x-apple.systempreferences:com.apple.preference.security?Privacy_Automation"
+
+// mailRunning reports whether Mail.app is open, without launching it.
+func mailRunning() (bool, error) {
+	out, err := exec.Command("osascript", "-e", `application "Mail" is running`).Output()
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(out)) == "true", nil
+}
+
+// mailAccounts counts Mail.app's accounts, which needs the automation
+// permission. The first call asks the user for it.
+func mailAccounts() (int, error) {
+	out, err := exec.Command("osascript", "-e", `tell application "Mail" to count of accounts`).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(out), "-1743") {
+			return 0, errAutomationDenied
+		}
+		return 0, errors.New(strings.TrimSpace(string(out)))
+	}
+	return strconv.Atoi(strings.TrimSpace(string(out)))
+}
+
+// check is one thing a backend needs to work, and what to do when it's
+// missing.
+type check struct {
+	name string
+	err  error
+	// fix says how to put err right.
+	fix string
+	// pane is a System Settings page that fixes it, which o opens.
+	pane string
+}
+
+// checker is implemented by backends that can say what's wrong with their
+// setup, which a failed poll's error often doesn't.
+type checker interface {
+	// checks runs the checks in order, stopping at the first that fails
+	// since the rest depend on it.
+	checks() []check
+}
+
+func (mailAppProvider) checks() []check {
+	if runtime.GOOS != "darwin" {
+		return []check{{name: "macOS", err: errors.New("Mail.app is only on macOS"), fix: "Use the imap or maildir backend in [backend]."}}
+	}
+	running, err := mailRunning()
+	if err == nil && !running {
+		err = errors.New("Mail.app isn't open")
+	}
+	if err != nil {
+		return []check{{name: "Mail.app running", err: err, fix: "Open Mail.app; mailnotify doesn't launch it."}}
+	}
+	done := []check{{name: "Mail.app running"}}
+	n, err := mailAccounts()
+	switch {
+	case errors.Is(err, errAutomationDenied):
+		return append(done, check{
+			name: "Automation permission",
+			err:  err,
+			fix:  "In System Settings → Privacy & Security → Automation, allow your terminal to control Mail. A terminal that was never asked isn't listed; quit and reopen it to be asked again.",
+			pane: automationPane,
+		})
+	case err != nil:
+		return append(done, check{name: "Automation permission", err: err})
+	}
+	done = append(done, check{name: "Automation permission"})
+	if n == 0 {
+		return append(done, check{name: "Mail.app accounts", err: errors.New("no accounts"), fix: "Add an account in Mail.app's settings."})
+	}
+	return append(done, check{name: "Mail.app accounts"})
+}
+
+func (p imapProvider) checks() []check {
+	conn, err := net.DialTimeout("tcp", p.addr(), imapTimeout)
+	if err != nil {
+		return []check{{name: "Server reachable at " + p.addr(), err: err, fix: "Check host and port in [backend], and that you're online."}}
+	}
+	conn.Close()
+	done := []check{{name: "Server reachable at " + p.addr()}}
+	c, err := p.connect()
+	if err != nil {
+		return append(done, check{name: "Login as " + p.cfg.Username, err: err, fix: "Check tls, username and password in [backend]."})
+	}
+	c.logout()
+	return append(done, check{name: "Login as " + p.cfg.Username})
+}
+
+func (p maildirProvider) checks() []check {
+	if _, err := p.monitoredMailboxes(); err != nil {
+		return []check{{name: "Maildir at " + p.root, err: err, fix: "Check the path in [backend]."}}
+	}
+	return []check{{name: "Maildir at " + p.root}}
+}
+
+// checksMsg carries the results of runChecks.
+type checksMsg []check
+
+func (c checksMsg) failed() bool {
+	for _, r := range c {
+		if r.err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func runChecks(c checker) tea.Cmd {
+	return func() tea.Msg {
+		return checksMsg(c.checks())
+	}
+}
+
+// startupChecks checks the backend's setup as mailnotify starts, so a first
+// run that's missing a permission says which instead of showing the error
+// the poll gets.
+func (m model) startupChecks() tea.Cmd {
+	if c, ok := m.provider.(checker); ok {
+		return runChecks(c)
+	}
+	return nil
+}
+
+// applyChecks shows the setup screen when a check failed, from the list, or
+// goes back to the list and polls once they pass there.
+func (m *model) applyChecks(msg checksMsg) tea.Cmd {
+	retest := m.finish(checkingSetup)
+	m.checks = msg
+	switch {
+	case msg.failed() && m.mode == listView:
+		m.mode = checksView
+	case !msg.failed() && m.mode == checksView && retest:
+		m.mode = listView
+		m.setNotice("Setup looks good")
+		return m.refresh()
+	}
+	return nil
+}
+
+// openChecks runs the checks again and shows their results.
+func (m *model) openChecks() tea.Cmd {
+	c, ok := m.provider.(checker)
+	if !ok {
+		m.setNotice(m.provider.name() + " has no setup to check")
+		return nil
+	}
+	m.mode = checksView
+	return tea.Batch(runChecks(c), m.begin(checkingSetup))
+}
+
+// checksKeys handles keys on the setup screen.
+func (m *model) checksKeys(key string) (tea.Cmd, bool) {
+	switch key {
+	case "r":
+		return m.openChecks(), true
+	case "o":
+		for _, c := range m.checks {
+			if c.err != nil && c.pane != "" {
+				return openLink(c.pane), true
+			}
+		}
+	case "esc":
+		m.mode = listView
+	case "q":
+		return m.quit(), true
+	}
+	return nil, true
+}
+
+func (m *model) updateChecks(tea.Msg) tea.Cmd {
+	return nil
+}
+
+func (m model) viewChecks(status string) string {
+	width := max(min(m.width-8, 72), 20)
+	var lines []string
+	pane := false
+	for _, c := range m.checks {
+		if c.err == nil {
+			lines = append(lines, senderStyle.Render("✓ ")+bodyStyle.Render(c.name))
+			continue
+		}
+		lines = append(lines, lipgloss.NewStyle().Foreground(errorColor).Render("✗ ")+bodyStyle.Render(c.name)+metaStyle.Render(": "+c.err.Error()))
+		if c.fix != "" {
+			lines = append(lines, lipgloss.NewStyle().Width(width).PaddingLeft(2).Render(dateStyle.Render(c.fix)))
+		}
+		pane = pane || c.pane != ""
+	}
+	title := headerStyle.Render("Setting up "+m.provider.name()) + "\n" + metaStyle.Render("mailnotify can't read mail until this is fixed")
+	bindings := [][]string{{"r", "test again"}}
+	if pane {
+		bindings = append(bindings, []string{"o", "open System Settings"})
+	}
+	bindings = append(bindings, []string{"esc", "continue anyway"}, []string{"q", "quit"})
+	body := lipgloss.NewStyle().Padding(1, 2).Render(title + "\n\n" + strings.Join(lines, "\n"))
+	gap := max(m.height-lipgloss.Height(body)-lipgloss.Height(status)-1, 0)
+	return body + strings.Repeat("\n", gap) + "\n" + status + renderHelpBar(m.width, bindings)
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// outgoingMessage is what the composer hands to the backend. draftID names
+// the draft it was resumed from, if any, so it can be replaced; inReplyTo is
+// the message being answered, so the reply is threaded with it.
+// autoSubmitted marks a message sent without the user, which RFC 3834 says
+// carries "Auto-Submitted: auto-replied".
+type outgoingMessage struct {
+	draftID       string
+	to            []string
+	subject       string
+	body          string
+	inReplyTo     email
+	autoSubmitted bool
+}
+
+// Composer fields, in tab order.
+const (
+	composeTo = iota
+	composeSubject
+	composeBody
+	numComposeFields
+)
+
+// composer edits a new message, a reply or a resumed draft.
+type composer struct {
+	to        textinput.Model
+	subject   textinput.Model
+	body      textarea.Model
+	focus     int
+	draftID   string
+	inReplyTo email
+	// away is set when the person replied to has sent an auto-reply;
+	// warned once the first ctrl+s has pointed it out.
+	away   *awayMsg
+	warned bool
+}
+
+func newComposer(d draft, body string) composer {
+	to := textinput.New()
+	to.Prompt = ""
+	to.Placeholder = "alice@example.com, bob@example.com"
+	to.SetValue(strings.Join(d.to, ", "))
+
+	subject := textinput.New()
+	subject.Prompt = ""
+	subject.SetValue(d.subject)
+
+	ta := textarea.New()
+	ta.ShowLineNumbers = false
+	ta.Prompt = ""
+	ta.CharLimit = 0
+	ta.SetValue(body)
+
+	c := composer{to: to, subject: subject, body: ta, draftID: d.id}
+	c.setFocus(composeTo)
+	return c
+}
+
+// newReply starts a reply to e, whose text is body: addressed to the
+// sender, with "Re:" on the subject and the original quoted below the
+// cursor.
+func newReply(e email, body string) composer {
+	c := newComposer(draft{to: []string{e.sender}, subject: replySubject(e.subject)}, "\n\n"+quoteBody(e, body))
+	c.inReplyTo = e
+	c.setFocus(composeBody)
+	for i := c.body.LineCount(); i > 0; i-- {
+		c.body.CursorUp()
+	}
+	c.body.CursorStart()
+	return c
+}
+
+// replySubject is subject with "Re: " in front, unless it's there already.
+func replySubject(subject string) string {
+	if strings.HasPrefix(strings.ToLower(subject), "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+// quoteBody prefixes each line of body with "> " under an attribution line.
+func quoteBody(e email, body string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "On %s, %s wrote:\n", e.date, e.sender)
+	for _, line := range strings.Split(strings.TrimRight(body, "\n"), "\n") {
+		if strings.HasPrefix(line, ">") {
+			b.WriteString(">" + line + "\n")
+		} else {
+			b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+		}
+	}
+	return b.String()
+}
+
+func (c *composer) setFocus(field int) {
+	c.focus = (field + numComposeFields) % numComposeFields
+	c.to.Blur()
+	c.subject.Blur()
+	c.body.Blur()
+	switch c.focus {
+	case composeTo:
+		c.to.Focus()
+	case composeSubject:
+		c.subject.Focus()
+	default:
+		c.body.Focus()
+	}
+}
+
+func (c *composer) setSize(width, height int) {
+	inner := width - 16
+	if inner < 20 {
+		inner = 20
+	}
+	c.to.Width = inner
+	c.subject.Width = inner
+	c.body.SetWidth(width - 8)
+	chrome := 14
+	if c.away != nil {
+		chrome++
+	}
+	if height-chrome > 3 {
+		c.body.SetHeight(height - chrome)
+	}
+}
+
+func (c composer) message() outgoingMessage {
+	return outgoingMessage{
+		draftID:   c.draftID,
+		to:        splitAddresses(c.to.Value()),
+		subject:   strings.TrimSpace(c.subject.Value()),
+		body:      c.body.Value(),
+		inReplyTo: c.inReplyTo,
+	}
+}
+
+// mailtoURL is msg as a mailto: link (RFC 6068), for handing it to the
+// default mail client.
+func mailtoURL(msg outgoingMessage) string {
+	escape := func(s string) string {
+		// Mail clients read + literally, so spaces are %20.
+		return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+	}
+	to := make([]string, len(msg.to))
+	for i, addr := range msg.to {
+		to[i] = url.PathEscape(addr)
+	}
+	var query []string
+	if msg.subject != "" {
+		query = append(query, "subject="+escape(msg.subject))
+	}
+	if msg.body != "" {
+		query = append(query, "body="+escape(strings.ReplaceAll(msg.body, "\n", "\r\n")))
+	}
+	if id := msg.inReplyTo.messageID; id != "" {
+		query = append(query, "In-Reply-To="+escape("<"+strings.Trim(id, "<>")+">"))
+	}
+	u := "mailto:" + strings.Join(to, ",")
+	if len(query) > 0 {
+		u += "?" + strings.Join(query, "&")
+	}
+	return u
+}
+
+type handedOffMsg struct {
+	err error
+}
+
+// handOff opens msg in the default mail client.
+func handOff(msg outgoingMessage) tea.Cmd {
+	u := mailtoURL(msg)
+	return func() tea.Msg {
+		return handedOffMsg{err: exec.Command(systemOpener(), u).Run()}
+	}
+}
+
+func (c composer) Update(msg tea.Msg) (composer, tea.Cmd) {
+	if k, ok := msg.(tea.KeyMsg); ok {
+		switch k.String() {
+		case "tab":
+			c.setFocus(c.focus + 1)
+			return c, nil
+		case "shift+tab":
+			c.setFocus(c.focus - 1)
+			return c, nil
+		case "enter":
+			// Enter moves on from the header fields and is a newline in the body.
+			if c.focus != composeBody {
+				c.setFocus(c.focus + 1)
+				return c, nil
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	switch c.focus {
+	case composeTo:
+		c.to, cmd = c.to.Update(msg)
+	case composeSubject:
+		c.subject, cmd = c.subject.Update(msg)
+	default:
+		c.body, cmd = c.body.Update(msg)
+	}
+	return c, cmd
+}
+
+func (c composer) View(width int) string {
+	boxWidth := width - 4
+	if boxWidth < 20 {
+		boxWidth = 20
+	}
+	title := "New Message"
+	switch {
+	case c.draftID != "":
+		title = "Edit Draft"
+	case c.inReplyTo.id != "":
+		title = "Reply"
+	}
+	label := func(s string, field int) string {
+		style := metaStyle
+		if c.focus == field {
+			style = senderStyle.Bold(true)
+		}
+		return style.Render(s)
+	}
+
+	content := headerStyle.Render(title) + "\n" +
+		label("To:      ", composeTo) + c.to.View() + "\n" +
+		label("Subject: ", composeSubject) + c.subject.View() + "\n"
+	if c.away != nil {
+		content += dateStyle.Render("⚠ "+c.away.warning()) + "\n"
+	}
+	content += dividerStyle.Render(strings.Repeat("─", boxWidth-4)) + "\n\n" +
+		c.body.View()
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(accentColor).
+		Padding(1, 2).
+		Width(boxWidth)
+	return lipgloss.NewStyle().PaddingLeft(2).Render(box.Render(content))
+}
+
+// composerKeys handles the composer's own bindings. Every other key is
+// typed into the focused field.
+func (m *model) composerKeys(key string) (tea.Cmd, bool) {
+	switch key {
+	case "esc":
+		m.mode = m.composeFrom
+		m.setNotice("Discarded changes")
+		return nil, true
+	case "ctrl+s":
+		if c := &m.composer; c.away != nil && !c.warned {
+			c.warned = true
+			m.setNotice(c.away.warning() + " • ctrl+s again to send anyway")
+			return nil, true
+		}
+		return tea.Batch(m.track(sendOutgoing(m.draftStore(), m.composer.message())), m.begin(savingDraft)), true
+	case "ctrl+o":
+		return tea.Batch(m.track(saveOutgoing(m.draftStore(), m.composer.message())), m.begin(savingDraft)), true
+	case "ctrl+g":
+		return handOff(m.composer.message()), true
+	}
+	return nil, false
+}
+
+func (m *model) updateComposer(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	m.composer, cmd = m.composer.Update(msg)
+	return cmd
+}
+
+func (m model) viewComposer(status string) string {
+	helpBar := renderHelpBar(m.width, [][]string{
+		{"tab", "next field"},
+		{"ctrl+s", "send"},
+		{"ctrl+o", "save draft"},
+		{"ctrl+g", "open in mail app"},
+		{"esc", "discard changes"},
+	})
+	return "\n" + m.composer.View(m.width) + "\n" + status + helpBar
+}
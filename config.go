@@ -0,0 +1,624 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+type config struct {
+	Backend     backendConfig     `toml:"backend"`
+	Accounts    []backendConfig   `toml:"accounts"`
+	Poll        pollConfig        `toml:"poll"`
+	Theme       themeConfig       `toml:"theme"`
+	Filter      filterConfig      `toml:"filter"`
+	Identity    identityConfig    `toml:"identity"`
+	Notes       notesConfig       `toml:"notes"`
+	Ticket      ticketConfig      `toml:"ticket"`
+	Update      updateConfig      `toml:"update"`
+	Mailboxes   mailboxConfig     `toml:"mailboxes"`
+	Schedule    scheduleConfig    `toml:"schedule"`
+	Battery     batteryConfig     `toml:"battery"`
+	Metered     meteredConfig     `toml:"metered"`
+	Attachments attachmentsConfig `toml:"attachments"`
+	Notify      notifyConfig      `toml:"notify"`
+	Senders     sendersConfig     `toml:"senders"`
+	Block       blockConfig       `toml:"block"`
+	Rules       []ruleConfig      `toml:"rules"`
+	Strip       []stripConfig     `toml:"strip"`
+	Dates       datesConfig       `toml:"dates"`
+	List        listConfig        `toml:"list"`
+	Read        readConfig        `toml:"read"`
+	Translate   translateConfig   `toml:"translate"`
+	Speech      speechConfig      `toml:"speech"`
+	Keys        map[string]string `toml:"keys"`
+	Confirm     map[string]string `toml:"confirm"`
+	Staging     stagingConfig     `toml:"staging"`
+	State       stateConfig       `toml:"state"`
+	API         apiConfig         `toml:"api"`
+	Status      statusConfig      `toml:"status"`
+	Shortcuts   shortcutsConfig   `toml:"shortcuts"`
+
+	// path is the file the config was loaded from.
+	path string
+	// schedule is Schedule parsed.
+	schedule schedule
+	// keys is Keys parsed.
+	keys keyMap
+	// rules is Rules parsed.
+	rules rules
+	// strip is Strip parsed.
+	strip strippers
+	// confirm is Confirm parsed.
+	confirm confirmPolicies
+	// metered is Metered.Mode parsed.
+	metered meteredMode
+	// times is Dates.Style parsed.
+	times timeStyle
+	// batch is Poll.Batch parsed.
+	batch batchMode
+	// dryRun is set by --dry-run: the provider only logs what would change
+	// the mailbox.
+	dryRun bool
+}
+
+// pollInterval is how often the daemon polls: the batch interval in batch
+// mode, otherwise Poll.Interval.
+func (c config) pollInterval() time.Duration {
+	if c.batch.every > 0 {
+		return c.batch.every
+	}
+	return c.Poll.interval()
+}
+
+// jittered is d moved by up to a tenth either way. The daemon, the TUI and
+// status bars started together would otherwise poll the backend in step.
+func jittered(d time.Duration) time.Duration {
+	spread := int64(d / 5)
+	if spread <= 0 {
+		return d
+	}
+	return d - d/10 + time.Duration(rand.Int64N(spread))
+}
+
+type statusConfig struct {
+	// Template is the text/template `mailnotify status` prints, executed
+	// against statusData. Defaults to defaultStatusTemplate.
+	Template string `toml:"template"`
+	// ExitMail, ExitNone and ExitError are its exit codes when there is
+	// unread mail, when there is none and when the poll failed. They
+	// default to 0, 1 and 2.
+	ExitMail  *int `toml:"exit_mail"`
+	ExitNone  *int `toml:"exit_none"`
+	ExitError *int `toml:"exit_error"`
+}
+
+func (s statusConfig) template() string {
+	if s.Template == "" {
+		return defaultStatusTemplate
+	}
+	return s.Template
+}
+
+func exitCode(code *int, def int) int {
+	if code == nil {
+		return def
+	}
+	return *code
+}
+
+func (s statusConfig) exitMail() int  { return exitCode(s.ExitMail, 0) }
+func (s statusConfig) exitNone() int  { return exitCode(s.ExitNone, 1) }
+func (s statusConfig) exitError() int { return exitCode(s.ExitError, 2) }
+
+type apiConfig struct {
+	// Listen is the address the daemon serves its HTTP API on, such as
+	// "127.0.0.1:8025". The API is off when it's empty.
+	Listen string `toml:"listen"`
+	// Token is the bearer token requests must carry. It may be a secret
+	// reference such as "keychain:mailnotify-api".
+	Token string `toml:"token"`
+}
+
+type stateConfig struct {
+	// Dir holds local state such as the sender history. Point it at a
+	// synced folder to share it between machines. Defaults to
+	// ~/.local/state/mailnotify.
+	Dir string `toml:"dir"`
+	// CacheDir holds the message cache. It's per machine, so it shouldn't
+	// be synced. Defaults to ~/.cache/mailnotify.
+	CacheDir string `toml:"cache_dir"`
+}
+
+func (s stateConfig) dir() string {
+	if s.Dir != "" {
+		return expandHome(s.Dir)
+	}
+	return defaultStateDir()
+}
+
+func (s stateConfig) cacheDir() string {
+	if s.CacheDir != "" {
+		return expandHome(s.CacheDir)
+	}
+	return defaultCacheDir()
+}
+
+// loadStateFile reads name in dir as JSON. A missing file, or no dir, is
+// T's zero value. A file that doesn't parse is an error, and the caller
+// mustn't then save over it, which would throw away what's in it.
+func loadStateFile[T any](dir, name string) (T, error) {
+	var v T
+	if dir == "" {
+		return v, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		var zero T
+		return zero, fmt.Errorf("%s is damaged, so it's left as it is: %w", filepath.Join(dir, name), err)
+	}
+	return v, nil
+}
+
+// writeStateFile writes v as JSON to name in dir, creating dir if need be.
+// It's written to a temporary file in dir and renamed over name, so a
+// reader or a crash never sees half of it, and two writers at once can't
+// rename each other's half-written file. The file is replaced whole, so
+// between machines sharing a synced state directory the last to write it
+// wins; only the sender history is kept in per-machine journals.
+func writeStateFile(dir, name string, v any) error {
+	if dir == "" {
+		return fmt.Errorf("no state directory")
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(dir, name))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+type pollConfig struct {
+	// Interval is how often to poll, such as "30s". Defaults to 10s.
+	Interval time.Duration `toml:"interval"`
+	// Max caps how many unread messages a poll returns, and how many more
+	// L loads at a time. Defaults to 20. With Threads it counts
+	// conversations instead.
+	Max int `toml:"max"`
+	// Threads groups the list by conversation.
+	Threads bool `toml:"threads"`
+	// Batch checks for mail in batches instead of continuously: "manual"
+	// only when asked with r, or an interval of at least a minute such as
+	// "1h". Empty or "off" polls every Interval as usual.
+	Batch string `toml:"batch"`
+}
+
+// batchMode is Poll.Batch parsed.
+type batchMode struct {
+	on bool
+	// every is the interval between checks, or 0 when they're only made on
+	// request.
+	every time.Duration
+}
+
+// manual reports whether mail is only checked on request.
+func (b batchMode) manual() bool {
+	return b.on && b.every == 0
+}
+
+func parseBatch(s string) (batchMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "off":
+		return batchMode{}, nil
+	case "manual":
+		return batchMode{on: true}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return batchMode{}, fmt.Errorf("%q is not off, manual or a duration such as 1h", s)
+	}
+	if d < time.Minute {
+		return batchMode{}, fmt.Errorf("%q is under a minute", s)
+	}
+	return batchMode{on: true, every: d}, nil
+}
+
+func (p pollConfig) interval() time.Duration {
+	if p.Interval <= 0 {
+		return defaultPollInterval
+	}
+	return p.Interval
+}
+
+func (p pollConfig) max() int {
+	if p.Max <= 0 {
+		return defaultMaxUnread
+	}
+	return p.Max
+}
+
+// fetchLimit is how many messages a poll asks the backend for: max, or
+// enough for max conversations of a few messages each when they're
+// grouped.
+func (p pollConfig) fetchLimit() int {
+	if p.Threads {
+		return p.max() * threadFetchFactor
+	}
+	return p.max()
+}
+
+type notifyConfig struct {
+	// Enabled turns the daemon's new-mail notifications on. Defaults to
+	// true.
+	Enabled *bool `toml:"enabled"`
+	// Tool is "terminal-notifier", "osascript" or "notify-send". Defaults
+	// to terminal-notifier when it's installed, then the platform's own.
+	Tool string `toml:"tool"`
+	// Launch opens the TUI in a new Terminal window when new mail arrives.
+	Launch bool `toml:"launch"`
+	// Sound is played when a poll in the TUI brings new mail: a sound file,
+	// or on macOS the name of a system sound such as "Glass".
+	Sound string `toml:"sound"`
+	// Bell rings the terminal bell when a poll in the TUI brings new mail.
+	Bell bool `toml:"bell"`
+}
+
+type attachmentsConfig struct {
+	// Dir is where saved attachments go. Defaults to ~/Downloads.
+	Dir string `toml:"dir"`
+	// Scanner is a shell command each attachment is piped through before
+	// it's saved, such as "clamscan --no-summary -". A nonzero exit blocks
+	// the save.
+	Scanner string `toml:"scanner"`
+}
+
+type batteryConfig struct {
+	// Threshold is the charge, in percent, below which polling slows down
+	// while on battery. Defaults to 20; 0 disables it.
+	Threshold *int `toml:"threshold"`
+	// Interval is the poll interval while the battery is low, such as
+	// "10m". Defaults to 5m.
+	Interval time.Duration `toml:"interval"`
+}
+
+type shortcutsConfig struct {
+	// CallbackSchemes are the URL schemes x-success and x-error may open.
+	// Defaults to ["shortcuts"]; web and file URLs are only opened when
+	// listed.
+	CallbackSchemes []string `toml:"callback_schemes"`
+}
+
+type meteredConfig struct {
+	// Mode is "off", the default; "on" to start in headers-only mode; or
+	// "auto" to switch to it while the connection is metered.
+	Mode string `toml:"mode"`
+}
+
+type scheduleConfig struct {
+	// Windows limits automatic polling to these weekly time ranges, such as
+	// "mon-fri 09:00-18:00". Polling is unrestricted when empty.
+	Windows []string `toml:"windows"`
+}
+
+type backendConfig struct {
+	// Type is "mail.app" (the default on macOS), "imap" or "maildir".
+	Type string `toml:"type"`
+	// Host and Port locate the IMAP server. Port defaults to 993, or 143
+	// without implicit TLS.
+	Host string `toml:"host"`
+	Port int    `toml:"port"`
+	// TLS is "tls" (the default), "starttls" or "none", which is only
+	// allowed for a loopback host since LOGIN then goes out unencrypted.
+	TLS      string `toml:"tls"`
+	Username string `toml:"username"`
+	// Password may be a secret reference such as "keychain:imap".
+	Password string `toml:"password"`
+	// Path is the root of a Maildir tree, such as "~/Mail/Fastmail".
+	Path string `toml:"path"`
+	// Account labels the messages, and names the account in [[accounts]].
+	// Defaults to the last element of Path for a Maildir, or to the
+	// username for IMAP.
+	Account string `toml:"account"`
+	// Timeout is how long a poll waits on this one of [[accounts]] before
+	// listing the others without it. Defaults to 30s.
+	Timeout time.Duration `toml:"timeout"`
+}
+
+type mailboxConfig struct {
+	// All sweeps every mailbox of every account instead of just the
+	// unified inbox.
+	All bool `toml:"all"`
+	// Include limits the sweep to mailboxes matching these globs, such as
+	// "INBOX" or "Lists/*". Setting it implies All.
+	Include []string `toml:"include"`
+	// Exclude lists mailbox globs the sweep skips. Defaults to
+	// defaultExcludedMailboxes when unset.
+	Exclude []string `toml:"exclude"`
+	// Archive is the mailbox archived messages are moved to, in the
+	// message's own account. Defaults to "Archive", or on IMAP the
+	// server's advertised archive mailbox.
+	Archive string `toml:"archive"`
+}
+
+type updateConfig struct {
+	// Check enables the startup release check and `mailnotify update`.
+	// Defaults to true.
+	Check *bool `toml:"check"`
+}
+
+func (u updateConfig) enabled() bool {
+	return u.Check == nil || *u.Check
+}
+
+type notesConfig struct {
+	// Target is "file" (the default) or "apple-notes".
+	Target string `toml:"target"`
+	// Path is the Markdown file entries are appended to.
+	Path string `toml:"path"`
+	// Note is the Apple Notes note entries are appended to.
+	Note string `toml:"note"`
+}
+
+type blockConfig struct {
+	// Mailbox and Account say where the rule created for a blocked sender
+	// moves their mail. It's deleted when Mailbox is empty.
+	Mailbox string `toml:"mailbox"`
+	Account string `toml:"account"`
+}
+
+type sendersConfig struct {
+	// Trusted lists sender globs, such as "*@example.com", that are never
+	// flagged as first-time senders.
+	Trusted []string `toml:"trusted"`
+}
+
+// defaultStagingDelay is long enough to notice a wrong d or e.
+const defaultStagingDelay = 10 * time.Second
+
+type stagingConfig struct {
+	// Delay is how long a delete or archive waits, out of the list, before
+	// it's made. Defaults to 10 seconds; 0 moves at once.
+	Delay *time.Duration `toml:"delay"`
+}
+
+func (s stagingConfig) delay() time.Duration {
+	if s.Delay == nil {
+		return defaultStagingDelay
+	}
+	return *s.Delay
+}
+
+type readConfig struct {
+	// MarkOnOpen marks a message read when it's opened. Defaults to true.
+	MarkOnOpen *bool `toml:"mark_on_open"`
+}
+
+func (r readConfig) markOnOpen() bool {
+	return r.MarkOnOpen == nil || *r.MarkOnOpen
+}
+
+type translateConfig struct {
+	// Command is a shell command the body of a message is piped through to
+	// translate it, such as "trans -b :en". MAILNOTIFY_LANG holds the code
+	// of the language it was detected as.
+	Command string `toml:"command"`
+	// Languages are the ISO 639-1 codes of the languages you read, which
+	// aren't offered for translation. Defaults to English.
+	Languages []string `toml:"languages"`
+}
+
+func (t translateConfig) languages() []string {
+	if len(t.Languages) == 0 {
+		return []string{"en"}
+	}
+	return t.Languages
+}
+
+type speechConfig struct {
+	// Voice is the voice to read aloud with, as say -v or espeak -v takes
+	// it. Defaults to the system's.
+	Voice string `toml:"voice"`
+	// Rate is the speaking rate in words per minute.
+	Rate int `toml:"rate"`
+}
+
+type listConfig struct {
+	// Summary replaces the list's title with a greeting and a summary of
+	// the inbox, such as "Good morning — 4 unread, 1 VIP, oldest 2d".
+	Summary bool `toml:"summary"`
+}
+
+type datesConfig struct {
+	// Style is "coarse", the default, for ages such as "3w ago", or
+	// "precise" for "3w 2d ago".
+	Style string `toml:"style"`
+}
+
+func (d datesConfig) style() (timeStyle, error) {
+	switch d.Style {
+	case "", "coarse":
+		return timeCoarse, nil
+	case "precise":
+		return timePrecise, nil
+	}
+	return timeCoarse, fmt.Errorf("style must be coarse or precise, not %q", d.Style)
+}
+
+// ruleConfig is a [[rules]] entry: Sender, Subject and To are regular
+// expressions, Domain a glob on the sender's domain such as "*.substack.com",
+// and Action is hide, highlight, priority or reply. Reply is the reply
+// action's text/template, executed against autoReplyData.
+type ruleConfig struct {
+	Sender  string `toml:"sender"`
+	Domain  string `toml:"domain"`
+	Subject string `toml:"subject"`
+	To      string `toml:"to"`
+	Action  string `toml:"action"`
+	Reply   string `toml:"reply"`
+}
+
+// stripConfig is a [[strip]] entry: boilerplate, such as an "[EXTERNAL
+// EMAIL]" banner or a legal footer, cut from bodies before they're shown.
+type stripConfig struct {
+	// Pattern is a case-insensitive regular expression for the text to
+	// cut. ^ and $ match at line breaks, and (?s) lets . match them too,
+	// for a footer that runs to the end.
+	Pattern string `toml:"pattern"`
+	// Account limits the entry to the mail of one account, by the name the
+	// backend's account setting gives it.
+	Account string `toml:"account"`
+}
+
+type identityConfig struct {
+	// Addresses are the user's own addresses, used to tell mail sent
+	// directly to them from CCs and list traffic.
+	Addresses []string `toml:"addresses"`
+	// Aliases are more addresses of the user's, such as one given only to
+	// shops. Mail to them, or to a plus address like me+shop@example.com,
+	// shows which one it came to.
+	Aliases []string `toml:"aliases"`
+}
+
+// me is every address of the user's, aliases included.
+func (i identityConfig) me() addressSet {
+	return newAddressSet(slices.Concat(i.Addresses, i.Aliases))
+}
+
+type filterConfig struct {
+	// Default is a focus query applied to every poll result, such as
+	// `-from:*@notifications.*`. It uses the same syntax as the '/' filter.
+	Default string `toml:"default"`
+}
+
+type themeConfig struct {
+	Palette      string            `toml:"palette"`
+	ColorProfile string            `toml:"color_profile"`
+	Colors       map[string]string `toml:"colors"`
+}
+
+type ticketConfig struct {
+	Endpoint string `toml:"endpoint"`
+	// Method defaults to POST.
+	Method  string            `toml:"method"`
+	Headers map[string]string `toml:"headers"`
+	// Template is a text/template for the JSON payload, with .Subject,
+	// .Sender, .Date, .Body and .Link and a json function for quoting.
+	Template string `toml:"template"`
+	// URLField is the dotted path of the ticket URL in the response.
+	// Defaults to "url".
+	URLField string `toml:"url_field"`
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/mailnotify/config.toml, falling
+// back to ~/.config/mailnotify/config.toml.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "mailnotify", "config.toml")
+}
+
+// expandHome replaces a leading "~/" in path with the home directory.
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// loadConfig reads the config file at path. A missing file is not an error
+// and yields the defaults.
+func loadConfig(path string) (config, error) {
+	cfg := config{path: path}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	cfg, err = parseConfig(path, data)
+	if err == nil {
+		// The relative times every command prints follow the loaded
+		// config.
+		relativeStyle = cfg.times
+	}
+	return cfg, err
+}
+
+// parseConfig parses data as the config file at path.
+func parseConfig(path string, data []byte) (config, error) {
+	cfg := config{path: path}
+	var err error
+	if err := toml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.schedule, err = parseSchedule(cfg.Schedule.Windows); err != nil {
+		return cfg, fmt.Errorf("%s: schedule: %w", path, err)
+	}
+	if cfg.keys, err = parseKeyMap(cfg.Keys); err != nil {
+		return cfg, fmt.Errorf("%s: keys: %w", path, err)
+	}
+	if cfg.confirm, err = parseConfirm(cfg.Confirm); err != nil {
+		return cfg, fmt.Errorf("%s: confirm: %w", path, err)
+	}
+	if cfg.rules, err = parseRules(cfg.Rules); err != nil {
+		return cfg, fmt.Errorf("%s: rules: %w", path, err)
+	}
+	if cfg.strip, err = parseStrippers(cfg.Strip); err != nil {
+		return cfg, fmt.Errorf("%s: strip: %w", path, err)
+	}
+	if cfg.times, err = cfg.Dates.style(); err != nil {
+		return cfg, fmt.Errorf("%s: dates: %w", path, err)
+	}
+	if cfg.batch, err = parseBatch(cfg.Poll.Batch); err != nil {
+		return cfg, fmt.Errorf("%s: poll: batch: %w", path, err)
+	}
+	if cfg.metered, err = cfg.Metered.mode(); err != nil {
+		return cfg, fmt.Errorf("%s: metered: %w", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDamagedStateFileIsKept(t *testing.T) {
+	dir := t.TempDir()
+	damaged := []byte(`{"a": {"until": "2026-10-14T09:00:00Z"`)
+	for _, name := range []string{snoozeFile, watchFile, savedSearchFile, mutedThreadFile, historyFile} {
+		if err := os.WriteFile(filepath.Join(dir, name), damaged, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := loadSnoozes(dir); err == nil {
+		t.Error("a damaged snoozed.json loaded without an error")
+	}
+	if _, err := checkWatches(dir, []email{{sender: "a@example.com", subject: "x"}}); err == nil {
+		t.Error("checking watches over a damaged watches.json didn't fail")
+	}
+	if _, err := recordUnread(dir, 0, time.Now()); err == nil {
+		t.Error("recording the history over a damaged file didn't fail")
+	}
+	muted := &mutedThreads{keys: map[string]time.Time{"k": {}}}
+	if err := muted.reload(dir); err == nil || len(muted.keys) != 1 {
+		t.Errorf("reloading a damaged muted-threads.json: err %v, keys %v", err, muted.keys)
+	}
+	for _, name := range []string{snoozeFile, watchFile, savedSearchFile, mutedThreadFile, historyFile} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != string(damaged) {
+			t.Errorf("%s was overwritten: %s", name, data)
+		}
+	}
+	if s, err := loadSnoozes(t.TempDir()); err != nil || s == nil {
+		t.Errorf("a missing snoozed.json: %v, %v", s, err)
+	}
+}
+
+func TestKeyMap(t *testing.T) {
+	k, err := parseKeyMap(map[string]string{"delete": "x", "archive": "d", "select": "V", "select_range": "space"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	press := func(s string) tea.KeyMsg {
+		switch s {
+		case "enter":
+			return tea.KeyMsg{Type: tea.KeyEnter}
+		case " ":
+			return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+		}
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+	}
+	for _, tt := range []struct {
+		mode      viewMode
+		key, want string
+	}{
+		{listView, "x", "d"},
+		{listView, "d", "e"},
+		{listView, "e", ""},
+		{listView, "enter", "enter"},
+		{listView, " ", "v"},
+		{listView, "V", " "},
+		{listView, "/", "/"},
+		{detailView, "x", "d"},
+		{detailView, "q", "q"},
+	} {
+		if got := k.resolve(tt.mode, press(tt.key)); got != tt.want {
+			t.Errorf("resolve(%v, %q) = %q, want %q", tt.mode, tt.key, got, tt.want)
+		}
+	}
+	if got := k.label(listView, "e"); got != "d" {
+		t.Errorf("archive's label = %q, want d", got)
+	}
+	if got := defaultKeys.resolve(listView, press("d")); got != "d" {
+		t.Errorf("without [keys], d resolves to %q", got)
+	}
+
+	for _, bad := range []map[string]string{
+		{"delete": "e"},
+		{"delete": "x", "archive": "x"},
+		{"nope": "x"},
+		{"delete": "  "},
+	} {
+		if _, err := parseKeyMap(bad); err == nil {
+			t.Errorf("parseKeyMap(%v) took it", bad)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// confirmPolicy is how many messages an action may act on without asking
+// first: 0 asks every time, and noConfirm never asks.
+type confirmPolicy int
+
+const noConfirm confirmPolicy = -1
+
+func (p confirmPolicy) asks(n int) bool {
+	return p != noConfirm && n > int(p)
+}
+
+// confirmActions are the actions [confirm] can set a policy for, as they're
+// named in [keys], and what they do without one. Only blocking asks by
+// default.
+var confirmActions = map[string]confirmPolicy{
+	"delete":        noConfirm,
+	"archive":       noConfirm,
+	"toggle_read":   noConfirm,
+	"mark_all_read": noConfirm,
+	"block":         0,
+}
+
+// confirmPolicies are the [confirm] table parsed, by action name.
+type confirmPolicies map[string]confirmPolicy
+
+// parseConfirm builds the policies from [confirm], which maps action names
+// to "always", "never" or ">N", asking only for more than N messages.
+func parseConfirm(table map[string]string) (confirmPolicies, error) {
+	policies := confirmPolicies{}
+	for name, value := range table {
+		if _, ok := confirmActions[name]; !ok {
+			return nil, fmt.Errorf("unknown action %q", name)
+		}
+		switch v := strings.TrimSpace(value); {
+		case v == "always":
+			policies[name] = 0
+		case v == "never":
+			policies[name] = noConfirm
+		case strings.HasPrefix(v, ">"):
+			n, err := strconv.Atoi(strings.TrimSpace(v[1:]))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%s: %q is not a count", name, v[1:])
+			}
+			policies[name] = confirmPolicy(n)
+		default:
+			return nil, fmt.Errorf("%s: want \"always\", \"never\" or \">N\", not %q", name, value)
+		}
+	}
+	return policies, nil
+}
+
+func (c confirmPolicies) policy(action string) confirmPolicy {
+	if p, ok := c[action]; ok {
+		return p
+	}
+	return confirmActions[action]
+}
+
+// confirmed reports whether action, with the default key key, may go ahead
+// on the n messages target names. When its policy asks, the first press
+// only says what a second will do, as what.
+func (m *model) confirmed(action, key, target string, n int, what string) bool {
+	pending := action + "\x00" + target
+	if m.takeConfirm(pending) || !m.cfg.confirm.policy(action).asks(n) {
+		return true
+	}
+	m.armConfirm(pending)
+	m.setNotice(fmt.Sprintf("Press %s again to %s", m.cfg.keys.label(m.mode, key), what))
+	return false
+}
+
+// armConfirm makes the next key, if it repeats this one, confirm pending.
+func (m *model) armConfirm(pending string) {
+	m.confirmPending = pending
+	m.confirmAt = m.keypresses
+}
+
+// takeConfirm reports whether the key being handled confirms pending: it
+// was armed by the key just before, and nothing changed the list since. It
+// disarms whatever was armed either way.
+func (m *model) takeConfirm(pending string) bool {
+	ok := m.confirmPending == pending && m.confirmAt == m.keypresses-1
+	m.confirmPending = ""
+	return ok
+}
+
+// countOf is "1 message" or "N messages", for confirmation prompts.
+func countOf(n int) string {
+	if n == 1 {
+		return "1 message"
+	}
+	return fmt.Sprintf("%d messages", n)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// runDaemon polls in the background without a UI, logs every change in the
+// unread count and posts a desktop notification for each newly arrived
+// message. It stays idle outside the configured schedule, and a lost
+// connection is logged once rather than on every poll. It runs until it
+// receives SIGTERM or SIGINT; SIGUSR1 polls immediately and SIGHUP reloads
+// the config. With [api] configured it also serves the unread list over
+// HTTP.
+func runDaemon(cfg config) error {
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return err
+	}
+	log.SetFlags(log.LstdFlags)
+	if cfg.batch.manual() {
+		log.Printf("daemon started in batch mode, polling only on SIGUSR1")
+	} else {
+		log.Printf("daemon started, polling every %s", formatInterval(cfg.pollInterval()))
+	}
+	if cfg.dryRun {
+		log.Printf("dry run: changes to the mailbox are logged, not made")
+	}
+	var api *apiServer
+	if cfg.API.Listen != "" {
+		if api, err = startAPI(cfg.API, provider); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(jittered(cfg.pollInterval()))
+	defer ticker.Stop()
+	signals := watchSignals()
+
+	last := -1
+	var arrived arrivals
+	senders := loadSenderHistory(cfg.State.dir(), cfg.Senders.Trusted)
+	resting := false
+	offline := false
+	interval := cfg.pollInterval()
+	poll := func() {
+		// Re-read the battery each poll; the low-battery interval is long
+		// enough that this stays cheap.
+		if next := cfg.Battery.pollInterval(cfg.pollInterval(), readBattery()); next != interval {
+			interval = next
+			ticker.Reset(interval)
+			log.Printf("polling every %s", formatInterval(interval))
+		}
+		if !cfg.schedule.allows(time.Now()) {
+			if !resting {
+				log.Printf("outside polling hours, idling")
+				resting = true
+			}
+			return
+		}
+		resting = false
+		emails, err := provider.unread()
+		failed, err := partialRead(err)
+		for name, err := range failed {
+			log.Printf("%s: poll failed: %v", name, err)
+		}
+		api.update(emails, err)
+		if err == nil && failed == nil {
+			// Shared with the TUI and with status bars polling without the
+			// API, which answer from it while it's fresh.
+			if err := loadMailCache(cfg.State.cacheDir()).setEmails(emails, len(emails)); err != nil {
+				log.Printf("couldn't save the cache: %v", err)
+			}
+		}
+		if connectionLost(err) {
+			if state := probeNetwork(); state != netOnline {
+				if !offline {
+					log.Printf("%s, waiting for the network", strings.ToLower(state.String()))
+					offline = true
+				}
+				return
+			}
+		} else if offline && err == nil {
+			log.Printf("back online")
+			offline = false
+		}
+		switch {
+		case err != nil:
+			log.Printf("poll failed: %v", err)
+		case len(emails) != last:
+			log.Printf("%d unread", len(emails))
+			last = len(emails)
+		}
+		if err != nil {
+			return
+		}
+		now := time.Now()
+		if _, err := recordUnread(cfg.State.dir(), len(emails), now); err != nil {
+			log.Printf("couldn't save the inbox history: %v", err)
+		}
+		snoozed, snoozeErr := loadSnoozes(cfg.State.dir())
+		// Blocks and mutes made in the TUI, here or on another machine.
+		senders.reload()
+		newMail := slices.DeleteFunc(arrived.update(emails), senders.blocked)
+		if cfg.rules.hasReplyRules() {
+			sent, err := sendAutoReplies(cfg, provider, newMail, now)
+			for _, addr := range sent {
+				log.Printf("auto-replied to %s", addr)
+			}
+			if err != nil {
+				log.Print(err)
+			}
+		}
+		found, err := checkWatches(cfg.State.dir(), newMail)
+		if err != nil {
+			log.Printf("couldn't save the watches: %v", err)
+		}
+		for _, e := range found {
+			log.Printf("watched-for mail arrived: %s from %s", e.subject, e.sender)
+		}
+		if len(found) > 0 {
+			if err := notifyWatched(cfg.Notify, cfg.path, found); err != nil {
+				log.Printf("watched-for alert failed: %v", err)
+			}
+		}
+		fresh := cfg.rules.notifiable(slices.Clone(newMail))
+		fresh = slices.DeleteFunc(fresh, senders.muted)
+		fresh = slices.DeleteFunc(fresh, func(e email) bool { return snoozed.hides(e, now) })
+		muted, err := loadMutedThreads(cfg.State.dir())
+		if err != nil {
+			log.Print(err)
+		}
+		fresh = slices.DeleteFunc(fresh, muted.has)
+		if woken := snoozed.wake(emails, now); len(woken) > 0 {
+			log.Printf("%d back from snooze", len(woken))
+			if snoozeErr != nil {
+				log.Printf("couldn't save snoozes: %v", snoozeErr)
+			} else if err := snoozed.save(cfg.State.dir(), now); err != nil {
+				log.Printf("couldn't save snoozes: %v", err)
+			}
+			if cfg.Notify.enabled() {
+				if err := notifyEmails(cfg.Notify, cfg.path, woken, "Back from snooze", "%d messages back from snooze"); err != nil {
+					log.Printf("notification failed: %v", err)
+				}
+			}
+		}
+		if len(fresh) == 0 || !cfg.Notify.enabled() {
+			return
+		}
+		if err := notifyNew(cfg.Notify, cfg.path, fresh); err != nil {
+			log.Printf("notification failed: %v", err)
+		}
+		if cfg.Notify.Launch {
+			if err := openTUI(cfg.path); err != nil {
+				log.Printf("couldn't open the TUI: %v", err)
+			}
+		}
+	}
+
+	poll()
+	for {
+		select {
+		case <-ticker.C:
+			// In manual batch mode only a signal checks for mail.
+			if !cfg.batch.manual() {
+				poll()
+				ticker.Reset(jittered(interval))
+			}
+		case action := <-signals:
+			switch action {
+			case signalRefresh:
+				poll()
+				ticker.Reset(jittered(interval))
+			case signalReload:
+				reloaded, err := loadConfig(cfg.path)
+				var p mailProvider
+				if err == nil {
+					reloaded.dryRun = cfg.dryRun
+					p, err = newProvider(reloaded)
+				}
+				if err != nil {
+					log.Printf("config reload failed: %v", err)
+					continue
+				}
+				if reloaded.API != cfg.API {
+					log.Printf("api settings changed; restart the daemon to apply them")
+				}
+				cfg, provider = reloaded, p
+				api.setProvider(p)
+				log.Printf("config reloaded from %s", cfg.path)
+			case signalShutdown:
+				log.Printf("daemon stopping")
+				return nil
+			}
+		}
+	}
+}
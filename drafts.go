@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// draft is a saved, unsent message in the Drafts mailbox.
+type draft struct {
+	id      string
+	to      []string
+	subject string
+	date    string
+}
+
+func (d draft) Title() string {
+	if d.subject == "" {
+		return "(no subject)"
+	}
+	return d.subject
+}
+
+func (d draft) Description() string {
+	to := strings.Join(d.to, ", ")
+	if to == "" {
+		to = "no recipients"
+	}
+	if d.date == "" {
+		return "To: " + to
+	}
+	return fmt.Sprintf("To: %s • %s", to, relativeTime(d.date))
+}
+
+func (d draft) FilterValue() string { return d.subject }
+
+type draftsMsg struct {
+	drafts []draft
+	err    error
+}
+
+type draftContentMsg struct {
+	draft draft
+	body  string
+	err   error
+}
+
+// draftActionMsg reports the result of sending, saving or deleting. done
+// describes what happened, for the status line.
+type draftActionMsg struct {
+	done string
+	err  error
+}
+
+func fetchDrafts(s draftStore) tea.Cmd {
+	return func() tea.Msg {
+		drafts, err := s.drafts()
+		return draftsMsg{drafts: drafts, err: err}
+	}
+}
+
+func fetchDraftContent(s draftStore, d draft) tea.Cmd {
+	return func() tea.Msg {
+		body, err := s.draftContent(d.id)
+		return draftContentMsg{draft: d, body: body, err: err}
+	}
+}
+
+// sendOutgoing sends msg, deleting the draft it was edited from.
+func sendOutgoing(s draftStore, msg outgoingMessage) tea.Cmd {
+	return func() tea.Msg {
+		return draftActionMsg{done: "Sent", err: s.compose(msg, true)}
+	}
+}
+
+// saveOutgoing stores msg in Drafts, replacing the draft it was edited from.
+func saveOutgoing(s draftStore, msg outgoingMessage) tea.Cmd {
+	return func() tea.Msg {
+		return draftActionMsg{done: "Saved to Drafts", err: s.compose(msg, false)}
+	}
+}
+
+func sendDraft(s draftStore, d draft) tea.Cmd {
+	return func() tea.Msg {
+		body, err := s.draftContent(d.id)
+		if err != nil {
+			return draftActionMsg{err: err}
+		}
+		msg := outgoingMessage{draftID: d.id, to: d.to, subject: d.subject, body: body}
+		return draftActionMsg{done: "Sent", err: s.compose(msg, true)}
+	}
+}
+
+func discardDraft(s draftStore, d draft) tea.Cmd {
+	return func() tea.Msg {
+		return draftActionMsg{done: "Deleted draft", err: s.deleteDraft(d.id)}
+	}
+}
+
+// draftByIDScript sets msg to the draft whose id is the first argument.
+const draftByIDScript = `
+		set msg to first message of drafts mailbox whose id is ((item 1 of argv) as integer)
+`
+
+func (mailAppProvider) drafts() ([]draft, error) {
+	script := `
+tell application "Mail"
+	set output to ""
+	repeat with msg in (messages of drafts mailbox)
+		set AppleScript's text item delimiters to ","
+		set toAddrs to (address of to recipients of msg) as string
+		set AppleScript's text item delimiters to ""
+		set dateSent to ""
+		try
+			set dateSent to (date sent of msg) as string
+		end try
+		set output to output & (id of msg) & "|||" & (subject of msg) & "|||" & toAddrs & "|||" & dateSent & linefeed
+	end repeat
+	return output
+end tell
+`
+	out, err := exec.Command("osascript", "-e", script).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var drafts []draft
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.Split(line, "|||")
+		if len(parts) < 4 {
+			continue
+		}
+		drafts = append(drafts, draft{
+			id:      strings.TrimSpace(parts[0]),
+			subject: strings.TrimSpace(parts[1]),
+			to:      splitAddresses(parts[2]),
+			date:    strings.TrimSpace(parts[3]),
+		})
+	}
+	return drafts, nil
+}
+
+func (mailAppProvider) draftContent(id string) (string, error) {
+	script := `
+on run argv
+	tell application "Mail"
+` + draftByIDScript + `
+		return content of msg
+	end tell
+end run
+`
+	out, err := exec.Command("osascript", "-e", script, id).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func (mailAppProvider) deleteDraft(id string) error {
+	script := `
+on run argv
+	tell application "Mail"
+` + draftByIDScript + `
+		delete msg
+	end tell
+end run
+`
+	return exec.Command("osascript", "-e", script, id).Run()
+}
+
+// compose builds msg as a new outgoing message and either sends it or saves
+// it to Drafts. A reply is built with Mail.app's own reply command so it
+// carries the threading headers. Mail.app can't edit a saved draft in
+// place, so the draft msg was resumed from is deleted once its replacement
+// exists. Mail.app's scripting can't add headers, so an autoSubmitted
+// message goes out without Auto-Submitted.
+func (mailAppProvider) compose(msg outgoingMessage, send bool) error {
+	action := "save"
+	if send {
+		action = "send"
+		if len(msg.to) == 0 {
+			return fmt.Errorf("no recipients")
+		}
+	}
+	script := `
+on run argv
+	set action to item 1 of argv
+	set draftID to item 2 of argv
+	set subj to item 3 of argv
+	set msgBody to item 4 of argv
+	set recipients to paragraphs of (item 5 of argv)
+	set replyID to item 6 of argv
+	tell application "Mail"
+		if replyID is not "" then
+			if (item 8 of argv) is not "" then
+				set orig to first message of mailbox (item 8 of argv) of account (item 7 of argv) whose id is (replyID as integer)
+			else
+				set orig to first message of inbox whose id is (replyID as integer)
+			end if
+			set out to reply orig without opening window
+			set subject of out to subj
+			set content of out to msgBody
+			delete every to recipient of out
+		else
+			set out to make new outgoing message with properties {subject:subj, content:msgBody, visible:false}
+		end if
+		tell out
+			repeat with addr in recipients
+				if (addr as string) is not "" then
+					make new to recipient at end of to recipients with properties {address:(addr as string)}
+				end if
+			end repeat
+		end tell
+		if action is "send" then
+			send out
+		else
+			close out saving yes
+		end if
+		if draftID is not "" then
+			delete (first message of drafts mailbox whose id is (draftID as integer))
+		end if
+	end tell
+end run
+`
+	args := []string{"-e", script, action, msg.draftID, msg.subject, msg.body, strings.Join(msg.to, "\n")}
+	args = append(args, messageArgs(msg.inReplyTo)...)
+	return exec.Command("osascript", args...).Run()
+}
+
+// draftListKeys handles keys in the Drafts list.
+func (m *model) draftListKeys(key string) (tea.Cmd, bool) {
+	if cmd, ok := m.commonKeys(key); ok {
+		return cmd, true
+	}
+	switch key {
+	case "q", "esc":
+		m.mode = listView
+		return nil, true
+	case "r":
+		return tea.Batch(fetchDrafts(m.draftStore()), m.begin(loadingDrafts)), true
+	case "x":
+		if d, ok := m.drafts.SelectedItem().(draft); ok {
+			m.setNotice("Deleting draft…")
+			return m.track(discardDraft(m.draftStore(), d)), true
+		}
+	case "S":
+		if d, ok := m.drafts.SelectedItem().(draft); ok {
+			m.setNotice("Sending…")
+			return m.track(sendDraft(m.draftStore(), d)), true
+		}
+	case "enter":
+		if d, ok := m.drafts.SelectedItem().(draft); ok {
+			return tea.Batch(fetchDraftContent(m.draftStore(), d), m.begin(openingDraft)), true
+		}
+	}
+	return nil, false
+}
+
+func (m *model) updateDraftList(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	m.drafts, cmd = m.drafts.Update(msg)
+	return cmd
+}
+
+func (m model) viewDraftList(status string) string {
+	helpBar := renderHelpBar(m.width, [][]string{
+		{"enter", "edit"},
+		{"S", "send"},
+		{"x", "delete"},
+		{"c", "compose"},
+		{"r", "refresh"},
+		{"q", "back"},
+	})
+	return m.drafts.View() + "\n" + status + helpBar
+}
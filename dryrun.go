@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// skip logs a call --dry-run didn't make, formatted as with fmt.Sprintf:
+// to stderr from the daemon and commands, and to the session log in the
+// TUI. It returns nil, which the skipped call returns in turn.
+func skip(format string, args ...any) error {
+	log.Print("dry run: " + fmt.Sprintf(format, args...))
+	return nil
+}
+
+// dryRunProvider reads mail through p but only logs what would change it:
+// marking read, moving, labelling, composing, deleting drafts and blocking.
+// Opening a message peeks at it, so it stays unread.
+type dryRunProvider struct {
+	p mailProvider
+}
+
+// describe names emails for the log by subject, the first three of them.
+func describe(emails []email) string {
+	var subjects []string
+	for _, e := range emails {
+		subjects = append(subjects, fmt.Sprintf("%q", e.subject))
+	}
+	if len(subjects) > 3 {
+		subjects = append(subjects[:3], fmt.Sprintf("and %d more", len(emails)-3))
+	}
+	return strings.Join(subjects, ", ")
+}
+
+func (d dryRunProvider) name() string {
+	return d.p.name() + " (dry run)"
+}
+
+func (d dryRunProvider) unread() ([]email, error) {
+	return d.p.unread()
+}
+
+func (d dryRunProvider) unreadPage(limit int) ([]email, int, error) {
+	if pg, ok := d.p.(pager); ok {
+		return pg.unreadPage(limit)
+	}
+	emails, err := d.p.unread()
+	return emails, len(emails), err
+}
+
+func (d dryRunProvider) content(e email) (messageContent, error) {
+	skip("mark read %s", describe([]email{e}))
+	return d.peek(e)
+}
+
+func (d dryRunProvider) peek(e email) (messageContent, error) {
+	if pk, ok := d.p.(peeker); ok {
+		return pk.peek(e)
+	}
+	return messageContent{}, fmt.Errorf("%s can't open a message without marking it read", d.p.name())
+}
+
+func (d dryRunProvider) markRead(emails []email) error {
+	return skip("mark read %s", describe(emails))
+}
+
+func (d dryRunProvider) editor() error {
+	if _, ok := d.p.(messageEditor); !ok {
+		return fmt.Errorf("%s can't change single messages", d.p.name())
+	}
+	return nil
+}
+
+func (d dryRunProvider) markUnread(e email) error {
+	if err := d.editor(); err != nil {
+		return err
+	}
+	return skip("mark unread %s", describe([]email{e}))
+}
+
+func (d dryRunProvider) trash(e email) error {
+	return d.trashAll([]email{e})
+}
+
+func (d dryRunProvider) archive(e email) error {
+	return d.archiveAll([]email{e})
+}
+
+func (d dryRunProvider) trashAll(emails []email) error {
+	if err := d.editor(); err != nil {
+		return err
+	}
+	return skip("move to Trash %s", describe(emails))
+}
+
+func (d dryRunProvider) archiveAll(emails []email) error {
+	if err := d.editor(); err != nil {
+		return err
+	}
+	return skip("archive %s", describe(emails))
+}
+
+// saveAttachment saves for real: it writes a local file, not the mailbox.
+func (d dryRunProvider) saveAttachment(e email, att attachment, dest string) error {
+	if s, ok := d.p.(attachmentSaver); ok {
+		return s.saveAttachment(e, att, dest)
+	}
+	return fmt.Errorf("%s can't save attachments", d.p.name())
+}
+
+func (d dryRunProvider) search(q mailSearch) ([]email, error) {
+	if s, ok := d.p.(searcher); ok {
+		return s.search(q)
+	}
+	return nil, fmt.Errorf("%s can't search", d.p.name())
+}
+
+func (d dryRunProvider) listMailboxes() ([]mailboxInfo, error) {
+	if b, ok := d.p.(mailboxBrowser); ok {
+		return b.listMailboxes()
+	}
+	return nil, fmt.Errorf("%s has no mailboxes to pick", d.p.name())
+}
+
+func (d dryRunProvider) reporting(p *pollProgress) mailProvider {
+	if r, ok := d.p.(progressReporter); ok {
+		return dryRunProvider{r.reporting(p)}
+	}
+	return d
+}
+
+func (d dryRunProvider) scoped(s mailScope) mailProvider {
+	if b, ok := d.p.(mailboxBrowser); ok {
+		return dryRunProvider{b.scoped(s)}
+	}
+	return d
+}
+
+func (d dryRunProvider) store() (draftStore, error) {
+	if s, ok := d.p.(draftStore); ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("%s can't compose", d.p.name())
+}
+
+func (d dryRunProvider) drafts() ([]draft, error) {
+	s, err := d.store()
+	if err != nil {
+		return nil, err
+	}
+	return s.drafts()
+}
+
+func (d dryRunProvider) draftContent(id string) (string, error) {
+	s, err := d.store()
+	if err != nil {
+		return "", err
+	}
+	return s.draftContent(id)
+}
+
+func (d dryRunProvider) deleteDraft(id string) error {
+	if _, err := d.store(); err != nil {
+		return err
+	}
+	return skip("delete draft %s", id)
+}
+
+func (d dryRunProvider) compose(msg outgoingMessage, send bool) error {
+	if _, err := d.store(); err != nil {
+		return err
+	}
+	verb := "save draft"
+	if send {
+		verb = "send"
+	}
+	if msg.autoSubmitted {
+		verb += " auto-reply"
+	}
+	return skip("%s %q to %s", verb, msg.subject, strings.Join(msg.to, ", "))
+}
+
+func (d dryRunProvider) setFlag(e email, f flagColor) error {
+	if _, ok := d.p.(flagger); !ok {
+		return fmt.Errorf("%s can't flag messages", d.p.name())
+	}
+	return skip("flag %s %s", strings.ToLower(f.String()), describe([]email{e}))
+}
+
+// labels only reads, so it goes through.
+func (d dryRunProvider) labels(e email) ([]string, []string, error) {
+	if l, ok := d.p.(labeler); ok {
+		return l.labels(e)
+	}
+	return nil, nil, fmt.Errorf("%s has no labels", d.p.name())
+}
+
+func (d dryRunProvider) relabel(e email, add, remove []string) error {
+	if _, ok := d.p.(labeler); !ok {
+		return fmt.Errorf("%s has no labels", d.p.name())
+	}
+	return skip("label %s +%s -%s", describe([]email{e}), strings.Join(add, ","), strings.Join(remove, ","))
+}
+
+func (d dryRunProvider) blockSender(addr string, cfg blockConfig) error {
+	if _, ok := d.p.(senderBlocker); !ok {
+		return fmt.Errorf("%s can't block senders", d.p.name())
+	}
+	return skip("block %s", addr)
+}
+
+func (d dryRunProvider) reconnect() {
+	if r, ok := d.p.(reconnector); ok {
+		r.reconnect()
+	}
+}
+
+func (d dryRunProvider) checks() []check {
+	if c, ok := d.p.(checker); ok {
+		return c.checks()
+	}
+	return nil
+}
+
+// backendOf returns the provider behind p's dry-run wrapping, if any.
+func backendOf(p mailProvider) mailProvider {
+	if d, ok := p.(dryRunProvider); ok {
+		return d.p
+	}
+	return p
+}
+
+// accountsOf returns the [[accounts]] provider behind p, if that's what p
+// is.
+func accountsOf(p mailProvider) (multiProvider, bool) {
+	mp, ok := backendOf(p).(multiProvider)
+	return mp, ok
+}
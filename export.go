@@ -0,0 +1,296 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// An export archive holds the config at configEntry and every file of the
+// state directory under stateEntry.
+const (
+	configEntry = "config.toml"
+	stateEntry  = "state/"
+)
+
+// runExport writes the config and local state to a gzipped tarball at dest.
+func runExport(cfg config, dest string) error {
+	if dest == "" {
+		dest = "mailnotify-export-" + time.Now().Format("20060102") + ".tar.gz"
+	}
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	files := 0
+	add := func(name, src string) error {
+		data, err := os.ReadFile(src)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: time.Now()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		files++
+		_, err = tw.Write(data)
+		return err
+	}
+
+	if cfg.path != "" {
+		if err := add(configEntry, cfg.path); err != nil {
+			return err
+		}
+	}
+	if dir := cfg.State.dir(); dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		for _, e := range entries {
+			if !e.Type().IsRegular() {
+				continue
+			}
+			if err := add(stateEntry+e.Name(), filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d file(s) to %s\n", files, dest)
+	return nil
+}
+
+// runImport merges an archive written by runExport into this machine. An
+// existing config is kept, with the imported one written alongside it for
+// comparison; state files with a known format are merged, and others are
+// only added when missing.
+func runImport(cfg config, src string) error {
+	if src == "" {
+		return fmt.Errorf("usage: mailnotify import <archive>")
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", src, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		switch name := path.Clean(hdr.Name); {
+		case name == configEntry:
+			if err := importConfig(cfg.path, data); err != nil {
+				return err
+			}
+		case strings.HasPrefix(name, stateEntry) && !strings.Contains(name[len(stateEntry):], "/"):
+			if err := importState(cfg.State.dir(), name[len(stateEntry):], data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func importConfig(configPath string, data []byte) error {
+	if configPath == "" {
+		return fmt.Errorf("no config path to import to")
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+		return err
+	}
+	err := writeNew(configPath, data)
+	if errors.Is(err, fs.ErrExist) {
+		dest := configPath + ".imported"
+		if err := os.WriteFile(dest, data, 0o600); err != nil {
+			return err
+		}
+		fmt.Printf("Kept %s; the imported config is in %s\n", configPath, dest)
+		return nil
+	}
+	if err == nil {
+		fmt.Printf("Imported config to %s\n", configPath)
+	}
+	return err
+}
+
+// importState merges the state file name, imported as data, into dir. The
+// sender history and the files in stateMerges are merged entry by entry,
+// keeping this machine's entry where both have one; any other file is only
+// written when dir has none, and reported as skipped otherwise.
+func importState(dir, name string, data []byte) error {
+	if dir == "" {
+		return fmt.Errorf("no state directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	dest := filepath.Join(dir, name)
+	if name == legacySenderFile || isSenderJournal(name) {
+		imported := parseSenderJournal(data)
+		if name == legacySenderFile {
+			imported = map[string]senderRecord{}
+			if err := json.Unmarshal(data, &imported); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		n, err := loadSenderHistory(dir, nil).importRecords(imported)
+		if err == nil {
+			fmt.Printf("Merged %d sender(s) from %s\n", n, name)
+		}
+		return err
+	}
+	if merge, ok := stateMerges[name]; ok {
+		n, err := merge(dir, data)
+		if err == nil {
+			fmt.Printf("Merged %d record(s) from %s\n", n, name)
+		}
+		return err
+	}
+	err := writeNew(dest, data)
+	switch {
+	case errors.Is(err, fs.ErrExist):
+		fmt.Printf("Skipped %s, which already exists\n", dest)
+		return nil
+	case err == nil:
+		fmt.Printf("Imported %s\n", dest)
+	}
+	return err
+}
+
+// stateMerges merge an imported copy of a state file into dir's, by the
+// file's name, and report how many entries came in.
+var stateMerges = map[string]func(dir string, data []byte) (int, error){
+	snoozeFile: func(dir string, data []byte) (int, error) {
+		return mergeStateFile(dir, snoozeFile, data, func(local, imported snoozes) (snoozes, int) {
+			return mergeMaps(local, imported)
+		})
+	},
+	mutedThreadFile: func(dir string, data []byte) (int, error) {
+		return mergeStateFile(dir, mutedThreadFile, data, func(local, imported map[string]time.Time) (map[string]time.Time, int) {
+			return mergeMaps(local, imported)
+		})
+	},
+	watchFile: func(dir string, data []byte) (int, error) {
+		return mergeStateFile(dir, watchFile, data, func(local, imported watches) (watches, int) {
+			return mergeLists(local, imported, func(w watch) string { return w.Query })
+		})
+	},
+	savedSearchFile: func(dir string, data []byte) (int, error) {
+		return mergeStateFile(dir, savedSearchFile, data, func(local, imported savedSearches) (savedSearches, int) {
+			return mergeLists(local, imported, func(s savedSearch) string { return strings.ToLower(s.Name) })
+		})
+	},
+	historyFile: func(dir string, data []byte) (int, error) {
+		return mergeStateFile(dir, historyFile, data, func(local, imported inboxHistory) (inboxHistory, int) {
+			days, n := mergeLists(local.Days, imported.Days, func(d string) string { return d })
+			slices.Sort(days)
+			local.Days = days[max(len(days)-historyDays, 0):]
+			if local.Clears == nil {
+				local.Clears = imported.Clears
+			}
+			return local, n
+		})
+	},
+}
+
+// mergeStateFile merges data, an imported copy of name, into dir's copy
+// with merge, which returns the result and how many entries it added. A
+// local file that doesn't parse is left alone.
+func mergeStateFile[T any](dir, name string, data []byte, merge func(local, imported T) (T, int)) (int, error) {
+	var imported T
+	if err := json.Unmarshal(data, &imported); err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	local, err := loadStateFile[T](dir, name)
+	if err != nil {
+		return 0, err
+	}
+	merged, n := merge(local, imported)
+	if n == 0 {
+		return 0, nil
+	}
+	return n, writeStateFile(dir, name, merged)
+}
+
+// mergeMaps adds imported's entries that local lacks.
+func mergeMaps[M ~map[string]V, V any](local, imported M) (M, int) {
+	if local == nil {
+		local = M{}
+	}
+	n := 0
+	for k, v := range imported {
+		if _, ok := local[k]; !ok {
+			local[k] = v
+			n++
+		}
+	}
+	return local, n
+}
+
+// mergeLists appends imported's entries whose key local has none of.
+func mergeLists[S ~[]E, E any](local, imported S, key func(E) string) (S, int) {
+	n := 0
+	for _, e := range imported {
+		if !slices.ContainsFunc(local, func(l E) bool { return key(l) == key(e) }) {
+			local = append(local, e)
+			n++
+		}
+	}
+	return local, n
+}
+
+// writeNew writes data to a file that must not exist yet.
+func writeNew(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
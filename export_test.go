@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestImportStateMerges(t *testing.T) {
+	dir := t.TempDir()
+	until := time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)
+	write := func(name string, v any) {
+		if err := writeStateFile(dir, name, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(snoozeFile, snoozes{"a": {Until: until, Subject: "here"}})
+	write(watchFile, watches{{Query: "from:acme"}})
+	write(savedSearchFile, savedSearches{{Name: "Boss", Query: "from:boss"}})
+	write(mutedThreadFile, map[string]time.Time{"id:a@x": until})
+	write(historyFile, inboxHistory{Days: []string{"2026-10-02"}})
+
+	imports := map[string]any{
+		snoozeFile:      snoozes{"a": {Until: until, Subject: "there"}, "b": {Until: until}},
+		watchFile:       watches{{Query: "from:acme"}, {Query: "subject:visa"}},
+		savedSearchFile: savedSearches{{Name: "boss", Query: "from:other"}, {Name: "Bills", Query: "invoice"}},
+		mutedThreadFile: map[string]time.Time{"id:b@x": until},
+		historyFile:     inboxHistory{Days: []string{"2026-10-01", "2026-10-02"}},
+	}
+	for name, v := range imports {
+		data, _ := json.Marshal(v)
+		if err := importState(dir, name, data); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	if err := importState(dir, "seen-version", []byte("v1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := importState(dir, "seen-version", []byte("v2\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := loadSnoozes(dir)
+	if len(s) != 2 || s["a"].Subject != "here" {
+		t.Errorf("snoozes = %v, want a kept as it was and b added", s)
+	}
+	if w, _ := loadWatches(dir); len(w) != 2 || w[1].Query != "subject:visa" {
+		t.Errorf("watches = %v", w)
+	}
+	if ss, _ := loadSavedSearches(dir); len(ss) != 2 || ss[0].Query != "from:boss" || ss[1].Name != "Bills" {
+		t.Errorf("saved searches = %v", ss)
+	}
+	if muted, _ := loadMutedThreads(dir); len(muted.keys) != 2 {
+		t.Errorf("muted = %v", muted.keys)
+	}
+	if h, _ := loadInboxHistory(dir); len(h.Days) != 2 || h.Days[0] != "2026-10-01" {
+		t.Errorf("history days = %v", h.Days)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "seen-version")); string(data) != "v1\n" {
+		t.Errorf("an unmerged file was overwritten: %q", data)
+	}
+}
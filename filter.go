@@ -0,0 +1,333 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/sahilm/fuzzy"
+)
+
+// Fields of an email's FilterValue, joined by filterFieldSep so the filter
+// can tell which part of the target a term matched.
+const (
+	fieldSubject = iota
+	fieldSender
+	fieldAccount
+	fieldDate
+	fieldPriority
+	fieldMailbox
+	fieldTo
+	numFilterFields
+)
+
+const filterFieldSep = "\x1f"
+
+var filterPrefixes = map[string]int{
+	"subj":     fieldSubject,
+	"subject":  fieldSubject,
+	"from":     fieldSender,
+	"acct":     fieldAccount,
+	"account":  fieldAccount,
+	"date":     fieldDate,
+	"prio":     fieldPriority,
+	"priority": fieldPriority,
+	"in":       fieldMailbox,
+	"folder":   fieldMailbox,
+	"to":       fieldTo,
+}
+
+// filterTerm is one whitespace-separated part of a filter query. field is -1
+// when the term has no prefix and may match any field. A leading '-'
+// negates the term, and a value containing '*' or '?' is a glob that must
+// match a whole field.
+type filterTerm struct {
+	field  int
+	value  string
+	negate bool
+	glob   *regexp.Regexp
+}
+
+// parseFilterQuery splits a query like `from:alice subj:"q3 report" budget`
+// into terms. Double quotes group words into a single value.
+func parseFilterQuery(query string) []filterTerm {
+	var terms []filterTerm
+	for _, tok := range tokenizeQuery(query) {
+		t := filterTerm{field: -1}
+		if len(tok) > 1 && tok[0] == '-' {
+			t.negate = true
+			tok = tok[1:]
+		}
+		t.value = tok
+		if i := strings.Index(tok, ":"); i > 0 {
+			if f, ok := filterPrefixes[strings.ToLower(tok[:i])]; ok {
+				t.field = f
+				t.value = tok[i+1:]
+			}
+		}
+		if strings.ContainsAny(t.value, "*?") {
+			t.glob = globToRegexp(t.value)
+		}
+		if t.value != "" {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+func globToRegexp(glob string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(glob)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+	return regexp.MustCompile("(?i)^" + pattern + "$")
+}
+
+func tokenizeQuery(query string) []string {
+	var (
+		tokens  []string
+		cur     strings.Builder
+		inQuote bool
+	)
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case unicode.IsSpace(r) && !inQuote:
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// filterMode selects how filter terms are compared against fields.
+type filterMode int
+
+const (
+	filterSubstring filterMode = iota
+	filterFuzzy
+	filterRegex
+)
+
+func (f filterMode) String() string {
+	switch f {
+	case filterFuzzy:
+		return "fuzzy"
+	case filterRegex:
+		return "regex"
+	default:
+		return "substring"
+	}
+}
+
+func (f filterMode) next() filterMode {
+	return (f + 1) % 3
+}
+
+// termMatcher reports the rune indexes of field that match value, and a
+// score used to order fuzzy results.
+type termMatcher func(field, value string) (matched []int, score int, ok bool)
+
+// newFilterFunc returns a list.FilterFunc for mode. Every term must match;
+// unprefixed terms match any field. Matched rune indexes are reported
+// relative to the whole target so the delegate can highlight them.
+func newFilterFunc(mode filterMode) list.FilterFunc {
+	return func(query string, targets []string) []list.Rank {
+		terms := parseFilterQuery(query)
+		if mode == filterRegex {
+			// '*' and '?' are regex syntax here, not globs.
+			for i := range terms {
+				terms[i].glob = nil
+			}
+		}
+		match := matcherFor(mode, terms)
+		if match == nil {
+			return nil
+		}
+
+		type scored struct {
+			rank  list.Rank
+			score int
+		}
+		var results []scored
+		for i, target := range targets {
+			if matched, score, ok := matchTerms(terms, target, match); ok {
+				results = append(results, scored{list.Rank{Index: i, MatchedIndexes: matched}, score})
+			}
+		}
+		if mode == filterFuzzy {
+			sort.SliceStable(results, func(a, b int) bool { return results[a].score > results[b].score })
+		}
+
+		ranks := make([]list.Rank, len(results))
+		for i, r := range results {
+			ranks[i] = r.rank
+		}
+		return ranks
+	}
+}
+
+// matcherFor builds the comparison for mode. It returns nil when the query
+// can't be used, such as an invalid regular expression.
+func matcherFor(mode filterMode, terms []filterTerm) termMatcher {
+	switch mode {
+	case filterFuzzy:
+		return func(field, value string) ([]int, int, bool) {
+			matches := fuzzy.Find(value, []string{field})
+			if len(matches) == 0 {
+				return nil, 0, false
+			}
+			return byteToRuneIndexes(field, matches[0].MatchedIndexes), matches[0].Score, true
+		}
+	case filterRegex:
+		compiled := make(map[string]*regexp.Regexp, len(terms))
+		for _, t := range terms {
+			if t.glob != nil {
+				continue
+			}
+			re, err := regexp.Compile("(?i)" + t.value)
+			if err != nil {
+				return nil
+			}
+			compiled[t.value] = re
+		}
+		return func(field, value string) ([]int, int, bool) {
+			loc := compiled[value].FindStringIndex(field)
+			if loc == nil {
+				return nil, 0, false
+			}
+			var idx []int
+			for i := range field[loc[0]:loc[1]] {
+				idx = append(idx, loc[0]+i)
+			}
+			return byteToRuneIndexes(field, idx), 0, true
+		}
+	default:
+		return func(field, value string) ([]int, int, bool) {
+			i := runeIndex(strings.ToLower(field), strings.ToLower(value))
+			if i < 0 {
+				return nil, 0, false
+			}
+			idx := make([]int, len([]rune(value)))
+			for j := range idx {
+				idx[j] = i + j
+			}
+			return idx, 0, true
+		}
+	}
+}
+
+// validateFilter reports why query can't be used in mode, if it can't.
+func validateFilter(mode filterMode, query string) error {
+	if mode != filterRegex {
+		return nil
+	}
+	for _, t := range parseFilterQuery(query) {
+		if _, err := regexp.Compile(t.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchTerms(terms []filterTerm, target string, match termMatcher) ([]int, int, bool) {
+	fields := strings.Split(target, filterFieldSep)
+	offsets := make([]int, len(fields))
+	off := 0
+	for i, f := range fields {
+		offsets[i] = off
+		off += len([]rune(f)) + 1
+	}
+
+	var (
+		matched []int
+		total   int
+	)
+	for _, t := range terms {
+		found := false
+		for i, f := range fields {
+			if t.field >= 0 && t.field != i || t.field < 0 && i == fieldTo {
+				// Everyone's own address is in To, so a plain word skips it.
+				continue
+			}
+			if t.glob != nil {
+				if t.glob.MatchString(f) {
+					found = true
+					break
+				}
+				continue
+			}
+			if idx, score, ok := match(f, t.value); ok {
+				if !t.negate {
+					for _, j := range idx {
+						matched = append(matched, offsets[i]+j)
+					}
+					total += score
+				}
+				found = true
+				break
+			}
+		}
+		if found == t.negate {
+			return nil, 0, false
+		}
+	}
+	return matched, total, true
+}
+
+// matchesQuery reports whether e satisfies every term, using substring
+// matching. It backs the focus query from the config.
+func matchesQuery(terms []filterTerm, e email) bool {
+	_, _, ok := matchTerms(terms, e.FilterValue(), matcherFor(filterSubstring, terms))
+	return ok
+}
+
+// fieldMatches picks out the matched indexes that fall inside field of an
+// email's FilterValue, rebased to the start of that field.
+func fieldMatches(e email, field int, matched []int) []int {
+	if len(matched) == 0 {
+		return nil
+	}
+	parts := strings.Split(e.FilterValue(), filterFieldSep)
+	start := 0
+	for i := 0; i < field; i++ {
+		start += len([]rune(parts[i])) + 1
+	}
+	end := start + len([]rune(parts[field]))
+
+	var out []int
+	for _, m := range matched {
+		if m >= start && m < end {
+			out = append(out, m-start)
+		}
+	}
+	return out
+}
+
+// runeIndex is strings.Index measured in runes.
+func runeIndex(s, substr string) int {
+	i := strings.Index(s, substr)
+	if i < 0 {
+		return -1
+	}
+	return len([]rune(s[:i]))
+}
+
+func byteToRuneIndexes(s string, byteIdx []int) []int {
+	out := make([]int, 0, len(byteIdx))
+	for _, b := range byteIdx {
+		out = append(out, utf8.RuneCountInString(s[:b]))
+	}
+	return out
+}
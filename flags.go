@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// flagColor is a message's color flag, as Mail.app has them: one of seven
+// colors, or none. It's Mail.app's flag index plus one, so the zero value
+// is unflagged.
+type flagColor int
+
+const (
+	flagNone flagColor = iota
+	flagRed
+	flagOrange
+	flagYellow
+	flagGreen
+	flagBlue
+	flagPurple
+	flagGray
+)
+
+var flagNames = []string{"No flag", "Red", "Orange", "Yellow", "Green", "Blue", "Purple", "Gray"}
+
+// flagColors are the flags' dots, Mail.app's own colors with near matches
+// for smaller palettes.
+var flagColors = []paletteColor{
+	{},
+	{TrueColor: "#FF453A", ANSI256: "203", ANSI: "9"},
+	{TrueColor: "#FF9F0A", ANSI256: "214", ANSI: "3"},
+	{TrueColor: "#FFD60A", ANSI256: "220", ANSI: "11"},
+	{TrueColor: "#32D74B", ANSI256: "77", ANSI: "10"},
+	{TrueColor: "#0A84FF", ANSI256: "33", ANSI: "12"},
+	{TrueColor: "#BF5AF2", ANSI256: "171", ANSI: "13"},
+	{TrueColor: "#98989D", ANSI256: "246", ANSI: "8"},
+}
+
+func (f flagColor) String() string {
+	if f < 0 || int(f) >= len(flagNames) {
+		return "Unknown flag"
+	}
+	return flagNames[f]
+}
+
+// dot is the flag drawn as a colored dot, or "" without one.
+func (f flagColor) dot() string {
+	if f <= flagNone || int(f) >= len(flagColors) {
+		return ""
+	}
+	return lipgloss.NewStyle().Foreground(flagColors[f].terminalColor()).Render("●")
+}
+
+// flagPicker is the flag screen's state: the message it's for, picked in
+// from, and the selected row.
+type flagPicker struct {
+	target email
+	from   viewMode
+	cursor int
+}
+
+// openFlags switches to the flag picker for e, from the view it was picked
+// in.
+func (m *model) openFlags(e email) tea.Cmd {
+	if _, ok := m.mail().(flagger); !ok {
+		m.setNotice("Color flags aren't supported by " + m.provider.name())
+		return nil
+	}
+	m.flags = flagPicker{target: e, from: m.mode, cursor: max(int(e.flag)-1, 0)}
+	m.mode = flagView
+	return nil
+}
+
+// setFlag flags the picked message f, in the list straight away and in
+// the backend behind it.
+func (m *model) setFlag(f flagColor) tea.Cmd {
+	m.mode = m.flags.from
+	e := m.flags.target
+	key := emailKey(e)
+	for i := range m.emails {
+		if emailKey(m.emails[i]) == key {
+			m.emails[i].flag = f
+		}
+	}
+	if m.currentEmail != nil && emailKey(*m.currentEmail) == key {
+		m.currentEmail.flag = f
+	}
+	fl := m.mail().(flagger)
+	done := "Flagged " + strings.ToLower(f.String())
+	if f == flagNone {
+		done = "Cleared the flag"
+	}
+	return tea.Batch(m.applyEmails(emailsMsg{emails: m.emails, err: m.err, total: m.total}),
+		m.track(actOnMessage("flag", done, func() error { return fl.setFlag(e, f) })))
+}
+
+// flagKeys handles keys in the flag picker. A digit picks that color, and
+// 0 clears the flag.
+func (m *model) flagKeys(key string) (tea.Cmd, bool) {
+	switch key {
+	case "up", "k":
+		m.flags.cursor = (m.flags.cursor + len(flagNames) - 1) % len(flagNames)
+	case "down", "j":
+		m.flags.cursor = (m.flags.cursor + 1) % len(flagNames)
+	case "enter":
+		return m.setFlag(flagAt(m.flags.cursor)), true
+	case "esc", "q":
+		m.mode = m.flags.from
+	default:
+		if n, err := strconv.Atoi(key); err == nil && n >= 0 && n < len(flagNames) {
+			return m.setFlag(flagColor(n)), true
+		}
+	}
+	return nil, true
+}
+
+// flagAt is the flag on row i of the picker, which lists the colors first
+// and no flag last.
+func flagAt(i int) flagColor {
+	return flagColor((i + 1) % len(flagNames))
+}
+
+func (m *model) updateFlags(tea.Msg) tea.Cmd {
+	return nil
+}
+
+func (m model) viewFlags(status string) string {
+	var lines []string
+	for i := range flagNames {
+		f := flagAt(i)
+		cursor := "  "
+		style := bodyStyle
+		if i == m.flags.cursor {
+			cursor = senderStyle.Render("▸ ")
+			style = senderStyle
+		}
+		mark := ""
+		if f == m.flags.target.flag {
+			mark = metaStyle.Render(" • now")
+		}
+		dot := f.dot()
+		if dot == "" {
+			dot = " "
+		}
+		lines = append(lines, cursor+metaStyle.Render(fmt.Sprintf("%d ", f))+dot+" "+style.Render(f.String())+mark)
+	}
+	title := headerStyle.Render("Flag…") + "\n" + metaStyle.Render(m.flags.target.sender+" • "+m.flags.target.subject)
+	bindings := [][]string{{"↑/↓", "select"}, {"enter", "flag"}, {"0", "clear"}, {"esc", "back"}}
+	body := lipgloss.NewStyle().Padding(1, 2).Render(title + "\n\n" + strings.Join(lines, "\n"))
+	gap := max(m.height-lipgloss.Height(body)-lipgloss.Height(status)-1, 0)
+	return body + strings.Repeat("\n", gap) + "\n" + status + renderHelpBar(m.width, bindings)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Gmail's IMAP server shows labels as mailboxes, and its X-GM-LABELS
+// extension reads and sets a message's labels directly, so a message can
+// have several at once and archiving is taking the inbox label away.
+
+// gmailInbox is the inbox's label, as X-GM-LABELS names it.
+const gmailInbox = `\Inbox`
+
+// labels returns the account's labels, the inbox first, and the ones e has.
+// e's may include system labels, such as \Important, that aren't listed.
+func (p imapProvider) labels(e email) (all, on []string, err error) {
+	if !p.gmail() {
+		return nil, nil, fmt.Errorf("%s isn't a Gmail account; only Gmail has labels", p.account())
+	}
+	c, err := p.connect()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer c.logout()
+
+	boxes, err := c.listMailboxes()
+	if err != nil {
+		return nil, nil, err
+	}
+	all = []string{gmailInbox}
+	for _, box := range boxes {
+		if !gmailSystemMailbox(box) {
+			all = append(all, box.name)
+		}
+	}
+	mailbox, err := p.serverMailbox(c, e)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := c.command("EXAMINE %s", imapQuote(mailbox)); err != nil {
+		return nil, nil, err
+	}
+	resps, err := c.command("UID FETCH %s (UID X-GM-LABELS)", e.id)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, r := range resps {
+		if uid, ok := fetchUID(r); ok && strconv.Itoa(uid) == e.id {
+			on = parseGmailLabels(r)
+		}
+	}
+	return all, on, nil
+}
+
+// relabel adds labels to e and takes others away. Taking gmailInbox away
+// archives it.
+func (p imapProvider) relabel(e email, add, remove []string) error {
+	if !p.gmail() {
+		return fmt.Errorf("%s isn't a Gmail account; only Gmail has labels", p.account())
+	}
+	c, err := p.connect()
+	if err != nil {
+		return err
+	}
+	defer c.logout()
+
+	mailbox, err := p.serverMailbox(c, e)
+	if err != nil {
+		return err
+	}
+	if _, err := c.command("SELECT %s", imapQuote(mailbox)); err != nil {
+		return err
+	}
+	if len(add) > 0 {
+		if _, err := c.command("UID STORE %s +X-GM-LABELS (%s)", e.id, gmailLabelList(add)); err != nil {
+			return err
+		}
+	}
+	if len(remove) > 0 {
+		if _, err := c.command("UID STORE %s -X-GM-LABELS (%s)", e.id, gmailLabelList(remove)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unlabelAll takes label away from emails, with one command per mailbox
+// they're in.
+func (p imapProvider) unlabelAll(emails []email, label string) error {
+	c, err := p.connect()
+	if err != nil {
+		return err
+	}
+	defer c.logout()
+
+	byMailbox := map[string][]int{}
+	var order []string
+	for _, e := range emails {
+		uid, err := strconv.Atoi(e.id)
+		if err != nil {
+			continue
+		}
+		mailbox, err := p.serverMailbox(c, e)
+		if err != nil {
+			return err
+		}
+		if _, ok := byMailbox[mailbox]; !ok {
+			order = append(order, mailbox)
+		}
+		byMailbox[mailbox] = append(byMailbox[mailbox], uid)
+	}
+	for _, mailbox := range order {
+		if _, err := c.command("SELECT %s", imapQuote(mailbox)); err != nil {
+			return err
+		}
+		if _, err := c.command("UID STORE %s -X-GM-LABELS (%s)", uidSet(byMailbox[mailbox]), gmailLabelList([]string{label})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gmailSystemMailbox reports whether box is the inbox or one of Gmail's
+// own folders, All Mail, Sent and the like, rather than a label.
+func gmailSystemMailbox(box imapMailbox) bool {
+	return strings.EqualFold(box.name, "INBOX") ||
+		strings.HasPrefix(box.name, "[Gmail]") || strings.HasPrefix(box.name, "[Google Mail]")
+}
+
+// gmailLabelList renders labels for X-GM-LABELS: system labels, which start
+// with a backslash, as atoms and the rest quoted.
+func gmailLabelList(labels []string) string {
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = imapQuote(l)
+		if strings.HasPrefix(l, `\`) {
+			parts[i] = l
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseGmailLabels reads the labels from `* 1 FETCH (X-GM-LABELS (\Inbox
+// "Lists/go") UID 7)`. Each is an atom, a quoted string or a literal.
+func parseGmailLabels(r imapResponse) []string {
+	_, rest, ok := strings.Cut(r.line, "X-GM-LABELS (")
+	if !ok {
+		return nil
+	}
+	literals := r.literals
+	var labels []string
+	for {
+		rest = strings.TrimLeft(rest, " ")
+		switch {
+		case rest == "" || rest[0] == ')':
+			return labels
+		case rest[0] == '"':
+			label, after, ok := imapUnquote(rest)
+			if !ok {
+				return labels
+			}
+			labels, rest = append(labels, label), after
+		case rest[0] == '{':
+			end := strings.IndexByte(rest, '}')
+			if end < 0 || len(literals) == 0 {
+				return labels
+			}
+			labels, rest, literals = append(labels, string(literals[0])), rest[end+1:], literals[1:]
+		default:
+			end := strings.IndexAny(rest, " )")
+			if end < 0 {
+				end = len(rest)
+			}
+			labels, rest = append(labels, rest[:end]), rest[end:]
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// accountHealth is what the accounts screen knows about one account of the
+// backend: its inbox, when a poll last read it, and the last error reading
+// it alone.
+type accountHealth struct {
+	name     string
+	inbox    mailScope
+	unread   int
+	synced   time.Time
+	err      error
+	errAt    time.Time
+	checking bool
+}
+
+// healthState is the backend's sync history for the accounts screen. The
+// first row of the screen is the backend as a whole; the rest are its
+// accounts.
+type healthState struct {
+	synced   time.Time
+	err      error
+	errAt    time.Time
+	accounts []accountHealth
+	cursor   int
+}
+
+// reconnector is implemented by backends that hold a connection open,
+// which reconnect drops so the next call makes a new one.
+type reconnector interface {
+	reconnect()
+}
+
+// reconnect stops the bridge; the next call starts a fresh osascript. It
+// waits for a call in progress, so it's only made from a Cmd.
+func (mailAppProvider) reconnect() {
+	mailBridge.mu.Lock()
+	defer mailBridge.mu.Unlock()
+	mailBridge.stop()
+}
+
+func (h *healthState) account(name string) *accountHealth {
+	for i := range h.accounts {
+		if h.accounts[i].name == name {
+			return &h.accounts[i]
+		}
+	}
+	h.accounts = append(h.accounts, accountHealth{name: name})
+	return &h.accounts[len(h.accounts)-1]
+}
+
+// track lists the [[accounts]] of p from the start, so one that fails
+// before it ever answers is still shown.
+func (h *healthState) track(p mailProvider) {
+	if mp, ok := accountsOf(p); ok {
+		for _, name := range mp.names() {
+			h.account(name)
+		}
+	}
+}
+
+// polled records a poll's outcome. A poll of the default inbox that worked
+// read every account but those it says failed, and clears the errors of
+// each; one of a picked mailbox only says the backend is reachable.
+func (h *healthState) polled(now time.Time, msg emailsMsg, all bool) {
+	if msg.err != nil {
+		h.err, h.errAt = msg.err, now
+		return
+	}
+	h.synced = now
+	if !all {
+		return
+	}
+	for _, e := range msg.emails {
+		if e.account != "" {
+			h.account(e.account)
+		}
+	}
+	for name := range msg.failed {
+		h.account(name)
+	}
+	for i := range h.accounts {
+		a := &h.accounts[i]
+		if err, ok := msg.failed[a.name]; ok {
+			a.err, a.errAt = err, now
+			continue
+		}
+		a.synced = now
+		a.err = nil
+	}
+}
+
+// accountsMsg carries the backend's inboxes, one per account.
+type accountsMsg struct {
+	boxes []mailboxInfo
+	err   error
+}
+
+// reconnectAndPoll drops the backend's connection, if it keeps one, and
+// polls through a new one.
+func reconnectAndPoll(p, poll mailProvider, limit int) tea.Cmd {
+	return func() tea.Msg {
+		if r, ok := p.(reconnector); ok {
+			r.reconnect()
+		}
+		return fetchEmails(poll, limit)()
+	}
+}
+
+func fetchAccounts(b mailboxBrowser) tea.Cmd {
+	return func() tea.Msg {
+		boxes, err := b.listMailboxes()
+		return accountsMsg{boxes: boxes, err: err}
+	}
+}
+
+// accountCheckedMsg is the result of reading one account's inbox.
+type accountCheckedMsg struct {
+	name   string
+	unread int
+	err    error
+}
+
+// checkAccount polls just the inbox of one account, to tell whether it's
+// that account that fails.
+func checkAccount(b mailboxBrowser, a accountHealth) tea.Cmd {
+	return func() tea.Msg {
+		emails, err := b.scoped(a.inbox).unread()
+		return accountCheckedMsg{name: a.name, unread: len(emails), err: err}
+	}
+}
+
+// openAccounts switches to the accounts screen and asks the backend for its
+// accounts.
+func (m *model) openAccounts() tea.Cmd {
+	m.mode = accountsView
+	m.health.cursor = 0
+	if b, ok := m.provider.(mailboxBrowser); ok {
+		return fetchAccounts(b)
+	}
+	return nil
+}
+
+// applyAccounts merges the listed inboxes into the accounts screen.
+func (m *model) applyAccounts(msg accountsMsg) {
+	if msg.err != nil {
+		m.setNotice(fmt.Sprintf("Couldn't list accounts: %v", msg.err))
+		return
+	}
+	for _, b := range msg.boxes {
+		if !b.inbox || b.account == "" {
+			continue
+		}
+		a := m.health.account(b.account)
+		a.inbox = mailScope{account: b.account, mailbox: b.path}
+		a.unread = b.unread
+	}
+}
+
+// applyAccountCheck records the result of checkAccount.
+func (m *model) applyAccountCheck(msg accountCheckedMsg) {
+	a := m.health.account(msg.name)
+	a.checking = false
+	if msg.err != nil {
+		a.err, a.errAt = msg.err, time.Now()
+		return
+	}
+	a.err, a.synced, a.unread = nil, time.Now(), msg.unread
+}
+
+// accountsKeys handles keys in the accounts screen. r on the backend
+// reconnects it and polls; on an account it reads just that account.
+func (m *model) accountsKeys(key string) (tea.Cmd, bool) {
+	h := &m.health
+	rows := len(h.accounts) + 1
+	switch key {
+	case "up", "k":
+		h.cursor = (h.cursor + rows - 1) % rows
+	case "down", "j":
+		h.cursor = (h.cursor + 1) % rows
+	case "esc", "q":
+		m.mode = listView
+	case "r":
+		if h.cursor == 0 {
+			m.log.add("reconnecting to %s", m.provider.name())
+			m.nextPoll = time.Now().Add(m.pollInterval())
+			return tea.Batch(reconnectAndPoll(m.provider, m.trackedMail(), m.fetchLimit()), m.begin(polling)), true
+		}
+		a := &h.accounts[h.cursor-1]
+		b, ok := m.provider.(mailboxBrowser)
+		if !ok || a.inbox == (mailScope{}) {
+			m.setNotice("Can't read " + a.name + " on its own")
+			return nil, true
+		}
+		a.checking = true
+		return checkAccount(b, *a), true
+	}
+	return nil, true
+}
+
+func (m *model) updateAccounts(tea.Msg) tea.Cmd {
+	return nil
+}
+
+// healthSince describes t for the accounts screen.
+func healthSince(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format("15:04:05") + " (" + formatInterval(time.Since(t).Truncate(time.Second)) + " ago)"
+}
+
+func (m model) viewAccounts(status string) string {
+	h := m.health
+	ok := lipgloss.NewStyle().Foreground(successColor)
+	bad := lipgloss.NewStyle().Foreground(errorColor)
+
+	state := ok.Render("Connected")
+	switch {
+	case m.network != netOnline:
+		state = bad.Render(m.network.String())
+	case h.err != nil && h.errAt.After(h.synced):
+		state = bad.Render("Failing")
+	case h.synced.IsZero():
+		state = metaStyle.Render("Not synced yet")
+	}
+	rows := [][]string{{m.provider.name(), state, "synced " + healthSince(h.synced)}}
+	errs := []string{""}
+	if h.err != nil {
+		errs[0] = fmt.Sprintf("last error at %s: %v", h.errAt.Format("15:04:05"), h.err)
+	}
+	for _, a := range h.accounts {
+		state := ok.Render("OK")
+		if a.inbox != (mailScope{}) {
+			state = ok.Render(fmt.Sprintf("%d unread", a.unread))
+		}
+		switch {
+		case a.checking:
+			state = metaStyle.Render("checking…")
+		case a.err != nil:
+			state = bad.Render("Failing")
+		}
+		rows = append(rows, []string{a.name, state, "synced " + healthSince(a.synced)})
+		e := ""
+		if a.err != nil {
+			e = fmt.Sprintf("last error at %s: %v", a.errAt.Format("15:04:05"), a.err)
+		}
+		errs = append(errs, e)
+	}
+
+	width := 0
+	for _, r := range rows {
+		width = max(width, lipgloss.Width(r[0]))
+	}
+	var lines []string
+	for i, r := range rows {
+		cursor := "  "
+		if i == h.cursor {
+			cursor = senderStyle.Render("▸ ")
+		}
+		name := fmt.Sprintf("%-*s", width, r[0])
+		if i == 0 {
+			name = bodyStyle.Bold(true).Render(name)
+		} else {
+			name = bodyStyle.Render(name)
+		}
+		lines = append(lines, cursor+name+"  "+r[1]+metaStyle.Render(" • "+r[2]))
+		if errs[i] != "" {
+			lines = append(lines, "    "+bad.Render(errs[i]))
+		}
+	}
+
+	refresh := "check account"
+	if h.cursor == 0 {
+		refresh = "reconnect"
+	}
+	bindings := [][]string{{"↑/↓", "select"}, {"r", refresh}, {"esc", "back"}}
+	body := lipgloss.NewStyle().Padding(1, 2).Render(headerStyle.Render("Accounts") + "\n\n" + strings.Join(lines, "\n"))
+	gap := max(m.height-lipgloss.Height(body)-lipgloss.Height(status)-1, 0)
+	return body + strings.Repeat("\n", gap) + "\n" + status + renderHelpBar(m.width, bindings)
+}
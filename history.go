@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// historyFile is in the state directory, so the TUI and the daemon both add
+// to it.
+const historyFile = "inbox-history.json"
+
+// Only so much history is kept: enough days for a long streak, and enough
+// clears for a fair average.
+const (
+	historyDays   = 400
+	historyClears = 30
+)
+
+// inboxHistory is when the inbox was last empty: the days it reached zero
+// unread, and how long it took to get there each time mail came in.
+type inboxHistory struct {
+	// Days are the dates, as 2006-01-02, the inbox was at zero, oldest
+	// first.
+	Days []string `json:"days"`
+	// Since is when unread mail last arrived in an empty inbox, while it
+	// isn't empty.
+	Since time.Time `json:"since,omitzero"`
+	// Clears are how long the inbox took to get back to zero, in seconds,
+	// oldest first.
+	Clears []int64 `json:"clears"`
+}
+
+func loadInboxHistory(dir string) (inboxHistory, error) {
+	return loadStateFile[inboxHistory](dir, historyFile)
+}
+
+func (h inboxHistory) save(dir string) error {
+	return writeStateFile(dir, historyFile, h)
+}
+
+// observe records a poll finding unread messages at now, and reports
+// whether that changed anything.
+func (h *inboxHistory) observe(unread int, now time.Time) bool {
+	if unread > 0 {
+		if !h.Since.IsZero() {
+			return false
+		}
+		h.Since = now
+		return true
+	}
+	changed := false
+	if !h.Since.IsZero() {
+		h.Clears = append(h.Clears, int64(now.Sub(h.Since)/time.Second))
+		if len(h.Clears) > historyClears {
+			h.Clears = h.Clears[len(h.Clears)-historyClears:]
+		}
+		h.Since = time.Time{}
+		changed = true
+	}
+	if today := now.Format(time.DateOnly); len(h.Days) == 0 || h.Days[len(h.Days)-1] != today {
+		h.Days = append(h.Days, today)
+		if len(h.Days) > historyDays {
+			h.Days = h.Days[len(h.Days)-historyDays:]
+		}
+		changed = true
+	}
+	return changed
+}
+
+// recordUnread adds a poll's unread count to the history in dir, on top of
+// what the daemon or another machine wrote, and returns the history.
+func recordUnread(dir string, unread int, now time.Time) (inboxHistory, error) {
+	h, err := loadInboxHistory(dir)
+	if err != nil {
+		return h, err
+	}
+	if !h.observe(unread, now) {
+		return h, nil
+	}
+	return h, h.save(dir)
+}
+
+// streak is how many days in a row, up to today, the inbox reached zero.
+// A streak that ran to yesterday still counts while today isn't over.
+func (h inboxHistory) streak(now time.Time) int {
+	day := now
+	i := len(h.Days) - 1
+	if i >= 0 && h.Days[i] != day.Format(time.DateOnly) {
+		day = day.AddDate(0, 0, -1)
+	}
+	n := 0
+	for ; i >= 0 && h.Days[i] == day.Format(time.DateOnly); i-- {
+		n++
+		day = day.AddDate(0, 0, -1)
+	}
+	return n
+}
+
+// best is the longest streak on record.
+func (h inboxHistory) best() int {
+	best, run := 0, 0
+	var prev time.Time
+	for _, d := range h.Days {
+		t, err := time.Parse(time.DateOnly, d)
+		if err != nil {
+			continue
+		}
+		if prev.AddDate(0, 0, 1).Equal(t) {
+			run++
+		} else {
+			run = 1
+		}
+		best = max(best, run)
+		prev = t
+	}
+	return best
+}
+
+// timeToZero is the average time the inbox took to get back to zero, or 0
+// before it ever has.
+func (h inboxHistory) timeToZero() time.Duration {
+	if len(h.Clears) == 0 {
+		return 0
+	}
+	var sum int64
+	for _, s := range h.Clears {
+		sum += s
+	}
+	return time.Duration(sum/int64(len(h.Clears))) * time.Second
+}
+
+// streakWidget is the empty inbox's line about the streak and the average
+// time to zero, or "" without any history yet.
+func (m model) streakWidget() string {
+	h := m.zero
+	if len(h.Days) == 0 {
+		return ""
+	}
+	text := "no inbox-zero streak running"
+	switch n := h.streak(time.Now()); {
+	case n == 1:
+		text = "🔥 1 day at inbox zero"
+	case n > 1:
+		text = fmt.Sprintf("🔥 %d days in a row at inbox zero", n)
+	}
+	if best := h.best(); best > 1 {
+		text += fmt.Sprintf(" • best %d", best)
+	}
+	switch avg := h.timeToZero(); {
+	case avg >= time.Minute:
+		// formatAge's precise style gives two units, as in "2h 10m".
+		text += " • back to zero in " + strings.TrimSuffix(formatAge(avg, timePrecise), " ago") + " on average"
+	case len(h.Clears) > 0:
+		text += " • back to zero in under a minute on average"
+	}
+	return text
+}
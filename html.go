@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	htmlDropped = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	htmlToken   = regexp.MustCompile(`(?s)<!--.*?-->|<![^>]*>|<(/?)([a-zA-Z][a-zA-Z0-9]*)([^>]*)>`)
+	htmlAttr    = regexp.MustCompile(`(?is)([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+// htmlToText renders HTML as readable terminal text: paragraphs, headings,
+// lists, quotes and rules keep their shape, bold and italic become *bold*
+// and _italic_, and links are numbered with their URLs listed as footnotes
+// at the end.
+func htmlToText(s string) string {
+	r := &htmlRenderer{lineStart: true}
+	s = htmlDropped.ReplaceAllString(s, "")
+	last := 0
+	for _, m := range htmlToken.FindAllStringSubmatchIndex(s, -1) {
+		r.text(s[last:m[0]])
+		last = m[1]
+		if m[4] < 0 {
+			continue // comment or doctype
+		}
+		closing := m[3] > m[2]
+		r.tag(strings.ToLower(s[m[4]:m[5]]), closing, s[m[6]:m[7]])
+	}
+	r.text(s[last:])
+	return r.String()
+}
+
+// htmlRenderer accumulates the rendered text. Whitespace in text is
+// collapsed outside <pre>, and newlines are capped so nested blocks don't
+// stack up blank lines.
+type htmlRenderer struct {
+	b         strings.Builder
+	newlines  int
+	lineStart bool
+	space     bool
+	quote     int
+	pre       int
+	// lists holds a counter per open list; -1 marks an unordered one.
+	lists []int
+	// anchors holds the open links' targets and where their text began.
+	anchors []htmlAnchor
+	links   []string
+	cells   int
+}
+
+type htmlAnchor struct {
+	href  string
+	start int
+}
+
+func (r *htmlRenderer) String() string {
+	out := strings.TrimSpace(r.b.String())
+	if len(r.links) == 0 {
+		return out
+	}
+	var b strings.Builder
+	b.WriteString(out + "\n\n")
+	for i, link := range r.links {
+		fmt.Fprintf(&b, "[%d] %s\n", i+1, link)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (r *htmlRenderer) write(s string) {
+	if s == "" {
+		return
+	}
+	if r.lineStart {
+		r.b.WriteString(strings.Repeat("> ", r.quote))
+		r.lineStart = false
+	} else if r.space {
+		r.b.WriteByte(' ')
+	}
+	r.space = false
+	r.newlines = 0
+	r.b.WriteString(s)
+}
+
+// newline ends the current line, leaving at most max line breaks in a
+// row.
+func (r *htmlRenderer) newline(max int) {
+	if r.b.Len() == 0 {
+		return
+	}
+	for r.newlines < max {
+		r.b.WriteByte('\n')
+		r.newlines++
+	}
+	r.lineStart = true
+	r.space = false
+}
+
+func (r *htmlRenderer) text(s string) {
+	s = html.UnescapeString(s)
+	if r.pre > 0 {
+		for i, line := range strings.Split(s, "\n") {
+			if i > 0 {
+				r.b.WriteByte('\n')
+				r.lineStart = true
+			}
+			r.write(line)
+		}
+		return
+	}
+	if s == "" {
+		return
+	}
+	if unicode.IsSpace(rune(s[0])) {
+		r.space = true
+	}
+	for _, word := range strings.FieldsFunc(s, unicode.IsSpace) {
+		r.write(word)
+		r.space = true
+	}
+	if !unicode.IsSpace(rune(s[len(s)-1])) {
+		r.space = false
+	}
+}
+
+func (r *htmlRenderer) tag(name string, closing bool, attrs string) {
+	switch name {
+	case "br":
+		r.b.WriteByte('\n')
+		r.newlines++
+		r.lineStart, r.space = true, false
+	case "p", "div", "section", "article", "header", "footer", "table", "center":
+		r.newline(2)
+	case "tr":
+		r.newline(1)
+		r.cells = 0
+	case "td", "th":
+		if !closing {
+			if r.cells > 0 && !r.lineStart {
+				r.b.WriteString("  ")
+				r.space = false
+			}
+			r.cells++
+		}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.newline(2)
+		if !closing {
+			r.write(strings.Repeat("#", int(name[1]-'0')) + " ")
+		}
+	case "ul", "ol":
+		if closing {
+			if len(r.lists) > 0 {
+				r.lists = r.lists[:len(r.lists)-1]
+			}
+		} else if name == "ol" {
+			r.lists = append(r.lists, 0)
+		} else {
+			r.lists = append(r.lists, -1)
+		}
+		r.newline(1)
+	case "li":
+		r.newline(1)
+		if closing {
+			return
+		}
+		indent := ""
+		bullet := "• "
+		if n := len(r.lists); n > 0 {
+			indent = strings.Repeat("  ", n-1)
+			if r.lists[n-1] >= 0 {
+				r.lists[n-1]++
+				bullet = fmt.Sprintf("%d. ", r.lists[n-1])
+			}
+		}
+		r.write(indent + bullet)
+	case "blockquote":
+		r.newline(2)
+		if closing {
+			if r.quote > 0 {
+				r.quote--
+			}
+		} else {
+			r.quote++
+		}
+	case "pre":
+		r.newline(2)
+		if closing {
+			if r.pre > 0 {
+				r.pre--
+			}
+		} else {
+			r.pre++
+		}
+	case "hr":
+		r.newline(1)
+		r.write(strings.Repeat("─", 24))
+		r.newline(1)
+	case "b", "strong":
+		r.emphasis("*", closing)
+	case "i", "em":
+		r.emphasis("_", closing)
+	case "img":
+		if alt := htmlAttrValue(attrs, "alt"); strings.TrimSpace(alt) != "" {
+			r.write("[" + strings.TrimSpace(html.UnescapeString(alt)) + "]")
+			r.space = true
+		}
+	case "a":
+		if !closing {
+			r.anchors = append(r.anchors, htmlAnchor{href: html.UnescapeString(htmlAttrValue(attrs, "href")), start: r.b.Len()})
+			return
+		}
+		if len(r.anchors) == 0 {
+			return
+		}
+		a := r.anchors[len(r.anchors)-1]
+		r.anchors = r.anchors[:len(r.anchors)-1]
+		text := strings.TrimSpace(r.b.String()[a.start:])
+		if !footnoteLink(a.href) || text == a.href || text == strings.TrimPrefix(a.href, "mailto:") {
+			return
+		}
+		r.links = append(r.links, a.href)
+		r.b.WriteString(fmt.Sprintf("[%d]", len(r.links)))
+		r.newlines = 0
+	}
+}
+
+// emphasis opens or closes a *bold* or _italic_ run, hugging the text.
+func (r *htmlRenderer) emphasis(mark string, closing bool) {
+	if closing {
+		r.b.WriteString(mark)
+		r.newlines = 0
+		return
+	}
+	r.write(mark)
+}
+
+// footnoteLink reports whether href is worth listing: not empty, a page
+// anchor or a script.
+func footnoteLink(href string) bool {
+	href = strings.TrimSpace(href)
+	return href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(strings.ToLower(href), "javascript:")
+}
+
+func htmlAttrValue(attrs, name string) string {
+	for _, m := range htmlAttr.FindAllStringSubmatch(attrs, -1) {
+		if strings.EqualFold(m[1], name) {
+			return m[2] + m[3] + m[4]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,964 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapTimeout bounds every network round trip so a stalled server can't
+// hang a poll.
+const imapTimeout = 30 * time.Second
+
+// imapFetchChunk is the most messages' headers one FETCH asks for. A
+// poll's fetch goes in chunks, a quarter of it at most, reporting its
+// progress after each.
+const imapFetchChunk = 100
+
+// imapFetchChunkMin keeps a small fetch's chunks from being so small the
+// round trips cost more than the progress shows.
+const imapFetchChunkMin = 5
+
+// imapHeaderFields are the headers fetched for the message list.
+const imapHeaderFields = "FROM SUBJECT DATE TO CC X-PRIORITY IMPORTANCE MESSAGE-ID AUTO-SUBMITTED PRECEDENCE LIST-ID REFERENCES IN-REPLY-TO"
+
+// imapProvider reads mail straight from an IMAP server. It opens a fresh
+// connection per operation, which keeps it stateless between polls.
+type imapProvider struct {
+	cfg       backendConfig
+	mailboxes mailboxConfig
+	scope     mailScope
+	// limit caps how many unread messages a poll returns.
+	limit int
+	// progress is the poll this provider reads for, if any.
+	progress *pollProgress
+	// ctx, if set, stops the provider's reads once it's done.
+	ctx context.Context
+}
+
+func newIMAPProvider(cfg backendConfig, mailboxes mailboxConfig, limit int) (imapProvider, error) {
+	if cfg.Host == "" {
+		return imapProvider{}, fmt.Errorf("imap backend: no host configured")
+	}
+	switch cfg.TLS {
+	case "", "tls", "starttls":
+	case "none":
+		// LOGIN would send the password in the clear; that's only safe when
+		// it never leaves the machine, as with a local Dovecot or a bridge.
+		if !loopbackHost(cfg.Host) {
+			return imapProvider{}, fmt.Errorf("imap backend: tls = \"none\" would send the password to %s unencrypted; use \"tls\" or \"starttls\", or a server on this machine", cfg.Host)
+		}
+	default:
+		return imapProvider{}, fmt.Errorf("imap backend: unknown tls mode %q", cfg.TLS)
+	}
+	return imapProvider{cfg: cfg, mailboxes: mailboxes, limit: limit}, nil
+}
+
+// loopbackHost reports whether host is this machine: localhost or a
+// loopback address.
+func loopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+func (p imapProvider) name() string {
+	return fmt.Sprintf("IMAP (%s)", p.cfg.Host)
+}
+
+// account labels messages the way Mail.app labels them with an account
+// name.
+func (p imapProvider) account() string {
+	if p.cfg.Account != "" {
+		return p.cfg.Account
+	}
+	if p.cfg.Username != "" {
+		return p.cfg.Username
+	}
+	return p.cfg.Host
+}
+
+func (p imapProvider) withContext(ctx context.Context) mailProvider {
+	p.ctx = ctx
+	return p
+}
+
+func (p imapProvider) reporting(progress *pollProgress) mailProvider {
+	p.progress = progress
+	return p
+}
+
+func (p imapProvider) unread() ([]email, error) {
+	emails, _, err := p.find("UNSEEN", p.limit)
+	return emails, err
+}
+
+// unreadPage returns up to limit unread messages and how many are unread
+// across the monitored mailboxes.
+func (p imapProvider) unreadPage(limit int) ([]email, int, error) {
+	return p.find("UNSEEN", limit)
+}
+
+// search finds messages, read or not, matching q, using the server's own
+// SEARCH, or on Gmail its web search syntax through X-GM-RAW.
+func (p imapProvider) search(q mailSearch) ([]email, error) {
+	criteria := imapCriteria(q)
+	if p.gmail() {
+		criteria = "X-GM-RAW " + imapQuote(gmailQuery(q))
+	}
+	for _, r := range criteria {
+		if r > 127 {
+			criteria = "CHARSET UTF-8 " + criteria
+			break
+		}
+	}
+	emails, _, err := p.find(criteria, maxSearchResults)
+	return emails, err
+}
+
+// gmail reports whether p is a Gmail account, whose server takes Gmail's
+// search syntax.
+func (p imapProvider) gmail() bool {
+	host := strings.ToLower(p.cfg.Host)
+	return host == "imap.gmail.com" || host == "imap.googlemail.com"
+}
+
+// imapCriteria translates q into IMAP SEARCH keys. IMAP has no key for
+// attachments, so has:attachment looks for a multipart/mixed message, which
+// is how most clients send them.
+func imapCriteria(q mailSearch) string {
+	var keys []string
+	if q.text != "" {
+		t := imapQuote(q.text)
+		keys = append(keys, fmt.Sprintf("OR OR FROM %s SUBJECT %s BODY %s", t, t, t))
+	}
+	if q.from != "" {
+		keys = append(keys, "FROM "+imapQuote(q.from))
+	}
+	if q.to != "" {
+		t := imapQuote(q.to)
+		keys = append(keys, fmt.Sprintf("OR TO %s CC %s", t, t))
+	}
+	if q.subject != "" {
+		keys = append(keys, "SUBJECT "+imapQuote(q.subject))
+	}
+	if !q.after.IsZero() {
+		keys = append(keys, "SINCE "+q.after.Format("2-Jan-2006"))
+	}
+	if !q.before.IsZero() {
+		keys = append(keys, "BEFORE "+q.before.Format("2-Jan-2006"))
+	}
+	if q.hasAttachment {
+		keys = append(keys, `HEADER Content-Type "multipart/mixed"`)
+	}
+	return strings.Join(keys, " ")
+}
+
+// gmailQuery translates q into Gmail's search syntax.
+func gmailQuery(q mailSearch) string {
+	term := func(op, v string) string {
+		if strings.ContainsAny(v, " \t") {
+			v = `"` + strings.ReplaceAll(v, `"`, "") + `"`
+		}
+		return op + v
+	}
+	var terms []string
+	if q.text != "" {
+		terms = append(terms, term("", q.text))
+	}
+	if q.from != "" {
+		terms = append(terms, term("from:", q.from))
+	}
+	if q.to != "" {
+		terms = append(terms, "{"+term("to:", q.to)+" "+term("cc:", q.to)+"}")
+	}
+	if q.subject != "" {
+		terms = append(terms, term("subject:", q.subject))
+	}
+	if !q.after.IsZero() {
+		terms = append(terms, "after:"+q.after.Format("2006/01/02"))
+	}
+	if !q.before.IsZero() {
+		terms = append(terms, "before:"+q.before.Format("2006/01/02"))
+	}
+	if q.hasAttachment {
+		terms = append(terms, "has:attachment")
+	}
+	return strings.Join(terms, " ")
+}
+
+// find returns the newest messages matching the SEARCH criteria across the
+// monitored mailboxes, up to limit, and how many match in all. Mailboxes
+// past the limit are still searched, to count them, but not fetched.
+func (p imapProvider) find(criteria string, limit int) ([]email, int, error) {
+	c, err := p.connect()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer c.logout()
+
+	boxes, err := p.monitoredMailboxes(c)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Search every mailbox first, so a long fetch can say how far it has
+	// got.
+	found := make([][]int, len(boxes))
+	total, want := 0, 0
+	selected := ""
+	for i, box := range boxes {
+		if _, err := c.command("EXAMINE %s", imapQuote(box.name)); err != nil {
+			return nil, 0, err
+		}
+		selected = box.name
+		uids, err := c.search(criteria)
+		if err != nil {
+			return nil, 0, err
+		}
+		total += len(uids)
+		// Newest first, like Mail.app's inbox order.
+		sort.Sort(sort.Reverse(sort.IntSlice(uids)))
+		if room := limit - want; len(uids) > room {
+			uids = uids[:room]
+		}
+		found[i] = uids
+		want += len(uids)
+	}
+
+	var emails []email
+	chunk := imapFetchChunk
+	if p.progress != nil {
+		chunk = min(imapFetchChunk, max(want/4, imapFetchChunkMin))
+		p.progress.report(p.account(), nil, want)
+	}
+	for i, box := range boxes {
+		if len(found[i]) == 0 {
+			continue
+		}
+		if box.name != selected {
+			if _, err := c.command("EXAMINE %s", imapQuote(box.name)); err != nil {
+				return nil, 0, err
+			}
+			selected = box.name
+		}
+		for uids := range slices.Chunk(found[i], chunk) {
+			resps, err := c.command("UID FETCH %s (UID RFC822.SIZE BODY.PEEK[HEADER.FIELDS (%s)])", uidSet(uids), imapHeaderFields)
+			if err != nil {
+				return nil, 0, err
+			}
+			fetched := map[int]email{}
+			for _, r := range resps {
+				uid, ok := fetchUID(r)
+				if !ok || len(r.literals) == 0 {
+					continue
+				}
+				e := parseHeaderEmail(r.literals[0])
+				e.id = strconv.Itoa(uid)
+				e.size = fetchSize(r)
+				e.account = p.account()
+				if p.mailboxes.sweep() || p.scope != (mailScope{}) {
+					e.mailbox = box.path
+				}
+				fetched[uid] = e
+			}
+			for _, uid := range uids {
+				if e, ok := fetched[uid]; ok {
+					emails = append(emails, e)
+				}
+			}
+			p.progress.report(p.account(), emails, want)
+		}
+	}
+	return emails, total, nil
+}
+
+func (p imapProvider) content(e email) (messageContent, error) {
+	// SELECT rather than EXAMINE so fetching the body sets \Seen, as
+	// opening a message in Mail.app does.
+	return p.open(e, "SELECT", "BODY[]")
+}
+
+// peek fetches with BODY.PEEK, which leaves \Seen alone.
+func (p imapProvider) peek(e email) (messageContent, error) {
+	return p.open(e, "EXAMINE", "BODY.PEEK[]")
+}
+
+func (p imapProvider) open(e email, mode, item string) (messageContent, error) {
+	c, err := p.connect()
+	if err != nil {
+		return messageContent{}, err
+	}
+	defer c.logout()
+
+	mailbox, err := p.serverMailbox(c, e)
+	if err != nil {
+		return messageContent{}, err
+	}
+	raw, err := c.fetchBody(mailbox, e.id, mode, item)
+	if err != nil {
+		return messageContent{}, err
+	}
+	content, err := parseMessage(raw)
+	if err != nil {
+		return messageContent{}, err
+	}
+	content.id = e.id
+	return content, nil
+}
+
+// markRead sets \Seen on emails by UID, one mailbox at a time.
+func (p imapProvider) markRead(emails []email) error {
+	byMailbox := map[string][]int{}
+	var order []string
+	for _, e := range emails {
+		uid, err := strconv.Atoi(e.id)
+		if err != nil {
+			continue
+		}
+		if _, ok := byMailbox[e.mailbox]; !ok {
+			order = append(order, e.mailbox)
+		}
+		byMailbox[e.mailbox] = append(byMailbox[e.mailbox], uid)
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	c, err := p.connect()
+	if err != nil {
+		return err
+	}
+	defer c.logout()
+
+	for _, path := range order {
+		mailbox, err := p.serverMailbox(c, email{mailbox: path})
+		if err != nil {
+			return err
+		}
+		if _, err := c.command("SELECT %s", imapQuote(mailbox)); err != nil {
+			return err
+		}
+		if _, err := c.command(`UID STORE %s +FLAGS.SILENT (\Seen)`, uidSet(byMailbox[path])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p imapProvider) saveAttachment(e email, a attachment, dest string) error {
+	c, err := p.connect()
+	if err != nil {
+		return err
+	}
+	defer c.logout()
+
+	mailbox, err := p.serverMailbox(c, e)
+	if err != nil {
+		return err
+	}
+	raw, err := c.fetchBody(mailbox, e.id, "EXAMINE", "BODY.PEEK[]")
+	if err != nil {
+		return err
+	}
+	data, err := attachmentData(raw, a.name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dest, data, 0o600)
+}
+
+func (p imapProvider) markUnread(e email) error {
+	c, err := p.connect()
+	if err != nil {
+		return err
+	}
+	defer c.logout()
+
+	mailbox, err := p.serverMailbox(c, e)
+	if err != nil {
+		return err
+	}
+	if _, err := c.command("SELECT %s", imapQuote(mailbox)); err != nil {
+		return err
+	}
+	_, err = c.command(`UID STORE %s -FLAGS.SILENT (\Seen)`, e.id)
+	return err
+}
+
+func (p imapProvider) trash(e email) error {
+	return p.trashAll([]email{e})
+}
+
+func (p imapProvider) archive(e email) error {
+	return p.archiveAll([]email{e})
+}
+
+func (p imapProvider) trashAll(emails []email) error {
+	return p.moveTo(emails, `\trash`, "Trash", "Deleted Items", "Deleted Messages")
+}
+
+func (p imapProvider) archiveAll(emails []email) error {
+	if p.mailboxes.Archive != "" {
+		return p.moveTo(emails, "", p.mailboxes.Archive)
+	}
+	if p.gmail() {
+		return p.unlabelAll(emails, gmailInbox)
+	}
+	return p.moveTo(emails, `\archive`, "Archive")
+}
+
+// moveTo moves emails to the mailbox with the special-use attribute use,
+// or failing that the first of names the server has, with one command per
+// mailbox they're in.
+func (p imapProvider) moveTo(emails []email, use string, names ...string) error {
+	c, err := p.connect()
+	if err != nil {
+		return err
+	}
+	defer c.logout()
+
+	byMailbox := map[string][]int{}
+	var order []string
+	for _, e := range emails {
+		uid, err := strconv.Atoi(e.id)
+		if err != nil {
+			continue
+		}
+		mailbox, err := p.serverMailbox(c, e)
+		if err != nil {
+			return err
+		}
+		if _, ok := byMailbox[mailbox]; !ok {
+			order = append(order, mailbox)
+		}
+		byMailbox[mailbox] = append(byMailbox[mailbox], uid)
+	}
+	boxes, err := c.listMailboxes()
+	if err != nil {
+		return err
+	}
+	dest := ""
+	for _, box := range boxes {
+		if use != "" && strings.Contains(box.attrs, use) {
+			dest = box.name
+			break
+		}
+	}
+	for _, name := range names {
+		for _, box := range boxes {
+			if dest == "" && (box.name == name || box.path == name) {
+				dest = box.name
+			}
+		}
+	}
+	if dest == "" {
+		return fmt.Errorf("no %s mailbox on the server", names[0])
+	}
+
+	for _, mailbox := range order {
+		uids := uidSet(byMailbox[mailbox])
+		if _, err := c.command("SELECT %s", imapQuote(mailbox)); err != nil {
+			return err
+		}
+		if _, err := c.command("UID MOVE %s %s", uids, imapQuote(dest)); err == nil {
+			continue
+		}
+		// Without MOVE (RFC 6851), copy and then expunge just these
+		// messages.
+		if _, err := c.command("UID COPY %s %s", uids, imapQuote(dest)); err != nil {
+			return err
+		}
+		if _, err := c.command(`UID STORE %s +FLAGS.SILENT (\Deleted)`, uids); err != nil {
+			return err
+		}
+		// UID EXPUNGE needs UIDPLUS; without it the originals stay flagged
+		// \Deleted for the next expunge, rather than risk expunging others.
+		c.command("UID EXPUNGE %s", uids)
+	}
+	return nil
+}
+
+// imapMailbox is a mailbox as the server names it, and as a slash-separated
+// path for matching against the config globs.
+type imapMailbox struct {
+	name string
+	path string
+	// attrs are the lower-cased LIST attributes, such as `\trash`.
+	attrs string
+}
+
+// serverMailbox finds the server's name for e's mailbox. Paths use '/'
+// whatever the server's delimiter is, so they're matched against a fresh
+// listing.
+func (p imapProvider) serverMailbox(c *imapConn, e email) (string, error) {
+	if e.mailbox == "" {
+		return "INBOX", nil
+	}
+	boxes, err := p.monitoredMailboxes(c)
+	if err != nil {
+		return "", err
+	}
+	for _, box := range boxes {
+		if box.path == e.mailbox {
+			return box.name, nil
+		}
+	}
+	return e.mailbox, nil
+}
+
+// monitoredMailboxes lists the selectable mailboxes the config selects, or
+// just INBOX when not sweeping. A scope narrows it to that one mailbox.
+func (p imapProvider) monitoredMailboxes(c *imapConn) ([]imapMailbox, error) {
+	if !p.mailboxes.sweep() && p.scope == (mailScope{}) {
+		return []imapMailbox{{name: "INBOX", path: "INBOX"}}, nil
+	}
+	all, err := c.listMailboxes()
+	if err != nil {
+		return nil, err
+	}
+	var boxes []imapMailbox
+	for _, box := range all {
+		selected := p.mailboxes.monitors(box.path)
+		if p.scope != (mailScope{}) {
+			selected = box.path == p.scope.mailbox
+		}
+		if selected {
+			boxes = append(boxes, box)
+		}
+	}
+	return boxes, nil
+}
+
+func (p imapProvider) listMailboxes() ([]mailboxInfo, error) {
+	c, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer c.logout()
+
+	boxes, err := c.listMailboxes()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]mailboxInfo, 0, len(boxes))
+	for _, box := range boxes {
+		info := mailboxInfo{account: p.account(), path: box.path, inbox: strings.EqualFold(box.name, "INBOX")}
+		resps, err := c.command("STATUS %s (UNSEEN)", imapQuote(box.name))
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resps {
+			if m := statusUnseenPattern.FindStringSubmatch(r.line); m != nil {
+				info.unread, _ = strconv.Atoi(m[1])
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+var statusUnseenPattern = regexp.MustCompile(`(?i)\bUNSEEN (\d+)`)
+
+func (p imapProvider) scoped(s mailScope) mailProvider {
+	p.scope = s
+	return p
+}
+
+// addr is the server's host and port, the port defaulting to the one for
+// the TLS mode.
+func (p imapProvider) addr() string {
+	port := p.cfg.Port
+	if port == 0 {
+		port = 993
+		if p.cfg.TLS == "starttls" || p.cfg.TLS == "none" {
+			port = 143
+		}
+	}
+	return net.JoinHostPort(p.cfg.Host, strconv.Itoa(port))
+}
+
+func (p imapProvider) connect() (*imapConn, error) {
+	addr := p.addr()
+	dialer := &net.Dialer{Timeout: imapTimeout}
+	tlsConfig := &tls.Config{ServerName: p.cfg.Host}
+	ctx := p.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var conn net.Conn
+	var err error
+	if p.cfg.TLS == "" || p.cfg.TLS == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, imapConnError{err}
+	}
+	// Closing the connection when ctx is done ends whatever command is
+	// waiting on the server.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	c := &imapConn{conn: conn, r: bufio.NewReader(conn), stop: stop}
+	if err := c.greeting(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if p.cfg.TLS == "starttls" {
+		if _, err := c.command("STARTTLS"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		c.conn, c.r = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	password, err := resolveSecret(p.cfg.Password)
+	if err != nil {
+		c.conn.Close()
+		return nil, fmt.Errorf("imap password: %w", err)
+	}
+	if _, err := c.command("LOGIN %s %s", imapQuote(p.cfg.Username), imapQuote(password)); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// imapConnError is the connection to an IMAP server failing, to dial, to
+// resolve or mid-command, rather than the server refusing something.
+type imapConnError struct {
+	err error
+}
+
+func (e imapConnError) Error() string { return e.err.Error() }
+func (e imapConnError) Unwrap() error { return e.err }
+
+// imapConn is a minimal IMAP4rev1 client: enough to list, search, fetch and
+// flag messages.
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	seq  int
+	// stop lets go of the connection's context.
+	stop func() bool
+}
+
+// imapResponse is one untagged server response. Literals are cut out of the
+// line and kept in order, leaving their {n} markers behind.
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+func (c *imapConn) greeting() error {
+	c.conn.SetDeadline(time.Now().Add(imapTimeout))
+	r, err := c.readResponse()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(r.line, "* OK") && !strings.HasPrefix(r.line, "* PREAUTH") {
+		return fmt.Errorf("imap: unexpected greeting %q", r.line)
+	}
+	return nil
+}
+
+// command sends a tagged command and collects the untagged responses up to
+// its completion, failing unless the server answers OK.
+func (c *imapConn) command(format string, args ...any) ([]imapResponse, error) {
+	c.seq++
+	tag := fmt.Sprintf("m%d", c.seq)
+	c.conn.SetDeadline(time.Now().Add(imapTimeout))
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, imapConnError{err}
+	}
+
+	var untagged []imapResponse
+	for {
+		r, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		status, tagged := strings.CutPrefix(r.line, tag+" ")
+		if !tagged {
+			untagged = append(untagged, r)
+			continue
+		}
+		if !strings.HasPrefix(status, "OK") {
+			return nil, fmt.Errorf("imap: %s", status)
+		}
+		return untagged, nil
+	}
+}
+
+func (c *imapConn) readResponse() (imapResponse, error) {
+	var (
+		r    imapResponse
+		line strings.Builder
+	)
+	for {
+		s, err := c.r.ReadString('\n')
+		if err != nil {
+			return r, imapConnError{err}
+		}
+		s = strings.TrimRight(s, "\r\n")
+		line.WriteString(s)
+		n, ok := literalSize(s)
+		if !ok {
+			break
+		}
+		lit := make([]byte, n)
+		if _, err := io.ReadFull(c.r, lit); err != nil {
+			return r, imapConnError{err}
+		}
+		r.literals = append(r.literals, lit)
+	}
+	r.line = line.String()
+	return r, nil
+}
+
+// literalSize reports the size of a "{n}" literal announced at the end of
+// line.
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	i := strings.LastIndexByte(line, '{')
+	if i < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[i+1:len(line)-1], "+"))
+	return n, err == nil
+}
+
+// listMailboxes lists the selectable mailboxes on the server.
+func (c *imapConn) listMailboxes() ([]imapMailbox, error) {
+	resps, err := c.command(`LIST "" "*"`)
+	if err != nil {
+		return nil, err
+	}
+	var boxes []imapMailbox
+	for _, r := range resps {
+		if box, ok := parseListResponse(r); ok {
+			boxes = append(boxes, box)
+		}
+	}
+	return boxes, nil
+}
+
+func (c *imapConn) search(criteria string) ([]int, error) {
+	resps, err := c.command("UID SEARCH %s", criteria)
+	if err != nil {
+		return nil, err
+	}
+	var uids []int
+	for _, r := range resps {
+		rest, ok := strings.CutPrefix(r.line, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, f := range strings.Fields(rest) {
+			if uid, err := strconv.Atoi(f); err == nil {
+				uids = append(uids, uid)
+			}
+		}
+	}
+	return uids, nil
+}
+
+// fetchBody opens mailbox with open (SELECT or EXAMINE) and fetches item for
+// the message with the given UID.
+func (c *imapConn) fetchBody(mailbox, uid, open, item string) ([]byte, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("message has no id")
+	}
+	if _, err := c.command("%s %s", open, imapQuote(mailbox)); err != nil {
+		return nil, err
+	}
+	resps, err := c.command("UID FETCH %s (UID %s)", uid, item)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resps {
+		if got, ok := fetchUID(r); ok && strconv.Itoa(got) == uid && len(r.literals) > 0 {
+			return r.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("message %s not found in %s", uid, mailbox)
+}
+
+func (c *imapConn) logout() {
+	c.command("LOGOUT")
+	c.conn.Close()
+	c.stop()
+}
+
+var fetchUIDPattern = regexp.MustCompile(`\bUID (\d+)`)
+
+var fetchSizePattern = regexp.MustCompile(`\bRFC822\.SIZE (\d+)`)
+
+// fetchSize is a FETCH response's RFC822.SIZE, or 0 without one.
+func fetchSize(r imapResponse) int {
+	m := fetchSizePattern.FindStringSubmatch(r.line)
+	if m == nil {
+		return 0
+	}
+	size, _ := strconv.Atoi(m[1])
+	return size
+}
+
+func fetchUID(r imapResponse) (int, bool) {
+	if !strings.HasPrefix(r.line, "* ") || !strings.Contains(r.line, " FETCH ") {
+		return 0, false
+	}
+	m := fetchUIDPattern.FindStringSubmatch(r.line)
+	if m == nil {
+		return 0, false
+	}
+	uid, err := strconv.Atoi(m[1])
+	return uid, err == nil
+}
+
+// parseListResponse reads `* LIST (\HasNoChildren) "/" "Lists/go"`. The
+// name may also be an atom or a literal.
+func parseListResponse(r imapResponse) (imapMailbox, bool) {
+	rest, ok := strings.CutPrefix(r.line, "* LIST ")
+	if !ok {
+		return imapMailbox{}, false
+	}
+	end := strings.IndexByte(rest, ')')
+	if !strings.HasPrefix(rest, "(") || end < 0 {
+		return imapMailbox{}, false
+	}
+	attrs := strings.ToLower(rest[1:end])
+	if strings.Contains(attrs, `\noselect`) {
+		return imapMailbox{}, false
+	}
+	rest = strings.TrimSpace(rest[end+1:])
+
+	delim := ""
+	if strings.HasPrefix(rest, "NIL") {
+		rest = strings.TrimSpace(rest[3:])
+	} else {
+		var ok bool
+		if delim, rest, ok = imapUnquote(rest); !ok {
+			return imapMailbox{}, false
+		}
+	}
+
+	var name string
+	switch {
+	case strings.HasPrefix(rest, "{") && len(r.literals) > 0:
+		name = string(r.literals[0])
+	case strings.HasPrefix(rest, `"`):
+		if name, _, ok = imapUnquote(rest); !ok {
+			return imapMailbox{}, false
+		}
+	default:
+		name = rest
+	}
+
+	path := name
+	if delim != "" && delim != "/" {
+		path = strings.ReplaceAll(name, delim, "/")
+	}
+	return imapMailbox{name: name, path: path, attrs: attrs}, true
+}
+
+// imapQuote renders s as an IMAP quoted string.
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// imapUnquote reads a quoted string from the start of s, returning it and
+// what follows.
+func imapUnquote(s string) (string, string, bool) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", s, false
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), strings.TrimSpace(s[i+1:]), true
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", s, false
+}
+
+func uidSet(uids []int) string {
+	parts := make([]string, len(uids))
+	for i, u := range uids {
+		parts[i] = strconv.Itoa(u)
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseHeaderEmail builds an email from a fetched header block.
+func parseHeaderEmail(raw []byte) email {
+	defer timeParse(time.Now())
+	msg, err := mail.ReadMessage(bytes.NewReader(append(raw, '\r', '\n')))
+	if err != nil {
+		return email{subject: "(unreadable headers)"}
+	}
+	h := msg.Header
+	e := email{
+		sender:    decodeHeader(h.Get("From")),
+		subject:   decodeHeader(h.Get("Subject")),
+		to:        headerAddresses(h, "To"),
+		cc:        headerAddresses(h, "Cc"),
+		messageID: strings.Trim(h.Get("Message-Id"), "<> "),
+	}
+	prio := h.Get("X-Priority")
+	if prio == "" {
+		prio = h.Get("Importance")
+	}
+	e.priority = parsePriority(prio)
+	e.automated = automatedHeaders(h.Get)
+	e.autoReplied = autoRepliedHeaders(h.Get)
+	e.threadRoot = rootMessageID(h.Get("References"), h.Get("In-Reply-To"), e.messageID)
+	if t, err := h.Date(); err == nil {
+		e.date = t.Local().Format(mailDateLayout)
+	} else {
+		e.date = h.Get("Date")
+	}
+	return e
+}
+
+func headerAddresses(h mail.Header, key string) []string {
+	list, err := h.AddressList(key)
+	if err != nil {
+		return splitAddresses(decodeHeader(h.Get(key)))
+	}
+	out := make([]string, len(list))
+	for i, a := range list {
+		out[i] = a.Address
+	}
+	return out
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// refresh polls now.
+func (m *model) refresh() tea.Cmd {
+	m.nextPoll = time.Now().Add(m.pollInterval())
+	return tea.Batch(m.pollMail(), m.begin(polling))
+}
+
+// inboxKeys handles keys in the unread list. While the filter input has
+// focus every key but ctrl+t belongs to it.
+func (m *model) inboxKeys(key string) (tea.Cmd, bool) {
+	if key == "ctrl+t" {
+		m.filterMode = m.filterMode.next()
+		m.list.Filter = newFilterFunc(m.filterMode)
+		// Re-setting the items re-runs the active filter with the new mode.
+		return m.list.SetItems(m.list.Items()), true
+	}
+	if m.filtering() {
+		return nil, false
+	}
+	if cmd, ok := m.markKeys(key); ok {
+		return cmd, true
+	}
+	if cmd, ok := m.commonKeys(key); ok {
+		return cmd, true
+	}
+	switch key {
+	case "q":
+		return m.quit(), true
+	case "esc":
+		// esc clears an applied filter first, in the list's own update.
+		if m.list.FilterState() == list.Unfiltered {
+			return m.quit(), true
+		}
+	case "r":
+		return m.refresh(), true
+	case "M":
+		m.toggleMetered()
+		return nil, true
+	case "t":
+		m.threads = !m.threads
+		if m.threads {
+			m.setNotice("Grouping conversations")
+		} else {
+			m.setNotice("Showing every message")
+		}
+		return m.applyEmails(emailsMsg{emails: m.emails, err: m.err, total: m.total}), true
+	case "s":
+		m.sortMode = m.sortMode.next()
+		return m.applyEmails(emailsMsg{emails: m.emails, err: m.err, total: m.total}), true
+	case "F":
+		if len(m.focus) > 0 || len(m.cfg.rules) > 0 || len(m.snoozed) > 0 {
+			m.showAll = !m.showAll
+			return m.applyEmails(emailsMsg{emails: m.emails, err: m.err, total: m.total}), true
+		}
+	case "p":
+		m.paused = !m.paused
+		if !m.paused {
+			m.nextPoll = time.Now().Add(m.pollInterval())
+		}
+		return nil, true
+	case "o":
+		if len(m.cfg.schedule) > 0 {
+			m.offSchedule = !m.offSchedule
+			return nil, true
+		}
+	case "+", "-":
+		if m.cfg.batch.on {
+			m.setNotice("Batch mode is on; [poll] batch sets when mail is checked")
+			return nil, true
+		}
+		m.interval = stepInterval(m.interval, key == "+")
+		m.nextPoll = time.Now().Add(m.pollInterval())
+		return nil, true
+	case "f":
+		return m.openSearch(), true
+	case "w":
+		return m.openWatches(), true
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		return m.runSavedSearch(int(key[0] - '0')), true
+	case ",":
+		m.openSettings()
+		return nil, true
+	case "A":
+		return m.openAccounts(), true
+	case "!":
+		return m.openChecks(), true
+	case "X":
+		m.openStaging()
+		return nil, true
+	case "m":
+		browser, ok := m.provider.(mailboxBrowser)
+		if !ok {
+			m.setNotice(m.provider.name() + " can't list mailboxes")
+			return nil, true
+		}
+		m.mode = mailboxView
+		return tea.Batch(fetchMailboxes(browser), m.begin(listingMailboxes)), true
+	case "W":
+		if e, ok := m.list.SelectedItem().(email); ok {
+			if err := m.senders.trust(e); err != nil {
+				m.setNotice(fmt.Sprintf("Couldn't trust sender: %v", err))
+			} else {
+				m.setNotice("Trusted " + normalizeAddress(e.sender))
+			}
+			return nil, true
+		}
+	case "B":
+		if e, ok := m.list.SelectedItem().(email); ok {
+			addr := normalizeAddress(e.sender)
+			blocker, _ := m.provider.(senderBlocker)
+			what := "hide mail from " + addr
+			if blocker != nil {
+				what = "block " + addr + " and add a rule to " + m.provider.name()
+			}
+			if !m.confirmed("block", "B", addr, 1, what) {
+				return nil, true
+			}
+			if err := m.senders.block(e); err != nil {
+				m.setNotice(fmt.Sprintf("Couldn't block sender: %v", err))
+				return nil, true
+			}
+			return tea.Batch(m.applyEmails(emailsMsg{emails: m.emails, err: m.err, total: m.total}), m.track(blockSender(blocker, m.cfg.Block, addr))), true
+		}
+	case "D":
+		if m.draftStore() == nil {
+			m.setNotice("Drafts aren't supported by " + m.provider.name())
+			return nil, true
+		}
+		m.mode = draftsView
+		return tea.Batch(fetchDrafts(m.draftStore()), m.begin(loadingDrafts)), true
+	case "z":
+		if e, ok := m.list.SelectedItem().(email); ok && !e.read {
+			return m.openSnooze(e), true
+		}
+	case "b":
+		m.big = !m.big
+		return nil, true
+	case "a":
+		if len(m.emails) > 0 {
+			if !m.confirmed("mark_all_read", "a", "", len(m.emails), fmt.Sprintf("mark all %d read", len(m.emails))) {
+				return nil, true
+			}
+			m.lastRead = m.emails
+			return tea.Batch(m.track(markAllAsRead(m.mail(), m.emails)), m.begin(markingRead)), true
+		}
+	case "L":
+		if _, ok := m.mail().(pager); !ok {
+			m.setNotice(m.provider.name() + " can't load more messages")
+			return nil, true
+		}
+		if !m.hasMore() {
+			m.setNotice("All unread mail is shown")
+			return nil, true
+		}
+		m.pages++
+		return tea.Batch(m.pollMail(), m.begin(loadingMore)), true
+	case "enter":
+		if item, ok := m.list.SelectedItem().(email); ok && item.threadSize > 1 {
+			key := threadKey(item.subject)
+			m.expanded[key] = !m.expanded[key]
+			return m.applyEmails(emailsMsg{emails: m.emails, err: m.err, total: m.total}), true
+		}
+		if item, ok := m.list.SelectedItem().(email); ok {
+			cached, isCached := m.cache.body(item)
+			if !isCached && !m.downloadOK(item) {
+				return nil, true
+			}
+			m.currentEmail = &item
+			m.detailFrom = listView
+			// A cached copy saves the download while offline or metered.
+			if isCached && (m.network != netOnline || m.metered) {
+				m.activity = openingMessage
+				return m.track(func() tea.Msg { return emailContentMsg{content: cached} }), true
+			}
+			// Opening a message marks it read in Mail.app.
+			return tea.Batch(m.track(fetchEmailContent(m.mail(), item, m.cfg.Read.markOnOpen())), m.begin(openingMessage)), true
+		}
+	}
+	return nil, false
+}
+
+func (m *model) updateInbox(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return cmd
+}
+
+// viewInbox draws the unread list, the big count, or the all-caught-up
+// screen when there's nothing unread.
+func (m model) viewInbox(status string) string {
+	if m.big {
+		return m.bigView()
+	}
+	if len(m.list.Items()) == 0 {
+		return m.viewCaughtUp()
+	}
+
+	bindings := [][]string{
+		{"enter", "read"},
+		{"r", "refresh"},
+	}
+	if m.hasMore() {
+		bindings = append(bindings, []string{"L", "load more"})
+	}
+	if len(m.lastRead) > 0 {
+		bindings = append(bindings, []string{"U", "undo read"})
+	}
+	bindings = append(bindings, [][]string{
+		{"u", "mark read"},
+		{"e", "archive"},
+		{"d", "delete"},
+		{"a", "mark all read"},
+		{"m", "mailboxes"},
+		{"D", "drafts"},
+		{"/", "filter"},
+		{"f", "search"},
+		{"s", "sort"},
+		{"t", "threads"},
+		{"b", "big count"},
+		{"i", "about"},
+		{"p", "pause"},
+		{"+/-", "interval"},
+		{"?", "all keys"},
+		{"q", "quit"},
+	}...)
+	helpBar := renderHelpBar(m.width, m.cfg.keys.relabel(listView, bindings))
+
+	timeInfo := lipgloss.NewStyle().
+		Foreground(dimColor).
+		Italic(true).
+		Render(fmt.Sprintf(" %s • %s", m.updatedStatus(), m.refreshStatus()))
+	if m.list.FilterState() != list.Unfiltered {
+		filterInfo := fmt.Sprintf(" • Filter: %s (ctrl+t to change)", m.filterMode)
+		if err := validateFilter(m.filterMode, m.list.FilterValue()); err != nil {
+			filterInfo = " • Invalid regex"
+		}
+		timeInfo += statusStyle.Render(filterInfo)
+	}
+	if m.sortMode != sortReceived {
+		timeInfo += statusStyle.Render(" • Sorted by " + m.sortMode.String())
+	}
+	if m.offSchedule {
+		timeInfo += statusStyle.Render(" • Ignoring schedule (o)")
+	}
+	if m.hidden > 0 {
+		timeInfo += statusStyle.Render(" • F to show all")
+	} else if m.showAll {
+		timeInfo += statusStyle.Render(" • Focus filter off (F)")
+	}
+	if n := len(m.marked()); n > 0 || m.marks.anchor >= 0 {
+		timeInfo += statusStyle.Render(fmt.Sprintf(" • %d selected (u, d or e acts on them, esc clears)", n))
+	}
+	if n := m.staged.count(); n > 0 {
+		timeInfo += statusStyle.Render(fmt.Sprintf(" • %d waiting to be moved (%s)", n, m.cfg.keys.label(listView, "X")))
+	}
+	if m.notice != "" {
+		timeInfo += statusStyle.Render(" • " + m.notice)
+	}
+
+	if line := m.accountsStatus(); line != "" {
+		timeInfo += "\n" + line
+	}
+	if m.perf == nil {
+		return m.list.View() + "\n" + timeInfo + "\n" + helpBar
+	}
+	start := time.Now()
+	rendered := m.list.View()
+	m.perf.render = time.Since(start)
+	return rendered + "\n" + timeInfo + statusStyle.Render(" • "+m.perf.String()) + "\n" + helpBar
+}
+
+// listChrome is the height of the list view's lines around the list: the
+// status line and the help bar, and the accounts line with [[accounts]].
+func (m model) listChrome() int {
+	if len(m.cfg.Accounts) > 1 {
+		return 5
+	}
+	return 4
+}
+
+// accountsStatus is the list's line about each of [[accounts]]: when it
+// last synced, or that it's failing and why. It's empty with one backend.
+func (m model) accountsStatus() string {
+	mp, ok := accountsOf(m.provider)
+	if !ok || len(mp.accounts) < 2 {
+		return ""
+	}
+	dim := lipgloss.NewStyle().Foreground(dimColor)
+	bad := lipgloss.NewStyle().Foreground(errorColor)
+	var parts []string
+	for _, name := range mp.names() {
+		for _, a := range m.health.accounts {
+			if a.name != name {
+				continue
+			}
+			switch {
+			case a.err != nil:
+				parts = append(parts, bad.Render("✗ "+name+": "+a.err.Error()))
+			case a.synced.IsZero():
+				parts = append(parts, dim.Render("… "+name))
+			default:
+				parts = append(parts, dim.Render("✓ "+name+" "+a.synced.Format("15:04")))
+			}
+		}
+	}
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(" " + strings.Join(parts, dim.Render(" • ")))
+}
+
+// viewCaughtUp is the inbox with nothing unread, or nothing the focus
+// filter lets through.
+func (m model) viewCaughtUp() string {
+	emptyStyle := lipgloss.NewStyle().
+		Foreground(successColor).
+		Bold(true).
+		Align(lipgloss.Center).
+		Width(m.width)
+
+	subtitleStyle := lipgloss.NewStyle().
+		Foreground(subtleColor).
+		Italic(true).
+		Align(lipgloss.Center).
+		Width(m.width)
+
+	timeInfo := lipgloss.NewStyle().
+		Foreground(dimColor).
+		Italic(true).
+		Align(lipgloss.Center).
+		Width(m.width).
+		Render(fmt.Sprintf("Last checked: %s • %s", m.lastPoll.Format("15:04:05"), m.refreshStatus()))
+
+	subtitle := "No unread emails in your inbox."
+	bindings := [][]string{
+		{"r", "refresh"},
+		{"p", "pause"},
+		{"q", "quit"},
+	}
+	if m.hidden > 0 {
+		subtitle = fmt.Sprintf("%d unread hidden by your focus filter and rules.", m.hidden)
+		bindings = append([][]string{{"F", "show all"}}, bindings...)
+	}
+
+	centerContent := emptyStyle.Render("All caught up!") + "\n\n" +
+		subtitleStyle.Render(subtitle) + "\n\n" +
+		timeInfo
+	if streak := m.streakWidget(); streak != "" {
+		centerContent += "\n\n" + lipgloss.NewStyle().
+			Foreground(accentColor).
+			Align(lipgloss.Center).
+			Width(m.width).
+			Render(streak)
+	}
+	if line := m.accountsStatus(); line != "" {
+		centerContent += "\n" + lipgloss.PlaceHorizontal(m.width, lipgloss.Center, line)
+	}
+
+	helpBar := renderHelpBar(m.width, bindings)
+
+	body := lipgloss.Place(m.width, m.height-1, lipgloss.Center, lipgloss.Center, centerContent)
+	return body + "\n" + helpBar
+}
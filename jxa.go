@@ -0,0 +1,514 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bridgeTimeout bounds one request to the bridge, so a Mail.app stuck
+// behind a dialog can't hang every later poll.
+const bridgeTimeout = 2 * time.Minute
+
+// bridgeScript is the Mail.app bridge: a JXA program that reads one JSON
+// request per line on stdin and answers each with one JSON line on stdout.
+// It reads message properties a whole list at a time rather than message by
+// message, and remembers each mailbox's unread messages along with their
+// ids, so a poll where nothing changed costs a count and a list of ids per
+// mailbox. Remembered lists are dropped after five minutes, or as
+// soon as a request reads or moves one of their messages.
+const bridgeScript = `
+ObjC.import('Foundation')
+
+const Mail = Application('Mail')
+
+const freshFor = 5 * 60 * 1000
+const unreadCache = {}
+const pathCache = {}
+
+// listedHeaders are the headers that say whether a message may be
+// auto-replied to, and which conversation it's in.
+const listedHeaders = ['Auto-Submitted', 'X-Autoreply', 'X-Autorespond', 'Precedence', 'List-Id', 'References', 'In-Reply-To']
+
+function box(ref) {
+	if (!ref.mailbox) return Mail.inbox
+	return Mail.accounts.byName(ref.account).mailboxes.byName(ref.mailbox)
+}
+
+function boxKey(ref) {
+	return ref.mailbox ? ref.account + '\u0000' + ref.mailbox : ''
+}
+
+function forget(ref) {
+	delete unreadCache[boxKey(ref)]
+}
+
+function message(ref) {
+	const found = box(ref).messages.whose({id: Number(ref.id)})
+	if (found.length === 0) throw new Error('no message ' + ref.id + ' in ' + (ref.mailbox || 'the inbox'))
+	return found[0]
+}
+
+function describe(found, ref, limit) {
+	const ids = found.id()
+	const n = Math.min(ids.length, limit)
+	if (n === 0) return []
+	const senders = found.sender(), subjects = found.subject(), dates = found.dateReceived(), mids = found.messageId()
+	let to = [], cc = [], accounts = [], flags = []
+	try { flags = found.flagIndex() } catch (e) {}
+	try { to = found.toRecipients.address() } catch (e) {}
+	try { cc = found.ccRecipients.address() } catch (e) {}
+	if (!ref.mailbox) {
+		try { accounts = found.mailbox.account.name() } catch (e) {}
+	}
+	const out = []
+	for (let i = 0; i < n; i++) {
+		let prio = ''
+		for (const name of ['X-Priority', 'Importance']) {
+			try {
+				const h = found[i].headers.whose({name})
+				if (h.length > 0) prio = h[0].content()
+			} catch (e) {}
+			if (prio) break
+		}
+		const headers = {}
+		try {
+			const hs = found[i].headers.whose({_or: listedHeaders.map(name => ({name}))})
+			const names = hs.name(), contents = hs.content()
+			for (let j = 0; j < names.length; j++) headers[names[j].toLowerCase()] = contents[j]
+		} catch (e) {}
+		out.push({
+			id: String(ids[i]),
+			sender: senders[i] || '',
+			subject: subjects[i] || '',
+			date: dates[i] ? dates[i].toISOString() : '',
+			account: ref.account || accounts[i] || '',
+			mailbox: ref.mailbox || '',
+			to: to[i] || [],
+			cc: cc[i] || [],
+			priority: prio,
+			message_id: mids[i] || '',
+			// Mail.app's flag index is -1 for none, or 0 to 6 for red to
+			// gray; the Go side counts from 1.
+			flag: flags[i] >= 0 ? flags[i] + 1 : 0,
+			headers,
+		})
+	}
+	return out
+}
+
+function refsOf(args) {
+	return args.boxes && args.boxes.length > 0 ? args.boxes : [{}]
+}
+
+function mailboxPaths(acct) {
+	const mbs = acct.mailboxes
+	const names = mbs.name()
+	const name = acct.name()
+	const cached = pathCache[name]
+	if (cached && cached.names === names.join('\u0000')) return cached.paths
+	const paths = names.map((leaf, i) => {
+		let path = leaf
+		try {
+			let parent = mbs[i].container()
+			while (parent.class() === 'mailbox') {
+				path = parent.name() + '/' + path
+				parent = parent.container()
+			}
+		} catch (e) {}
+		return path
+	})
+	pathCache[name] = {names: names.join('\u0000'), paths}
+	return paths
+}
+
+function readMessage(msg) {
+	const atts = msg.mailAttachments
+	const attachments = []
+	let names = [], types = []
+	try {
+		names = atts.name()
+		types = atts.mimeType()
+	} catch (e) {}
+	names.forEach((name, i) => {
+		let size = 0
+		try { size = atts[i].fileSize() } catch (e) {}
+		attachments.push({name, mime_type: types[i] || '', size})
+	})
+	return {attachments, body: msg.content(), source: msg.source()}
+}
+
+const ops = {
+	unread(args) {
+		const out = []
+		const now = Date.now()
+		let total = 0
+		for (const ref of refsOf(args)) {
+			const b = box(ref)
+			const count = b.unreadCount()
+			total += count
+			if (out.length >= args.limit) continue
+			// The unread messages' ids, one cheap request, tell whether the
+			// list changed even when the count didn't: one message read and
+			// another arriving between polls.
+			const unread = b.messages.whose({readStatus: false})
+			const ids = unread.id().join(',')
+			let c = unreadCache[boxKey(ref)]
+			if (!c || c.ids !== ids || c.limit < args.limit || now - c.at > freshFor) {
+				c = {ids, limit: args.limit, at: now, msgs: describe(unread, ref, args.limit)}
+				unreadCache[boxKey(ref)] = c
+			}
+			out.push(...c.msgs.slice(0, args.limit - out.length))
+		}
+		return {total, messages: out}
+	},
+
+	search(args) {
+		const q = args.query
+		const conds = []
+		if (q) conds.push({_or: [{sender: {_contains: q}}, {subject: {_contains: q}}, {content: {_contains: q}}]})
+		if (args.from) conds.push({sender: {_contains: args.from}})
+		if (args.subject) conds.push({subject: {_contains: args.subject}})
+		if (args.after) conds.push({dateReceived: {_greaterThanEquals: new Date(args.after)}})
+		if (args.before) conds.push({dateReceived: {_lessThan: new Date(args.before)}})
+		// Recipients and attachments are elements, which a whose clause
+		// can't test, so they're checked on what it found, of a few times
+		// as many messages.
+		const sift = args.to || args.attachment
+		const to = (args.to || '').toLowerCase()
+		const out = []
+		for (const ref of refsOf(args)) {
+			if (out.length >= args.limit) break
+			const b = box(ref).messages
+			const found = conds.length === 0 ? b : b.whose(conds.length === 1 ? conds[0] : {_and: conds})
+			let msgs = describe(found, ref, sift ? 5 * args.limit : args.limit - out.length)
+			if (to) msgs = msgs.filter(m => m.to.concat(m.cc).some(a => a.toLowerCase().includes(to)))
+			if (args.attachment) msgs = msgs.filter(m => found.whose({id: Number(m.id)})[0].mailAttachments.length > 0)
+			out.push(...msgs.slice(0, args.limit - out.length))
+		}
+		return out
+	},
+
+	mailboxes() {
+		const out = []
+		const inboxes = Mail.inbox.mailboxes
+		const names = inboxes.name(), unread = inboxes.unreadCount()
+		let accounts = []
+		try { accounts = inboxes.account.name() } catch (e) {}
+		names.forEach((name, i) => {
+			if (accounts[i]) out.push({account: accounts[i], path: name, unread: unread[i], inbox: true})
+		})
+		for (const acct of Mail.accounts()) {
+			const name = acct.name()
+			const counts = acct.mailboxes.unreadCount()
+			mailboxPaths(acct).forEach((path, i) => out.push({account: name, path, unread: counts[i], inbox: false}))
+		}
+		return out
+	},
+
+	content(ref) {
+		const msg = message(ref)
+		const out = readMessage(msg)
+		msg.readStatus = true
+		forget(ref)
+		return out
+	},
+
+	peek(ref) {
+		return readMessage(message(ref))
+	},
+
+	mark(args) {
+		for (const ref of args.messages) {
+			try { message(ref).readStatus = args.read } catch (e) {}
+			forget(ref)
+		}
+	},
+
+	flag(args) {
+		const msg = message(args.message)
+		if (args.index < 0) {
+			msg.flaggedStatus = false
+		} else {
+			msg.flagIndex = args.index
+			msg.flaggedStatus = true
+		}
+		forget(args.message)
+	},
+
+	// trash and archive move every message they can, and then fail with
+	// what went wrong for the rest.
+	trash(args) {
+		const failed = []
+		for (const ref of args.messages) {
+			try { Mail.delete(message(ref)) } catch (e) { failed.push(String(e.message || e)) }
+			forget(ref)
+		}
+		if (failed.length > 0) throw new Error(failed.join('; '))
+	},
+
+	archive(args) {
+		const failed = []
+		for (const ref of args.messages) {
+			try {
+				const msg = message(ref)
+				Mail.move(msg, {to: msg.mailbox().account().mailboxes.byName(args.mailbox)})
+			} catch (e) {
+				failed.push(String(e.message || e))
+			}
+			forget(ref)
+		}
+		if (failed.length > 0) throw new Error(failed.join('; '))
+	},
+}
+
+function send(obj) {
+	const line = $(JSON.stringify(obj) + '\n')
+	$.NSFileHandle.fileHandleWithStandardOutput.writeData(line.dataUsingEncoding($.NSUTF8StringEncoding))
+}
+
+function handle(line) {
+	let req
+	try { req = JSON.parse(line) } catch (e) { return }
+	try {
+		const op = ops[req.op]
+		if (!op) throw new Error('unknown request ' + req.op)
+		send({id: req.id, result: op(req.args || {})})
+	} catch (e) {
+		send({id: req.id, error: String(e.message || e)})
+	}
+}
+
+function run() {
+	const stdin = $.NSFileHandle.fileHandleWithStandardInput
+	let partial = $.NSMutableData.alloc.init
+	let text = ''
+	for (;;) {
+		const chunk = stdin.availableData
+		if (chunk.length === 0) return
+		partial.appendData(chunk)
+		// A read can end inside a UTF-8 sequence; wait for the rest.
+		const decoded = $.NSString.alloc.initWithDataEncoding(partial, $.NSUTF8StringEncoding)
+		if (decoded.isNil()) continue
+		partial = $.NSMutableData.alloc.init
+		text += decoded.js
+		let nl
+		while ((nl = text.indexOf('\n')) >= 0) {
+			const line = text.slice(0, nl)
+			text = text.slice(nl + 1)
+			if (line.trim() !== '') handle(line)
+		}
+	}
+}
+`
+
+// jxaBridge runs bridgeScript in one long-lived osascript process, started
+// on first use and restarted after it exits. Requests are serialized:
+// Mail.app answers Apple events one at a time anyway.
+type jxaBridge struct {
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	reader *bufio.Reader
+	stderr bytes.Buffer
+	next   int
+}
+
+// mailBridge is shared by every Mail.app provider, scoped or not, so there
+// is only ever one bridge process.
+var mailBridge = &jxaBridge{}
+
+type bridgeRequest struct {
+	ID   int    `json:"id"`
+	Op   string `json:"op"`
+	Args any    `json:"args,omitempty"`
+}
+
+type bridgeResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+}
+
+func (b *jxaBridge) start() error {
+	cmd := exec.Command("osascript", "-l", "JavaScript", "-e", bridgeScript)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	b.stderr.Reset()
+	cmd.Stderr = &b.stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("mail bridge: %w", err)
+	}
+	log.Printf("mail bridge started")
+	b.cmd, b.stdin, b.stdout, b.reader = cmd, stdin, stdout, bufio.NewReaderSize(stdout, 64<<10)
+	return nil
+}
+
+// stop ends the bridge process and returns what it printed to stderr,
+// which explains a crash.
+func (b *jxaBridge) stop() string {
+	if b.cmd == nil {
+		return ""
+	}
+	b.stdin.Close()
+	b.cmd.Process.Kill()
+	b.cmd.Wait()
+	b.cmd = nil
+	msg := strings.TrimSpace(b.stderr.String())
+	if len(msg) > 300 {
+		msg = msg[len(msg)-300:]
+	}
+	return msg
+}
+
+// call sends one request and decodes its result into out, if out isn't
+// nil. A bridge that has exited since the last call is restarted once, as
+// nothing of the request reached it.
+func (b *jxaBridge) call(op string, args, out any) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.next++
+	req, err := json.Marshal(bridgeRequest{ID: b.next, Op: op, Args: args})
+	if err != nil {
+		return err
+	}
+	req = append(req, '\n')
+	for attempt := 0; ; attempt++ {
+		if b.cmd == nil {
+			if err := b.start(); err != nil {
+				return err
+			}
+		}
+		if _, err = b.stdin.Write(req); err == nil {
+			break
+		}
+		b.stop()
+		if attempt > 0 {
+			return fmt.Errorf("mail bridge: %w", err)
+		}
+	}
+
+	if d, ok := b.stdout.(interface{ SetReadDeadline(time.Time) error }); ok {
+		d.SetReadDeadline(time.Now().Add(bridgeTimeout))
+	}
+	line, err := b.reader.ReadBytes('\n')
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			if msg := b.stop(); msg != "" {
+				log.Printf("mail bridge exited: %s", msg)
+				return fmt.Errorf("mail bridge exited: %s", msg)
+			}
+			log.Printf("mail bridge exited")
+			return errors.New("mail bridge exited")
+		}
+		b.stop()
+		log.Printf("mail bridge stopped: Mail.app didn't answer %s within %s", op, bridgeTimeout)
+		return fmt.Errorf("mail bridge: Mail.app didn't answer %s within %s", op, bridgeTimeout)
+	}
+	var resp bridgeResponse
+	if err := json.Unmarshal(line, &resp); err != nil || resp.ID != b.next {
+		// Out of step with the bridge; start over rather than misread
+		// later answers.
+		b.stop()
+		log.Printf("mail bridge stopped: unexpected reply to %s", op)
+		return fmt.Errorf("mail bridge: unexpected reply to %s", op)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	if out == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, out)
+}
+
+// bridgeRef addresses a message, or with no id a mailbox, for the bridge.
+// An empty mailbox means the unified inbox.
+type bridgeRef struct {
+	ID      string `json:"id,omitempty"`
+	Account string `json:"account,omitempty"`
+	Mailbox string `json:"mailbox,omitempty"`
+}
+
+func refOf(e email) bridgeRef {
+	return bridgeRef{ID: e.id, Account: e.account, Mailbox: e.mailbox}
+}
+
+// bridgeMessage is a message in the bridge's unread and search results.
+type bridgeMessage struct {
+	ID        string   `json:"id"`
+	Sender    string   `json:"sender"`
+	Subject   string   `json:"subject"`
+	Date      string   `json:"date"`
+	Account   string   `json:"account"`
+	Mailbox   string   `json:"mailbox"`
+	To        []string `json:"to"`
+	Cc        []string `json:"cc"`
+	Priority  string   `json:"priority"`
+	MessageID string   `json:"message_id"`
+	Flag      int      `json:"flag"`
+	// Headers holds the listedHeaders the message has, by lower-case name.
+	Headers map[string]string `json:"headers"`
+}
+
+// decodeBridgeMessages turns the bridge's message list into emails, with
+// dates in Mail.app's own long spelling like the other backends use.
+func decodeBridgeMessages(data []byte) ([]email, error) {
+	defer timeParse(time.Now())
+	var msgs []bridgeMessage
+	if err := json.Unmarshal(data, &msgs); err != nil {
+		return nil, fmt.Errorf("mail bridge: %w", err)
+	}
+	emails := make([]email, 0, len(msgs))
+	for _, m := range msgs {
+		e := email{
+			id:        strings.TrimSpace(m.ID),
+			sender:    strings.TrimSpace(m.Sender),
+			subject:   strings.TrimSpace(m.Subject),
+			date:      m.Date,
+			account:   m.Account,
+			to:        trimAddresses(m.To),
+			cc:        trimAddresses(m.Cc),
+			priority:  parsePriority(m.Priority),
+			messageID: strings.Trim(m.MessageID, "<> "),
+			flag:      flagColor(m.Flag),
+			mailbox:   m.Mailbox,
+		}
+		header := func(name string) string { return m.Headers[strings.ToLower(name)] }
+		e.automated = automatedHeaders(header)
+		e.autoReplied = autoRepliedHeaders(header)
+		e.threadRoot = rootMessageID(header("References"), header("In-Reply-To"), e.messageID)
+		if t, err := time.Parse(time.RFC3339, m.Date); err == nil {
+			e.date = t.Local().Format(mailDateLayout)
+		}
+		emails = append(emails, e)
+	}
+	return emails, nil
+}
+
+// trimAddresses drops blank entries from a recipient list.
+func trimAddresses(addrs []string) []string {
+	var out []string
+	for _, a := range addrs {
+		if a = strings.TrimSpace(a); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// keyAction is a remappable command: its default key, the views it works
+// in, and what the help screen says it does.
+type keyAction struct {
+	key   string
+	modes []viewMode
+	help  string
+}
+
+var (
+	inList   = []viewMode{listView}
+	inDetail = []viewMode{detailView}
+	inBoth   = []viewMode{listView, detailView}
+)
+
+// keyActions are the commands [keys] can rebind, by config name. Keys
+// handled by the composer, the mailbox picker and the filter input are
+// fixed.
+var keyActions = map[string]keyAction{
+	"quit":            {"q", inList, "quit"},
+	"refresh":         {"r", inList, "refresh now"},
+	"headers_only":    {"M", inList, "headers only, for metered connections, or back"},
+	"open":            {"enter", inList, "read the message, or open the thread"},
+	"mark_all_read":   {"a", inList, "mark all read"},
+	"mailboxes":       {"m", inList, "pick a mailbox"},
+	"drafts":          {"D", inList, "drafts"},
+	"sort":            {"s", inList, "change the sort order"},
+	"show_all":        {"F", inList, "show what the focus filter, rules and snoozes hide"},
+	"big":             {"b", inList, "big count"},
+	"pause":           {"p", inList, "pause auto-refresh"},
+	"schedule":        {"o", inList, "poll outside the schedule"},
+	"faster":          {"-", inList, "refresh more often"},
+	"slower":          {"+", inList, "refresh less often"},
+	"trust":           {"W", inList, "trust the sender"},
+	"block":           {"B", inList, "block the sender (twice)"},
+	"about":           {"i", inList, "about"},
+	"search":          {"f", inList, "search mailboxes"},
+	"threads":         {"t", inList, "group by conversation"},
+	"load_more":       {"L", inList, "load more messages"},
+	"settings":        {",", inList, "settings"},
+	"accounts":        {"A", inList, "account health"},
+	"snooze":          {"z", inList, "snooze the message, or wake it"},
+	"setup":           {"!", inList, "check the backend's setup"},
+	"staging":         {"X", inList, "deletes and archives still waiting, to take one back"},
+	"watch":           {"w", inList, "watch for a message, to be alerted when it arrives"},
+	"select":          {" ", inList, "select the message for u, d or e, or unselect it"},
+	"select_range":    {"v", inList, "start selecting a range, or end it"},
+	"compose":         {"c", inBoth, "compose"},
+	"toggle_read":     {"u", inBoth, "mark read, or unread when open"},
+	"delete":          {"d", inBoth, "move to Trash"},
+	"archive":         {"e", inBoth, "archive"},
+	"help":            {"?", inBoth, "all keys"},
+	"undo_read":       {"U", inBoth, "undo mark read"},
+	"log":             {"E", inBoth, "session log of actions and polls"},
+	"back":            {"q", inDetail, "back"},
+	"reply":           {"R", inDetail, "reply"},
+	"notes":           {"n", inDetail, "append to notes"},
+	"ticket":          {"T", inDetail, "create a ticket"},
+	"next_attachment": {"tab", inDetail, "next attachment"},
+	"save":            {"s", inDetail, "save the attachment"},
+	"quick_look":      {"v", inDetail, "Quick Look the attachment"},
+	"fold":            {"z", inDetail, "expand or collapse the attachment"},
+	"html":            {"H", inDetail, "rendered or raw HTML"},
+	"preformatted":    {"w", inDetail, "lines as sent, for patches and code, or wrapped"},
+	"load_all":        {"L", inDetail, "load all of a long message"},
+	"source":          {"S", inDetail, "raw source"},
+	"read_receipt":    {"M", inDetail, "send the read receipt asked for"},
+	"links":           {"l", inDetail, "pick a link to open"},
+	"sender":          {"a", inDetail, "actions on the sender: Contacts, copy, their mail, VIP, mute"},
+	"translate":       {"t", inDetail, "translate, or back to the original"},
+	"read_aloud":      {"P", inDetail, "read aloud, or stop"},
+	"pause_reading":   {"p", inDetail, "pause or resume reading aloud"},
+	"open_in_mail":    {"O", inBoth, "open in Mail.app"},
+	"mute_thread":     {"K", inBoth, "mute the conversation, or unmute it"},
+	"flag":            {"*", inBoth, "set the color flag, as Mail.app shows it"},
+	"labels":          {"#", inBoth, "add or remove Gmail labels"},
+}
+
+// keyMap holds each view's remappable actions, bound to the keys [keys]
+// gives them or to their default ones. The update loop matches a pressed key
+// against the bindings with key.Matches and hands the view's handler the
+// matched action's default key, which is what the handlers switch on.
+type keyMap map[viewMode][]boundAction
+
+// boundAction is an action's binding in one view, and its default key.
+type boundAction struct {
+	binding key.Binding
+	def     string
+}
+
+// defaultKeys is the keyMap without a [keys] table.
+var defaultKeys, _ = parseKeyMap(nil)
+
+// parseKeyMap builds a keyMap from the [keys] table, which maps action
+// names to keys. A rebound action's default key stops working unless
+// another action is bound to it.
+func parseKeyMap(bindings map[string]string) (keyMap, error) {
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bound := map[string]string{}
+	taken := map[viewMode]map[string]string{}
+	for _, name := range names {
+		action, ok := keyActions[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown action %q", name)
+		}
+		key := strings.TrimSpace(bindings[name])
+		if key == "" {
+			return nil, fmt.Errorf("%s: empty key", name)
+		}
+		if key == "space" {
+			key = " "
+		}
+		for _, mode := range action.modes {
+			if taken[mode] == nil {
+				taken[mode] = map[string]string{}
+			}
+			if other, ok := taken[mode][key]; ok {
+				return nil, fmt.Errorf("%s: %q is already bound to %s", name, key, other)
+			}
+			for other, a := range keyActions {
+				if _, rebound := bindings[other]; !rebound && other != name && a.key == key && slices.Contains(a.modes, mode) {
+					return nil, fmt.Errorf("%s: %q is already bound to %s", name, key, other)
+				}
+			}
+			taken[mode][key] = name
+		}
+		bound[name] = key
+	}
+
+	all := make([]string, 0, len(keyActions))
+	for name := range keyActions {
+		all = append(all, name)
+	}
+	sort.Strings(all)
+	k := keyMap{}
+	for _, name := range all {
+		action := keyActions[name]
+		pressed, ok := bound[name]
+		if !ok {
+			pressed = action.key
+		}
+		label := pressed
+		if label == " " {
+			label = "space"
+		}
+		for _, mode := range action.modes {
+			b := key.NewBinding(key.WithKeys(pressed), key.WithHelp(label, action.help))
+			k[mode] = append(k[mode], boundAction{binding: b, def: action.key})
+		}
+	}
+	return k, nil
+}
+
+func (k keyMap) actions(mode viewMode) []boundAction {
+	if k == nil {
+		return defaultKeys[mode]
+	}
+	return k[mode]
+}
+
+// resolve returns the default key of the action msg matches in mode, msg's
+// own key when it matches none, or "" when it's the default key of an
+// action bound elsewhere.
+func (k keyMap) resolve(mode viewMode, msg tea.KeyMsg) string {
+	actions := k.actions(mode)
+	for _, a := range actions {
+		if key.Matches(msg, a.binding) {
+			return a.def
+		}
+	}
+	pressed := msg.String()
+	for _, a := range actions {
+		if a.def == pressed {
+			return ""
+		}
+	}
+	return pressed
+}
+
+// label returns the key bound to the command whose default key is key, for
+// the help bar.
+func (k keyMap) label(mode viewMode, key string) string {
+	for _, a := range k.actions(mode) {
+		if a.def == key {
+			return a.binding.Keys()[0]
+		}
+	}
+	return key
+}
+
+// relabel rewrites a help bar's keys to the configured ones.
+func (k keyMap) relabel(mode viewMode, bindings [][]string) [][]string {
+	out := make([][]string, len(bindings))
+	for i, b := range bindings {
+		out[i] = []string{k.label(mode, b[0]), b[1]}
+	}
+	return out
+}
+
+// fixedKeys are the keys of each view [keys] can't rebind, for the help
+// screen.
+var fixedKeys = map[viewMode][]key.Binding{
+	listView: {
+		key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter the list")),
+		key.NewBinding(key.WithKeys("ctrl+t"), key.WithHelp("ctrl+t", "change the filter mode")),
+		key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "clear the filter, or quit")),
+		key.NewBinding(key.WithKeys("1"), key.WithHelp("1-9", "run a saved search")),
+		key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit now")),
+	},
+	detailView: {
+		key.NewBinding(key.WithKeys("up", "down"), key.WithHelp("↑/↓", "scroll")),
+		key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back to the list")),
+		key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit now")),
+	},
+	draftsView: {
+		key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "edit the draft")),
+		key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "send the draft")),
+		key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "delete the draft")),
+		key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "compose")),
+		key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
+		key.NewBinding(key.WithKeys("q", "esc"), key.WithHelp("q", "back")),
+	},
+}
+
+// bindings are the keys that work in mode as the config binds them, sorted
+// by key, followed by the fixed ones.
+func (k keyMap) bindings(mode viewMode) []key.Binding {
+	var out []key.Binding
+	for _, a := range k.actions(mode) {
+		out = append(out, a.binding)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].Help().Key, out[j].Help().Key
+		if la, lb := strings.ToLower(a), strings.ToLower(b); la != lb {
+			return la < lb
+		}
+		return a > b
+	})
+	return append(out, fixedKeys[mode]...)
+}
+
+// helpView renders the ? overlay: every key of the current view, in as
+// many columns as the screen needs.
+func (m model) helpView() string {
+	title := lipgloss.NewStyle().Bold(true).Foreground(accentColor).Render("Keys")
+	bindings := m.cfg.keys.bindings(m.mode)
+	rows := max(m.height-10, 5)
+	var columns [][]key.Binding
+	for len(bindings) > rows {
+		columns = append(columns, bindings[:rows])
+		bindings = bindings[rows:]
+	}
+	columns = append(columns, bindings)
+
+	h := help.New()
+	h.Styles.FullKey = lipgloss.NewStyle().Bold(true).Foreground(accentColor)
+	h.Styles.FullDesc = bodyStyle
+	h.Styles.FullSeparator = metaStyle
+	h.FullSeparator = "    "
+	hint := statusStyle.Render("Rebind these in [keys] • any key to close")
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(accentColor).
+		Padding(1, 2).
+		Render(title + "\n\n" + h.FullHelpView(columns) + "\n\n" + hint)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// labelPicker is the label screen's state: the message it's for, picked
+// in from, the account's labels and which of them are ticked, as read from
+// the server in was and as picked in on.
+type labelPicker struct {
+	target email
+	from   viewMode
+	all    []string
+	was    map[string]bool
+	on     map[string]bool
+	cursor int
+}
+
+type labelsMsg struct {
+	all []string
+	on  []string
+	err error
+}
+
+func fetchLabels(l labeler, e email) tea.Cmd {
+	return func() tea.Msg {
+		all, on, err := l.labels(e)
+		return labelsMsg{all: all, on: on, err: err}
+	}
+}
+
+// openLabels reads e's labels for the label picker, which opens once
+// they're in.
+func (m *model) openLabels(e email) tea.Cmd {
+	l, ok := m.mail().(labeler)
+	if !ok {
+		m.setNotice("Labels aren't supported by " + m.provider.name())
+		return nil
+	}
+	m.labels = labelPicker{target: e, from: m.mode}
+	return tea.Batch(fetchLabels(l, e), m.begin(loadingLabels))
+}
+
+func (m *model) applyLabels(msg labelsMsg) {
+	if !m.finish(loadingLabels) {
+		return
+	}
+	if msg.err != nil {
+		m.setNotice(fmt.Sprintf("Couldn't read the labels: %v", msg.err))
+		return
+	}
+	m.labels.all = msg.all
+	m.labels.was, m.labels.on = map[string]bool{}, map[string]bool{}
+	for _, l := range msg.on {
+		m.labels.was[l], m.labels.on[l] = true, true
+	}
+	m.mode = labelView
+}
+
+// relabel applies the labels ticked in the picker, taking the message off
+// the list straight away when the inbox label went.
+func (m *model) relabel() tea.Cmd {
+	lp := m.labels
+	m.mode = lp.from
+	var add, remove []string
+	for _, l := range lp.all {
+		switch {
+		case lp.on[l] && !lp.was[l]:
+			add = append(add, l)
+		case !lp.on[l] && lp.was[l]:
+			remove = append(remove, l)
+		}
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return nil
+	}
+	l := m.mail().(labeler)
+	do := m.track(actOnMessage("label", "Labels changed", func() error { return l.relabel(lp.target, add, remove) }))
+	if slices.Contains(remove, gmailInbox) {
+		return tea.Batch(m.dropEmail(lp.target), do)
+	}
+	return do
+}
+
+// labelKeys handles keys in the label picker: space ticks or unticks
+// a label and enter applies them all.
+func (m *model) labelKeys(key string) (tea.Cmd, bool) {
+	n := len(m.labels.all)
+	switch key {
+	case "up", "k":
+		if n > 0 {
+			m.labels.cursor = (m.labels.cursor + n - 1) % n
+		}
+	case "down", "j":
+		if n > 0 {
+			m.labels.cursor = (m.labels.cursor + 1) % n
+		}
+	case " ", "x":
+		if m.labels.cursor < n {
+			l := m.labels.all[m.labels.cursor]
+			m.labels.on[l] = !m.labels.on[l]
+		}
+	case "enter":
+		return m.relabel(), true
+	case "esc", "q":
+		m.mode = m.labels.from
+	}
+	return nil, true
+}
+
+func (m *model) updateLabels(tea.Msg) tea.Cmd {
+	return nil
+}
+
+// labelName is l as the picker shows it.
+func labelName(l string) string {
+	if l == gmailInbox {
+		return "Inbox"
+	}
+	return l
+}
+
+func (m model) viewLabels(status string) string {
+	lp := m.labels
+	// Keep the cursor in sight on accounts with more labels than fit.
+	rows := max(m.height-10, 3)
+	first := max(min(lp.cursor-rows/2, len(lp.all)-rows), 0)
+	var lines []string
+	for i := first; i < min(first+rows, len(lp.all)); i++ {
+		l := lp.all[i]
+		cursor := "  "
+		style := bodyStyle
+		if i == lp.cursor {
+			cursor = senderStyle.Render("▸ ")
+			style = senderStyle
+		}
+		box := "[ ] "
+		if lp.on[l] {
+			box = "[x] "
+		}
+		note := ""
+		if l == gmailInbox && lp.was[l] {
+			note = metaStyle.Render(" • untick to archive")
+		}
+		lines = append(lines, cursor+metaStyle.Render(box)+style.Render(labelName(l))+note)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, metaStyle.Render("The account has no labels"))
+	}
+	title := headerStyle.Render("Labels") + "\n" + metaStyle.Render(lp.target.sender+" • "+lp.target.subject)
+	bindings := [][]string{{"↑/↓", "select"}, {"space", "tick"}, {"enter", "apply"}, {"esc", "cancel"}}
+	body := lipgloss.NewStyle().Padding(1, 2).Render(title + "\n\n" + strings.Join(lines, "\n"))
+	gap := max(m.height-lipgloss.Height(body)-lipgloss.Height(status)-1, 0)
+	return body + strings.Repeat("\n", gap) + "\n" + status + renderHelpBar(m.width, bindings)
+}
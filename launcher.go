@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// The list and act subcommands are shaped for launcher extensions such as
+// Raycast script commands and Alfred workflows. They ask the running daemon
+// over its API, which answers from its cache, and only poll the backend
+// themselves when no daemon is listening.
+
+// apiClient talks to a running daemon's API.
+type apiClient struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+// errNoDaemon means nothing answered at the configured API address.
+var errNoDaemon = errors.New("no daemon is serving the api")
+
+func newAPIClient(cfg apiConfig) (*apiClient, error) {
+	if cfg.Listen == "" {
+		return nil, errNoDaemon
+	}
+	token, err := resolveSecret(cfg.Token)
+	if err != nil {
+		return nil, err
+	}
+	host, port, err := net.SplitHostPort(cfg.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("api: %w", err)
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return &apiClient{
+		base:  "http://" + net.JoinHostPort(host, port),
+		token: token,
+		http:  &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+// do sends a request and decodes a JSON response into out, if it's not nil.
+func (c *apiClient) do(method, path string, query url.Values, out any) error {
+	u := c.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return errNoDaemon
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("daemon: %s", apiErr.Error)
+		}
+		return fmt.Errorf("daemon: %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// runList prints the unread list for a launcher.
+//
+//	mailnotify list [-format text|json|alfred-json] [-q query]
+func runList(cfg config, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	format := fs.String("format", "text", "output format: text, json or alfred-json")
+	query := fs.String("q", "", "only list messages matching a filter query")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	list, err := fetchList(cfg, *query)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "text":
+		for _, e := range list.Emails {
+			fmt.Printf("%s\t%s\t%s\n", e.ID, e.Sender, e.Subject)
+		}
+		return nil
+	case "json":
+		return json.NewEncoder(os.Stdout).Encode(list)
+	case "alfred-json":
+		return json.NewEncoder(os.Stdout).Encode(alfredItems(list))
+	}
+	return fmt.Errorf("unknown format %q", *format)
+}
+
+// fetchList gets the unread list from the daemon, or polls the backend
+// when there's no daemon to ask. A cache the daemon or the TUI saved within
+// a poll interval stands in for the poll, and a poll made here is saved to
+// it, so status bars refreshing every few seconds don't each poll the
+// backend too.
+func fetchList(cfg config, query string) (apiList, error) {
+	var list apiList
+	client, err := newAPIClient(cfg.API)
+	if err == nil {
+		path, q := "/v1/unread", url.Values(nil)
+		if query != "" {
+			path, q = "/v1/search", url.Values{"q": {query}}
+		}
+		if err = client.do("GET", path, q, &list); err == nil {
+			return list, nil
+		}
+	}
+	if !errors.Is(err, errNoDaemon) {
+		return list, err
+	}
+
+	cache := loadMailCache(cfg.State.cacheDir())
+	emails, ok := cache.complete(cfg.pollInterval())
+	updated := cache.Saved
+	if !ok {
+		provider, err := newProvider(cfg)
+		if err != nil {
+			return list, err
+		}
+		emails, err = provider.unread()
+		failed, err := partialRead(err)
+		if err != nil {
+			return list, err
+		}
+		if failed == nil {
+			cache.setEmails(emails, len(emails))
+		}
+		updated = time.Now()
+	}
+	terms := parseFilterQuery(query)
+	list = apiList{Emails: []apiEmail{}, Updated: updated}
+	for _, e := range emails {
+		if matchesQuery(terms, e) {
+			list.Emails = append(list.Emails, toAPIEmail(e))
+		}
+	}
+	return list, nil
+}
+
+// alfredFeedback is Alfred's Script Filter JSON format.
+type alfredFeedback struct {
+	Items []alfredItem `json:"items"`
+}
+
+type alfredItem struct {
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	Subtitle  string `json:"subtitle"`
+	Arg       string `json:"arg"`
+	Match     string `json:"match"`
+	Variables struct {
+		Account string `json:"account,omitempty"`
+		Mailbox string `json:"mailbox,omitempty"`
+	} `json:"variables"`
+}
+
+// alfredItems shapes the list for an Alfred Script Filter. Each item's arg
+// is the message id, ready to pass to `mailnotify act`; the account and
+// mailbox ride along as workflow variables.
+func alfredItems(list apiList) alfredFeedback {
+	out := alfredFeedback{Items: []alfredItem{}}
+	for _, e := range list.Emails {
+		item := alfredItem{
+			UID:      e.Account + "/" + e.Mailbox + "/" + e.ID,
+			Title:    e.Subject,
+			Subtitle: e.Sender + " • " + relativeTime(e.Date),
+			Arg:      e.ID,
+			Match:    e.Subject + " " + e.Sender,
+		}
+		item.Variables.Account, item.Variables.Mailbox = e.Account, e.Mailbox
+		out.Items = append(out.Items, item)
+	}
+	return out
+}
+
+// runAct applies an action to one message. open shows it in Mail.app.
+//
+//	mailnotify act [-account name] [-mailbox name] <id> read|archive|open
+func runAct(cfg config, args []string) error {
+	fs := flag.NewFlagSet("act", flag.ContinueOnError)
+	account := fs.String("account", "", "the message's account, when the id is ambiguous")
+	mailbox := fs.String("mailbox", "", "the message's mailbox, when the id is ambiguous")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: mailnotify act [-account name] [-mailbox name] <id> read|archive|open")
+	}
+	id, action := fs.Arg(0), fs.Arg(1)
+	if action == "open" {
+		return openListed(cfg, id, *account, *mailbox)
+	}
+
+	client, err := newAPIClient(cfg.API)
+	if err == nil {
+		q := url.Values{}
+		if *account != "" {
+			q.Set("account", *account)
+		}
+		if *mailbox != "" {
+			q.Set("mailbox", *mailbox)
+		}
+		if err = client.do("POST", "/v1/messages/"+url.PathEscape(id)+"/"+url.PathEscape(action), q, nil); err == nil {
+			return nil
+		}
+	}
+	if !errors.Is(err, errNoDaemon) {
+		return err
+	}
+
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return err
+	}
+	act, err := messageAction(provider, action)
+	if err != nil {
+		return err
+	}
+	emails, err := provider.unread()
+	if _, err := partialRead(err); err != nil {
+		return err
+	}
+	var found []email
+	for _, e := range emails {
+		if e.id == id && (*account == "" || e.account == *account) && (*mailbox == "" || e.mailbox == *mailbox) {
+			found = append(found, e)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return fmt.Errorf("no unread message %s", id)
+	case 1:
+		return act(found[0])
+	default:
+		return fmt.Errorf("message id %s is ambiguous; add -account or -mailbox", id)
+	}
+}
+
+// openListed shows the unread message id in Mail.app, finding its
+// Message-ID in the list the daemon, the cache or a poll gives.
+func openListed(cfg config, id, account, mailbox string) error {
+	if runtime.GOOS != "darwin" {
+		return fmt.Errorf("opening a message needs Mail.app, on macOS")
+	}
+	list, err := fetchList(cfg, "")
+	if err != nil {
+		return err
+	}
+	var found []apiEmail
+	for _, e := range list.Emails {
+		if e.ID == id && (account == "" || e.Account == account) && (mailbox == "" || e.Mailbox == mailbox) {
+			found = append(found, e)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		return fmt.Errorf("no unread message %s", id)
+	case len(found) > 1:
+		return fmt.Errorf("message id %s is ambiguous; add -account or -mailbox", id)
+	case found[0].MessageID == "":
+		return fmt.Errorf("message %s has no Message-ID for Mail.app to find it by", id)
+	}
+	return exec.Command("open", messageURL(found[0].MessageID)).Run()
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	bareURL  = regexp.MustCompile(`(?i)\b(?:https?://|mailto:)[^\s<>"'\[\]{}]+`)
+	footnote = regexp.MustCompile(`^\[(\d+)\] (\S+)$`)
+)
+
+// footnoteLinks numbers the links in text. HTML rendering already lists
+// its links as footnotes; those keep their numbers, and any URL written
+// out in the text that isn't among them is added after. It returns text
+// with the footnotes completed, and the links in footnote order.
+func footnoteLinks(text string) (string, []string) {
+	body, links := splitFootnotes(text)
+	seen := map[string]bool{}
+	for _, l := range links {
+		seen[l] = true
+	}
+	added := 0
+	for _, u := range bareURL.FindAllString(body, -1) {
+		// Sentence punctuation after a URL isn't part of it, and neither is
+		// a closing parenthesis without an opening one.
+		u = strings.TrimRight(u, ".,;:!?")
+		if strings.HasSuffix(u, ")") && !strings.Contains(u, "(") {
+			u = strings.TrimRight(u, ")")
+		}
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		links = append(links, u)
+		added++
+	}
+	if added == 0 {
+		return text, links
+	}
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(body, "\n") + "\n\n")
+	for i, l := range links {
+		fmt.Fprintf(&b, "[%d] %s\n", i+1, l)
+	}
+	return strings.TrimRight(b.String(), "\n"), links
+}
+
+// splitFootnotes separates the "[1] http://…" lines ending text from the
+// rest of it. The footnotes have to be numbered from 1 in order, as
+// htmlToText writes them, so a body that happens to end in a bracketed
+// number isn't taken for one.
+func splitFootnotes(text string) (string, []string) {
+	lines := strings.Split(text, "\n")
+	start := len(lines)
+	for start > 0 && footnote.MatchString(lines[start-1]) {
+		start--
+	}
+	if start == len(lines) || start > 0 && lines[start-1] != "" {
+		return text, nil
+	}
+	var links []string
+	for i, line := range lines[start:] {
+		m := footnote.FindStringSubmatch(line)
+		if n, _ := strconv.Atoi(m[1]); n != i+1 {
+			return text, nil
+		}
+		links = append(links, m[2])
+	}
+	return strings.Join(lines[:start], "\n"), links
+}
+
+// openableLink reports whether link is safe to hand to the system opener:
+// a web page or an address, never a local file or an app's URL scheme.
+func openableLink(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return true
+	}
+	return false
+}
+
+// systemOpener is the command that opens a URL in its default app.
+func systemOpener() string {
+	if runtime.GOOS == "darwin" {
+		return "open"
+	}
+	return "xdg-open"
+}
+
+type linkOpenedMsg struct {
+	link string
+	err  error
+}
+
+func openLink(link string) tea.Cmd {
+	return func() tea.Msg {
+		return linkOpenedMsg{link: link, err: exec.Command(systemOpener(), link).Run()}
+	}
+}
+
+// openLinks switches to the link picker for the open message.
+func (m *model) openLinks() {
+	m.mode = linksView
+	m.linkCursor = 0
+}
+
+// selectLink opens the i'th link and goes back to the message.
+func (m *model) selectLink(i int) tea.Cmd {
+	link := m.links[i]
+	if !openableLink(link) {
+		m.setNotice("Only web and mailto links are opened")
+		return nil
+	}
+	m.mode = detailView
+	return openLink(link)
+}
+
+// linksKeys handles keys in the link picker. A digit opens that footnote
+// directly.
+func (m *model) linksKeys(key string) (tea.Cmd, bool) {
+	switch key {
+	case "up", "k":
+		m.linkCursor = (m.linkCursor + len(m.links) - 1) % len(m.links)
+	case "down", "j":
+		m.linkCursor = (m.linkCursor + 1) % len(m.links)
+	case "enter":
+		return m.selectLink(m.linkCursor), true
+	case "esc", "q", "l":
+		m.mode = detailView
+	default:
+		if n, err := strconv.Atoi(key); err == nil && n >= 1 && n <= len(m.links) {
+			return m.selectLink(n - 1), true
+		}
+	}
+	return nil, true
+}
+
+func (m *model) updateLinks(tea.Msg) tea.Cmd {
+	return nil
+}
+
+func (m model) viewLinks(status string) string {
+	room := max(m.height-8, 3)
+	first := 0
+	if m.linkCursor >= room {
+		first = m.linkCursor - room + 1
+	}
+	width := max(m.width-12, 20)
+	var lines []string
+	for i := first; i < len(m.links) && i < first+room; i++ {
+		cursor := "  "
+		style := bodyStyle
+		if i == m.linkCursor {
+			cursor = senderStyle.Render("▸ ")
+			style = senderStyle
+		}
+		link := m.links[i]
+		if r := []rune(link); len(r) > width {
+			link = string(r[:width-1]) + "…"
+		}
+		lines = append(lines, cursor+metaStyle.Render(fmt.Sprintf("[%d] ", i+1))+style.Render(link))
+	}
+
+	title := "Links"
+	if m.currentEmail != nil {
+		title += " in " + m.currentEmail.subject
+	}
+	bindings := [][]string{{"↑/↓", "select"}, {"enter", "open"}, {"1-9", "open that link"}, {"esc", "back"}}
+	body := lipgloss.NewStyle().Padding(1, 2).Render(headerStyle.Render(title) + "\n\n" + strings.Join(lines, "\n"))
+	gap := max(m.height-lipgloss.Height(body)-lipgloss.Height(status)-1, 0)
+	return body + strings.Repeat("\n", gap) + "\n" + status + renderHelpBar(m.width, bindings)
+}
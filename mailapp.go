@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// mailAppProvider reads mail through Mail.app. Listing, opening, searching
+// and marking go through the long-lived JXA bridge (see jxa.go); the rarer
+// actions, such as drafts and saving attachments, still run an AppleScript
+// each.
+type mailAppProvider struct {
+	mailboxes mailboxConfig
+	scope     mailScope
+	// limit caps how many unread messages a poll returns.
+	limit int
+}
+
+func (mailAppProvider) name() string { return "Mail.app (JXA)" }
+
+// listMailboxes returns every mailbox of every account. An account's inbox
+// is listed once, marked as such. Nested mailboxes are reported by their
+// full slash-separated path, which is also how Mail.app addresses them.
+func (mailAppProvider) listMailboxes() ([]mailboxInfo, error) {
+	var found []struct {
+		Account string `json:"account"`
+		Path    string `json:"path"`
+		Unread  int    `json:"unread"`
+		Inbox   bool   `json:"inbox"`
+	}
+	if err := mailBridge.call("mailboxes", nil, &found); err != nil {
+		return nil, err
+	}
+	var boxes []mailboxInfo
+	seen := map[[2]string]bool{}
+	for _, b := range found {
+		key := [2]string{b.Account, b.Path}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		boxes = append(boxes, mailboxInfo{account: b.Account, path: b.Path, unread: b.Unread, inbox: b.Inbox})
+	}
+	return boxes, nil
+}
+
+func (p mailAppProvider) scoped(s mailScope) mailProvider {
+	p.scope = s
+	return p
+}
+
+// sweepBoxes returns the mailboxes to read: the scoped mailbox, or the
+// mailboxes the config selects, skipping those without unread mail unless
+// all is set. ok is false when reading the unified inbox instead.
+func (p mailAppProvider) sweepBoxes(all bool) (boxes []bridgeRef, ok bool, err error) {
+	if p.scope != (mailScope{}) {
+		return []bridgeRef{{Account: p.scope.account, Mailbox: p.scope.mailbox}}, true, nil
+	}
+	if !p.mailboxes.sweep() {
+		return nil, false, nil
+	}
+	found, err := p.listMailboxes()
+	if err != nil {
+		return nil, true, err
+	}
+	for _, b := range found {
+		if (all || b.unread > 0) && p.mailboxes.monitors(b.path) {
+			boxes = append(boxes, bridgeRef{Account: b.account, Mailbox: b.path})
+		}
+	}
+	return boxes, true, nil
+}
+
+// bridgeQuery asks the bridge for messages in boxes, or the unified inbox
+// when there are none.
+type bridgeQuery struct {
+	Limit int         `json:"limit"`
+	Query string      `json:"query,omitempty"`
+	Boxes []bridgeRef `json:"boxes,omitempty"`
+	// The search operators, with dates in JavaScript's Date format.
+	From       string `json:"from,omitempty"`
+	To         string `json:"to,omitempty"`
+	Subject    string `json:"subject,omitempty"`
+	After      string `json:"after,omitempty"`
+	Before     string `json:"before,omitempty"`
+	Attachment bool   `json:"attachment,omitempty"`
+}
+
+// unread returns the unread messages, up to the limit.
+func (p mailAppProvider) unread() ([]email, error) {
+	emails, _, err := p.unreadPage(p.limit)
+	return emails, err
+}
+
+// unreadPage returns up to limit unread messages and the unread count of
+// every mailbox read. The bridge answers from what it remembers for
+// mailboxes whose unread count hasn't changed.
+func (p mailAppProvider) unreadPage(limit int) ([]email, int, error) {
+	var out struct {
+		Total    int             `json:"total"`
+		Messages json.RawMessage `json:"messages"`
+	}
+	if ok, err := p.ask("unread", bridgeQuery{Limit: limit}, false, &out); !ok {
+		return nil, 0, err
+	}
+	emails, err := decodeBridgeMessages(out.Messages)
+	return emails, out.Total, err
+}
+
+// search finds messages, read or not, matching q, through a whose clause.
+// It looks through the same mailboxes polling does.
+func (p mailAppProvider) search(q mailSearch) ([]email, error) {
+	bq := bridgeQuery{Limit: maxSearchResults, Query: q.text, From: q.from, To: q.to, Subject: q.subject, Attachment: q.hasAttachment}
+	if !q.after.IsZero() {
+		bq.After = q.after.Format(time.RFC3339)
+	}
+	if !q.before.IsZero() {
+		bq.Before = q.before.Format(time.RFC3339)
+	}
+	var raw json.RawMessage
+	if ok, err := p.ask("search", bq, true, &raw); !ok {
+		return nil, err
+	}
+	return decodeBridgeMessages(raw)
+}
+
+// ask sends q to the bridge for the mailboxes sweepBoxes picks. It reports
+// false, with any error, when there was nothing to ask, or asking failed.
+func (p mailAppProvider) ask(op string, q bridgeQuery, all bool, out any) (bool, error) {
+	boxes, ok, err := p.sweepBoxes(all)
+	if ok && (err != nil || len(boxes) == 0) {
+		return false, err
+	}
+	q.Boxes = boxes
+	if err := mailBridge.call(op, q, out); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// messageByIDScript sets msg to the message whose id is the first argument,
+// looked up in the mailbox named by the second and third arguments (account
+// and mailbox) when they are given, or the inbox. Ids stay valid as new
+// mail arrives, unlike positions in the unread list.
+const messageByIDScript = `
+		set msgID to (item 1 of argv) as integer
+		if (count of argv) > 2 and (item 3 of argv) is not "" then
+			set msg to first message of mailbox (item 3 of argv) of account (item 2 of argv) whose id is msgID
+		else
+			set msg to first message of inbox whose id is msgID
+		end if
+`
+
+// messageArgs are the script arguments messageByIDScript expects for e.
+func messageArgs(e email) []string {
+	return []string{e.id, e.account, e.mailbox}
+}
+
+// content returns e's body and attachments, marking it read.
+func (p mailAppProvider) content(e email) (messageContent, error) {
+	return p.read("content", e)
+}
+
+// peek returns e's body and attachments and leaves it unread.
+func (p mailAppProvider) peek(e email) (messageContent, error) {
+	return p.read("peek", e)
+}
+
+// read opens e with the bridge's content or peek request.
+func (mailAppProvider) read(op string, e email) (messageContent, error) {
+	if e.id == "" {
+		return messageContent{}, fmt.Errorf("message has no id")
+	}
+	var out struct {
+		Attachments []struct {
+			Name     string  `json:"name"`
+			MIMEType string  `json:"mime_type"`
+			Size     float64 `json:"size"`
+		} `json:"attachments"`
+		Body   string `json:"body"`
+		Source string `json:"source"`
+	}
+	if err := mailBridge.call(op, refOf(e), &out); err != nil {
+		return messageContent{}, err
+	}
+	c := messageContent{id: e.id, body: strings.TrimSpace(out.Body), source: out.Source}
+	for _, a := range out.Attachments {
+		if a.Name != "" {
+			c.attachments = append(c.attachments, attachment{name: a.Name, mimeType: a.MIMEType, size: int64(a.Size)})
+		}
+	}
+	// Mail.app's content is already plain text; the HTML part, if any, has
+	// to come from the raw source.
+	if parsed, err := parseMessage([]byte(out.Source)); err == nil {
+		c.html = parsed.html
+	}
+	return c, nil
+}
+
+// markRead marks emails read in one request, addressing each by id so
+// mail that arrived since the last poll is left alone.
+func (mailAppProvider) markRead(emails []email) error {
+	return markMessages(emails, true)
+}
+
+// markUnread flags e unread again.
+func (mailAppProvider) markUnread(e email) error {
+	return markMessages([]email{e}, false)
+}
+
+func markMessages(emails []email, read bool) error {
+	refs := make([]bridgeRef, 0, len(emails))
+	for _, e := range emails {
+		if e.id != "" {
+			refs = append(refs, refOf(e))
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	return mailBridge.call("mark", struct {
+		Messages []bridgeRef `json:"messages"`
+		Read     bool        `json:"read"`
+	}{refs, read}, nil)
+}
+
+// trash moves e to its account's Trash.
+func (p mailAppProvider) trash(e email) error {
+	return p.trashAll([]email{e})
+}
+
+// archive moves e to the archive mailbox of its own account.
+func (p mailAppProvider) archive(e email) error {
+	return p.archiveAll([]email{e})
+}
+
+// trashAll moves emails to their accounts' Trash in one request.
+func (mailAppProvider) trashAll(emails []email) error {
+	refs, err := movedRefs(emails)
+	if err != nil {
+		return err
+	}
+	return mailBridge.call("trash", struct {
+		Messages []bridgeRef `json:"messages"`
+	}{refs}, nil)
+}
+
+// archiveAll moves emails to the archive mailboxes of their own accounts
+// in one request.
+func (p mailAppProvider) archiveAll(emails []email) error {
+	refs, err := movedRefs(emails)
+	if err != nil {
+		return err
+	}
+	name := p.mailboxes.Archive
+	if name == "" {
+		name = "Archive"
+	}
+	return mailBridge.call("archive", struct {
+		Messages []bridgeRef `json:"messages"`
+		Mailbox  string      `json:"mailbox"`
+	}{refs, name}, nil)
+}
+
+// movedRefs addresses the messages a move is for, all of which need an id.
+func movedRefs(emails []email) ([]bridgeRef, error) {
+	refs := make([]bridgeRef, len(emails))
+	for i, e := range emails {
+		if e.id == "" {
+			return nil, fmt.Errorf("message has no id")
+		}
+		refs[i] = refOf(e)
+	}
+	return refs, nil
+}
+
+// setFlag sets e's color flag, which Mail.app and its other devices show
+// too.
+func (mailAppProvider) setFlag(e email, f flagColor) error {
+	if e.id == "" {
+		return fmt.Errorf("message has no id")
+	}
+	return mailBridge.call("flag", struct {
+		Message bridgeRef `json:"message"`
+		Index   int       `json:"index"`
+	}{refOf(e), int(f) - 1}, nil)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var defaultExcludedMailboxes = []string{
+	"Junk", "Spam", "Trash", "Deleted Messages", "Sent", "Sent Messages", "Drafts", "Archive",
+}
+
+// sweep reports whether mailboxes beyond the unified inbox are monitored.
+func (m mailboxConfig) sweep() bool {
+	return m.All || len(m.Include) > 0
+}
+
+func (m mailboxConfig) excluded() []string {
+	if m.Exclude == nil {
+		return defaultExcludedMailboxes
+	}
+	return m.Exclude
+}
+
+// monitors reports whether the mailbox at the slash-separated path is
+// selected by the include and exclude globs. Every backend filters its
+// mailboxes through this so the same config means the same thing
+// everywhere. Matching is case-insensitive and '*' stays within one level.
+func (m mailboxConfig) monitors(mailbox string) bool {
+	if len(m.Include) > 0 && !matchMailboxGlobs(m.Include, mailbox) {
+		return false
+	}
+	return !matchMailboxGlobs(m.excluded(), mailbox)
+}
+
+func matchMailboxGlobs(globs []string, mailbox string) bool {
+	mailbox = strings.ToLower(mailbox)
+	for _, g := range globs {
+		if ok, err := path.Match(strings.ToLower(g), mailbox); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mailboxItem is an entry in the mailbox picker. The zero item is the
+// default view.
+type mailboxItem struct {
+	info mailboxInfo
+}
+
+func (i mailboxItem) scope() mailScope {
+	return mailScope{account: i.info.account, mailbox: i.info.path}
+}
+
+func (i mailboxItem) Title() string {
+	switch {
+	case i.info.account == "":
+		return "All Inboxes"
+	case i.info.inbox:
+		return i.info.account + " › Inbox"
+	default:
+		return i.info.account + " › " + i.info.path
+	}
+}
+
+func (i mailboxItem) Description() string {
+	if i.info.account == "" {
+		return "Every account, as configured"
+	}
+	return fmt.Sprintf("%d unread", i.info.unread)
+}
+
+func (i mailboxItem) FilterValue() string { return i.info.account + " " + i.info.path }
+
+// scopeLabel names s for the list title.
+func scopeLabel(s mailScope) string {
+	if s == (mailScope{}) {
+		return ""
+	}
+	return s.account + " › " + s.mailbox
+}
+
+type mailboxesMsg struct {
+	boxes []mailboxInfo
+	err   error
+}
+
+func fetchMailboxes(b mailboxBrowser) tea.Cmd {
+	return func() tea.Msg {
+		boxes, err := b.listMailboxes()
+		return mailboxesMsg{boxes: boxes, err: err}
+	}
+}
+
+// mailboxPickerKeys handles keys in the mailbox picker. Picking a mailbox
+// switches the list to it and polls right away.
+func (m *model) mailboxPickerKeys(key string) (tea.Cmd, bool) {
+	if m.mailboxes.FilterState() == list.Filtering {
+		return nil, false
+	}
+	switch key {
+	case "esc", "q":
+		if m.mailboxes.FilterState() == list.Unfiltered {
+			m.mode = listView
+			return nil, true
+		}
+	case "enter":
+		if item, ok := m.mailboxes.SelectedItem().(mailboxItem); ok {
+			m.scope = item.scope()
+			m.readHere, m.lastRead = nil, nil
+			m.pages = 0
+			m.mode = listView
+			m.lastPoll = time.Now()
+			m.nextPoll = m.lastPoll.Add(m.pollInterval())
+			return tea.Batch(m.pollMail(), m.begin(polling)), true
+		}
+	}
+	return nil, false
+}
+
+func (m *model) updateMailboxPicker(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	m.mailboxes, cmd = m.mailboxes.Update(msg)
+	return cmd
+}
+
+func (m model) viewMailboxPicker(status string) string {
+	helpBar := renderHelpBar(m.width, [][]string{
+		{"enter", "show"},
+		{"/", "filter"},
+		{"esc", "back"},
+	})
+	return m.mailboxes.View() + "\n" + status + helpBar
+}
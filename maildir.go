@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maildirProvider reads a local Maildir tree, such as one kept in sync by
+// mbsync or offlineimap. Read state lives in the file names' flags (the
+// "S" in "…:2,S"), so marking a message read renames its file; notmuch, with
+// its default synchronize_flags, picks the change up on its next run.
+type maildirProvider struct {
+	root      string
+	label     string
+	mailboxes mailboxConfig
+	scope     mailScope
+	// limit caps how many unread messages a poll returns.
+	limit int
+}
+
+// maildirBox is a mailbox of the tree: its slash-separated path and its
+// directory, the one holding cur, new and tmp.
+type maildirBox struct {
+	path string
+	dir  string
+}
+
+func newMaildirProvider(cfg backendConfig, mailboxes mailboxConfig, limit int) (maildirProvider, error) {
+	if cfg.Path == "" {
+		return maildirProvider{}, fmt.Errorf("maildir backend: no path configured")
+	}
+	root := expandHome(cfg.Path)
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return maildirProvider{}, fmt.Errorf("maildir backend: %s is not a directory", root)
+	}
+	label := cfg.Account
+	if label == "" {
+		label = filepath.Base(root)
+	}
+	return maildirProvider{root: root, label: label, mailboxes: mailboxes, limit: limit}, nil
+}
+
+func (p maildirProvider) name() string {
+	return fmt.Sprintf("Maildir (%s)", p.root)
+}
+
+func (p maildirProvider) unread() ([]email, error) {
+	emails, _, err := p.unreadPage(p.limit)
+	return emails, err
+}
+
+// unreadPage returns the newest limit unread messages, by delivery time,
+// and how many are unread across the monitored mailboxes.
+func (p maildirProvider) unreadPage(limit int) ([]email, int, error) {
+	boxes, err := p.monitoredMailboxes()
+	if err != nil {
+		return nil, 0, err
+	}
+	type found struct {
+		box  maildirBox
+		path string
+		mod  time.Time
+	}
+	var files []found
+	for _, box := range boxes {
+		paths, err := box.unreadFiles()
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			files = append(files, found{box, path, info.ModTime()})
+		}
+	}
+	// Newest first, like Mail.app's inbox order.
+	sort.SliceStable(files, func(i, j int) bool { return files[i].mod.After(files[j].mod) })
+	total := len(files)
+	if len(files) > limit {
+		files = files[:limit]
+	}
+
+	emails := make([]email, 0, len(files))
+	for _, f := range files {
+		header, err := readHeaderBlock(f.path)
+		if err != nil {
+			continue
+		}
+		e := parseHeaderEmail(header)
+		e.id = maildirUnique(filepath.Base(f.path))
+		e.account = p.label
+		if p.mailboxes.sweep() || p.scope != (mailScope{}) {
+			e.mailbox = f.box.path
+		}
+		emails = append(emails, e)
+	}
+	return emails, total, nil
+}
+
+func (p maildirProvider) content(e email) (messageContent, error) {
+	content, path, err := p.read(e)
+	if err != nil {
+		return messageContent{}, err
+	}
+	// Opening a message marks it read, as it does in Mail.app.
+	if _, err := setMaildirFlag(path, 'S', true); err != nil {
+		return messageContent{}, err
+	}
+	return content, nil
+}
+
+func (p maildirProvider) peek(e email) (messageContent, error) {
+	content, _, err := p.read(e)
+	return content, err
+}
+
+// read parses e's file, returning it with the file's path.
+func (p maildirProvider) read(e email) (messageContent, string, error) {
+	path, err := p.messageFile(e)
+	if err != nil {
+		return messageContent{}, "", err
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return messageContent{}, "", err
+	}
+	content, err := parseMessage(raw)
+	if err != nil {
+		return messageContent{}, "", err
+	}
+	content.id = e.id
+	return content, path, nil
+}
+
+func (p maildirProvider) markRead(emails []email) error {
+	find := p.fileFinder()
+	for _, e := range emails {
+		path, err := find(e)
+		if err != nil {
+			return err
+		}
+		if _, err := setMaildirFlag(path, 'S', true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p maildirProvider) markUnread(e email) error {
+	path, err := p.messageFile(e)
+	if err != nil {
+		return err
+	}
+	_, err = setMaildirFlag(path, 'S', false)
+	return err
+}
+
+func (p maildirProvider) trash(e email) error {
+	return p.trashAll([]email{e})
+}
+
+func (p maildirProvider) archive(e email) error {
+	return p.archiveAll([]email{e})
+}
+
+func (p maildirProvider) trashAll(emails []email) error {
+	return p.moveAll(emails, "Trash", "Deleted Messages", "Deleted Items")
+}
+
+func (p maildirProvider) archiveAll(emails []email) error {
+	if p.mailboxes.Archive != "" {
+		return p.moveAll(emails, p.mailboxes.Archive)
+	}
+	return p.moveAll(emails, "Archive")
+}
+
+// moveAll moves emails' files into the cur directory of the first of names
+// the tree has, keeping their flags. Each mailbox is looked up and listed
+// once, however many of the messages it holds.
+func (p maildirProvider) moveAll(emails []email, names ...string) error {
+	dest, err := p.firstMailbox(names)
+	if err != nil {
+		return err
+	}
+	find := p.fileFinder()
+	for _, e := range emails {
+		path, err := find(e)
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(path, filepath.Join(dest.dir, "cur", maildirMovedName(filepath.Base(path)))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// firstMailbox finds the first of names the tree has.
+func (p maildirProvider) firstMailbox(names []string) (maildirBox, error) {
+	for _, name := range names {
+		if box, ok, err := p.mailbox(name); err != nil || ok {
+			return box, err
+		}
+	}
+	return maildirBox{}, fmt.Errorf("no %s mailbox in %s", names[0], p.root)
+}
+
+// mailbox finds the mailbox at path. It tries the directories each layout
+// would keep it in before walking the whole tree for one whose name only
+// differs in case.
+func (p maildirProvider) mailbox(path string) (maildirBox, bool, error) {
+	var dirs []string
+	if strings.EqualFold(path, "INBOX") {
+		dirs = append(dirs, p.root)
+	}
+	dirs = append(dirs, filepath.Join(p.root, "."+strings.ReplaceAll(path, "/", ".")), filepath.Join(p.root, filepath.FromSlash(path)))
+	for _, dir := range dirs {
+		if isMaildir(dir) {
+			return maildirBox{path: path, dir: dir}, true, nil
+		}
+	}
+	boxes, err := p.allMailboxes()
+	if err != nil {
+		return maildirBox{}, false, err
+	}
+	for _, box := range boxes {
+		if strings.EqualFold(box.path, path) {
+			return box, true, nil
+		}
+	}
+	return maildirBox{}, false, nil
+}
+
+func (p maildirProvider) saveAttachment(e email, a attachment, dest string) error {
+	path, err := p.messageFile(e)
+	if err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	data, err := attachmentData(raw, a.name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dest, data, 0o600)
+}
+
+func (p maildirProvider) listMailboxes() ([]mailboxInfo, error) {
+	boxes, err := p.allMailboxes()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]mailboxInfo, 0, len(boxes))
+	for _, box := range boxes {
+		files, err := box.unreadFiles()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, mailboxInfo{account: p.label, path: box.path, unread: len(files), inbox: strings.EqualFold(box.path, "INBOX")})
+	}
+	return infos, nil
+}
+
+func (p maildirProvider) scoped(s mailScope) mailProvider {
+	p.scope = s
+	return p
+}
+
+// allMailboxes finds every maildir in the tree. The root itself, when it
+// is one, is INBOX. Both mbsync's layouts are understood: folders nested as
+// directories ("Lists/go") and Maildir++ dot-folders (".Lists.go").
+func (p maildirProvider) allMailboxes() ([]maildirBox, error) {
+	var boxes []maildirBox
+	err := filepath.WalkDir(p.root, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		switch d.Name() {
+		case "cur", "new", "tmp":
+			return fs.SkipDir
+		}
+		if !isMaildir(dir) {
+			return nil
+		}
+		rel, _ := filepath.Rel(p.root, dir)
+		path := filepath.ToSlash(rel)
+		switch {
+		case rel == ".":
+			path = "INBOX"
+		case strings.HasPrefix(rel, ".") && !strings.ContainsRune(rel, filepath.Separator):
+			path = strings.ReplaceAll(strings.TrimPrefix(rel, "."), ".", "/")
+		}
+		boxes = append(boxes, maildirBox{path: path, dir: dir})
+		return nil
+	})
+	return boxes, err
+}
+
+// monitoredMailboxes lists the mailboxes the config selects, or just INBOX
+// when not sweeping. A scope narrows it to that one mailbox.
+func (p maildirProvider) monitoredMailboxes() ([]maildirBox, error) {
+	all, err := p.allMailboxes()
+	if err != nil {
+		return nil, err
+	}
+	var boxes []maildirBox
+	for _, box := range all {
+		selected := strings.EqualFold(box.path, "INBOX")
+		switch {
+		case p.scope != (mailScope{}):
+			selected = box.path == p.scope.mailbox
+		case p.mailboxes.sweep():
+			selected = p.mailboxes.monitors(box.path)
+		}
+		if selected {
+			boxes = append(boxes, box)
+		}
+	}
+	if len(boxes) == 0 && p.scope == (mailScope{}) && !p.mailboxes.sweep() {
+		return nil, fmt.Errorf("no INBOX maildir in %s", p.root)
+	}
+	return boxes, nil
+}
+
+// messageFile finds e's file in its mailbox, under whatever flags it has
+// now.
+func (p maildirProvider) messageFile(e email) (string, error) {
+	return p.fileFinder()(e)
+}
+
+// fileFinder returns a messageFile for acting on many messages, which
+// lists each of their mailboxes once.
+func (p maildirProvider) fileFinder() func(email) (string, error) {
+	listed := map[string]map[string]string{}
+	return func(e email) (string, error) {
+		mailbox := e.mailbox
+		if mailbox == "" {
+			mailbox = "INBOX"
+		}
+		files, ok := listed[mailbox]
+		if !ok {
+			var err error
+			if files, err = p.mailboxFiles(mailbox); err != nil {
+				return "", err
+			}
+			listed[mailbox] = files
+		}
+		if path, ok := files[e.id]; ok {
+			return path, nil
+		}
+		return "", fmt.Errorf("message not found in %s; it may have been moved or deleted", mailbox)
+	}
+}
+
+// mailboxFiles maps the unique names of the messages in mailbox to their
+// files, preferring cur's when new has one too.
+func (p maildirProvider) mailboxFiles(mailbox string) (map[string]string, error) {
+	files := map[string]string{}
+	box, ok, err := p.mailbox(mailbox)
+	if err != nil || !ok {
+		return files, err
+	}
+	for _, sub := range []string{"new", "cur"} {
+		entries, err := os.ReadDir(filepath.Join(box.dir, sub))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			files[maildirUnique(entry.Name())] = filepath.Join(box.dir, sub, entry.Name())
+		}
+	}
+	return files, nil
+}
+
+func isMaildir(dir string) bool {
+	for _, sub := range []string{"cur", "new"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// unreadFiles lists the messages in new, which are all unread, and those in
+// cur without the S flag.
+func (b maildirBox) unreadFiles() ([]string, error) {
+	var files []string
+	for _, sub := range []string{"new", "cur"} {
+		entries, err := os.ReadDir(filepath.Join(b.dir, sub))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || strings.HasPrefix(name, ".") {
+				continue
+			}
+			if sub == "cur" && strings.ContainsRune(maildirFlags(name), 'S') {
+				continue
+			}
+			files = append(files, filepath.Join(b.dir, sub, name))
+		}
+	}
+	return files, nil
+}
+
+// maildirUnique is a file name without its ":2,flags" info, which stays
+// the same as the flags change.
+func maildirUnique(name string) string {
+	unique, _, _ := strings.Cut(name, ":")
+	return unique
+}
+
+func maildirFlags(name string) string {
+	_, flags, _ := strings.Cut(name, ":2,")
+	return flags
+}
+
+// maildirMovedName is name as it's spelled once moved into another
+// mailbox's cur, which always carries the info part. mbsync's ",U=<uid>" is
+// dropped: it's the UID in the mailbox the file came from, and kept it would
+// make mbsync take the file for a different message of the new one.
+func maildirMovedName(name string) string {
+	unique := maildirUnique(name)
+	if i := strings.Index(unique, ",U="); i >= 0 {
+		end := i + len(",U=")
+		for end < len(unique) && unique[end] >= '0' && unique[end] <= '9' {
+			end++
+		}
+		unique = unique[:i] + unique[end:]
+	}
+	return unique + ":2," + maildirFlags(name)
+}
+
+// setMaildirFlag sets or clears flag on the message at path, moving it
+// from new to cur as any client that has seen it does, and returns its new
+// path.
+func setMaildirFlag(path string, flag rune, on bool) (string, error) {
+	name := filepath.Base(path)
+	flags := []rune(maildirFlags(name))
+	has := slices.Contains(flags, flag)
+	switch {
+	case on && !has:
+		flags = append(flags, flag)
+	case !on && has:
+		flags = slices.DeleteFunc(flags, func(r rune) bool { return r == flag })
+	}
+	// Flags are kept in ASCII order.
+	slices.Sort(flags)
+	dest := filepath.Join(filepath.Dir(filepath.Dir(path)), "cur", maildirUnique(name)+":2,"+string(flags))
+	if dest == path {
+		return path, nil
+	}
+	return dest, os.Rename(path, dest)
+}
+
+// readHeaderBlock reads the header of the message at path, up to the blank
+// line before the body.
+func readHeaderBlock(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var header bytes.Buffer
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimRight(line, "\r\n")) == 0 {
+			return header.Bytes(), nil
+		}
+		header.Write(line)
+		if err != nil {
+			return header.Bytes(), nil
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeMaildir makes dir a mailbox, with cur, new and tmp, and puts
+// the named files in its cur.
+func writeMaildir(t *testing.T, dir string, cur ...string) {
+	t.Helper()
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range cur {
+		msg := "From: ann@example.com\r\nSubject: Offsite\r\n\r\nSee you there.\r\n"
+		if err := os.WriteFile(filepath.Join(dir, "cur", name), []byte(msg), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMaildirMoves(t *testing.T) {
+	for _, layout := range []struct {
+		name, trash, archive string
+	}{
+		{"Maildir++", ".Trash", ".Lists.Archive"},
+		{"nested", "Trash", "Lists/Archive"},
+	} {
+		t.Run(layout.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeMaildir(t, root, "1700000000.1.host,U=12:2,F", "1700000000.2.host,U=13:2,")
+			writeMaildir(t, filepath.Join(root, layout.trash))
+			writeMaildir(t, filepath.Join(root, filepath.FromSlash(layout.archive)))
+			p := maildirProvider{root: root, mailboxes: mailboxConfig{Archive: "Lists/Archive"}, limit: 20}
+
+			if err := p.trash(email{id: "1700000000.1.host,U=12"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(root, layout.trash, "cur", "1700000000.1.host:2,F")); err != nil {
+				t.Errorf("trashed message isn't in Trash without its UID and with its flags: %v", err)
+			}
+			if err := p.archive(email{id: "1700000000.2.host,U=13"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(layout.archive), "cur", "1700000000.2.host:2,")); err != nil {
+				t.Errorf("archived message isn't in %s without its UID: %v", layout.archive, err)
+			}
+			if entries, _ := os.ReadDir(filepath.Join(root, "cur")); len(entries) != 0 {
+				t.Errorf("INBOX still holds %d file(s)", len(entries))
+			}
+		})
+	}
+}
+
+func TestMaildirFlags(t *testing.T) {
+	root := t.TempDir()
+	writeMaildir(t, root, "1700000000.1.host,U=12:2,F")
+	p := maildirProvider{root: root, limit: 20}
+	e := email{id: "1700000000.1.host,U=12"}
+
+	if err := p.markRead([]email{e}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "cur", "1700000000.1.host,U=12:2,FS")); err != nil {
+		t.Errorf("read message isn't flagged S in place: %v", err)
+	}
+	if emails, _ := p.unread(); len(emails) != 0 {
+		t.Errorf("unread = %d message(s) after markRead", len(emails))
+	}
+	if err := p.markUnread(e); err != nil {
+		t.Fatal(err)
+	}
+	if emails, _ := p.unread(); len(emails) != 1 || emails[0].id != e.id {
+		t.Errorf("unread = %v after markUnread, want the message back", emails)
+	}
+}
+
+func TestMaildirMovedName(t *testing.T) {
+	for name, want := range map[string]string{
+		"1700000000.1.host,U=12:2,FS":    "1700000000.1.host:2,FS",
+		"1700000000.1.host,U=12,FMD5=ab": "1700000000.1.host,FMD5=ab:2,",
+		"1700000000.1.host:2,S":          "1700000000.1.host:2,S",
+	} {
+		if got := maildirMovedName(name); got != want {
+			t.Errorf("maildirMovedName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestMaildirTrashAll(t *testing.T) {
+	root := t.TempDir()
+	writeMaildir(t, root, "1700000000.1.host,U=12:2,S")
+	writeMaildir(t, filepath.Join(root, ".Lists.go"), "1700000000.2.host,U=40:2,")
+	writeMaildir(t, filepath.Join(root, ".Trash"))
+	p := maildirProvider{root: root, limit: 20}
+
+	err := p.trashAll([]email{{id: "1700000000.1.host,U=12"}, {id: "1700000000.2.host,U=40", mailbox: "Lists/go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(root, ".Trash", "cur"))
+	if len(entries) != 2 {
+		t.Errorf("Trash holds %d file(s), want both messages", len(entries))
+	}
+	if err := p.trashAll([]email{{id: "1700000000.1.host,U=12"}}); err == nil {
+		t.Error("trashing a message that's gone succeeded")
+	}
+}
@@ -1,123 +1,222 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
 	"os"
-	"os/exec"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// Colors and styles are populated by applyTheme from the active palette.
 var (
-	accentColor  = lipgloss.Color("#2563EB")
-	subtleColor  = lipgloss.Color("#6B7280")
-	senderColor  = lipgloss.Color("#60A5FA")
-	dateColor    = lipgloss.Color("#93C5FD")
-	textColor    = lipgloss.Color("#E5E7EB")
-	dimColor     = lipgloss.Color("#4B5563")
-	successColor = lipgloss.Color("#34D399")
-	errorColor   = lipgloss.Color("#FF6B6B")
-
-	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#FFFFFF")).
-			Background(accentColor).
-			Padding(0, 1)
-
-	statusStyle = lipgloss.NewStyle().
-			Foreground(subtleColor).
-			Italic(true)
-
-	headerStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(accentColor).
-			MarginBottom(1)
-
-	metaStyle = lipgloss.NewStyle().
-			Foreground(subtleColor)
+	accentColor    lipgloss.TerminalColor
+	subtleColor    lipgloss.TerminalColor
+	senderColor    lipgloss.TerminalColor
+	dateColor      lipgloss.TerminalColor
+	textColor      lipgloss.TerminalColor
+	dimColor       lipgloss.TerminalColor
+	successColor   lipgloss.TerminalColor
+	errorColor     lipgloss.TerminalColor
+	titleTextColor lipgloss.TerminalColor
+	keyTextColor   lipgloss.TerminalColor
+	keyBgColor     lipgloss.TerminalColor
+	barTextColor   lipgloss.TerminalColor
+	barBgColor     lipgloss.TerminalColor
+
+	titleStyle   lipgloss.Style
+	statusStyle  lipgloss.Style
+	headerStyle  lipgloss.Style
+	metaStyle    lipgloss.Style
+	senderStyle  lipgloss.Style
+	dateStyle    lipgloss.Style
+	bodyStyle    lipgloss.Style
+	dividerStyle lipgloss.Style
+)
 
-	senderStyle = lipgloss.NewStyle().
-			Foreground(senderColor)
+// mailDateLayout is Mail.app's long en_US spelling of a date, which the
+// backends that get dates in other forms convert to.
+const mailDateLayout = "Monday, January 2, 2006 at 3:04:05 PM"
+
+// mailDateFormats are the ways a received date may be spelled: Mail.app's
+// "date as string" in the common English locales, with 12- and 24-hour
+// clocks, and the header formats the IMAP backend may pass through.
+var mailDateFormats = []string{
+	mailDateLayout,
+	"Monday, 2 January 2006 at 3:04:05 PM",
+	"January 2, 2006 at 3:04:05 PM",
+	"2 January 2006 at 3:04:05 PM",
+	"Monday, January 2, 2006 at 15:04:05",
+	"Monday, 2 January 2006 at 15:04:05",
+	"January 2, 2006 at 15:04:05",
+	"2 January 2006 at 15:04:05",
+	"1/2/06, 3:04 PM",
+	"2006-01-02 15:04:05",
+	"Mon Jan 2 15:04:05 2006",
+	time.RFC1123Z,
+	time.RFC1123,
+}
 
-	dateStyle = lipgloss.NewStyle().
-			Foreground(dateColor)
+// parseMailDate reads a received date in any of mailDateFormats. Extra
+// spaces, including the narrow no-break space newer macOS puts before AM
+// and PM, are tolerated.
+func parseMailDate(s string) (time.Time, bool) {
+	s = strings.Join(strings.Fields(strings.ReplaceAll(s, "\u202f", " ")), " ")
+	for _, f := range mailDateFormats {
+		if t, err := time.ParseInLocation(f, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
 
-	bodyStyle = lipgloss.NewStyle().
-			Foreground(textColor)
+// timeStyle is how relativeTime spells an age.
+type timeStyle int
 
-	dividerStyle = lipgloss.NewStyle().
-			Foreground(dimColor)
+const (
+	// timeCoarse gives the largest whole unit: "3w ago".
+	timeCoarse timeStyle = iota
+	// timePrecise adds the next unit down: "3w 2d ago".
+	timePrecise
 )
 
-func relativeTime(dateStr string) string {
-	formats := []string{
-		"Monday, January 2, 2006 at 3:04:05 PM",
-		"Monday, 2 January 2006 at 3:04:05 PM",
-		"January 2, 2006 at 3:04:05 PM",
-		"2 January 2006 at 3:04:05 PM",
-		"1/2/06, 3:04 PM",
-		"2006-01-02 15:04:05",
-		"Mon Jan 2 15:04:05 2006",
-	}
+// relativeStyle is the [dates] style of the loaded config.
+var relativeStyle = timeCoarse
+
+// timeUnits are the units of a relative time, largest first. A month is 30
+// days and a year 365.
+var timeUnits = []struct {
+	name string
+	size time.Duration
+}{
+	{"y", 365 * 24 * time.Hour},
+	{"mo", 30 * 24 * time.Hour},
+	{"w", 7 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+}
 
-	var t time.Time
-	var err error
-	for _, f := range formats {
-		t, err = time.Parse(f, dateStr)
-		if err == nil {
-			break
-		}
-	}
-	if err != nil {
+func relativeTime(dateStr string) string {
+	t, ok := parseMailDate(dateStr)
+	if !ok {
 		return dateStr
 	}
+	return formatAge(time.Since(t), relativeStyle)
+}
 
-	d := time.Since(t)
-	if d < 0 {
-		return "just now"
+// formatAge describes an age d, such as "5m ago" or "yesterday". A negative
+// age, for mail dated in the future, reads "in 5m"; under a minute either
+// way is "just now", which absorbs small clock differences.
+func formatAge(d time.Duration, style timeStyle) string {
+	future := d < 0
+	if future {
+		d = -d
 	}
-	switch {
-	case d < time.Minute:
+	if d < time.Minute {
 		return "just now"
-	case d < time.Hour:
-		m := int(d.Minutes())
-		if m == 1 {
-			return "1m ago"
-		}
-		return fmt.Sprintf("%dm ago", m)
-	case d < 24*time.Hour:
-		h := int(d.Hours())
-		if h == 1 {
-			return "1h ago"
-		}
-		return fmt.Sprintf("%dh ago", h)
-	case d < 48*time.Hour:
+	}
+	if !future && style == timeCoarse && d >= 24*time.Hour && d < 48*time.Hour {
 		return "yesterday"
-	default:
-		days := int(d.Hours() / 24)
-		return fmt.Sprintf("%dd ago", days)
 	}
+	var parts []string
+	for i, u := range timeUnits {
+		// Seconds only matter under a minute, which is "just now".
+		if d < u.size || u.size == time.Second {
+			continue
+		}
+		n := d / u.size
+		parts = append(parts, fmt.Sprintf("%d%s", n, u.name))
+		if style == timePrecise && i+1 < len(timeUnits)-1 {
+			next := timeUnits[i+1]
+			if rest := (d - n*u.size) / next.size; rest > 0 {
+				parts = append(parts, fmt.Sprintf("%d%s", rest, next.name))
+			}
+		}
+		break
+	}
+	age := strings.Join(parts, " ")
+	if future {
+		return "in " + age
+	}
+	return age + " ago"
 }
 
 type email struct {
-	sender  string
-	subject string
-	date    string
-	index   int
+	sender    string
+	subject   string
+	date      string
+	account   string
+	to        []string
+	cc        []string
+	priority  priority
+	messageID string
+	flag      flagColor
+	size      int    // in bytes, on backends that list it
+	mailbox   string // set when sweeping all mailboxes
+	id        string // the backend's stable id, used to address the message
+	// read marks a message opened this session, listed under the unread
+	// ones.
+	read bool
+	// automated marks mail a machine sent or a list delivered, going by its
+	// Auto-Submitted, Precedence or List-Id header.
+	automated bool
+	// autoReplied marks an auto-responder's reply, going by its
+	// Auto-Submitted, X-Autoreply or X-Autorespond header.
+	autoReplied bool
+	// threadRoot is the Message-ID of the message that started e's
+	// conversation, when its headers say.
+	threadRoot string
+
+	// threadSize is set on the row heading a conversation of several
+	// messages when threads are grouped; inThread marks the messages listed
+	// under an expanded one.
+	threadSize int
+	inThread   bool
 }
 
 func (e email) Title() string       { return e.subject }
 func (e email) Description() string { return fmt.Sprintf("%s • %s", e.sender, relativeTime(e.date)) }
-func (e email) FilterValue() string { return e.subject }
+func (e email) FilterValue() string {
+	fields := make([]string, numFilterFields)
+	fields[fieldSubject] = e.subject
+	fields[fieldSender] = e.sender
+	fields[fieldAccount] = e.account
+	fields[fieldDate] = e.date + " " + relativeTime(e.date)
+	fields[fieldPriority] = e.priority.String()
+	fields[fieldMailbox] = e.mailbox
+	fields[fieldTo] = strings.Join(slices.Concat(e.to, e.cc), ", ")
+	return strings.Join(fields, filterFieldSep)
+}
 
-type emailDelegate struct{}
+type emailDelegate struct {
+	me      addressSet
+	aliases addressSet
+	senders *senderHistory
+	// expanded is the model's set of open conversations, by thread key.
+	expanded map[string]bool
+	rules    rules
+	// accounts tags each row with its account, when [[accounts]] lists
+	// several.
+	accounts bool
+	// marks are the rows picked for a batch action.
+	marks *listMarks
+	// muted are the muted conversations, drawn dimmed.
+	muted *mutedThreads
+}
 
 func (d emailDelegate) Height() int                             { return 3 }
 func (d emailDelegate) Spacing() int                            { return 0 }
@@ -130,15 +229,67 @@ func (d emailDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 	}
 
 	isSelected := index == m.Index()
+	matches := m.MatchesForItem(index)
 
+	indent := ""
+	if e.inThread {
+		indent = "  "
+	}
 	subject := e.subject
-	maxSubjectLen := m.Width() - 16
+	maxSubjectLen := m.Width() - 16 - len(indent)
 	if maxSubjectLen < 10 {
 		maxSubjectLen = 10
 	}
 	if len(subject) > maxSubjectLen {
 		subject = subject[:maxSubjectLen-1] + "…"
 	}
+	addr := d.me.classify(e)
+	glyph := priorityStyle(e.priority).Render(e.priority.glyph())
+	subjectStyle := lipgloss.NewStyle().Foreground(textColor)
+	switch {
+	case d.marks != nil && d.marks.has(e, index, m.Index()):
+		glyph = lipgloss.NewStyle().Foreground(accentColor).Bold(true).Render("●")
+	case e.read:
+		glyph = lipgloss.NewStyle().Foreground(dimColor).Render("✓")
+		subjectStyle = lipgloss.NewStyle().Foreground(dimColor)
+	case d.muted.has(e):
+		glyph = lipgloss.NewStyle().Foreground(dimColor).Render("∅")
+		subjectStyle = lipgloss.NewStyle().Foreground(dimColor)
+	case d.rules.match(e) == rulePriority:
+		glyph = lipgloss.NewStyle().Foreground(accentColor).Bold(true).Render("★")
+	case d.rules.match(e) == ruleHighlight:
+		subjectStyle = lipgloss.NewStyle().Foreground(senderColor).Bold(true)
+	}
+	badge := indent + addressingStyle(addr).Render(addressingBadges[addr]) + glyph
+	threadText := ""
+	if e.threadSize > 1 {
+		marker := "▸"
+		if d.expanded[threadKey(e.subject)] {
+			marker = "▾"
+		}
+		threadText = lipgloss.NewStyle().Foreground(accentColor).Render(fmt.Sprintf(" %s %d", marker, e.threadSize))
+	}
+	if dot := e.flag.dot(); dot != "" {
+		threadText += " " + dot
+	}
+	locationText := ""
+	switch {
+	case e.mailbox != "":
+		locationText = lipgloss.NewStyle().Foreground(dimColor).Render(" • " + e.account + " › " + e.mailbox)
+	case d.accounts && e.account != "":
+		locationText = lipgloss.NewStyle().Foreground(dimColor).Render(" • " + e.account)
+	}
+	if via := d.me.via(e, d.aliases); via != "" {
+		locationText += lipgloss.NewStyle().Foreground(dimColor).Render(" • via " + via)
+	}
+	if d.senders.firstContact(e) {
+		locationText = lipgloss.NewStyle().Foreground(errorColor).Render(" • new sender") + locationText
+	}
+	if d.senders.vip(e) {
+		locationText = lipgloss.NewStyle().Foreground(accentColor).Render(" ★ VIP") + locationText
+	}
+	subjectMatches := fieldMatches(e, fieldSubject, matches)
+	senderMatches := fieldMatches(e, fieldSender, matches)
 
 	relTime := relativeTime(e.date)
 
@@ -146,7 +297,7 @@ func (d emailDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 	if isSelected {
 		borderChar = "│"
 		borderStyle := lipgloss.NewStyle().Foreground(accentColor).Bold(true)
-		titleText := lipgloss.NewStyle().Foreground(accentColor).Bold(true).Render("  " + subject)
+		titleText := " " + badge + " " + highlightMatches(subject, subjectMatches, lipgloss.NewStyle().Foreground(accentColor).Bold(true)) + threadText
 		timeText := lipgloss.NewStyle().Foreground(dateColor).Render(relTime)
 
 		gap := m.Width() - lipgloss.Width(titleText) - lipgloss.Width(timeText) - 4
@@ -155,11 +306,11 @@ func (d emailDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 		}
 		titleLine = borderStyle.Render(borderChar) + titleText + strings.Repeat(" ", gap) + timeText
 
-		senderText := lipgloss.NewStyle().Foreground(senderColor).Render("  " + e.sender)
-		descLine = borderStyle.Render(borderChar) + senderText
+		senderText := "  " + indent + highlightMatches(e.sender, senderMatches, lipgloss.NewStyle().Foreground(senderColor))
+		descLine = borderStyle.Render(borderChar) + senderText + locationText
 	} else {
 		borderChar = " "
-		titleText := lipgloss.NewStyle().Foreground(textColor).Render("  " + subject)
+		titleText := " " + badge + " " + highlightMatches(subject, subjectMatches, subjectStyle) + threadText
 		timeText := lipgloss.NewStyle().Foreground(dimColor).Render(relTime)
 
 		gap := m.Width() - lipgloss.Width(titleText) - lipgloss.Width(timeText) - 4
@@ -168,18 +319,61 @@ func (d emailDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 		}
 		titleLine = borderChar + titleText + strings.Repeat(" ", gap) + timeText
 
-		senderText := lipgloss.NewStyle().Foreground(subtleColor).Render("  " + e.sender)
-		descLine = borderChar + senderText
+		senderText := "  " + indent + highlightMatches(e.sender, senderMatches, lipgloss.NewStyle().Foreground(subtleColor))
+		descLine = borderChar + senderText + locationText
 	}
 
 	fmt.Fprintf(w, "%s\n%s\n", titleLine, descLine)
 }
 
+func addressingStyle(a addressing) lipgloss.Style {
+	switch a {
+	case addressedDirect:
+		return lipgloss.NewStyle().Foreground(senderColor).Bold(true)
+	case addressedCC:
+		return lipgloss.NewStyle().Foreground(subtleColor)
+	default:
+		return lipgloss.NewStyle().Foreground(dimColor)
+	}
+}
+
+func priorityStyle(p priority) lipgloss.Style {
+	switch p {
+	case priorityHigh:
+		return lipgloss.NewStyle().Foreground(errorColor).Bold(true)
+	default:
+		return lipgloss.NewStyle().Foreground(dimColor)
+	}
+}
+
+// highlightMatches renders s in base, underlining the runes at matched.
+func highlightMatches(s string, matched []int, base lipgloss.Style) string {
+	if len(matched) == 0 {
+		return base.Render(s)
+	}
+	return lipgloss.StyleRunes(s, matched, base.Underline(true).Bold(true), base)
+}
+
 type viewMode int
 
 const (
 	listView viewMode = iota
 	detailView
+	draftsView
+	composeView
+	mailboxView
+	searchView
+	settingsView
+	accountsView
+	linksView
+	snoozeView
+	checksView
+	logView
+	stagingView
+	senderView
+	watchView
+	flagView
+	labelView
 )
 
 type model struct {
@@ -189,140 +383,288 @@ type model struct {
 	emails       []email
 	err          error
 	lastPoll     time.Time
+	nextPoll     time.Time
+	interval     time.Duration
+	paused       bool
 	width        int
 	height       int
 	mode         viewMode
 	currentEmail *email
 	emailBody    string
-	loading      bool
+	// emailHTML is the open message's HTML part. It's shown rendered in
+	// place of the body, or as markup when rawHTML is set. Either is cut to
+	// maxBodyBytes (truncated) until L sets fullBody. emailSource is the raw
+	// message, shown instead while showSource is set; unreadable says the
+	// body looked like binary and a notice is shown in its place.
+	emailHTML   string
+	rawHTML     bool
+	fullBody    bool
+	truncated   bool
+	emailSource string
+	showSource  bool
+	// receiptTo is where the open message asks for a read receipt, until M
+	// sends one.
+	receiptTo string
+	// preformatted shows the open message's lines as they were sent, for
+	// patches and code: unwrapped, with tabs at tabStop, scrolling sideways.
+	preformatted bool
+	// links are the open message's links, numbered as its footnotes, for
+	// the link picker.
+	links      []string
+	linkCursor int
+	// rendering counts the detail renders started, so one that finishes
+	// after a later one started is dropped.
+	rendering int
+	// language is what the open message's body was detected as.
+	// translation is its body through [translate] command, shown beside
+	// the original while translated is set.
+	language    string
+	translation string
+	translated  bool
+	// speech is the open message being read aloud, if it is.
+	speech      *speaker
+	unreadable  bool
+	activity    activity
+	notice      string
+	noticeUntil time.Time
+	cfg         config
+	overlay     overlay
+	pendingOps  int
+	about       *diagnostics
+	big         bool
+	pending     *emailsMsg
+	filterMode  filterMode
+	sortMode    sortMode
+	focus       []filterTerm
+	showAll     bool
+	hidden      int
+	drafts      list.Model
+	composer    composer
+	composeFrom viewMode
+	offSchedule bool
+	network     netState
+	battery     batteryStatus
+	batteryDue  time.Time
+	provider    mailProvider
+	attachments []attachment
+	attachment  int
+	unfolded    map[int]bool
+	mailboxes   list.Model
+	scope       mailScope
+	senders     *senderHistory
+	// confirmPending is the action a second press confirms, and on what,
+	// armed by key number confirmAt of keypresses; only the very next key
+	// can confirm it.
+	confirmPending string
+	confirmAt      int
+	keypresses     int
+	// search is the search view.
+	search searchScreen
+	// detailFrom is the view the detail view returns to.
+	detailFrom viewMode
+	cache      *mailCache
+	// stale is set while the list is from the cache or from before a
+	// failed poll.
+	stale bool
+	// cached is set while the list is the cache's from the last run and the
+	// first poll is still on its way.
+	cached bool
+	// perf holds the --perf timings. It's a pointer so View, which can't
+	// change the model, can record how long rendering took.
+	perf *perfStats
+	// threads groups the list by conversation; expanded holds the open
+	// ones, by thread key.
+	threads  bool
+	expanded map[string]bool
+	// marks are the messages picked in the list for a batch action.
+	marks *listMarks
+	// pages is how many more batches of [poll] max L has loaded; total is
+	// the unread count the last poll reported, 0 when unknown.
+	pages    int
+	total    int
+	settings settingsForm
+	health   healthState
+	// whatsNew holds the release notes of the versions since the last one
+	// run.
+	whatsNew []releaseNote
+	// arrived is the unread set of the last poll of the default inbox, to
+	// tell which messages a poll brought in.
+	arrived arrivals
+	// readHere are the messages opening marked read this session, newest
+	// first, which stay listed as read; lastRead is what U marks unread
+	// again.
+	readHere []email
+	// snoozed are the snoozed messages, as of the last poll or change.
+	// snoozeWake is when the pending snoozeDueMsg, if any, is due.
+	snoozed    snoozes
+	snoozeWake time.Time
+	// snoozing is the snooze picker.
+	snoozing snoozePicker
+	lastRead []email
+	// checks are the latest results of the backend's setup checks.
+	checks checksMsg
+	// zero is the inbox-zero history, as of the last poll.
+	zero inboxHistory
+	// log is this session's actions and polls, for the log screen.
+	log *sessionLog
+	// staged are the deletes and archives waiting for [staging] delay.
+	staged staging
+	// stagingScreen is the staging screen.
+	stagingScreen stagingScreen
+	// senderMenu is the sender menu.
+	senderMenu senderMenu
+	// progress is how far the poll's header fetch has got, while the
+	// loading screen waits on it.
+	progress headerProgress
+	// fetches tracks each poll's progress.
+	fetches *progressTracker
+	// watch is the watch screen.
+	watch watchScreen
+	// muted are the muted conversations, shared with the list's delegate.
+	muted *mutedThreads
+	// flags is the flag picker.
+	flags flagPicker
+	// labels is the label picker.
+	labels labelPicker
+	// metered is headers-only mode. meteredLink is whether the connection
+	// was metered when last checked, at meteredDue minus the interval.
+	metered     bool
+	meteredLink bool
+	meteredDue  time.Time
 }
 
 type tickMsg time.Time
 type emailsMsg struct {
 	emails []email
 	err    error
+	// total counts the unread mail, if the backend knows, including what
+	// the poll left out.
+	total int
+	// network is the connectivity found after a failed poll.
+	network netState
+	timings pollTimings
+	// failed are the [[accounts]] that couldn't be read, when others could.
+	failed accountErrors
 }
 type emailContentMsg struct {
-	body string
-	err  error
+	content messageContent
+	err     error
+	// marked is set when opening the message marked it read.
+	marked bool
 }
 
 type markAllReadMsg struct {
 	err error
 }
 
-func fetchEmails() tea.Cmd {
+// fetchEmails polls p for up to limit unread messages. Backends that can't
+// page return as many as their own limit allows.
+func fetchEmails(p mailProvider, limit int) tea.Cmd {
 	return func() tea.Msg {
-		emails, err := getUnreadEmails()
-		return emailsMsg{emails: emails, err: err}
+		takeParseTime()
+		start := time.Now()
+		var emails []email
+		var total int
+		var err error
+		if pg, ok := p.(pager); ok {
+			emails, total, err = pg.unreadPage(limit)
+		} else {
+			emails, err = p.unread()
+		}
+		elapsed, parse := time.Since(start), takeParseTime()
+		failed, err := partialRead(err)
+		if err != nil {
+			// Tell a dropped connection apart from a real failure so it can
+			// be waited out quietly.
+			network := netOnline
+			if connectionLost(err) {
+				network = probeNetwork()
+			}
+			return emailsMsg{err: err, network: network}
+		}
+		return emailsMsg{emails: emails, total: total, failed: failed, timings: pollTimings{fetch: elapsed - parse, parse: parse}}
 	}
 }
 
-func fetchEmailContent(index int) tea.Cmd {
+// fetchEmailContent opens e. Unless mark is set it's left unread, if the
+// backend can open it without marking it.
+func fetchEmailContent(p mailProvider, e email, mark bool) tea.Cmd {
 	return func() tea.Msg {
-		body, err := getEmailContent(index)
-		return emailContentMsg{body: body, err: err}
+		if peeker, ok := p.(peeker); ok && !mark {
+			content, err := peeker.peek(e)
+			return emailContentMsg{content: content, err: err}
+		}
+		content, err := p.content(e)
+		return emailContentMsg{content: content, err: err, marked: err == nil}
 	}
 }
 
-func markAllAsRead() tea.Cmd {
+func markAllAsRead(p mailProvider, emails []email) tea.Cmd {
 	return func() tea.Msg {
-		err := setAllEmailsRead()
+		err := p.markRead(emails)
 		return markAllReadMsg{err: err}
 	}
 }
 
-func tickCmd() tea.Cmd {
-	return tea.Tick(10*time.Second, func(t time.Time) tea.Msg {
-		return tickMsg(t)
-	})
+// pollIntervals are the steps '+' and '-' move through.
+var pollIntervals = []time.Duration{
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
 }
 
-func getUnreadEmails() ([]email, error) {
-	script := `
-tell application "Mail"
-	set output to ""
-	set unreadMessages to (messages of inbox whose read status is false)
-	set msgCount to count of unreadMessages
-	if msgCount > 20 then set msgCount to 20
-	repeat with i from 1 to msgCount
-		set msg to item i of unreadMessages
-		set senderAddr to sender of msg
-		set subjectLine to subject of msg
-		set dateReceived to date received of msg
-		set output to output & (i as string) & "|||" & senderAddr & "|||" & subjectLine & "|||" & (dateReceived as string) & "
-"
-	end repeat
-	return output
-end tell
-`
-	cmd := exec.Command("osascript", "-e", script)
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
+const defaultPollInterval = 10 * time.Second
 
-	var emails []email
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		parts := strings.Split(line, "|||")
-		if len(parts) >= 4 {
-			idx := 0
-			fmt.Sscanf(parts[0], "%d", &idx)
-			emails = append(emails, email{
-				index:   idx,
-				sender:  strings.TrimSpace(parts[1]),
-				subject: strings.TrimSpace(parts[2]),
-				date:    strings.TrimSpace(parts[3]),
-			})
-		}
-	}
-	return emails, nil
-}
-
-func getEmailContent(index int) (string, error) {
-	script := fmt.Sprintf(`
-tell application "Mail"
-	set unreadMessages to (messages of inbox whose read status is false)
-	set msg to item %d of unreadMessages
-	set msgContent to content of msg
-	set read status of msg to true
-	return msgContent
-end tell
-`, index)
-	cmd := exec.Command("osascript", "-e", script)
-	out, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSpace(string(out)), nil
-}
+// defaultMaxUnread caps how many unread messages a poll returns unless the
+// config says otherwise.
+const defaultMaxUnread = 20
 
-func setAllEmailsRead() error {
-	script := `
-tell application "Mail"
-	set unreadMessages to (messages of inbox whose read status is false)
-	repeat with msg in unreadMessages
-		set read status of msg to true
-	end repeat
-end tell
-`
-	cmd := exec.Command("osascript", "-e", script)
-	return cmd.Run()
+// tickCmd fires once a second. Polling is driven from the tick so the
+// countdown stays live and interval changes take effect immediately.
+func tickCmd() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
 }
 
-func initialModel() model {
-	delegate := emailDelegate{}
+func initialModel(cfg config, provider mailProvider) model {
+	senders := loadSenderHistory(cfg.State.dir(), cfg.Senders.Trusted)
+	expanded := map[string]bool{}
+	marks := newListMarks()
+	muted, mutedErr := loadMutedThreads(cfg.State.dir())
+	delegate := emailDelegate{me: cfg.Identity.me(), aliases: newAddressSet(cfg.Identity.Aliases), senders: senders, expanded: expanded, rules: cfg.rules, accounts: len(cfg.Accounts) > 1, marks: marks, muted: muted}
 
 	l := list.New([]list.Item{}, delegate, 0, 0)
 	l.Title = "Unread Emails"
 	l.Styles.Title = titleStyle
 	l.SetShowStatusBar(true)
 	l.SetFilteringEnabled(true)
+	l.Filter = newFilterFunc(filterSubstring)
 	l.SetShowHelp(false)
+	l.DisableQuitKeybindings()
+
+	mailboxes := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	mailboxes.Title = "Mailboxes"
+	mailboxes.Styles.Title = titleStyle
+	mailboxes.SetShowHelp(false)
+	mailboxes.DisableQuitKeybindings()
+
+	results := list.New([]list.Item{}, delegate, 0, 0)
+	results.Title = "Search"
+	results.Styles.Title = titleStyle
+	results.SetShowHelp(false)
+	results.DisableQuitKeybindings()
+
+	drafts := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	drafts.Title = "Drafts"
+	drafts.Styles.Title = titleStyle
+	drafts.SetFilteringEnabled(false)
+	drafts.SetShowHelp(false)
+	drafts.DisableQuitKeybindings()
 
 	vp := viewport.New(0, 0)
 
@@ -330,129 +672,924 @@ func initialModel() model {
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(accentColor)
 
-	return model{
-		list:     l,
-		viewport: vp,
-		spinner:  s,
-		lastPoll: time.Now(),
-		mode:     listView,
-		loading:  true,
+	saved, savedErr := loadSavedSearches(cfg.State.dir())
+	snoozed, snoozeErr := loadSnoozes(cfg.State.dir())
+	zero, zeroErr := loadInboxHistory(cfg.State.dir())
+	m := model{
+		list:      l,
+		drafts:    drafts,
+		mailboxes: mailboxes,
+		search:    searchScreen{input: newSearchInput(), naming: newSearchNameInput(), saved: saved, results: results},
+		viewport:  vp,
+		spinner:   s,
+		lastPoll:  time.Now(),
+		nextPoll:  time.Now().Add(cfg.Poll.interval()),
+		interval:  cfg.Poll.interval(),
+		mode:      listView,
+		activity:  polling,
+		focus:     parseFilterQuery(cfg.Filter.Default),
+		cfg:       cfg,
+		provider:  provider,
+		senders:   senders,
+		cache:     loadMailCache(cfg.State.cacheDir()),
+		threads:   cfg.Poll.Threads,
+		expanded:  expanded,
+		marks:     marks,
+		muted:     muted,
+		whatsNew:  loadWhatsNew(cfg.State.dir()),
+		snoozed:   snoozed,
+		zero:      zero,
+		log:       newSessionLog(),
+		fetches:   &progressTracker{},
+	}
+	if err := errors.Join(mutedErr, savedErr, snoozeErr, zeroErr); err != nil {
+		m.log.add("%v", err)
+		m.setNotice(err.Error())
+	}
+	if len(m.whatsNew) > 0 {
+		m.overlay = whatsNewOverlay
+	}
+	m.health.track(provider)
+	m.metered = cfg.metered == meteredOn
+	// Show the last list straight away, rather than a loading screen while
+	// the first poll waits on the backend; the poll replaces it.
+	if len(m.cache.Emails) > 0 {
+		m.cached = true
+		m.applyEmails(emailsMsg{emails: m.cache.emails()})
+		m.activity = idle
+		m.stale = true
+		m.lastPoll = m.cache.Saved
+	}
+	return m
+}
+
+// reloadConfig re-reads the config file and applies it to the running UI.
+func (m *model) reloadConfig() error {
+	cfg, err := loadConfig(m.cfg.path)
+	if err != nil {
+		return err
+	}
+	cfg.dryRun = m.cfg.dryRun
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return err
+	}
+	if _, err := setupTheme(cfg.Theme); err != nil {
+		return err
+	}
+	m.provider = provider
+	m.health.track(provider)
+	m.list.Styles.Title = titleStyle
+	m.drafts.Styles.Title = titleStyle
+	m.mailboxes.Styles.Title = titleStyle
+	m.search.results.Styles.Title = titleStyle
+	m.spinner.Style = lipgloss.NewStyle().Foreground(accentColor)
+	m.senders.trusted = cfg.Senders.Trusted
+	delegate := emailDelegate{me: cfg.Identity.me(), aliases: newAddressSet(cfg.Identity.Aliases), senders: m.senders, expanded: m.expanded, rules: cfg.rules, accounts: len(cfg.Accounts) > 1, marks: m.marks, muted: m.muted}
+	m.list.SetDelegate(delegate)
+	m.search.results.SetDelegate(delegate)
+	m.focus = parseFilterQuery(cfg.Filter.Default)
+	if cfg.Poll.interval() != m.cfg.Poll.interval() {
+		m.interval = cfg.Poll.interval()
 	}
+	if cfg.Poll.Threads != m.cfg.Poll.Threads {
+		m.threads = cfg.Poll.Threads
+	}
+	if cfg.metered != m.cfg.metered {
+		m.metered, m.meteredLink = cfg.metered == meteredOn, false
+	}
+	m.cfg = cfg
+	m.list.SetSize(m.width, m.height-m.listChrome())
+	return nil
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(fetchEmails(), tickCmd(), m.spinner.Tick)
+	return tea.Batch(m.pollMail(), m.startupChecks(), tickCmd(), m.spinner.Tick, checkForUpdate(m.cfg.Update))
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Show what commands' goroutines have logged since.
+	m.log.refresh()
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c":
-			return m, tea.Quit
-		case "q":
-			if m.mode == detailView {
-				m.mode = listView
-				m.currentEmail = nil
-				m.emailBody = ""
-				return m, nil
-			}
-			return m, tea.Quit
-		case "esc":
-			if m.mode == detailView {
-				m.mode = listView
-				m.currentEmail = nil
-				m.emailBody = ""
-				return m, nil
-			}
-		case "r":
-			if m.mode == listView {
-				m.loading = true
-				return m, tea.Batch(fetchEmails(), m.spinner.Tick)
+		m.keypresses++
+		if m.overlay == quitOverlay {
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "esc":
+				m.overlay = noOverlay
 			}
-		case "a":
-			if m.mode == listView && len(m.emails) > 0 {
-				m.loading = true
-				return m, tea.Batch(markAllAsRead(), m.spinner.Tick)
+			return m, nil
+		}
+		if m.overlay == whatsNewOverlay && msg.String() == "enter" {
+			if err := markVersionSeen(m.cfg.State.dir()); err != nil {
+				m.setNotice(fmt.Sprintf("Couldn't save that the notes were read: %v", err))
 			}
-		case "enter":
-			if m.mode == listView && !m.loading {
-				if item, ok := m.list.SelectedItem().(email); ok {
-					m.currentEmail = &item
-					m.loading = true
-					return m, tea.Batch(fetchEmailContent(item.index), m.spinner.Tick)
-				}
+		}
+		if m.overlay != noOverlay && msg.String() != "ctrl+c" {
+			m.overlay = noOverlay
+			return m, nil
+		}
+		// The view behind the spinner isn't shown, so its keys are held
+		// until the wait is over or given up.
+		if m.busy() && msg.String() != "ctrl+c" {
+			switch {
+			case msg.String() == "esc":
+				m.activity = idle
+			case msg.String() == "enter" && m.activity == polling && len(m.progress.emails) > 0:
+				return m, m.usePartial()
 			}
+			return m, nil
+		}
+		if msg.String() == "ctrl+c" {
+			return m, m.quit()
+		}
+		key := m.cfg.keys.resolve(m.mode, msg)
+		// A rebound action's default key does nothing, rather than
+		// reaching the list or viewport's own binding for it.
+		if key == "" && !m.filtering() {
+			return m, nil
+		}
+		if cmd, ok := screens[m.mode].keys(&m, key); ok {
+			return m, cmd
 		}
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.list.SetSize(msg.Width, msg.Height-4)
+		m.list.SetSize(msg.Width, msg.Height-m.listChrome())
 		m.viewport.Width = msg.Width - 10
 		m.viewport.Height = msg.Height - 12
+		m.drafts.SetSize(msg.Width, msg.Height-4)
+		m.mailboxes.SetSize(msg.Width, msg.Height-4)
+		m.search.results.SetSize(msg.Width, msg.Height-5)
+		m.search.input.Width = msg.Width - 12
+		m.log.setSize(msg.Width, msg.Height)
+		// The composer only exists once it's been opened; showComposer
+		// sizes it then.
+		if m.mode == composeView {
+			m.composer.setSize(msg.Width, msg.Height)
+		}
+		// The body is wrapped to the viewport's width, so wrap it again.
+		if m.mode == detailView && m.currentEmail != nil && !m.preformatted {
+			return m, m.setDetailContent()
+		}
+
+	case signalMsg:
+		switch signalAction(msg) {
+		case signalRefresh:
+			if m.mode == listView && !m.filtering() {
+				m.lastPoll = time.Now()
+				m.nextPoll = m.lastPoll.Add(m.pollInterval())
+				return m, m.pollMail()
+			}
+			// Poll as soon as the user is back in the list.
+			m.nextPoll = time.Now()
+		case signalReload:
+			if err := m.reloadConfig(); err != nil {
+				m.setNotice(fmt.Sprintf("Config reload failed: %v", err))
+				return m, nil
+			}
+			m.setNotice("Config reloaded")
+			return m, m.applyEmails(emailsMsg{emails: m.emails, err: m.err, total: m.total})
+		case signalShutdown:
+			return m, m.quit()
+		}
+		return m, nil
 
 	case tickMsg:
-		if m.mode == listView {
-			m.lastPoll = time.Time(msg)
-			return m, tea.Batch(fetchEmails(), tickCmd())
+		now := time.Time(msg)
+		if m.notice != "" && now.After(m.noticeUntil) {
+			m.notice = ""
+		}
+		cmds := []tea.Cmd{tickCmd(), m.flushStaged(now, false)}
+		m.progress = headerProgress{}
+		if m.activity == polling {
+			m.progress = m.fetches.current()
+		}
+		if !now.Before(m.batteryDue) {
+			m.batteryDue = now.Add(batteryCheckInterval)
+			cmds = append(cmds, checkBattery())
+		}
+		if m.cfg.metered == meteredAuto && !now.Before(m.meteredDue) {
+			m.meteredDue = now.Add(meteredCheckInterval)
+			cmds = append(cmds, checkMetered())
 		}
-		return m, tickCmd()
+		if m.network != netOnline && !now.Before(m.nextPoll) {
+			m.nextPoll = now.Add(offlineProbeInterval)
+			cmds = append(cmds, checkNetwork())
+		} else if m.mode == listView && !m.paused && !m.filtering() && !m.cfg.batch.manual() && m.scheduled(now) && !now.Before(m.nextPoll) {
+			m.lastPoll = now
+			m.nextPoll = now.Add(jittered(m.pollInterval()))
+			cmds = append(cmds, m.pollMail())
+		}
+		return m, tea.Batch(cmds...)
+
+	case batteryMsg:
+		wasLow := m.cfg.Battery.low(m.battery)
+		m.battery = batteryStatus(msg)
+		if low := m.cfg.Battery.low(m.battery); low != wasLow {
+			if low {
+				m.setNotice(fmt.Sprintf("Battery at %d%%, polling every %s", m.battery.percent, formatInterval(m.pollInterval())))
+			}
+			m.nextPoll = m.lastPoll.Add(m.pollInterval())
+		}
+		return m, nil
+
+	case meteredMsg:
+		m.applyMetered(msg)
+		return m, nil
 
 	case spinner.TickMsg:
-		if m.loading {
+		if m.spinning() {
 			var cmd tea.Cmd
 			m.spinner, cmd = m.spinner.Update(msg)
 			return m, cmd
 		}
 
 	case emailsMsg:
-		m.loading = false
-		m.err = msg.err
-		m.emails = msg.emails
+		// What L pages in is older mail, not new arrivals.
+		more := !m.finish(polling) && m.finish(loadingMore)
+		if m.cached {
+			m.cached = false
+			m.list.Title = strings.TrimSuffix(m.list.Title, cachedTitle)
+		}
+		m.health.polled(time.Now(), msg, m.scope == (mailScope{}))
+		m.log.logPoll(msg)
+		if msg.network != netOnline {
+			// Keep showing the last good list and probe until the network
+			// is back.
+			m.network = msg.network
+			m.nextPoll = time.Now().Add(offlineProbeInterval)
+			return m, nil
+		}
+		m.network = netOnline
+		if m.perf != nil {
+			m.perf.poll = msg.timings
+		}
 		m.lastPoll = time.Now()
-
-		items := make([]list.Item, len(msg.emails))
-		for i, e := range msg.emails {
-			items[i] = e
+		m.nextPoll = m.lastPoll.Add(jittered(m.pollInterval()))
+		var alert tea.Cmd
+		if msg.err == nil {
+			m.senders.observe(msg.emails)
+			// A damaged file keeps what was read before; saving reports it.
+			if s, err := loadSnoozes(m.cfg.State.dir()); err == nil {
+				m.snoozed = s
+			}
+			m.muted.reload(m.cfg.State.dir())
+			if m.scope == (mailScope{}) {
+				m.cache.setEmails(msg.emails, msg.total)
+				alert = m.announce(msg.emails, more)
+				if zero, err := recordUnread(m.cfg.State.dir(), max(msg.total, len(msg.emails)), time.Now()); err == nil {
+					m.zero = zero
+				}
+			}
+			m.stale = false
+		} else if len(m.emails) > 0 {
+			// Keep showing the last list rather than replacing it with the
+			// error.
+			m.stale = true
+			m.setNotice(fmt.Sprintf("Poll failed: %v", msg.err))
+			return m, nil
 		}
-		m.list.SetItems(items)
-		if len(msg.emails) > 0 {
-			m.list.Title = fmt.Sprintf("Unread Emails (%d)", len(msg.emails))
-		} else {
-			m.list.Title = "Unread Emails"
+		// Replacing the items mid-keystroke would reset the filter input, so
+		// hold the result until the user is done typing.
+		if m.filtering() {
+			m.pending = &msg
+			return m, alert
 		}
+		return m, tea.Batch(m.applyEmails(msg), alert, m.wakeOnSnooze())
 
-	case emailContentMsg:
-		m.loading = false
+	case snoozeDueMsg:
+		return m, m.applySnoozeDue()
+
+	case alertDoneMsg:
 		if msg.err != nil {
-			m.emailBody = fmt.Sprintf("Error loading email: %v", msg.err)
+			m.setNotice(fmt.Sprintf("Couldn't play the new-mail sound: %v", msg.err))
+		}
+		return m, nil
+
+	case emailContentMsg:
+		done := m.opDone()
+		if !m.finish(openingMessage) || done != nil {
+			return m, done
+		}
+		m.attachments = nil
+		m.attachment = 0
+		m.unfolded = map[int]bool{}
+		m.emailHTML = ""
+		m.rawHTML = false
+		m.fullBody = false
+		m.emailSource = ""
+		m.showSource = false
+		m.receiptTo = ""
+		m.language, m.translation, m.translated = "", "", false
+		content, err := msg.content, msg.err
+		if err != nil && m.currentEmail != nil {
+			if cached, ok := m.cache.body(*m.currentEmail); ok {
+				m.setNotice(fmt.Sprintf("Showing a saved copy: %v", err))
+				content, err = cached, nil
+			}
+		}
+		if err != nil {
+			m.emailBody = fmt.Sprintf("Error loading email: %v", err)
 		} else {
-			m.emailBody = msg.body
+			m.emailBody = content.body
+			m.emailHTML = content.html
+			m.emailSource = content.source
+			m.receiptTo = receiptRequest(content.source)
+			m.language = detectLanguage(content.body)
+			m.attachments = content.attachments
+			if m.currentEmail != nil {
+				m.cache.setBody(*m.currentEmail, content)
+			}
+		}
+		var kept tea.Cmd
+		if msg.marked && m.currentEmail != nil {
+			kept = m.keepAsRead(*m.currentEmail)
 		}
 		m.mode = detailView
-		m.viewport.SetContent(m.emailBody)
+		m.links, m.truncated, m.unreadable = nil, false, false
+		m.setPreformatted(looksLikePatch(m.emailBody))
+		m.viewport.SetContent(metaStyle.Render("Rendering…"))
 		m.viewport.GotoTop()
+		cmds := []tea.Cmd{kept, m.setDetailContent()}
+		if saver, ok := m.provider.(attachmentSaver); ok {
+			for _, a := range m.attachments {
+				if a.inlineable() && a.text == "" {
+					cmds = append(cmds, loadInlineAttachments(saver, *m.currentEmail, m.attachments))
+					break
+				}
+			}
+		}
+		return m, tea.Batch(cmds...)
+
+	case detailRenderedMsg:
+		if msg.render != m.rendering {
+			return m, nil
+		}
+		m.viewport.SetContent(msg.content)
+		m.links, m.truncated, m.unreadable = msg.links, msg.truncated, msg.unreadable
+		return m, nil
+
+	case inlineAttachmentsMsg:
+		if m.currentEmail == nil || m.currentEmail.id != msg.id {
+			return m, nil
+		}
+		for i, text := range msg.texts {
+			m.attachments[i].text = text
+		}
+		return m, m.setDetailContent()
+
+	case searchResultsMsg:
+		if !m.finish(searching) {
+			return m, nil
+		}
+		return m, m.showResults(msg)
+
+	case accountsMsg:
+		m.applyAccounts(msg)
+		return m, nil
+
+	case accountCheckedMsg:
+		m.applyAccountCheck(msg)
+		return m, nil
+
+	case mailboxesMsg:
+		m.finish(listingMailboxes)
+		if msg.err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't list mailboxes: %v", msg.err))
+		}
+		items := []list.Item{mailboxItem{}}
+		selected := 0
+		for _, b := range msg.boxes {
+			item := mailboxItem{info: b}
+			if item.scope() == m.scope {
+				selected = len(items)
+			}
+			items = append(items, item)
+		}
+		cmd := m.mailboxes.SetItems(items)
+		m.mailboxes.Select(selected)
+		return m, cmd
+
+	case draftsMsg:
+		m.finish(loadingDrafts)
+		if msg.err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't load drafts: %v", msg.err))
+		}
+		items := make([]list.Item, len(msg.drafts))
+		for i, d := range msg.drafts {
+			items[i] = d
+		}
+		m.drafts.Title = fmt.Sprintf("Drafts (%d)", len(items))
+		return m, m.drafts.SetItems(items)
+
+	case draftContentMsg:
+		if !m.finish(openingDraft) {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't open draft: %v", msg.err))
+			return m, nil
+		}
+		m.openComposer(msg.draft, msg.body)
+		return m, textarea.Blink
+
+	case draftActionMsg:
+		m.finish(savingDraft)
+		if done := m.opDone(); done != nil {
+			return m, done
+		}
+		if msg.err != nil {
+			m.log.add("draft failed: %v", msg.err)
+			m.setNotice(fmt.Sprintf("Failed: %v", msg.err))
+			return m, nil
+		}
+		m.log.add("%s", strings.ToLower(msg.done))
+		m.setNotice(msg.done)
+		if m.mode == composeView {
+			m.mode = m.composeFrom
+		}
+		if m.mode == draftsView {
+			return m, fetchDrafts(m.draftStore())
+		}
+		return m, nil
+
+	case netStateMsg:
+		if netState(msg) != netOnline {
+			m.network = netState(msg)
+			return m, nil
+		}
+		if m.network != netOnline {
+			m.log.add("back online")
+		}
+		m.network = netOnline
+		m.setNotice("Back online")
+		m.nextPoll = time.Now()
+		return m, nil
+
+	case attachmentSavedMsg:
+		if msg.err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't save attachment: %v", msg.err))
+		} else {
+			m.setNotice("Saved to " + msg.path)
+		}
+		return m, m.opDone()
+
+	case senderBlockedMsg:
+		switch {
+		case msg.err != nil:
+			m.setNotice(fmt.Sprintf("Hid %s, but couldn't add a rule: %v", msg.addr, msg.err))
+		case msg.rule:
+			m.setNotice("Blocked " + msg.addr + " with a " + m.provider.name() + " rule")
+		default:
+			m.setNotice("Hid mail from " + msg.addr + "; " + m.provider.name() + " has no rules to block it for good")
+		}
+		return m, m.opDone()
+
+	case messageActionMsg:
+		m.log.logAction(msg)
+		if msg.err != nil {
+			// The list was updated optimistically; poll to put it right.
+			m.setNotice(fmt.Sprintf("Couldn't %s: %v", msg.verb, msg.err))
+			m.nextPoll = time.Now()
+		} else if m.cfg.dryRun {
+			m.setNotice(msg.done + " (dry run, nothing changed)")
+		} else {
+			m.setNotice(msg.done)
+		}
+		return m, m.opDone()
+
+	case awayMsg:
+		if a := m.composer.away; m.mode == composeView && a != nil && a.sender == msg.sender {
+			a.until = msg.until
+		}
+		return m, nil
+
+	case speechDoneMsg:
+		if msg.s != m.speech {
+			return m, nil
+		}
+		m.speech = nil
+		if msg.err != nil {
+			m.setNotice(fmt.Sprintf("Reading aloud failed: %v", msg.err))
+		}
+		return m, nil
+
+	case translatedMsg:
+		if m.currentEmail == nil || m.currentEmail.id != msg.id {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't translate: %v", msg.err))
+			return m, nil
+		}
+		m.translation, m.translated = msg.text, true
+		m.setNotice("")
+		return m, m.setDetailContent()
+
+	case checksMsg:
+		return m, m.applyChecks(msg)
+
+	case linkOpenedMsg:
+		if msg.err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't open %s: %v", msg.link, msg.err))
+		}
+		return m, nil
+
+	case handedOffMsg:
+		if msg.err != nil {
+			m.log.add("couldn't hand the message to your mail app: %v", msg.err)
+			m.setNotice(fmt.Sprintf("Couldn't open your mail app: %v", msg.err))
+			return m, nil
+		}
+		m.log.add("handed the message to your mail app")
+		if m.mode == composeView {
+			m.mode = m.composeFrom
+		}
+		m.setNotice("Opened in your mail app")
+		return m, nil
+
+	case openedInMailMsg:
+		if msg.err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't open in Mail.app: %v", msg.err))
+		}
+		return m, nil
+
+	case quickLookMsg:
+		if msg.err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't preview attachment: %v", msg.err))
+		}
+		return m, nil
+
+	case noteAppendedMsg:
+		if msg.err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't append to notes: %v", msg.err))
+		} else {
+			m.setNotice("Appended to " + msg.target)
+		}
+		return m, m.opDone()
+
+	case diagnosticsMsg:
+		d := diagnostics(msg)
+		m.about = &d
+		return m, nil
+
+	case updateAvailableMsg:
+		m.setNotice(fmt.Sprintf("mailnotify %s is available; run `mailnotify update`", msg.version))
+		return m, nil
+
+	case autoRepliedMsg:
+		m.applyAutoReplied(msg)
+		return m, nil
+
+	case watchedMsg:
+		return m, m.applyWatched(msg)
+
+	case bellMsg:
+		os.Stdout.WriteString("\a")
+		return m, nil
+
+	case labelsMsg:
+		m.applyLabels(msg)
+		return m, nil
+
+	case ticketCreatedMsg:
+		if msg.err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't create ticket: %v", msg.err))
+		} else {
+			m.setNotice("Created ticket " + msg.url)
+		}
+		return m, m.opDone()
 
 	case markAllReadMsg:
-		m.loading = false
+		m.finish(markingRead)
 		if msg.err != nil {
 			m.err = msg.err
 		}
-		return m, fetchEmails()
+		if done := m.opDone(); done != nil {
+			return m, done
+		}
+		return m, m.pollMail()
 	}
 
-	var cmd tea.Cmd
-	if m.mode == listView {
-		m.list, cmd = m.list.Update(msg)
-	} else {
-		m.viewport, cmd = m.viewport.Update(msg)
+	cmd := screens[m.mode].update(&m, msg)
+	// Hold a poll's result until the filter is applied or cancelled, not
+	// just until the next key.
+	if m.pending != nil && !m.filtering() {
+		pending := *m.pending
+		m.pending = nil
+		cmd = tea.Batch(cmd, m.applyEmails(pending))
 	}
 	return m, cmd
 }
 
+// detailRenderedMsg is the open message's viewport text, rendered by
+// setDetailContent.
+type detailRenderedMsg struct {
+	render     int
+	content    string
+	links      []string
+	truncated  bool
+	unreadable bool
+}
+
+// setDetailContent renders the viewport text for the open message off the
+// UI goroutine: converting a big newsletter's HTML and numbering its links
+// takes long enough to make keys lag. The viewport keeps what it shows until
+// the render arrives.
+func (m *model) setDetailContent() tea.Cmd {
+	m.rendering++
+	r := *m
+	r.unfolded = maps.Clone(m.unfolded)
+	r.attachments = slices.Clone(m.attachments)
+	return func() tea.Msg {
+		content, links, truncated, unreadable := r.detailContent()
+		return detailRenderedMsg{render: r.rendering, content: content, links: links, truncated: truncated, unreadable: unreadable}
+	}
+}
+
+// detailContent is the viewport text for the open message: the body, then
+// a foldable section for each text attachment shown inline. The body's
+// links are listed as footnotes and returned. It reports whether the body
+// was cut to maxBodyBytes, and whether it was replaced by a notice for
+// looking like binary.
+func (m model) detailContent() (content string, links []string, truncated, unreadable bool) {
+	var b strings.Builder
+	text, size, truncated := m.bodyText()
+	switch {
+	case !m.showSource && looksBinary(text):
+		text, truncated, unreadable = m.unreadableNotice(), false, true
+	case !m.showSource && !m.rawHTML:
+		var account string
+		if m.currentEmail != nil {
+			account = m.currentEmail.account
+		}
+		var stripped bool
+		if text, stripped = m.cfg.strip.apply(text, account); stripped {
+			text = metaStyle.Render("▸ Boilerplate cut by [[strip]] • "+m.cfg.keys.label(detailView, "S")+" shows the source") + "\n\n" + text
+		}
+		text, links = footnoteLinks(text)
+		if m.translated {
+			text = sideBySide(text, m.translation, m.viewport.Width)
+		}
+	}
+	if m.preformatted {
+		text = expandTabs(text)
+	}
+	b.WriteString(breakLongLines(text, maxLineBytes))
+	if truncated {
+		b.WriteString("\n\n" + metaStyle.Render(fmt.Sprintf("▸ Message truncated at %s of %s • L to load all", formatBytes(maxBodyBytes), formatBytes(int64(size)))))
+	}
+	for i, a := range m.attachments {
+		if a.text == "" {
+			continue
+		}
+		b.WriteString("\n\n")
+		if !m.unfolded[i] {
+			b.WriteString(metaStyle.Render("▸ " + a.String() + " • tab to select, z to expand"))
+			continue
+		}
+		b.WriteString(metaStyle.Render("▾ "+a.String()) + "\n")
+		b.WriteString(dividerStyle.Render(strings.Repeat("─", max(m.viewport.Width-2, 10))) + "\n")
+		if m.preformatted {
+			b.WriteString(expandTabs(renderInline(a)))
+		} else {
+			b.WriteString(renderInline(a))
+		}
+	}
+	if m.preformatted {
+		return b.String(), links, truncated, unreadable
+	}
+	// Wrap long lines, rather than leave the viewport to cut them off.
+	return lipgloss.NewStyle().Width(m.viewport.Width).Render(b.String()), links, truncated, unreadable
+}
+
+// fetchLimit is how many messages a poll asks for: [poll] max, or enough
+// for that many conversations, for each page loaded.
+func (m model) fetchLimit() int {
+	return m.cfg.Poll.fetchLimit() * (m.pages + 1)
+}
+
+// hasMore reports whether the last poll left unread mail out of the list.
+func (m model) hasMore() bool {
+	return m.total > len(m.emails)
+}
+
+// mail is the provider for the current view: the backend narrowed to the
+// picked mailbox, if one was picked.
+func (m model) mail() mailProvider {
+	if b, ok := m.provider.(mailboxBrowser); ok && m.scope != (mailScope{}) {
+		return b.scoped(m.scope)
+	}
+	return m.provider
+}
+
+// draftStore returns the backend's drafts support, or nil without it.
+func (m model) draftStore() draftStore {
+	s, _ := m.provider.(draftStore)
+	return s
+}
+
+// openComposer switches to the composer, editing d with body.
+func (m *model) openComposer(d draft, body string) {
+	m.showComposer(newComposer(d, body))
+}
+
+// showComposer switches to c. Leaving it returns to the current view.
+func (m *model) showComposer(c composer) {
+	m.composer = c
+	m.composer.setSize(m.width, m.height)
+	m.composeFrom = m.mode
+	m.mode = composeView
+}
+
+// track counts cmd as an in-flight mutating operation. Its result handler
+// must call opDone.
+func (m *model) track(cmd tea.Cmd) tea.Cmd {
+	m.pendingOps++
+	return cmd
+}
+
+// opDone marks a tracked operation finished. It returns tea.Quit once the
+// last one finishes while a quit is waiting on them.
+func (m *model) opDone() tea.Cmd {
+	if m.pendingOps > 0 {
+		m.pendingOps--
+	}
+	if m.overlay == quitOverlay && m.pendingOps == 0 {
+		return tea.Quit
+	}
+	return nil
+}
+
+// quit exits immediately when nothing is in flight. Otherwise it waits for
+// pending operations so they aren't dropped, showing a shutdown screen.
+func (m *model) quit() tea.Cmd {
+	m.stopSpeech()
+	// Staged moves are made before leaving rather than dropped.
+	flush := m.flushStaged(time.Now(), true)
+	if m.pendingOps == 0 {
+		return tea.Quit
+	}
+	m.overlay = quitOverlay
+	return tea.Batch(flush, m.spinner.Tick)
+}
+
+// noticeDuration is how long a notice stays in the status line.
+const noticeDuration = 5 * time.Second
+
+// setNotice shows a transient message in the status line.
+func (m *model) setNotice(s string) {
+	m.notice = s
+	m.noticeUntil = time.Now().Add(noticeDuration)
+}
+
+// applyEmails replaces the list contents with a poll result. Messages
+// excluded by the focus query or a hide rule are left out unless showAll is
+// set, and those a priority rule matches go first.
+func (m *model) applyEmails(msg emailsMsg) tea.Cmd {
+	// What a confirmation would act on may have changed.
+	m.confirmPending = ""
+	m.err = msg.err
+	m.emails = msg.emails
+	m.total = msg.total
+	m.hidden = 0
+
+	sorted := append([]email(nil), msg.emails...)
+	sortEmails(sorted, m.sortMode)
+	m.cfg.rules.prioritize(sorted)
+	m.senders.prioritize(sorted)
+
+	now := time.Now()
+	var visible []email
+	for _, e := range sorted {
+		if m.senders.blocked(e) || m.staged.has(e) {
+			continue
+		}
+		if !m.showAll && (len(m.focus) > 0 && !matchesQuery(m.focus, e) || m.cfg.rules.match(e) == ruleHide || m.snoozed.hides(e, now)) {
+			m.hidden++
+			continue
+		}
+		visible = append(visible, e)
+	}
+	shown := visible
+	if m.threads {
+		shown = groupThreads(visible, m.expanded, m.cfg.Poll.max()*(m.pages+1))
+	}
+	shown = slices.Concat(shown, m.listedRead(msg.emails))
+	items := make([]list.Item, len(shown))
+	for i, e := range shown {
+		items[i] = e
+	}
+	cmd := m.list.SetItems(items)
+	count := fmt.Sprintf("%d", len(visible))
+	if m.threads {
+		threads, messages := 0, 0
+		for _, e := range shown {
+			if !e.inThread {
+				threads++
+				messages += max(e.threadSize, 1)
+			}
+		}
+		count = fmt.Sprintf("%d in %d threads", messages, threads)
+	}
+	if m.hasMore() {
+		count = fmt.Sprintf("showing %s of %d", count, m.total)
+	}
+	switch {
+	case m.cfg.List.Summary:
+		m.list.Title = m.summaryTitle(visible, count, now)
+	case len(items) > 0 && m.hidden > 0:
+		m.list.Title = fmt.Sprintf("Unread Emails (%s, %d hidden)", count, m.hidden)
+	case len(items) > 0:
+		m.list.Title = fmt.Sprintf("Unread Emails (%s)", count)
+	default:
+		m.list.Title = "Unread Emails"
+	}
+	if label := scopeLabel(m.scope); label != "" {
+		m.list.Title += " • " + label
+	}
+	if m.cfg.dryRun {
+		m.list.Title += " • dry run"
+	}
+	if m.cached {
+		m.list.Title += cachedTitle
+	}
+	return cmd
+}
+
+// cachedTitle marks the list title while it's the last run's list.
+const cachedTitle = " • cached, refreshing…"
+
+// greeting is the summary title's salutation for the time of day.
+func greeting(now time.Time) string {
+	switch h := now.Hour(); {
+	case h >= 5 && h < 12:
+		return "Good morning"
+	case h >= 12 && h < 18:
+		return "Good afternoon"
+	default:
+		return "Good evening"
+	}
+}
+
+// summaryTitle is the [list] summary title for the visible messages. count
+// is the unread count as the plain title spells it, with any threads and
+// the total. VIPs are the messages a priority rule matches.
+func (m model) summaryTitle(visible []email, count string, now time.Time) string {
+	if len(visible) == 0 {
+		return greeting(now) + " — nothing unread"
+	}
+	parts := []string{count + " unread"}
+	vip := 0
+	var oldest time.Time
+	for _, e := range visible {
+		if m.cfg.rules.match(e) == rulePriority {
+			vip++
+		}
+		if t, ok := parseMailDate(e.date); ok && (oldest.IsZero() || t.Before(oldest)) {
+			oldest = t
+		}
+	}
+	if vip > 0 {
+		parts = append(parts, fmt.Sprintf("%d VIP", vip))
+	}
+	if age := now.Sub(oldest); !oldest.IsZero() && age >= time.Minute {
+		parts = append(parts, "oldest "+strings.TrimSuffix(formatAge(age, timeCoarse), " ago"))
+	}
+	if m.hidden > 0 {
+		parts = append(parts, fmt.Sprintf("%d hidden", m.hidden))
+	}
+	return greeting(now) + " — " + strings.Join(parts, ", ")
+}
+
 func (m model) View() string {
-	if m.err != nil {
+	if m.overlay == quitOverlay {
+		ops := "operation"
+		if m.pendingOps != 1 {
+			ops = "operations"
+		}
+		text := fmt.Sprintf("%s Finishing %d %s…", m.spinner.View(), m.pendingOps, ops) + "\n\n" +
+			statusStyle.Render("esc cancel • ctrl+c quit now")
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, text)
+	}
+
+	if m.overlay == aboutOverlay {
+		return m.aboutView()
+	}
+
+	if m.overlay == helpOverlay {
+		return m.helpView()
+	}
+
+	if m.overlay == whatsNewOverlay {
+		return m.whatsNewView()
+	}
+
+	if m.err != nil && m.mode != checksView {
 		errBox := lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
 			BorderForeground(errorColor).
@@ -468,112 +1605,184 @@ func (m model) View() string {
 			Foreground(textColor).
 			Render(fmt.Sprintf("%v", m.err))
 
+		hint := "Make sure Mail.app is running and permissions are granted."
+		switch backendOf(m.provider).(type) {
+		case imapProvider:
+			hint = "Check the server, port and credentials in the [backend] config."
+		case maildirProvider:
+			hint = "Check the path in the [backend] config."
+		}
 		errHint := lipgloss.NewStyle().
 			Foreground(subtleColor).
 			Italic(true).
-			Render("Make sure Mail.app is running and permissions are granted.\n\n'r' retry • 'q' quit")
+			Render(hint + "\n\n'r' retry • '!' check setup • 'q' quit")
 
 		box := errBox.Render(fmt.Sprintf("%s\n\n%s\n\n%s", errTitle, errMsg, errHint))
 
 		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
 	}
 
-	if m.loading {
-		loadingText := fmt.Sprintf("%s Loading...", m.spinner.View())
+	if m.busy() && m.activity == polling && m.progress.total > 0 {
+		loadingText := fmt.Sprintf("%s Fetching message headers…", m.spinner.View()) + "\n\n" + m.progress.view() + "\n\n"
+		if len(m.progress.emails) > 0 {
+			loadingText += statusStyle.Render("enter show what's loaded • esc cancel")
+		} else {
+			loadingText += statusStyle.Render("esc cancel")
+		}
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, loadingText)
+	}
+	if m.busy() {
+		loadingText := fmt.Sprintf("%s %s", m.spinner.View(), m.activity) + "\n\n" +
+			statusStyle.Render("esc cancel")
 		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, loadingText)
 	}
 
-	if m.mode == listView && len(m.emails) == 0 {
-		emptyStyle := lipgloss.NewStyle().
-			Foreground(successColor).
-			Bold(true).
-			Align(lipgloss.Center).
-			Width(m.width)
+	status := ""
+	if m.notice != "" {
+		status = statusStyle.Render(" "+m.notice) + "\n"
+	}
 
-		subtitleStyle := lipgloss.NewStyle().
-			Foreground(subtleColor).
-			Italic(true).
-			Align(lipgloss.Center).
-			Width(m.width)
+	return screens[m.mode].view(m, status)
+}
 
-		timeInfo := lipgloss.NewStyle().
-			Foreground(dimColor).
-			Italic(true).
-			Align(lipgloss.Center).
-			Width(m.width).
-			Render(fmt.Sprintf("Last checked: %s • Auto-refresh: 10s", m.lastPoll.Format("15:04:05")))
+// filtering reports whether the user is typing into the list filter.
+// Background refreshes hold off while it's true.
+func (m model) filtering() bool {
+	return m.list.FilterState() == list.Filtering
+}
 
-		centerContent := emptyStyle.Render("All caught up!") + "\n\n" +
-			subtitleStyle.Render("No unread emails in your inbox.") + "\n\n" +
-			timeInfo
+// updatedStatus says how fresh the list is, for the status line.
+func (m model) updatedStatus() string {
+	if m.cached {
+		return "Cached from " + m.lastPoll.Format("Jan 2 15:04") + ", refreshing…"
+	}
+	if m.stale {
+		return "Stale, last updated " + m.lastPoll.Format("Jan 2 15:04")
+	}
+	return "Updated " + m.lastPoll.Format("15:04:05")
+}
 
-		helpBar := renderHelpBar(m.width, [][]string{
-			{"r", "refresh"},
-			{"q", "quit"},
-		})
+// refreshStatus describes the auto-refresh state for the status line.
+func (m model) refreshStatus() string {
+	if m.paused {
+		return fmt.Sprintf("Auto-refresh paused (%s)", formatInterval(m.interval))
+	}
+	if m.filtering() {
+		return "Auto-refresh on hold while filtering"
+	}
+	if m.network != netOnline {
+		return fmt.Sprintf("%s, showing cached mail until it reconnects", m.network)
+	}
+	if !m.scheduled(time.Now()) {
+		status := "Outside polling hours"
+		if next := m.cfg.schedule.nextOpen(time.Now()); !next.IsZero() {
+			status += ", resumes " + next.Format("Mon 15:04")
+		}
+		return status + " (o to poll anyway)"
+	}
+	if m.cfg.batch.manual() {
+		return "Batch mode, checking only when you press r"
+	}
+	if m.cfg.batch.on {
+		return fmt.Sprintf("Batch mode, next check at %s (r to check now)", m.nextPoll.Format("15:04"))
+	}
+	remaining := time.Until(m.nextPoll).Round(time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+	every := formatInterval(m.pollInterval())
+	if m.cfg.Battery.low(m.battery) {
+		every += fmt.Sprintf(" on battery, %d%%", m.battery.percent)
+	}
+	if m.metered {
+		every += ", headers only"
+	}
+	return fmt.Sprintf("Next refresh in %s (every %s)", formatInterval(remaining), every)
+}
 
-		body := lipgloss.Place(m.width, m.height-1, lipgloss.Center, lipgloss.Center, centerContent)
-		return body + "\n" + helpBar
+// pollInterval is the interval in effect: the batch interval in batch mode,
+// stretched while the battery is low.
+func (m model) pollInterval() time.Duration {
+	if m.cfg.batch.every > 0 {
+		return m.cfg.Battery.pollInterval(m.cfg.batch.every, m.battery)
 	}
+	return m.cfg.Battery.pollInterval(m.interval, m.battery)
+}
+
+// scheduled reports whether automatic polling is allowed at t.
+func (m model) scheduled(t time.Time) bool {
+	return m.offSchedule || m.cfg.schedule.allows(t)
+}
 
-	if m.mode == detailView && m.currentEmail != nil {
-		boxWidth := m.width - 4
-		if boxWidth < 20 {
-			boxWidth = 20
+// stepInterval returns the next longer or shorter entry in pollIntervals.
+func stepInterval(cur time.Duration, longer bool) time.Duration {
+	for i, d := range pollIntervals {
+		if longer && d > cur {
+			return d
+		}
+		if !longer && d >= cur {
+			if i == 0 {
+				return pollIntervals[0]
+			}
+			return pollIntervals[i-1]
 		}
+	}
+	return pollIntervals[len(pollIntervals)-1]
+}
 
-		header := headerStyle.Render(m.currentEmail.subject)
-		meta := metaStyle.Render("From: ") + senderStyle.Render(m.currentEmail.sender) + "\n" +
-			metaStyle.Render("Date: ") + dateStyle.Render(m.currentEmail.date)
-		innerDivider := dividerStyle.Render(strings.Repeat("─", boxWidth-4))
+func formatInterval(d time.Duration) string {
+	if d >= time.Minute && d%time.Minute == 0 {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	if d >= time.Minute {
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%ds", int(d.Seconds()))
+}
 
-		content := fmt.Sprintf("%s\n%s\n%s\n\n%s",
-			header,
-			meta,
-			innerDivider,
-			m.viewport.View(),
-		)
+// bigView renders the glanceable kiosk screen: the unread count and the
+// latest subject in block letters, sized for a small always-on pane.
+func (m model) bigView() string {
+	countColor := accentColor
+	if len(m.emails) == 0 {
+		countColor = successColor
+	}
+	count := lipgloss.NewStyle().
+		Foreground(countColor).
+		Bold(true).
+		Render(renderBigText(fmt.Sprintf("%d", len(m.emails))))
 
-		detailBox := lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(accentColor).
-			Padding(1, 2).
-			Width(boxWidth)
-
-		helpBar := renderHelpBar(m.width, [][]string{
-			{"↑/↓", "scroll"},
-			{"q", "back"},
-			{"esc", "back to list"},
-		})
-		return "\n" + lipgloss.NewStyle().PaddingLeft(2).Render(detailBox.Render(content)) + "\n" + helpBar
-	}
-
-	helpBar := renderHelpBar(m.width, [][]string{
-		{"enter", "read"},
-		{"r", "refresh"},
-		{"a", "mark all read"},
-		{"/", "filter"},
-		{"q", "quit"},
-	})
+	label := "unread"
+	if len(m.emails) == 0 {
+		label = "all caught up"
+	}
+	content := count + "\n\n" + statusStyle.Render(label)
 
-	timeInfo := lipgloss.NewStyle().
-		Foreground(dimColor).
-		Italic(true).
-		Render(fmt.Sprintf(" Updated %s • Auto-refresh: 10s", m.lastPoll.Format("15:04:05")))
+	if len(m.emails) > 0 {
+		latest := m.emails[0]
+		subject := fitBigText(latest.subject, m.width-2)
+		if subject != "" {
+			content += "\n\n" + lipgloss.NewStyle().Foreground(textColor).Render(renderBigText(subject))
+		} else {
+			content += "\n\n" + bodyStyle.Render(latest.subject)
+		}
+		content += "\n\n" + senderStyle.Render(latest.sender) + metaStyle.Render(" • "+relativeTime(latest.date))
+	}
 
-	return m.list.View() + "\n" + timeInfo + "\n" + helpBar
+	content = lipgloss.NewStyle().Align(lipgloss.Center).Render(content)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
 }
 
 func renderHelpBar(width int, bindings [][]string) string {
 	keyStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("#FFFFFF")).
-		Background(lipgloss.Color("#3F3F46")).
+		Foreground(keyTextColor).
+		Background(keyBgColor).
 		Padding(0, 1)
 
 	descStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#A1A1AA")).
-		Background(lipgloss.Color("#27272A")).
+		Foreground(barTextColor).
+		Background(barBgColor).
 		Padding(0, 1)
 
 	var parts []string
@@ -582,7 +1791,7 @@ func renderHelpBar(width int, bindings [][]string) string {
 	}
 
 	bar := lipgloss.NewStyle().
-		Background(lipgloss.Color("#27272A")).
+		Background(barBgColor).
 		Width(width).
 		Render(strings.Join(parts, " "))
 
@@ -590,8 +1799,166 @@ func renderHelpBar(width int, bindings [][]string) string {
 }
 
 func main() {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	configPath := flag.String("config", defaultConfigPath(), "path to the config file")
+	big := flag.Bool("big", false, "start in the glanceable big-count view")
+	perf := flag.Bool("perf", false, "show how long polling, parsing and rendering take")
+	daemon := flag.Bool("daemon", false, "run headless, notifying of new mail")
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	verbose := flag.Bool("verbose", false, "with -version, print environment diagnostics")
+	dryRun := flag.Bool("dry-run", false, "log what would change the mailbox, such as marking read or archiving, instead of doing it")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *showVersion {
+		if !*verbose {
+			fmt.Println("mailnotify", versionString())
+			return
+		}
+		cfg, err := loadConfig(*configPath)
+		exitOnError(err)
+		fmt.Print(collectDiagnostics(cfg))
+		return
+	}
+
+	switch flag.Arg(0) {
+	case "":
+	case "install-agent":
+		exitOnError(installAgent(*configPath))
+		return
+	case "uninstall-agent":
+		exitOnError(uninstallAgent())
+		return
+	case "update":
+		cfg, err := loadConfig(*configPath)
+		exitOnError(err)
+		exitOnError(runUpdate(cfg.Update))
+		return
+	case "open":
+		exitOnError(openTUI(*configPath))
+		return
+	case "export", "import":
+		cfg, err := loadConfig(*configPath)
+		exitOnError(err)
+		if flag.Arg(0) == "export" {
+			exitOnError(runExport(cfg, flag.Arg(1)))
+		} else {
+			exitOnError(runImport(cfg, flag.Arg(1)))
+		}
+		return
+	case "list", "act", "url":
+		cfg, err := loadConfig(*configPath)
+		exitOnError(err)
+		cfg.dryRun = *dryRun
+		switch flag.Arg(0) {
+		case "list":
+			exitOnError(runList(cfg, flag.Args()[1:]))
+		case "act":
+			exitOnError(runAct(cfg, flag.Args()[1:]))
+		default:
+			exitOnError(runURL(cfg, flag.Args()[1:]))
+		}
+		return
+	case "send":
+		cfg, err := loadConfig(*configPath)
+		exitOnError(err)
+		cfg.dryRun = *dryRun
+		exitOnError(runSend(cfg, flag.Args()[1:]))
+		return
+	case "status":
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(statusConfig{}.exitError())
+		}
+		os.Exit(runStatus(cfg, flag.Args()[1:]))
+	case "check":
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		os.Exit(runCheck(cfg, flag.Args()[1:]))
+	case "install-service":
+		cfg, err := loadConfig(*configPath)
+		exitOnError(err)
+		exitOnError(installService(cfg))
+		return
+	case "uninstall-service":
+		exitOnError(uninstallService())
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	exitOnError(err)
+	cfg.dryRun = *dryRun
+
+	if *daemon {
+		exitOnError(runDaemon(cfg))
+		return
+	}
+
+	warnings, err := setupTheme(cfg.Theme)
+	exitOnError(err)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: theme: %s\n", w)
+	}
+
+	provider, err := newProvider(cfg)
+	exitOnError(err)
+
+	m := initialModel(cfg, provider)
+	// The log would draw over the screen; the session log shows it.
+	slog.SetDefault(m.log.logger)
+	m.big = *big
+	if *perf {
+		m.perf = &perfStats{}
+	}
+	if len(warnings) > 0 {
+		m.setNotice(fmt.Sprintf("%d theme contrast warning(s), see stderr", len(warnings)))
+	}
+
+	// Signals are routed through Update so SIGTERM takes the same graceful
+	// path as 'q'.
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithoutSignalHandler())
+	go func() {
+		for action := range watchSignals() {
+			p.Send(signalMsg(action))
+		}
+	}()
+	_, err = p.Run()
+	exitOnError(err)
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: mailnotify [flags] [command]
+
+Commands:
+  install-agent     run the daemon at login via a launchd agent
+  uninstall-agent   remove the launchd agent
+  update            install the latest release
+  open              open the TUI in a new Terminal window (macOS)
+  export [file]     archive the config and local state for another machine
+  import <file>     merge an archive written by export
+  list              print the unread list (-format text, json or alfred-json)
+  act <id> <action> mark a message read, archive it or open it in Mail.app
+  url <url>         run a mailnotify://x-callback-url/ action, for Shortcuts
+  send              send a template to every row of a CSV file (-template, -csv)
+  status            print an unread summary for a status bar and exit
+  check             exit 0 if there is unread mail and 1 if not, for scripts
+  install-service   run the daemon via a systemd user service (Linux)
+  uninstall-service remove the systemd user service
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+func exitOnError(err error) {
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
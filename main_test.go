@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestModel builds a model over a Maildir with one unread message per
+// subject, sized and with the first poll applied.
+func newTestModel(t *testing.T, subjects ...string) (model, mailProvider) {
+	t.Helper()
+	return newTestModelWith(t, "", subjects...)
+}
+
+// newTestModelWith is newTestModel with extra config appended.
+func newTestModelWith(t *testing.T, extra string, subjects ...string) (model, mailProvider) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, d := range []string{"cur", "new", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, "INBOX", d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, s := range subjects {
+		msg := fmt.Sprintf("From: sender%d@example.com\r\nSubject: %s\r\nMessage-ID: <%d@example.com>\r\nDate: Tue, 13 Oct 2026 08:0%d:00 +0000\r\n\r\nbody\r\n", i, s, i, i)
+		if err := os.WriteFile(filepath.Join(dir, "INBOX", "new", fmt.Sprint(i+1)), []byte(msg), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	toml := fmt.Sprintf("[backend]\ntype = \"maildir\"\npath = %q\n[state]\ndir = %q\n", dir, t.TempDir()) + extra
+	cfg, err := parseConfig("test.toml", []byte(toml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := initialModel(cfg, p)
+	m = update(m, tea.WindowSizeMsg{Width: 100, Height: 30})
+	m = update(m, fetchEmails(p, 20)())
+	return m, p
+}
+
+func update(m model, msg tea.Msg) model {
+	next, _ := m.Update(msg)
+	return next.(model)
+}
+
+func keys(m model, typed string) model {
+	for _, r := range typed {
+		m = update(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return m
+}
+
+func listSubjects(m model) string {
+	var subjects []string
+	for _, item := range m.list.Items() {
+		subjects = append(subjects, item.(email).subject)
+	}
+	return strings.Join(subjects, ", ")
+}
+
+func TestPollHeldWhileFiltering(t *testing.T) {
+	m, _ := newTestModel(t, "Alpha", "Beta")
+	before := listSubjects(m)
+	m = keys(m, "/a")
+	if !m.filtering() {
+		t.Fatal("/ didn't start filtering")
+	}
+	m = update(m, emailsMsg{emails: []email{{id: "9", sender: "x@example.com", subject: "Gamma"}}})
+	m = keys(m, "l")
+	if got := listSubjects(m); got != before {
+		t.Errorf("items while filtering = %q, want %q", got, before)
+	}
+	if got := m.list.FilterInput.Value(); got != "al" {
+		t.Errorf("filter input = %q, want %q", got, "al")
+	}
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if got := listSubjects(m); got != "Gamma" {
+		t.Errorf("items once the filter is applied = %q, want the held poll's", got)
+	}
+}
+
+func TestQuitWaitsForPendingOps(t *testing.T) {
+	for _, k := range []tea.KeyMsg{{Type: tea.KeyEsc}, {Type: tea.KeyRunes, Runes: []rune("q")}} {
+		m, _ := newTestModel(t, "Alpha")
+		m.pendingOps = 1
+		next, cmd := m.Update(k)
+		if m = next.(model); m.overlay != quitOverlay {
+			t.Errorf("%s with an operation pending: overlay = %v, want the shutdown screen", k, m.overlay)
+		}
+		if cmd != nil {
+			if _, ok := cmd().(tea.QuitMsg); ok {
+				t.Errorf("%s with an operation pending quit at once", k)
+			}
+		}
+	}
+}
+
+func TestRemappedQuit(t *testing.T) {
+	m, _ := newTestModelWith(t, "[keys]\nquit = \"Q\"\n", "Alpha")
+	m.pendingOps = 1
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if m = next.(model); m.overlay == quitOverlay || cmd != nil {
+		t.Error("q still quits with quit rebound to Q")
+	}
+	if m = keys(m, "Q"); m.overlay != quitOverlay {
+		t.Error("Q with an operation pending didn't wait for it")
+	}
+}
+
+func TestConfirmOnlyByTheNextKey(t *testing.T) {
+	asking := func(m model) bool { return strings.Contains(m.notice, "again") }
+	m, _ := newTestModelWith(t, "[confirm]\ndelete = \"always\"\n", "Alpha", "Beta")
+	if m = keys(m, "d"); !asking(m) {
+		t.Fatalf("d didn't ask first: notice %q", m.notice)
+	}
+	if m = keys(m, "jkd"); !asking(m) {
+		t.Errorf("d after other keys went ahead without asking again: notice %q", m.notice)
+	}
+	m = update(m, fetchEmails(m.provider, 20)())
+	if m = keys(m, "d"); !asking(m) {
+		t.Errorf("d after the list was updated went ahead without asking again: notice %q", m.notice)
+	}
+	if m = keys(m, "d"); asking(m) {
+		t.Errorf("dd still asks: notice %q", m.notice)
+	}
+}
+
+func TestSearchResultsBack(t *testing.T) {
+	m, _ := newTestModel(t, "Alpha", "Beta")
+	m.mode = searchView
+	m.begin(searching)
+	m = update(m, searchResultsMsg{query: "a", emails: m.emails})
+	m = keys(m, "/")
+	next, filter := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Al")})
+	m = update(next.(model), filter())
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.search.results.FilterState() != list.FilterApplied {
+		t.Fatalf("filter state = %v, want applied", m.search.results.FilterState())
+	}
+	if next := update(m, tea.KeyMsg{Type: tea.KeyEsc}); next.mode != searchView || next.search.results.FilterState() != list.Unfiltered {
+		t.Errorf("esc with a filter applied: mode %v, filter %v; want the filter cleared", next.mode, next.search.results.FilterState())
+	}
+	if m = keys(m, "q"); m.mode != listView {
+		t.Errorf("q with a filter applied left the mode at %v", m.mode)
+	}
+}
+
+func TestBellRungFromUpdate(t *testing.T) {
+	if _, ok := alertNew(notifyConfig{Bell: true})().(bellMsg); !ok {
+		t.Error("the bell alert doesn't go through Update")
+	}
+	m, _ := newTestModel(t, "Alpha")
+	if cmd := m.applyWatched(watchedMsg{found: m.emails}); cmd == nil {
+		t.Error("watched-for mail doesn't ring the bell")
+	} else if _, ok := cmd().(bellMsg); !ok {
+		t.Error("watched-for mail rings the bell outside Update")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultMergeDelay is how long `mailnotify send` waits between messages,
+// so a long list doesn't trip the sending account's rate limits.
+const defaultMergeDelay = 5 * time.Second
+
+// mergeMessage is a message rendered from the template for one row.
+type mergeMessage struct {
+	row int
+	msg outgoingMessage
+}
+
+// runSend renders the template once per row of the CSV file and sends the
+// results through the account replies go through. The template starts with
+// header lines, Subject and optionally To, then a blank line and the body;
+// it's executed against the row, keyed by the CSV's header, so {{.name}} is
+// the row's name column. Without a To header the row's email column is the
+// recipient. Every row is rendered before anything is sent, so a bad row
+// stops the run before the first message goes out.
+//
+//	mailnotify send -template t.md -csv people.csv [-delay 5s] [-dry-run]
+func runSend(cfg config, args []string) error {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	tmplPath := fs.String("template", "", "the message template")
+	csvPath := fs.String("csv", "", "the recipients, one row each under a header row")
+	delay := fs.Duration("delay", defaultMergeDelay, "how long to wait between messages")
+	preview := fs.Bool("dry-run", cfg.dryRun, "print each message instead of sending it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *tmplPath == "" || *csvPath == "" {
+		return fmt.Errorf("usage: mailnotify send -template t.md -csv people.csv [-delay 5s] [-dry-run]")
+	}
+
+	text, err := os.ReadFile(*tmplPath)
+	if err != nil {
+		return err
+	}
+	tmpl, err := parseMergeTemplate(string(text))
+	if err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	rows, err := readMergeRows(*csvPath)
+	if err != nil {
+		return err
+	}
+	var messages []mergeMessage
+	for i, row := range rows {
+		msg, err := renderMerge(tmpl, row)
+		if err != nil {
+			// Row 1 is the header, so data rows start at 2 as a
+			// spreadsheet numbers them.
+			return fmt.Errorf("%s: row %d: %w", *csvPath, i+2, err)
+		}
+		messages = append(messages, mergeMessage{row: i + 2, msg: msg})
+	}
+
+	if *preview {
+		for _, m := range messages {
+			fmt.Printf("To: %s\nSubject: %s\n\n%s\n\n", strings.Join(m.msg.to, ", "), m.msg.subject, strings.TrimRight(m.msg.body, "\n"))
+		}
+		fmt.Printf("%d messages, none sent\n", len(messages))
+		return nil
+	}
+
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return err
+	}
+	store, ok := provider.(draftStore)
+	if !ok {
+		return fmt.Errorf("%s can't send mail", provider.name())
+	}
+	for i, m := range messages {
+		if i > 0 {
+			time.Sleep(*delay)
+		}
+		if err := store.compose(m.msg, true); err != nil {
+			return fmt.Errorf("row %d, to %s: %w (sent %d of %d)", m.row, strings.Join(m.msg.to, ", "), err, i, len(messages))
+		}
+		fmt.Printf("sent %d of %d to %s\n", i+1, len(messages), strings.Join(m.msg.to, ", "))
+	}
+	return nil
+}
+
+// readMergeRows reads the CSV file at path into one map per row, keyed by
+// the header row.
+func readMergeRows(path string) ([]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("%s: want a header row and at least one recipient", path)
+	}
+	header := records[0]
+	// Spreadsheets often save a byte-order mark ahead of the first column.
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	var rows []map[string]string
+	for _, rec := range records[1:] {
+		row := map[string]string{}
+		for i, name := range header {
+			row[strings.TrimSpace(name)] = strings.TrimSpace(rec[i])
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseMergeTemplate parses a send template. A field the CSV doesn't have
+// is an error rather than "<no value>" in someone's mail.
+func parseMergeTemplate(text string) (*template.Template, error) {
+	return template.New("send").Option("missingkey=error").Parse(text)
+}
+
+// renderMerge executes tmpl for row and splits the result into its header
+// lines and body.
+func renderMerge(tmpl *template.Template, row map[string]string) (outgoingMessage, error) {
+	var out strings.Builder
+	if err := tmpl.Execute(&out, row); err != nil {
+		return outgoingMessage{}, err
+	}
+	var msg outgoingMessage
+	lines := strings.Split(strings.ReplaceAll(out.String(), "\r\n", "\n"), "\n")
+	// A final newline ends the last line rather than starting another.
+	if n := len(lines); lines[n-1] == "" {
+		lines = lines[:n-1]
+	}
+	var body []string
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			body = lines[i+1:]
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "subject":
+			msg.subject = value
+		case "to":
+			for _, addr := range strings.Split(value, ",") {
+				if addr = strings.TrimSpace(addr); addr != "" {
+					msg.to = append(msg.to, addr)
+				}
+			}
+		default:
+			if !ok {
+				return msg, fmt.Errorf("want Subject and To header lines, then a blank line, not %q", line)
+			}
+			return msg, fmt.Errorf("unknown header %q", name)
+		}
+	}
+	if len(msg.to) == 0 && row["email"] != "" {
+		msg.to = []string{row["email"]}
+	}
+	switch {
+	case len(msg.to) == 0:
+		return msg, fmt.Errorf("no recipient: add a To header or an email column")
+	case msg.subject == "":
+		return msg, fmt.Errorf("no Subject header")
+	}
+	msg.body = strings.Join(body, "\n") + "\n"
+	return msg, nil
+}
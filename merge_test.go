@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMerge(t *testing.T) {
+	long := strings.Repeat("x", 100<<10)
+	tests := []struct {
+		name     string
+		template string
+		row      map[string]string
+		subject  string
+		to       string
+		body     string
+		err      string
+	}{
+		{
+			name:     "headers and body",
+			template: "Subject: Hi {{.name}}\nTo: {{.email}}\n\nHello {{.name}},\n\nSee you.\n",
+			row:      map[string]string{"name": "Ann", "email": "ann@example.com"},
+			subject:  "Hi Ann",
+			to:       "ann@example.com",
+			body:     "Hello Ann,\n\nSee you.\n",
+		},
+		{
+			name:     "recipient from the email column",
+			template: "Subject: Hi\r\n\r\nHello\r\n",
+			row:      map[string]string{"email": "bob@example.com"},
+			subject:  "Hi",
+			to:       "bob@example.com",
+			body:     "Hello\n",
+		},
+		{
+			name:     "body line longer than a scanner buffer",
+			template: "Subject: Log\nTo: a@example.com\n\n{{.log}}\nend\n",
+			row:      map[string]string{"log": long},
+			subject:  "Log",
+			to:       "a@example.com",
+			body:     long + "\nend\n",
+		},
+		{
+			name:     "missing field",
+			template: "Subject: {{.nope}}\n\nx\n",
+			row:      map[string]string{"email": "a@example.com"},
+			err:      `map has no entry for key "nope"`,
+		},
+		{
+			name:     "unknown header",
+			template: "Subject: Hi\nFrom: me@example.com\n\nx\n",
+			row:      map[string]string{"email": "a@example.com"},
+			err:      `unknown header "From"`,
+		},
+		{
+			name:     "no subject",
+			template: "To: a@example.com\n\nx\n",
+			err:      "no Subject header",
+		},
+	}
+	for _, tt := range tests {
+		tmpl, err := parseMergeTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		msg, err := renderMerge(tmpl, tt.row)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if msg.subject != tt.subject || strings.Join(msg.to, ", ") != tt.to || msg.body != tt.body {
+			t.Errorf("%s: got subject %q, to %q, body of %d bytes; want %q, %q, %d bytes", tt.name, msg.subject, msg.to, len(msg.body), tt.subject, tt.to, len(tt.body))
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// maxMIMEDepth bounds nesting so a hostile message can't recurse forever.
+const maxMIMEDepth = 10
+
+var headerDecoder = &mime.WordDecoder{
+	// Only UTF-8 and US-ASCII are decoded by default; pass other charsets
+	// through rather than failing the whole header.
+	CharsetReader: func(_ string, r io.Reader) (io.Reader, error) { return r, nil },
+}
+
+// decodeHeader decodes RFC 2047 encoded words, falling back to the raw
+// value.
+func decodeHeader(s string) string {
+	d, err := headerDecoder.DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return d
+}
+
+// mimePart is a leaf of a message's MIME tree with its transfer encoding
+// already undone.
+type mimePart struct {
+	mediaType string
+	filename  string
+	data      []byte
+}
+
+func (p mimePart) isAttachment() bool {
+	return p.filename != ""
+}
+
+// parseMessage extracts the readable body, the HTML part and the
+// attachments from a raw RFC 5322 message. The body is the plain text part,
+// or the HTML reduced to text when there isn't one.
+func parseMessage(raw []byte) (messageContent, error) {
+	parts, err := messageParts(raw)
+	if err != nil {
+		return messageContent{}, err
+	}
+	var plain, htmlBody string
+	var attachments []attachment
+	for _, p := range parts {
+		switch {
+		case p.isAttachment():
+			a := attachment{name: p.filename, mimeType: p.mediaType, size: int64(len(p.data))}
+			if a.inlineable() {
+				a.text = string(p.data)
+			}
+			attachments = append(attachments, a)
+		case p.mediaType == "text/plain" && plain == "":
+			plain = string(p.data)
+		case p.mediaType == "text/html" && htmlBody == "":
+			htmlBody = string(p.data)
+		}
+	}
+	if plain == "" && htmlBody != "" {
+		plain = htmlToText(htmlBody)
+	}
+	return messageContent{
+		body:        strings.TrimSpace(strings.ReplaceAll(plain, "\r\n", "\n")),
+		html:        htmlBody,
+		attachments: attachments,
+		source:      string(raw),
+	}, nil
+}
+
+// attachmentData returns the decoded contents of the attachment called
+// name.
+func attachmentData(raw []byte, name string) ([]byte, error) {
+	parts, err := messageParts(raw)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range parts {
+		if p.isAttachment() && p.filename == name {
+			return p.data, nil
+		}
+	}
+	return nil, fmt.Errorf("attachment %q not found", name)
+}
+
+func messageParts(raw []byte) ([]mimePart, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	var parts []mimePart
+	err = walkMIME(textproto.MIMEHeader(msg.Header), msg.Body, 0, &parts)
+	return parts, err
+}
+
+func walkMIME(h textproto.MIMEHeader, body io.Reader, depth int, parts *[]mimePart) error {
+	if depth > maxMIMEDepth {
+		return fmt.Errorf("message nested too deeply")
+	}
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := walkMIME(p.Header, p, depth+1, parts); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(transferDecoder(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+	*parts = append(*parts, mimePart{mediaType: mediaType, filename: partFilename(h, params), data: data})
+	return nil
+}
+
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// partFilename is the attachment name from Content-Disposition, or the
+// older Content-Type name parameter.
+func partFilename(h textproto.MIMEHeader, typeParams map[string]string) string {
+	disposition, params, err := mime.ParseMediaType(h.Get("Content-Disposition"))
+	if err == nil && params["filename"] != "" {
+		return decodeHeader(params["filename"])
+	}
+	if typeParams["name"] != "" && disposition != "inline" {
+		return decodeHeader(typeParams["name"])
+	}
+	if disposition == "attachment" {
+		return "attachment"
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// meteredMode is how [metered] decides on headers-only mode, in which the
+// list is fetched as usual, headers only, but a message's body is only
+// downloaded once a second press confirms it.
+type meteredMode int
+
+const (
+	meteredOff meteredMode = iota
+	meteredOn
+	meteredAuto
+)
+
+func (c meteredConfig) mode() (meteredMode, error) {
+	switch c.Mode {
+	case "", "off":
+		return meteredOff, nil
+	case "on":
+		return meteredOn, nil
+	case "auto":
+		return meteredAuto, nil
+	}
+	return meteredOff, fmt.Errorf("mode must be off, on or auto, not %q", c.Mode)
+}
+
+type meteredMsg bool
+
+// meteredCheckInterval is how often auto mode looks at the connection.
+const meteredCheckInterval = time.Minute
+
+func checkMetered() tea.Cmd {
+	return func() tea.Msg {
+		return meteredMsg(readMetered())
+	}
+}
+
+// readMetered reports whether the connection looks metered: as
+// NetworkManager judges it on Linux, and on macOS, which doesn't say, when
+// the default route goes through a phone's hotspot.
+func readMetered() bool {
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := exec.Command("route", "-n", "get", "default").Output()
+		if err != nil {
+			return false
+		}
+		return hotspotRoute(string(out))
+	case "linux":
+		out, err := exec.Command("busctl", "--system", "get-property", "org.freedesktop.NetworkManager",
+			"/org/freedesktop/NetworkManager", "org.freedesktop.NetworkManager", "Metered").Output()
+		if err != nil {
+			return false
+		}
+		return nmMetered(string(out))
+	}
+	return false
+}
+
+// hotspotGateways are the addresses phones give themselves as the gateway
+// of their hotspots: the iPhone's Personal Hotspot, then Android's.
+var hotspotGateways = []string{"172.20.10.1", "192.168.43.1"}
+
+var routeGateway = regexp.MustCompile(`(?m)^\s*gateway:\s*(\S+)`)
+
+// hotspotRoute reads `route -n get default` output such as:
+//
+//	   route to: default
+//	destination: default
+//	    gateway: 172.20.10.1
+//	  interface: en0
+func hotspotRoute(out string) bool {
+	m := routeGateway.FindStringSubmatch(out)
+	return m != nil && slices.Contains(hotspotGateways, m[1])
+}
+
+// nmMetered reads NetworkManager's Metered property as busctl prints it,
+// "u 1": 1 is metered and 3 guessed to be.
+func nmMetered(out string) bool {
+	f := strings.Fields(out)
+	return len(f) == 2 && f[0] == "u" && (f[1] == "1" || f[1] == "3")
+}
+
+// applyMetered switches headers-only mode as the connection goes metered
+// or stops being, leaving what was picked by hand alone in between.
+func (m *model) applyMetered(msg meteredMsg) {
+	if bool(msg) == m.meteredLink {
+		return
+	}
+	m.meteredLink = bool(msg)
+	m.metered = m.meteredLink
+	if m.metered {
+		m.setNotice("Metered connection: headers only, and opening a message asks first")
+	} else {
+		m.setNotice("Off the metered connection: opening messages as usual")
+	}
+}
+
+// toggleMetered turns headers-only mode on or off by hand.
+func (m *model) toggleMetered() {
+	m.metered = !m.metered
+	if m.metered {
+		m.setNotice("Headers only: opening a message asks first, with its size")
+	} else {
+		m.setNotice("Opening messages as usual")
+	}
+}
+
+// downloadOK reports whether e's body may be downloaded. In headers-only
+// mode the first press only says how big it is and a second fetches it.
+// Backends that don't list sizes, being local or syncing themselves, never
+// ask.
+func (m *model) downloadOK(e email) bool {
+	if !m.metered || e.size == 0 {
+		return true
+	}
+	pending := "download\x00" + emailKey(e)
+	if m.takeConfirm(pending) {
+		return true
+	}
+	m.armConfirm(pending)
+	m.setNotice(fmt.Sprintf("Headers only: press %s again to download this message, about %s",
+		m.cfg.keys.label(m.mode, "enter"), formatBytes(int64(e.size))))
+	return false
+}
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultAccountTimeout is how long a poll waits on one of several
+// accounts before giving up on it for that poll.
+const defaultAccountTimeout = 30 * time.Second
+
+// backendAccount is one entry of [[accounts]]: a backend and the name its
+// messages and status are listed under.
+type backendAccount struct {
+	name    string
+	p       mailProvider
+	timeout time.Duration
+}
+
+// multiProvider polls several backends at once and lists their mail
+// together. Each message's account says which backend it came from, so
+// actions on it go back to that one.
+type multiProvider struct {
+	accounts []backendAccount
+	// kept is each account's last good poll, listed in its place while it
+	// fails.
+	kept *keptPolls
+}
+
+type keptPolls struct {
+	mu     sync.Mutex
+	emails map[string][]email
+}
+
+// newMultiProvider builds a backend for each of cfg.Accounts. Their names
+// have to differ, since they're what routes a message back to its backend.
+func newMultiProvider(cfg config) (multiProvider, error) {
+	m := multiProvider{kept: &keptPolls{emails: map[string][]email{}}}
+	for i, b := range cfg.Accounts {
+		p, err := newBackend(b, cfg)
+		if err != nil {
+			return multiProvider{}, fmt.Errorf("accounts[%d]: %w", i, err)
+		}
+		a := backendAccount{name: backendName(p), p: p, timeout: b.Timeout}
+		if a.timeout <= 0 {
+			a.timeout = defaultAccountTimeout
+		}
+		if slices.ContainsFunc(m.accounts, func(o backendAccount) bool { return o.name == a.name }) {
+			return multiProvider{}, fmt.Errorf("accounts[%d]: another account is named %q; set account to tell them apart", i, a.name)
+		}
+		m.accounts = append(m.accounts, a)
+	}
+	return m, nil
+}
+
+// backendName is the account name of p: Mail.app for Mail.app, whose
+// messages carry the names of its own accounts, otherwise the label its
+// messages have.
+func backendName(p mailProvider) string {
+	switch p := p.(type) {
+	case imapProvider:
+		return p.account()
+	case maildirProvider:
+		return p.label
+	}
+	return "Mail.app"
+}
+
+func (p multiProvider) name() string {
+	return fmt.Sprintf("%d accounts (%s)", len(p.accounts), strings.Join(p.names(), ", "))
+}
+
+// names are the accounts' names, in config order.
+func (p multiProvider) names() []string {
+	names := make([]string, len(p.accounts))
+	for i, a := range p.accounts {
+		names[i] = a.name
+	}
+	return names
+}
+
+// owner is the account a message or mailbox of account belongs to: the
+// one of that name, or Mail.app for the names of its own accounts.
+func (p multiProvider) owner(account string) (backendAccount, error) {
+	for _, a := range p.accounts {
+		if a.name == account {
+			return a, nil
+		}
+	}
+	for _, a := range p.accounts {
+		if _, ok := a.p.(mailAppProvider); ok {
+			return a, nil
+		}
+	}
+	return backendAccount{}, fmt.Errorf("no account %q", account)
+}
+
+// accountErrors are the accounts a poll couldn't read, by name, when the
+// others could be.
+type accountErrors map[string]error
+
+func (errs accountErrors) Error() string {
+	var parts []string
+	for name, err := range errs {
+		parts = append(parts, name+": "+err.Error())
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, "; ")
+}
+
+// join is every account's error, named, as one error that still wraps
+// them.
+func (errs accountErrors) join() error {
+	names := slices.Sorted(maps.Keys(errs))
+	all := make([]error, len(names))
+	for i, name := range names {
+		all[i] = fmt.Errorf("%s: %w", name, errs[name])
+	}
+	return errors.Join(all...)
+}
+
+// partialRead separates the accounts that failed from a read that
+// otherwise worked: when some accounts answered, rest is nil and failed
+// names the others.
+func partialRead(err error) (failed accountErrors, rest error) {
+	if errors.As(err, &failed) {
+		return failed, nil
+	}
+	return nil, err
+}
+
+// withContext is the account with its backend's reads stopped once ctx is
+// done, if it can be.
+func (a backendAccount) withContext(ctx context.Context) backendAccount {
+	if c, ok := a.p.(canceler); ok {
+		a.p = c.withContext(ctx)
+	}
+	return a
+}
+
+type accountPoll struct {
+	emails []email
+	total  int
+	err    error
+}
+
+// each runs f on every account at once, and gives up on an account that
+// takes longer than its timeout. A backend that can be interrupted, as
+// IMAP can, is stopped; any other finishes on its own goroutine and its
+// answer is dropped.
+func (p multiProvider) each(f func(a backendAccount) accountPoll) []accountPoll {
+	results := make([]accountPoll, len(p.accounts))
+	var wg sync.WaitGroup
+	for i, a := range p.accounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+			defer cancel()
+			done := make(chan accountPoll, 1)
+			go func() { done <- f(a.withContext(ctx)) }()
+			select {
+			case r := <-done:
+				results[i] = r
+			case <-ctx.Done():
+				results[i] = accountPoll{err: fmt.Errorf("no answer in %s", formatInterval(a.timeout))}
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// merge puts the accounts' messages together, newest first, up to limit
+// of them. When some accounts failed and others didn't, the error is an
+// accountErrors, and the messages are the others' and, with kept, those of
+// the failed accounts' last good poll.
+func (p multiProvider) merge(results []accountPoll, limit int, kept *keptPolls) ([]email, int, error) {
+	if kept != nil {
+		kept.mu.Lock()
+		defer kept.mu.Unlock()
+	}
+	var emails []email
+	total := 0
+	errs := accountErrors{}
+	for i, r := range results {
+		name := p.accounts[i].name
+		if r.err != nil {
+			errs[name] = r.err
+			if kept != nil {
+				emails = append(emails, kept.emails[name]...)
+				total += len(kept.emails[name])
+			}
+			continue
+		}
+		for j := range r.emails {
+			if r.emails[j].account == "" {
+				r.emails[j].account = name
+			}
+		}
+		if kept != nil {
+			kept.emails[name] = r.emails
+		}
+		emails = append(emails, r.emails...)
+		total += max(r.total, len(r.emails))
+	}
+	if len(errs) > 0 && len(errs) == len(results) {
+		return nil, 0, errs.join()
+	}
+	slices.SortStableFunc(emails, func(a, b email) int {
+		ta, _ := parseMailDate(a.date)
+		tb, _ := parseMailDate(b.date)
+		return cmp.Compare(tb.Unix(), ta.Unix())
+	})
+	if limit > 0 && len(emails) > limit {
+		emails = emails[:limit]
+	}
+	if len(errs) > 0 {
+		return emails, total, errs
+	}
+	return emails, total, nil
+}
+
+func (p multiProvider) unread() ([]email, error) {
+	emails, _, err := p.unreadPage(0)
+	return emails, err
+}
+
+// unreadPage reads up to limit unread messages from each account and keeps
+// the newest limit of them all.
+func (p multiProvider) unreadPage(limit int) ([]email, int, error) {
+	return p.merge(p.each(func(a backendAccount) accountPoll {
+		if pg, ok := a.p.(pager); ok && limit > 0 {
+			emails, total, err := pg.unreadPage(limit)
+			return accountPoll{emails, total, err}
+		}
+		emails, err := a.p.unread()
+		return accountPoll{emails: emails, err: err}
+	}), limit, p.kept)
+}
+
+func (p multiProvider) content(e email) (messageContent, error) {
+	a, err := p.owner(e.account)
+	if err != nil {
+		return messageContent{}, err
+	}
+	return a.p.content(e)
+}
+
+// markRead marks each account's messages read through that account.
+func (p multiProvider) markRead(emails []email) error {
+	byAccount := map[string][]email{}
+	var order []string
+	for _, e := range emails {
+		a, err := p.owner(e.account)
+		if err != nil {
+			return err
+		}
+		if _, ok := byAccount[a.name]; !ok {
+			order = append(order, a.name)
+		}
+		byAccount[a.name] = append(byAccount[a.name], e)
+	}
+	var errs []error
+	for _, name := range order {
+		a, _ := p.owner(name)
+		if err := a.p.markRead(byAccount[name]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p multiProvider) peek(e email) (messageContent, error) {
+	a, err := p.owner(e.account)
+	if err != nil {
+		return messageContent{}, err
+	}
+	if pk, ok := a.p.(peeker); ok {
+		return pk.peek(e)
+	}
+	return messageContent{}, fmt.Errorf("%s can't open a message without marking it read", a.name)
+}
+
+// editor is the messageEditor of the account e belongs to.
+func (p multiProvider) editor(e email) (messageEditor, error) {
+	a, err := p.owner(e.account)
+	if err != nil {
+		return nil, err
+	}
+	if ed, ok := a.p.(messageEditor); ok {
+		return ed, nil
+	}
+	return nil, fmt.Errorf("%s can't change single messages", a.name)
+}
+
+func (p multiProvider) markUnread(e email) error {
+	ed, err := p.editor(e)
+	if err != nil {
+		return err
+	}
+	return ed.markUnread(e)
+}
+
+func (p multiProvider) trash(e email) error {
+	ed, err := p.editor(e)
+	if err != nil {
+		return err
+	}
+	return ed.trash(e)
+}
+
+func (p multiProvider) archive(e email) error {
+	ed, err := p.editor(e)
+	if err != nil {
+		return err
+	}
+	return ed.archive(e)
+}
+
+// trashAll moves each account's messages through that account, in one go
+// where it can.
+func (p multiProvider) trashAll(emails []email) error {
+	return p.moveAll(emails, bulkEditor.trashAll, messageEditor.trash)
+}
+
+func (p multiProvider) archiveAll(emails []email) error {
+	return p.moveAll(emails, bulkEditor.archiveAll, messageEditor.archive)
+}
+
+func (p multiProvider) moveAll(emails []email, all func(bulkEditor, []email) error, one func(messageEditor, email) error) error {
+	byAccount := map[string][]email{}
+	var order []string
+	for _, e := range emails {
+		a, err := p.owner(e.account)
+		if err != nil {
+			return err
+		}
+		if _, ok := byAccount[a.name]; !ok {
+			order = append(order, a.name)
+		}
+		byAccount[a.name] = append(byAccount[a.name], e)
+	}
+	var errs []error
+	for _, name := range order {
+		a, _ := p.owner(name)
+		if b, ok := a.p.(bulkEditor); ok {
+			if err := all(b, byAccount[name]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+			continue
+		}
+		ed, ok := a.p.(messageEditor)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s can't move messages", name))
+			continue
+		}
+		for _, e := range byAccount[name] {
+			if err := one(ed, e); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p multiProvider) saveAttachment(e email, att attachment, dest string) error {
+	a, err := p.owner(e.account)
+	if err != nil {
+		return err
+	}
+	if s, ok := a.p.(attachmentSaver); ok {
+		return s.saveAttachment(e, att, dest)
+	}
+	return fmt.Errorf("%s can't save attachments", a.name)
+}
+
+// search searches every account that can, and returns what those that
+// answered found.
+func (p multiProvider) search(q mailSearch) ([]email, error) {
+	emails, _, err := p.merge(p.each(func(a backendAccount) accountPoll {
+		s, ok := a.p.(searcher)
+		if !ok {
+			return accountPoll{}
+		}
+		emails, err := s.search(q)
+		return accountPoll{emails: emails, err: err}
+	}), maxSearchResults, nil)
+	_, err = partialRead(err)
+	return emails, err
+}
+
+// listMailboxes lists the mailboxes of every account that can. It fails
+// only when none could.
+func (p multiProvider) listMailboxes() ([]mailboxInfo, error) {
+	var boxes []mailboxInfo
+	var errs []error
+	for _, a := range p.accounts {
+		b, ok := a.p.(mailboxBrowser)
+		if !ok {
+			continue
+		}
+		infos, err := b.listMailboxes()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", a.name, err))
+			continue
+		}
+		boxes = append(boxes, infos...)
+	}
+	if len(boxes) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return boxes, nil
+}
+
+// scoped reads just the mailbox in s, through the account it belongs to.
+func (p multiProvider) scoped(s mailScope) mailProvider {
+	a, err := p.owner(s.account)
+	if err != nil {
+		return p
+	}
+	if b, ok := a.p.(mailboxBrowser); ok {
+		return b.scoped(s)
+	}
+	return a.p
+}
+
+// reporting has each account that can report its polls report to p.
+func (p multiProvider) reporting(progress *pollProgress) mailProvider {
+	accounts := slices.Clone(p.accounts)
+	for i, a := range accounts {
+		if r, ok := a.p.(progressReporter); ok {
+			accounts[i].p = r.reporting(progress)
+		}
+	}
+	p.accounts = accounts
+	return p
+}
+
+// drafts is the first account that keeps drafts. Composing goes through
+// it.
+func (p multiProvider) drafts() ([]draft, error) {
+	s, err := p.draftAccount()
+	if err != nil {
+		return nil, err
+	}
+	return s.drafts()
+}
+
+func (p multiProvider) draftContent(id string) (string, error) {
+	s, err := p.draftAccount()
+	if err != nil {
+		return "", err
+	}
+	return s.draftContent(id)
+}
+
+func (p multiProvider) deleteDraft(id string) error {
+	s, err := p.draftAccount()
+	if err != nil {
+		return err
+	}
+	return s.deleteDraft(id)
+}
+
+func (p multiProvider) compose(msg outgoingMessage, send bool) error {
+	s, err := p.draftAccount()
+	if err != nil {
+		return err
+	}
+	return s.compose(msg, send)
+}
+
+func (p multiProvider) draftAccount() (draftStore, error) {
+	for _, a := range p.accounts {
+		if s, ok := a.p.(draftStore); ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("none of the accounts can compose")
+}
+
+func (p multiProvider) setFlag(e email, f flagColor) error {
+	a, err := p.owner(e.account)
+	if err != nil {
+		return err
+	}
+	if fl, ok := a.p.(flagger); ok {
+		return fl.setFlag(e, f)
+	}
+	return fmt.Errorf("%s can't flag messages", a.name)
+}
+
+func (p multiProvider) labels(e email) ([]string, []string, error) {
+	a, err := p.owner(e.account)
+	if err != nil {
+		return nil, nil, err
+	}
+	if l, ok := a.p.(labeler); ok {
+		return l.labels(e)
+	}
+	return nil, nil, fmt.Errorf("%s has no labels", a.name)
+}
+
+func (p multiProvider) relabel(e email, add, remove []string) error {
+	a, err := p.owner(e.account)
+	if err != nil {
+		return err
+	}
+	if l, ok := a.p.(labeler); ok {
+		return l.relabel(e, add, remove)
+	}
+	return fmt.Errorf("%s has no labels", a.name)
+}
+
+// blockSender installs the block in every account that can take one.
+func (p multiProvider) blockSender(addr string, cfg blockConfig) error {
+	var errs []error
+	for _, a := range p.accounts {
+		if b, ok := a.p.(senderBlocker); ok {
+			if err := b.blockSender(addr, cfg); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", a.name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p multiProvider) reconnect() {
+	for _, a := range p.accounts {
+		if r, ok := a.p.(reconnector); ok {
+			r.reconnect()
+		}
+	}
+}
+
+// checks runs each account's setup checks, one account after another.
+func (p multiProvider) checks() []check {
+	var all []check
+	for _, a := range p.accounts {
+		c, ok := a.p.(checker)
+		if !ok {
+			continue
+		}
+		for _, r := range c.checks() {
+			r.name = a.name + ": " + r.name
+			all = append(all, r)
+		}
+	}
+	return all
+}
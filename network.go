@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// netState is the result of a connectivity probe.
+type netState int
+
+const (
+	netOnline netState = iota
+	netOffline
+	// netCaptive means a network is up but a captive portal is intercepting
+	// requests until the user signs in.
+	netCaptive
+)
+
+func (s netState) String() string {
+	switch s {
+	case netOffline:
+		return "Offline"
+	case netCaptive:
+		return "Network needs sign-in"
+	default:
+		return "Online"
+	}
+}
+
+// captiveProbeURL answers with a fixed "Success" page; anything else means
+// the request was intercepted.
+const captiveProbeURL = "http://captive.apple.com/hotspot-detect.html"
+
+// offlineProbeInterval is how often connectivity is re-checked while
+// offline, instead of polling.
+const offlineProbeInterval = 10 * time.Second
+
+type netStateMsg netState
+
+var probeClient = &http.Client{
+	Timeout: 3 * time.Second,
+	// A portal answers with a redirect to its login page; don't follow it.
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// probeNetwork checks whether the internet is reachable.
+func probeNetwork() netState {
+	resp, err := probeClient.Get(captiveProbeURL)
+	if err != nil {
+		return netOffline
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "Success") {
+		return netCaptive
+	}
+	return netOnline
+}
+
+// connectionLost reports whether a poll failed for a remote account's
+// connection, the one failure the network being down explains. Refused
+// logins, Mail.app's permissions and local Maildir errors are shown as
+// they are, without a probe that could mistake them for being offline.
+func connectionLost(err error) bool {
+	// Every account's connection has to be down for the network to be.
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := joined.Unwrap()
+		return len(errs) > 0 && !slices.ContainsFunc(errs, func(err error) bool { return !connectionLost(err) })
+	}
+	var lost imapConnError
+	return errors.As(err, &lost)
+}
+
+func checkNetwork() tea.Cmd {
+	return func() tea.Msg {
+		return netStateMsg(probeNetwork())
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConnectionLost(t *testing.T) {
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "imap.example.com"}}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"dial", imapConnError{dial}, true},
+		{"dropped mid-command", fmt.Errorf("Work: %w", imapConnError{io.ErrUnexpectedEOF}), true},
+		{"refused login", errors.New("imap: NO [AUTHENTICATIONFAILED] Invalid credentials"), false},
+		{"maildir", &fs.PathError{Op: "open", Path: "/home/me/Mail/INBOX/new", Err: fs.ErrPermission}, false},
+		{"mail.app", errors.New("Mail.app: not authorized to send Apple events"), false},
+		{"unwrapped net error", dial, false},
+		{"every account lost", errors.Join(fmt.Errorf("Home: %w", imapConnError{dial}), fmt.Errorf("Work: %w", imapConnError{io.EOF})), true},
+		{"one account refused", errors.Join(fmt.Errorf("Home: %w", imapConnError{dial}), errors.New("Work: imap: NO [AUTHENTICATIONFAILED]")), false},
+	}
+	for _, tt := range tests {
+		if got := connectionLost(tt.err); got != tt.want {
+			t.Errorf("%s: connectionLost = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAccountTimeoutClosesConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	closed := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Never greet, and wait for the client to hang up.
+		conn.Read(make([]byte, 1))
+		close(closed)
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	imap, err := newIMAPProvider(backendConfig{Host: addr.IP.String(), Port: addr.Port, TLS: "none"}, mailboxConfig{}, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := multiProvider{accounts: []backendAccount{{name: "Work", p: imap, timeout: 50 * time.Millisecond}}}
+	_, _, err = p.unreadPage(20)
+	if err == nil {
+		t.Fatal("a server that never answers was read")
+	}
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Error("the timed-out account's connection is still open")
+	}
+}
+
+func TestIMAPPlaintextOnlyToLoopback(t *testing.T) {
+	for host, ok := range map[string]bool{
+		"localhost":        true,
+		"127.0.0.1":        true,
+		"::1":              true,
+		"[::1]":            true,
+		"imap.example.com": false,
+		"192.168.1.10":     false,
+	} {
+		_, err := newIMAPProvider(backendConfig{Host: host, TLS: "none"}, mailboxConfig{}, 20)
+		if (err == nil) != ok {
+			t.Errorf("tls = none to %s: err = %v, want allowed %v", host, err, ok)
+		}
+	}
+	if _, err := newIMAPProvider(backendConfig{Host: "imap.example.com", TLS: "starttls"}, mailboxConfig{}, 20); err != nil {
+		t.Errorf("starttls refused: %v", err)
+	}
+}
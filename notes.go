@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// noteExcerptLen caps how much of the body is copied into a note.
+const noteExcerptLen = 600
+
+type noteAppendedMsg struct {
+	target string
+	err    error
+}
+
+// appendToNotes logs e and an excerpt of body to the configured notes
+// target: a Markdown file, or a note in Apple Notes.
+func appendToNotes(cfg notesConfig, e email, body string) tea.Cmd {
+	return func() tea.Msg {
+		excerpt := noteExcerpt(body)
+		if cfg.Target == "apple-notes" {
+			name := cfg.Note
+			if name == "" {
+				name = "Mail log"
+			}
+			return noteAppendedMsg{target: name, err: appendAppleNote(name, e, excerpt)}
+		}
+		path := expandHome(cfg.Path)
+		if path == "" {
+			return noteAppendedMsg{err: fmt.Errorf("no notes path configured")}
+		}
+		return noteAppendedMsg{target: filepath.Base(path), err: appendMarkdownNote(path, e, excerpt)}
+	}
+}
+
+func noteExcerpt(body string) string {
+	body = strings.TrimSpace(body)
+	if r := []rune(body); len(r) > noteExcerptLen {
+		body = strings.TrimSpace(string(r[:noteExcerptLen])) + "…"
+	}
+	return body
+}
+
+// messageURL is the message:// deep link Mail.app opens a message from.
+func messageURL(messageID string) string {
+	if messageID == "" {
+		return ""
+	}
+	return "message://" + url.PathEscape("<"+strings.Trim(messageID, "<>")+">")
+}
+
+func appendMarkdownNote(path string, e email, excerpt string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n## %s\n\n", e.subject)
+	fmt.Fprintf(&b, "- **From:** %s\n", e.sender)
+	fmt.Fprintf(&b, "- **Date:** %s\n", e.date)
+	if link := messageURL(e.messageID); link != "" {
+		fmt.Fprintf(&b, "- **Link:** <%s>\n", link)
+	}
+	fmt.Fprintf(&b, "- **Logged:** %s\n", time.Now().Format("2006-01-02 15:04"))
+	if excerpt != "" {
+		b.WriteString("\n")
+		for _, line := range strings.Split(excerpt, "\n") {
+			b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func appendAppleNote(name string, e email, excerpt string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h2>%s</h2>", html.EscapeString(e.subject))
+	fmt.Fprintf(&b, "<div><b>From:</b> %s</div>", html.EscapeString(e.sender))
+	fmt.Fprintf(&b, "<div><b>Date:</b> %s</div>", html.EscapeString(e.date))
+	if link := messageURL(e.messageID); link != "" {
+		fmt.Fprintf(&b, `<div><a href="%s">Open in Mail</a></div>`, html.EscapeString(link))
+	}
+	if excerpt != "" {
+		fmt.Fprintf(&b, "<blockquote>%s</blockquote>", strings.ReplaceAll(html.EscapeString(excerpt), "\n", "<br>"))
+	}
+
+	// Values are passed as arguments rather than spliced into the script so
+	// quotes in subjects can't break it.
+	script := `
+on run argv
+	set noteName to item 1 of argv
+	set entry to item 2 of argv
+	tell application "Notes"
+		if not (exists note noteName) then
+			make new note with properties {name:noteName, body:"<h1>" & noteName & "</h1>"}
+		end if
+		set n to note noteName
+		set body of n to (body of n) & entry
+	end tell
+end run
+`
+	return exec.Command("osascript", "-e", script, name, b.String()).Run()
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxNotifications is how many new messages are announced one by one;
+// beyond that a single summary is shown instead.
+const maxNotifications = 3
+
+func (n notifyConfig) enabled() bool {
+	return n.Enabled == nil || *n.Enabled
+}
+
+// tool is the configured notification tool or the best one available.
+func (n notifyConfig) tool() string {
+	if n.Tool != "" {
+		return n.Tool
+	}
+	if _, err := exec.LookPath("terminal-notifier"); err == nil {
+		return "terminal-notifier"
+	}
+	if runtime.GOOS == "darwin" {
+		return "osascript"
+	}
+	return "notify-send"
+}
+
+// emailKey identifies a message across polls.
+func emailKey(e email) string {
+	if e.messageID != "" {
+		return e.messageID
+	}
+	return e.account + "\x00" + e.mailbox + "\x00" + e.id
+}
+
+// arrivals tracks which unread messages have already been announced.
+type arrivals struct {
+	seen   map[string]bool
+	primed bool
+}
+
+// update records emails as the current unread set and returns the ones not
+// seen in the previous poll. The first poll only primes it, so starting the
+// daemon doesn't announce the whole inbox.
+func (a *arrivals) update(emails []email) []email {
+	seen := make(map[string]bool, len(emails))
+	var fresh []email
+	for _, e := range emails {
+		k := emailKey(e)
+		seen[k] = true
+		if a.primed && !a.seen[k] {
+			fresh = append(fresh, e)
+		}
+	}
+	a.seen, a.primed = seen, true
+	return fresh
+}
+
+// known adds emails to the current unread set, so a message marked unread
+// again isn't announced as new.
+func (a *arrivals) known(emails []email) {
+	if !a.primed {
+		return
+	}
+	for _, e := range emails {
+		a.seen[emailKey(e)] = true
+	}
+}
+
+// notifyNew posts a desktop notification for each of emails, or one summary
+// when there are many. configPath is passed on so clicking a notification
+// opens the TUI with the same config.
+func notifyNew(cfg notifyConfig, configPath string, emails []email) error {
+	return notifyEmails(cfg, configPath, emails, "New mail", "%d new messages")
+}
+
+// notifyEmails posts a notification titled title for each of emails, or
+// one titled with summary, a format for the count, when there are many.
+func notifyEmails(cfg notifyConfig, configPath string, emails []email, title, summary string) error {
+	if len(emails) > maxNotifications {
+		latest := emails[0]
+		return postNotification(cfg, configPath, fmt.Sprintf(summary, len(emails)),
+			"Latest from "+latest.sender, latest.subject)
+	}
+	for _, e := range emails {
+		subject := e.subject
+		if subject == "" {
+			subject = "(no subject)"
+		}
+		if err := postNotification(cfg, configPath, title, e.sender, subject); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postNotification shows one notification with the configured tool.
+// terminal-notifier is preferred when installed because, unlike
+// `display notification`, clicking it can run a command.
+func postNotification(cfg notifyConfig, configPath, title, subtitle, message string) error {
+	tool := cfg.tool()
+	switch tool {
+	case "terminal-notifier":
+		args := []string{"-title", title, "-subtitle", subtitle, "-message", message, "-group", "mailnotify"}
+		if exe, err := os.Executable(); err == nil {
+			args = append(args, "-execute", openCommand(exe, configPath))
+		}
+		return exec.Command("terminal-notifier", args...).Run()
+	case "osascript":
+		script := `
+on run argv
+	display notification (item 3 of argv) with title (item 1 of argv) subtitle (item 2 of argv)
+end run
+`
+		return exec.Command("osascript", "-e", script, title, subtitle, message).Run()
+	case "notify-send":
+		return exec.Command("notify-send", "-a", "mailnotify", title+": "+subtitle, message).Run()
+	default:
+		return fmt.Errorf("unknown notification tool %q", tool)
+	}
+}
+
+// soundCommand is the command that plays the configured sound: afplay on
+// macOS, paplay or aplay elsewhere.
+func (n notifyConfig) soundCommand() (*exec.Cmd, error) {
+	sound := expandHome(n.Sound)
+	if runtime.GOOS == "darwin" {
+		if !strings.ContainsRune(sound, '/') && filepath.Ext(sound) == "" {
+			sound = "/System/Library/Sounds/" + sound + ".aiff"
+		}
+		return exec.Command("afplay", sound), nil
+	}
+	for _, player := range []string{"paplay", "aplay"} {
+		if _, err := exec.LookPath(player); err == nil {
+			return exec.Command(player, sound), nil
+		}
+	}
+	return nil, fmt.Errorf("no sound player found; install paplay or aplay")
+}
+
+type alertDoneMsg struct {
+	err error
+}
+
+// bellMsg rings the terminal bell. Commands return it rather than write
+// the bell themselves, so the terminal is only written to from Update and
+// the renderer, never from a command's goroutine.
+type bellMsg struct{}
+
+func ringBell() tea.Msg { return bellMsg{} }
+
+// alertNew plays the sound and rings the bell the config asks for.
+func alertNew(n notifyConfig) tea.Cmd {
+	var bell, sound tea.Cmd
+	if n.Bell {
+		bell = ringBell
+	}
+	if n.Sound != "" {
+		sound = func() tea.Msg {
+			cmd, err := n.soundCommand()
+			if err == nil {
+				err = cmd.Run()
+			}
+			return alertDoneMsg{err: err}
+		}
+	}
+	return tea.Batch(bell, sound)
+}
+
+// announce flashes a notice for the messages a poll brought in that weren't
+// in the previous one, and plays the alerts. quiet updates the unread set
+// without announcing anything.
+func (m *model) announce(emails []email, quiet bool) tea.Cmd {
+	arrived := slices.DeleteFunc(m.arrived.update(emails), m.senders.blocked)
+	fresh := m.cfg.rules.notifiable(slices.Clone(arrived))
+	fresh = slices.DeleteFunc(fresh, m.senders.muted)
+	fresh = slices.DeleteFunc(fresh, m.muted.has)
+	now := time.Now()
+	fresh = slices.DeleteFunc(fresh, func(e email) bool { return m.snoozed.hides(e, now) })
+	if quiet {
+		return nil
+	}
+	replies := tea.Batch(autoReply(m.cfg, m.provider, arrived), watchFor(m.cfg, arrived))
+	switch len(fresh) {
+	case 0:
+		return replies
+	case 1:
+		m.setNotice("New message from " + fresh[0].sender)
+	default:
+		m.setNotice(fmt.Sprintf("%d new messages", len(fresh)))
+	}
+	return tea.Batch(alertNew(m.cfg.Notify), replies)
+}
+
+// openCommand is the shell command that opens the TUI in a new terminal
+// window.
+func openCommand(exe, configPath string) string {
+	return shellQuote(exe) + " -config " + shellQuote(configPath) + " open"
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// openTUI starts the TUI in a new Terminal window, for launching it from a
+// notification or the daemon.
+func openTUI(configPath string) error {
+	if runtime.GOOS != "darwin" {
+		return fmt.Errorf("opening a terminal window is only supported on macOS")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	script := `
+on run argv
+	tell application "Terminal"
+		activate
+		do script (item 1 of argv)
+	end tell
+end run
+`
+	cmd := shellQuote(exe) + " -config " + shellQuote(configPath)
+	return exec.Command("osascript", "-e", script, cmd).Run()
+}
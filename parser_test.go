@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"mime"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+	"unicode/utf8"
+)
+
+// Property and fuzz tests for the layer that turns backend output into
+// emails. The fuzzers' seed corpora run with every `go test`; run one for
+// longer with, for example, `go test -fuzz FuzzDecodeBridgeMessages`.
+
+func TestBridgeMessagesRoundTrip(t *testing.T) {
+	f := func(m bridgeMessage) bool {
+		m.Date = ""
+		data, err := json.Marshal([]bridgeMessage{m})
+		if err != nil {
+			return false
+		}
+		got, err := decodeBridgeMessages(data)
+		if err != nil || len(got) != 1 {
+			return false
+		}
+		e := got[0]
+		return e.id == strings.TrimSpace(m.ID) && e.sender == strings.TrimSpace(m.Sender) &&
+			e.subject == strings.TrimSpace(m.Subject) && e.account == m.Account && e.mailbox == m.Mailbox &&
+			len(e.to) == len(trimAddresses(m.To)) && e.priority == parsePriority(m.Priority)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func FuzzDecodeBridgeMessages(f *testing.F) {
+	f.Add([]byte(`[{"id":"1","sender":"a@example.com","subject":"Hi","date":"2026-10-13T08:00:00.000Z","account":"Work","mailbox":"","to":["me@example.com"],"cc":[],"priority":"1 (Highest)","message_id":"m@x"}]`))
+	f.Add([]byte(`[{"id":"2","date":"Tuesday, October 13, 2026 at 10:00:00 AM","to":[" ",""],"cc":null}]`))
+	f.Add([]byte(`[{}]`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`{"id":1}`))
+	f.Add([]byte("[{\"subject\":\"\xff\xfe\"}]"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		emails, err := decodeBridgeMessages(data)
+		if err != nil {
+			return
+		}
+		for _, e := range emails {
+			relativeTime(e.date)
+			for _, a := range append(e.to, e.cc...) {
+				if a == "" || a != strings.TrimSpace(a) {
+					t.Fatalf("recipient %q isn't trimmed", a)
+				}
+			}
+		}
+	})
+}
+
+func TestDecodeHeaderRoundTrip(t *testing.T) {
+	f := func(s string) bool {
+		if !utf8.ValidString(s) {
+			return true
+		}
+		for _, enc := range []mime.WordEncoder{mime.QEncoding, mime.BEncoding} {
+			if got := decodeHeader(enc.Encode("utf-8", s)); got != s {
+				t.Logf("%q encoded with %c decoded to %q", s, enc, got)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func FuzzDecodeHeader(f *testing.F) {
+	f.Add("=?UTF-8?Q?J=C3=B6rg?= <j@example.com>")
+	f.Add("=?ISO-8859-1?B?SvZyZw==?=")
+	f.Add("=?UTF-8?Q?unterminated")
+	f.Add("=??=")
+	f.Add("plain text")
+	f.Fuzz(func(t *testing.T, s string) {
+		got := decodeHeader(s)
+		if !strings.Contains(s, "=?") && got != s {
+			t.Fatalf("%q has no encoded words but decoded to %q", s, got)
+		}
+	})
+}
+
+func FuzzParseHeaderEmail(f *testing.F) {
+	f.Add([]byte(imapHeaderBlock(1)))
+	f.Add([]byte("From: \r\nSubject: =?UTF-8?B?////?=\r\nDate: yesterday\r\n\r\n"))
+	f.Add([]byte("Subject: no blank line"))
+	f.Add([]byte("\r\n\r\n"))
+	f.Add([]byte("To: a, b, , <c@d>\r\nCc: \"x, y\" <z@w>\r\nX-Priority: 1 (Highest)\r\n\r\n"))
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		e := parseHeaderEmail(raw)
+		relativeTime(e.date)
+		newAddressSet([]string{"me@example.com"}).classify(e)
+	})
+}
+
+func TestNormalizeAddressIdempotent(t *testing.T) {
+	f := func(name, local, domain string) bool {
+		for _, a := range []string{local + "@" + domain, name + " <" + local + "@" + domain + ">", name} {
+			once := normalizeAddress(a)
+			if twice := normalizeAddress(once); twice != once {
+				t.Logf("%q normalized to %q, then %q", a, once, twice)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func FuzzNormalizeAddress(f *testing.F) {
+	f.Add("Jörg <Jorg@Example.COM>")
+	f.Add(`"Doe, Jane" <jane@example.com>`)
+	f.Add("<>")
+	f.Add("  spaced@example.com  ")
+	f.Add("=?UTF-8?Q?J=C3=B6rg?= <j@example.com>")
+	f.Fuzz(func(t *testing.T, a string) {
+		once := normalizeAddress(a)
+		if twice := normalizeAddress(once); twice != once {
+			t.Fatalf("%q normalized to %q, then %q", a, once, twice)
+		}
+		if once != strings.ToLower(once) {
+			t.Fatalf("%q normalized to %q, which isn't lower-cased", a, once)
+		}
+	})
+}
+
+func FuzzSplitAddresses(f *testing.F) {
+	f.Add("a@example.com,b@example.com")
+	f.Add(" , ,a@example.com,, ")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, s string) {
+		parts := splitAddresses(s)
+		for _, p := range parts {
+			if p == "" || p != strings.TrimSpace(p) || strings.Contains(p, ",") {
+				t.Fatalf("%q split into %q", s, parts)
+			}
+		}
+		again := splitAddresses(strings.Join(parts, ","))
+		if strings.Join(again, ",") != strings.Join(parts, ",") {
+			t.Fatalf("splitting %q isn't stable: %q then %q", s, parts, again)
+		}
+	})
+}
+
+// TestMailDateFormatsRoundTrip checks every supported spelling parses back
+// to the time it was formatted from, across enough times of day that 12-
+// and 24-hour clocks both meet noon and midnight.
+func TestMailDateFormatsRoundTrip(t *testing.T) {
+	f := func(unix int64) bool {
+		// Two-digit years only reach 2068.
+		tm := time.Unix(unix%3122064000, 0).In(time.Local)
+		if tm.Year() < 1970 {
+			return true
+		}
+		for _, layout := range mailDateFormats {
+			got, ok := parseMailDate(tm.Format(layout))
+			if !ok {
+				t.Logf("%q didn't parse with %q", tm.Format(layout), layout)
+				return false
+			}
+			if got.Format(layout) != tm.Format(layout) {
+				t.Logf("%q parsed to %s", tm.Format(layout), got)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseMailDateLocales(t *testing.T) {
+	for _, s := range []string{
+		"Tuesday, October 13, 2026 at 10:00:00 AM",       // en_US
+		"Tuesday, October 13, 2026 at 10:00:00\u202fAM",  // en_US, macOS 13 and later
+		"Tuesday, 13 October 2026 at 10:00:00",           // en_GB
+		"Tuesday, 13 October 2026 at 10:00:00 PM",        // en_AU
+		"October 13, 2026 at 22:00:00",                   // en_US, 24-hour clock
+		"13 October 2026 at 10:00:00 AM",                 // en_IE
+		"Tue, 13 Oct 2026 10:00:00 +0200",                // a Date header
+		"  Tuesday,  October 13, 2026  at 10:00:00 AM  ", // stray spaces
+	} {
+		if _, ok := parseMailDate(s); !ok {
+			t.Errorf("%q didn't parse", s)
+		}
+	}
+}
+
+func FuzzRelativeTime(f *testing.F) {
+	f.Add("Tuesday, October 13, 2026 at 10:00:00 AM")
+	f.Add("Dienstag, 13. Oktober 2026 um 10:00:00")
+	f.Add("13/10/2026 10:00")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, s string) {
+		got := relativeTime(s)
+		if _, ok := parseMailDate(s); !ok && got != s {
+			t.Fatalf("unparseable %q shown as %q rather than as is", s, got)
+		}
+	})
+}
+
+// TestAPIEmailRules checks that a message read back from the API is matched
+// by rules as the original is, To and Cc included.
+func TestAPIEmailRules(t *testing.T) {
+	cfg, err := parseConfig("test.toml", []byte("[[rules]]\nto = \"team@\"\naction = \"hide\"\n[[rules]]\nsender = \"boss@\"\naction = \"highlight\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		e    email
+		want ruleAction
+	}{
+		{email{sender: "ann@example.com", subject: "Standup", to: []string{"team@example.com"}}, ruleHide},
+		{email{sender: "ann@example.com", subject: "Standup", to: []string{"me@example.com"}, cc: []string{"team@example.com"}}, ruleHide},
+		{email{sender: "boss@example.com", subject: "Review", priority: priorityHigh}, ruleHighlight},
+		{email{sender: "bob@example.com", subject: "Lunch"}, ruleNone},
+	} {
+		back := mustRoundTripJSON(t, toAPIEmail(tt.e)).email()
+		if got := cfg.rules.match(back); got != tt.want {
+			t.Errorf("%s to %v cc %v: rules match the API's copy as %v, want %v", tt.e.sender, tt.e.to, tt.e.cc, got, tt.want)
+		}
+	}
+}
+
+func mustRoundTripJSON(t *testing.T, e apiEmail) apiEmail {
+	t.Helper()
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back apiEmail
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	return back
+}
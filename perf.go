@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// pollTimings splits a poll into waiting on the backend and parsing what it
+// returned.
+type pollTimings struct {
+	fetch time.Duration
+	parse time.Duration
+}
+
+// perfStats is what the --perf overlay shows: the last poll's timings and
+// how long the list took to render.
+type perfStats struct {
+	poll   pollTimings
+	render time.Duration
+}
+
+func (p *perfStats) String() string {
+	return fmt.Sprintf("fetch %s, parse %s, render %s", formatMillis(p.poll.fetch), formatMillis(p.poll.parse), formatMillis(p.render))
+}
+
+func formatMillis(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+// parseTimer accumulates the time backends spend parsing their output, so a
+// poll's total can be split into fetching and parsing.
+var parseTimer struct {
+	sync.Mutex
+	total time.Duration
+}
+
+// timeParse adds the time since start to parseTimer. Deferred at the top of
+// a parse function, it times the whole call.
+func timeParse(start time.Time) {
+	d := time.Since(start)
+	parseTimer.Lock()
+	parseTimer.total += d
+	parseTimer.Unlock()
+}
+
+// takeParseTime returns the parse time accumulated so far and resets it.
+func takeParseTime() time.Duration {
+	parseTimer.Lock()
+	defer parseTimer.Unlock()
+	d := parseTimer.total
+	parseTimer.total = 0
+	return d
+}
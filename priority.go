@@ -0,0 +1,111 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// priority is a message's X-Priority or Importance header, reduced to
+// three levels.
+type priority int
+
+const (
+	priorityNormal priority = iota
+	priorityHigh
+	priorityLow
+)
+
+func (p priority) String() string {
+	switch p {
+	case priorityHigh:
+		return "high"
+	case priorityLow:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// glyph is drawn between the addressing badge and the subject.
+func (p priority) glyph() string {
+	switch p {
+	case priorityHigh:
+		return "!"
+	case priorityLow:
+		return "↓"
+	default:
+		return " "
+	}
+}
+
+// rank orders priorities for sorting, high first.
+func (p priority) rank() int {
+	switch p {
+	case priorityHigh:
+		return 0
+	case priorityLow:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// parsePriority interprets an X-Priority value ("1 (Highest)", "5") or an
+// Importance/Priority value ("high", "urgent", "non-urgent").
+func parsePriority(v string) priority {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if v == "" {
+		return priorityNormal
+	}
+	switch v[0] {
+	case '1', '2':
+		return priorityHigh
+	case '4', '5':
+		return priorityLow
+	}
+	switch {
+	case strings.HasPrefix(v, "high"), strings.HasPrefix(v, "urgent"):
+		return priorityHigh
+	case strings.HasPrefix(v, "low"), strings.HasPrefix(v, "non-urgent"):
+		return priorityLow
+	}
+	return priorityNormal
+}
+
+// sortMode is the list ordering cycled with 's'.
+type sortMode int
+
+const (
+	sortReceived sortMode = iota
+	sortPriority
+	sortSender
+)
+
+func (s sortMode) String() string {
+	switch s {
+	case sortPriority:
+		return "priority"
+	case sortSender:
+		return "sender"
+	default:
+		return "received"
+	}
+}
+
+func (s sortMode) next() sortMode {
+	return (s + 1) % 3
+}
+
+// sortEmails orders emails in place. sortReceived keeps the mailbox order.
+func sortEmails(emails []email, mode sortMode) {
+	switch mode {
+	case sortPriority:
+		sort.SliceStable(emails, func(i, j int) bool {
+			return emails[i].priority.rank() < emails[j].priority.rank()
+		})
+	case sortSender:
+		sort.SliceStable(emails, func(i, j int) bool {
+			return strings.ToLower(emails[i].sender) < strings.ToLower(emails[j].sender)
+		})
+	}
+}
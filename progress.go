@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// pollProgress is how far one poll has got fetching headers, by account.
+// Backends that fetch in chunks report to it as they go, and the loading
+// screen reads it on each tick.
+type pollProgress struct {
+	mu       sync.Mutex
+	accounts map[string]headerProgress
+}
+
+// headerProgress is a fetch's progress: the messages fetched so far, newest
+// first, out of total.
+type headerProgress struct {
+	emails []email
+	total  int
+}
+
+// report records account's fetch so far. A nil p, a fetch that isn't
+// part of a poll, ignores it.
+func (p *pollProgress) report(account string, emails []email, total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.accounts == nil {
+		p.accounts = map[string]headerProgress{}
+	}
+	p.accounts[account] = headerProgress{emails: slices.Clone(emails), total: total}
+}
+
+// current adds up the poll's accounts.
+func (p *pollProgress) current() headerProgress {
+	var all headerProgress
+	if p == nil {
+		return all
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, a := range p.accounts {
+		all.emails = append(all.emails, a.emails...)
+		all.total += a.total
+	}
+	return all
+}
+
+// progressTracker hands each poll its own pollProgress, so polls that
+// overlap don't write over each other's, and keeps the latest for the
+// loading screen. The model's copies share it.
+type progressTracker struct {
+	mu   sync.Mutex
+	poll *pollProgress
+}
+
+// begin starts tracking a new poll.
+func (t *progressTracker) begin() *pollProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.poll = &pollProgress{}
+	return t.poll
+}
+
+// current is the latest poll's progress.
+func (t *progressTracker) current() headerProgress {
+	t.mu.Lock()
+	poll := t.poll
+	t.mu.Unlock()
+	return poll.current()
+}
+
+// progressWidth is the width of the loading screen's progress bar.
+const progressWidth = 30
+
+// view is the loading screen's progress bar and count.
+func (p headerProgress) view() string {
+	filled := progressWidth * len(p.emails) / max(p.total, 1)
+	bar := lipgloss.NewStyle().Foreground(accentColor).Render(strings.Repeat("█", filled)) +
+		lipgloss.NewStyle().Foreground(dimColor).Render(strings.Repeat("░", progressWidth-filled))
+	return bar + "\n" + metaStyle.Render(fmt.Sprintf("%d of %d messages", len(p.emails), p.total))
+}
+
+// usePartial shows the messages fetched so far and stops waiting on the
+// poll, which replaces them when it's done.
+func (m *model) usePartial() tea.Cmd {
+	n := len(m.progress.emails)
+	cmd := m.applyEmails(emailsMsg{emails: m.progress.emails, total: m.progress.total})
+	m.activity = idle
+	m.setNotice(fmt.Sprintf("Showing the first %d of %d while the rest loads", n, m.progress.total))
+	return cmd
+}
+
+// pollMail polls the backend for the list.
+func (m model) pollMail() tea.Cmd {
+	return fetchEmails(m.trackedMail(), m.fetchLimit())
+}
+
+// trackedMail is the backend the list is read from, for a new poll whose
+// progress the loading screen follows.
+func (m model) trackedMail() mailProvider {
+	p := m.mail()
+	if r, ok := p.(progressReporter); ok {
+		p = r.reporting(m.fetches.begin())
+	}
+	return p
+}
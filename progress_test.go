@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestProgressTracker(t *testing.T) {
+	var tr progressTracker
+	if got := tr.current(); got.total != 0 {
+		t.Errorf("progress before any poll = %+v", got)
+	}
+	first := tr.begin()
+	first.report("work", []email{{id: "1"}}, 20)
+	first.report("home", nil, 5)
+	if got := tr.current(); len(got.emails) != 1 || got.total != 25 {
+		t.Errorf("progress = %d of %d, want 1 of 25", len(got.emails), got.total)
+	}
+
+	second := tr.begin()
+	first.report("work", []email{{id: "1"}, {id: "2"}}, 20)
+	if got := tr.current(); len(got.emails) != 0 || got.total != 0 {
+		t.Errorf("an older poll's report shows in the new one's progress: %+v", got)
+	}
+	second.report("work", []email{{id: "3"}}, 10)
+	if got := tr.current(); len(got.emails) != 1 || got.total != 10 {
+		t.Errorf("progress = %d of %d, want 1 of 10", len(got.emails), got.total)
+	}
+
+	var none *pollProgress
+	none.report("work", nil, 10)
+}
+
+func TestUsePartial(t *testing.T) {
+	m, _ := newTestModel(t, "Offsite")
+	m.activity = polling
+	m.progress = headerProgress{emails: []email{{id: "9", sender: "ann@example.com", subject: "Budget"}}, total: 20}
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(model)
+	if m.busy() {
+		t.Error("still waiting on the poll")
+	}
+	if got := listSubjects(m); got != "Budget" {
+		t.Errorf("list = %q, want the partial fetch", got)
+	}
+
+	// With a filter applied, the list only shows the new messages once
+	// the command applyEmails returns has run.
+	m = keys(m, "/Bud")
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnter})
+	m.activity = polling
+	m.progress = headerProgress{emails: []email{{id: "10", sender: "bob@example.com", subject: "Budget v2"}}, total: 20}
+	next, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("the list's refilter was dropped")
+	}
+	m = update(next.(model), cmd())
+	if got := listSubjects(m); got != "Budget v2" {
+		t.Errorf("list = %q, want the partial fetch", got)
+	}
+}
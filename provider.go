@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+// mailProvider is a mail backend. Implementations honor the mailbox
+// selection from the config so it means the same thing everywhere.
+type mailProvider interface {
+	// name describes the backend for diagnostics.
+	name() string
+	// unread returns the unread messages of the monitored mailboxes.
+	unread() ([]email, error)
+	// content returns e's body and attachments and marks it read.
+	content(e email) (messageContent, error)
+	// markRead marks the given messages read.
+	markRead(emails []email) error
+}
+
+// mailboxBrowser is implemented by backends that can enumerate their
+// mailboxes and read just one of them.
+type mailboxBrowser interface {
+	listMailboxes() ([]mailboxInfo, error)
+	// scoped returns a provider that reads only the mailbox in s.
+	scoped(s mailScope) mailProvider
+}
+
+// pager is implemented by backends that can read past the poll limit and
+// count the unread mail a poll leaves out.
+type pager interface {
+	// unreadPage returns up to limit unread messages, like unread, and how
+	// many unread messages there are in all.
+	unreadPage(limit int) ([]email, int, error)
+}
+
+// canceler is implemented by backends whose reads can be given up on.
+type canceler interface {
+	// withContext returns the backend with its reads stopped once ctx is
+	// done.
+	withContext(ctx context.Context) mailProvider
+}
+
+// progressReporter is implemented by backends that can say how far a poll
+// has got.
+type progressReporter interface {
+	// reporting returns the backend with its polls reporting to p.
+	reporting(p *pollProgress) mailProvider
+}
+
+// mailScope picks a single mailbox of an account. The zero scope means the
+// configured default: the unified inbox or the mailbox sweep.
+type mailScope struct {
+	account string
+	mailbox string
+}
+
+// mailboxInfo describes one mailbox for the picker.
+type mailboxInfo struct {
+	account string
+	path    string
+	unread  int
+	inbox   bool
+}
+
+// messageEditor is implemented by backends that can change a single
+// message: flag it unread again, or move it to the trash or the archive.
+type messageEditor interface {
+	markUnread(e email) error
+	trash(e email) error
+	archive(e email) error
+}
+
+// flagger is implemented by backends that can set a message's color flag.
+type flagger interface {
+	setFlag(e email, f flagColor) error
+}
+
+// labeler is implemented by backends whose messages have labels rather
+// than a single mailbox: Gmail's.
+type labeler interface {
+	labels(e email) (all, on []string, err error)
+	relabel(e email, add, remove []string) error
+}
+
+// bulkEditor is implemented by backends that can move many messages in
+// one go, rather than one call per message.
+type bulkEditor interface {
+	trashAll(emails []email) error
+	archiveAll(emails []email) error
+}
+
+// draftStore is implemented by backends that can list, send and delete
+// drafts.
+type draftStore interface {
+	drafts() ([]draft, error)
+	draftContent(id string) (string, error)
+	deleteDraft(id string) error
+	// compose sends msg, or saves it to Drafts when send is false.
+	compose(msg outgoingMessage, send bool) error
+}
+
+// peeker is implemented by backends that can open a message without
+// marking it read.
+type peeker interface {
+	peek(e email) (messageContent, error)
+}
+
+// searcher is implemented by backends that can search whole mailboxes,
+// read mail included, translating the query's operators into their own
+// search.
+type searcher interface {
+	search(q mailSearch) ([]email, error)
+}
+
+// maxSearchResults caps how many messages a search returns.
+const maxSearchResults = 100
+
+// attachmentSaver is implemented by backends that can write an attachment
+// to disk.
+type attachmentSaver interface {
+	saveAttachment(e email, a attachment, dest string) error
+}
+
+// messageContent is an opened message. id is the backend's stable handle for
+// it, the same as the email's. html is the message's HTML part, if it has
+// one, as markup.
+type messageContent struct {
+	id          string
+	body        string
+	html        string
+	attachments []attachment
+	// source is the raw message, when the backend fetched it.
+	source string
+}
+
+// newProvider builds the backend selected in cfg. Mail.app is the default
+// on macOS; elsewhere a backend has to be configured. [[accounts]], when
+// there are any, take the place of [backend].
+func newProvider(cfg config) (mailProvider, error) {
+	var p mailProvider
+	var err error
+	if len(cfg.Accounts) > 0 {
+		p, err = newMultiProvider(cfg)
+	} else {
+		p, err = newBackend(cfg.Backend, cfg)
+	}
+	if err != nil || !cfg.dryRun {
+		return p, err
+	}
+	return dryRunProvider{p}, nil
+}
+
+// newBackend builds the backend b describes.
+func newBackend(b backendConfig, cfg config) (mailProvider, error) {
+	switch b.Type {
+	case "":
+		if runtime.GOOS != "darwin" {
+			return nil, fmt.Errorf("no mail backend configured; set [backend] type = \"imap\" or \"maildir\"")
+		}
+		return mailAppProvider{mailboxes: cfg.Mailboxes, limit: cfg.Poll.fetchLimit()}, nil
+	case "mail.app":
+		return mailAppProvider{mailboxes: cfg.Mailboxes, limit: cfg.Poll.fetchLimit()}, nil
+	case "imap":
+		return newIMAPProvider(b, cfg.Mailboxes, cfg.Poll.fetchLimit())
+	case "maildir":
+		return newMaildirProvider(b, cfg.Mailboxes, cfg.Poll.fetchLimit())
+	default:
+		return nil, fmt.Errorf("unknown backend %q", b.Type)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// readerKeys handles keys in the detail view of an open message.
+func (m *model) readerKeys(key string) (tea.Cmd, bool) {
+	if cmd, ok := m.commonKeys(key); ok {
+		return cmd, true
+	}
+	switch key {
+	case "q", "esc":
+		m.leaveDetail()
+		return nil, true
+	case "a":
+		m.openSenderMenu()
+		return nil, true
+	case "s":
+		if m.currentEmail != nil && len(m.attachments) > 0 {
+			saver, ok := m.provider.(attachmentSaver)
+			if !ok {
+				m.setNotice("Attachments can't be saved from " + m.provider.name())
+				return nil, true
+			}
+			a := m.attachments[m.attachment]
+			if m.cfg.Attachments.Scanner != "" {
+				m.setNotice("Scanning " + a.name + "…")
+			}
+			return m.track(saveToDisk(saver, m.cfg.Attachments, *m.currentEmail, a)), true
+		}
+	case "R":
+		if m.currentEmail != nil {
+			if m.draftStore() == nil {
+				m.setNotice("Replying isn't supported by " + m.provider.name())
+				return nil, true
+			}
+			m.showComposer(newReply(*m.currentEmail, m.emailBody))
+			return tea.Batch(textarea.Blink, m.warnIfAway(*m.currentEmail)), true
+		}
+	case "S":
+		if m.emailSource != "" {
+			m.showSource = !m.showSource
+			m.fullBody = false
+			m.viewport.GotoTop()
+			return m.setDetailContent(), true
+		}
+	case "n":
+		if m.currentEmail != nil {
+			return m.track(appendToNotes(m.cfg.Notes, *m.currentEmail, m.emailBody)), true
+		}
+	case "tab":
+		if len(m.attachments) > 0 {
+			m.attachment = (m.attachment + 1) % len(m.attachments)
+			return nil, true
+		}
+	case "w":
+		if m.currentEmail != nil {
+			m.setPreformatted(!m.preformatted)
+			if m.preformatted {
+				m.setNotice("Preformatted: lines as sent, ←/→ to scroll sideways")
+			} else {
+				m.setNotice("Wrapping long lines")
+			}
+			return m.setDetailContent(), true
+		}
+	case "H":
+		if m.emailHTML != "" {
+			m.rawHTML = !m.rawHTML
+			m.viewport.GotoTop()
+			return m.setDetailContent(), true
+		}
+	case "L":
+		if m.truncated {
+			m.fullBody = true
+			return m.setDetailContent(), true
+		}
+	case "z":
+		if len(m.attachments) > 0 && m.attachments[m.attachment].text != "" {
+			m.unfolded[m.attachment] = !m.unfolded[m.attachment]
+			return m.setDetailContent(), true
+		}
+	case "v":
+		if m.currentEmail != nil && len(m.attachments) > 0 {
+			saver, ok := m.provider.(attachmentSaver)
+			if !ok {
+				m.setNotice("Attachments can't be saved from " + m.provider.name())
+				return nil, true
+			}
+			a := m.attachments[m.attachment]
+			m.setNotice("Previewing " + a.name + "…")
+			return quickLook(saver, *m.currentEmail, a), true
+		}
+	case "t":
+		if m.currentEmail != nil && m.cfg.Translate.foreign(m.language) {
+			if m.translation != "" {
+				m.translated = !m.translated
+				return m.setDetailContent(), true
+			}
+			if m.cfg.Translate.Command == "" {
+				m.setNotice("Set [translate] command to translate messages")
+				return nil, true
+			}
+			m.setNotice("Translating from " + languageNames[m.language] + "…")
+			return translate(m.cfg.Translate, m.currentEmail.id, m.emailBody, m.language), true
+		}
+	case "P":
+		if m.currentEmail != nil {
+			return m.readAloud(), true
+		}
+	case "p":
+		if m.speech != nil {
+			if err := m.speech.toggle(); err != nil {
+				m.setNotice(fmt.Sprintf("Couldn't pause: %v", err))
+			} else if m.speech.paused {
+				m.setNotice("Paused • p to resume")
+			} else {
+				m.setNotice("Reading aloud")
+			}
+			return nil, true
+		}
+	case "M":
+		if m.currentEmail != nil && m.receiptTo != "" {
+			if m.draftStore() == nil {
+				m.setNotice("Read receipts can't be sent from " + m.provider.name())
+				return nil, true
+			}
+			to := m.receiptTo
+			m.receiptTo = ""
+			return m.track(sendReadReceipt(m.draftStore(), *m.currentEmail, to)), true
+		}
+	case "l":
+		if len(m.links) > 0 {
+			m.openLinks()
+			return nil, true
+		}
+	case "T":
+		if m.currentEmail != nil {
+			m.setNotice("Creating ticket…")
+			return m.track(createTicket(m.cfg.Ticket, *m.currentEmail, m.emailBody)), true
+		}
+	}
+	return nil, false
+}
+
+func (m *model) updateReader(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return cmd
+}
+
+// viewReader draws the open message in a box, with its headers and
+// attachments above the scrolling body.
+func (m model) viewReader(status string) string {
+	if m.currentEmail == nil {
+		return m.viewInbox(status)
+	}
+	boxWidth := m.width - 4
+	if boxWidth < 20 {
+		boxWidth = 20
+	}
+
+	header := headerStyle.Render(m.currentEmail.subject)
+	if dot := m.currentEmail.flag.dot(); dot != "" {
+		header += " " + dot
+	}
+	meta := metaStyle.Render("From: ") + senderStyle.Render(m.currentEmail.sender) + "\n" +
+		metaStyle.Render("Date: ") + dateStyle.Render(m.currentEmail.date)
+	if via := m.cfg.Identity.me().via(*m.currentEmail, newAddressSet(m.cfg.Identity.Aliases)); via != "" {
+		meta += "\n" + metaStyle.Render("Via: ") + dateStyle.Render(via)
+	}
+	if len(m.attachments) > 0 {
+		names := make([]string, len(m.attachments))
+		for i, a := range m.attachments {
+			style := metaStyle
+			if i == m.attachment {
+				style = senderStyle.Underline(true)
+			}
+			names[i] = style.Render(a.String())
+		}
+		meta += "\n" + metaStyle.Render("Attachments: ") + strings.Join(names, metaStyle.Render(", "))
+	}
+	if m.cfg.Translate.foreign(m.language) {
+		meta += "\n" + metaStyle.Render("Language: ") + dateStyle.Render(languageNames[m.language])
+	}
+	if m.receiptTo != "" {
+		note := "Read receipt requested by " + m.receiptTo
+		if receiptMismatch(*m.currentEmail, m.receiptTo) {
+			note += ", not the sender"
+		}
+		meta += "\n" + metaStyle.Render("Receipt: ") + dateStyle.Render(note+" • not sent")
+	}
+	innerDivider := dividerStyle.Render(strings.Repeat("─", boxWidth-4))
+
+	content := fmt.Sprintf("%s\n%s\n%s\n\n%s",
+		header,
+		meta,
+		innerDivider,
+		m.viewport.View(),
+	)
+
+	detailBox := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(accentColor).
+		Padding(1, 2).
+		Width(boxWidth)
+
+	bindings := [][]string{
+		{"↑/↓", "scroll"},
+		{"R", "reply"},
+		{"u", "mark unread"},
+		{"e", "archive"},
+		{"d", "delete"},
+		{"n", "append to notes"},
+		{"T", "create ticket"},
+	}
+	if m.emailHTML != "" {
+		if m.rawHTML {
+			bindings = append(bindings, []string{"H", "rendered"})
+		} else {
+			bindings = append(bindings, []string{"H", "raw HTML"})
+		}
+	}
+	switch {
+	case m.speech == nil:
+		bindings = append(bindings, []string{"P", "read aloud"})
+	case m.speech.paused:
+		bindings = append(bindings, []string{"p", "resume"}, []string{"P", "stop reading"})
+	default:
+		bindings = append(bindings, []string{"p", "pause"}, []string{"P", "stop reading"})
+	}
+	if m.receiptTo != "" {
+		bindings = append(bindings, []string{"M", "send read receipt"})
+	}
+	if m.preformatted {
+		bindings = append(bindings, []string{"←/→", "scroll sideways"}, []string{"w", "wrap lines"})
+	}
+	if len(m.links) > 0 {
+		bindings = append(bindings, []string{"l", "links"})
+	}
+	if m.cfg.Translate.foreign(m.language) {
+		if m.translated {
+			bindings = append(bindings, []string{"t", "original only"})
+		} else {
+			bindings = append(bindings, []string{"t", "translate"})
+		}
+	}
+	if m.truncated {
+		bindings = append(bindings, []string{"L", "load all"})
+	}
+	if m.showSource {
+		bindings = append(bindings, []string{"S", "message"})
+	} else if m.unreadable && m.emailSource != "" {
+		bindings = append(bindings, []string{"S", "raw source"})
+	}
+	if m.unreadable && canOpenInMail(*m.currentEmail) {
+		bindings = append(bindings, []string{"O", "open in Mail.app"})
+	}
+	if len(m.attachments) > 0 {
+		bindings = append(bindings, []string{"tab", "next attachment"}, []string{"s", "save"}, []string{"v", "quick look"})
+		if m.attachments[m.attachment].text != "" {
+			bindings = append(bindings, []string{"z", "expand/collapse"})
+		}
+	}
+	bindings = append(bindings, []string{"?", "all keys"}, []string{"q", "back"}, []string{"esc", "back to list"})
+	helpBar := renderHelpBar(m.width, m.cfg.keys.relabel(detailView, bindings))
+	return "\n" + lipgloss.NewStyle().PaddingLeft(2).Render(detailBox.Render(content)) + "\n" + status + helpBar
+}
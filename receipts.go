@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// receiptRequest returns the address a message's Disposition-Notification-To
+// header asks for a read receipt at, or "" when it doesn't ask. mailnotify
+// never answers these on its own; M in the detail view sends one.
+func receiptRequest(source string) string {
+	if source == "" {
+		return ""
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(headerOnly(source)))
+	if err != nil {
+		return ""
+	}
+	to := strings.TrimSpace(msg.Header.Get("Disposition-Notification-To"))
+	if to == "" {
+		return ""
+	}
+	if addr, err := mail.ParseAddress(decodeHeader(to)); err == nil {
+		return addr.Address
+	}
+	return decodeHeader(to)
+}
+
+// headerOnly cuts source at the blank line ending its header, so a large
+// message isn't read twice just to check one field.
+func headerOnly(source string) []byte {
+	for _, sep := range []string{"\r\n\r\n", "\n\n"} {
+		if i := strings.Index(source, sep); i >= 0 {
+			return []byte(source[:i+len(sep)])
+		}
+	}
+	return []byte(source + "\n\n")
+}
+
+// receiptMismatch reports whether a receipt for e would go somewhere other
+// than its sender, which RFC 8098 says users should be warned about.
+func receiptMismatch(e email, to string) bool {
+	return normalizeAddress(to) != normalizeAddress(e.sender)
+}
+
+// readReceipt is the receipt for e: a plain note in the form of the
+// human-readable part of an RFC 8098 notification, as Mail.app can't send
+// a multipart/report.
+func readReceipt(e email, to string, now time.Time) outgoingMessage {
+	body := fmt.Sprintf("This is a read receipt for the message you sent on %s.\n\nSubject: %s\n\nIt was displayed on %s. This doesn't guarantee that it was read or understood.",
+		e.date, e.subject, now.Format("Mon, 2 Jan 2006 15:04 MST"))
+	return outgoingMessage{to: []string{to}, subject: "Read: " + e.subject, body: body}
+}
+
+func sendReadReceipt(s draftStore, e email, to string) tea.Cmd {
+	return func() tea.Msg {
+		return draftActionMsg{done: "Sent a read receipt to " + to, err: s.compose(readReceipt(e, to, time.Now()), true)}
+	}
+}
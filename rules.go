@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// ruleAction is what a [[rules]] entry does to the mail it matches.
+type ruleAction int
+
+const (
+	ruleNone ruleAction = iota
+	// ruleHide keeps the message out of the list, like the focus filter,
+	// and out of notifications.
+	ruleHide
+	ruleHighlight
+	// rulePriority lists the message first, with a badge.
+	rulePriority
+	// ruleReply answers the message with the rule's reply template, once a
+	// day per sender.
+	ruleReply
+)
+
+var ruleActions = map[string]ruleAction{
+	"hide":      ruleHide,
+	"highlight": ruleHighlight,
+	"priority":  rulePriority,
+	"reply":     ruleReply,
+}
+
+// rule is a [[rules]] entry parsed. A message matches when it matches every
+// condition the entry sets.
+type rule struct {
+	sender  *regexp.Regexp
+	subject *regexp.Regexp
+	// to is matched against each of the To and Cc addresses.
+	to *regexp.Regexp
+	// domain is a glob on the domain of the sender's address.
+	domain string
+	action ruleAction
+	reply  *template.Template
+}
+
+type rules []rule
+
+// parseRules compiles the [[rules]] entries. Patterns are case-insensitive.
+func parseRules(entries []ruleConfig) (rules, error) {
+	var rs rules
+	for i, c := range entries {
+		action, ok := ruleActions[strings.ToLower(c.Action)]
+		if !ok {
+			return nil, fmt.Errorf("rule %d: action must be hide, highlight, priority or reply, not %q", i+1, c.Action)
+		}
+		if c.Sender == "" && c.Domain == "" && c.Subject == "" && c.To == "" {
+			return nil, fmt.Errorf("rule %d: needs a sender, domain, subject or to to match", i+1)
+		}
+		if (action == ruleReply) != (c.Reply != "") {
+			return nil, fmt.Errorf("rule %d: reply is the template for action \"reply\", and only for it", i+1)
+		}
+		r := rule{domain: strings.ToLower(c.Domain), action: action}
+		if _, err := path.Match(r.domain, ""); err != nil {
+			return nil, fmt.Errorf("rule %d: domain: %w", i+1, err)
+		}
+		var err error
+		if r.sender, err = compileRulePattern(c.Sender); err != nil {
+			return nil, fmt.Errorf("rule %d: sender: %w", i+1, err)
+		}
+		if r.subject, err = compileRulePattern(c.Subject); err != nil {
+			return nil, fmt.Errorf("rule %d: subject: %w", i+1, err)
+		}
+		if r.to, err = compileRulePattern(c.To); err != nil {
+			return nil, fmt.Errorf("rule %d: to: %w", i+1, err)
+		}
+		if c.Reply != "" {
+			if r.reply, err = template.New("reply").Parse(c.Reply); err != nil {
+				return nil, fmt.Errorf("rule %d: reply: %w", i+1, err)
+			}
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+func compileRulePattern(s string) (*regexp.Regexp, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return regexp.Compile("(?i)" + s)
+}
+
+func (r rule) matches(e email) bool {
+	if r.sender != nil && !r.sender.MatchString(e.sender) {
+		return false
+	}
+	if r.subject != nil && !r.subject.MatchString(e.subject) {
+		return false
+	}
+	if r.to != nil && !slices.ContainsFunc(slices.Concat(e.to, e.cc), r.to.MatchString) {
+		return false
+	}
+	if r.domain != "" {
+		addr := normalizeAddress(e.sender)
+		domain := addr[strings.LastIndexByte(addr, '@')+1:]
+		if ok, _ := path.Match(r.domain, domain); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns the action of the first rule e matches.
+func (rs rules) match(e email) ruleAction {
+	if r, ok := rs.first(e); ok {
+		return r.action
+	}
+	return ruleNone
+}
+
+// first returns the first rule e matches.
+func (rs rules) first(e email) (rule, bool) {
+	for _, r := range rs {
+		if r.matches(e) {
+			return r, true
+		}
+	}
+	return rule{}, false
+}
+
+// notifiable drops the messages a hide rule matches.
+func (rs rules) notifiable(emails []email) []email {
+	if len(rs) == 0 {
+		return emails
+	}
+	var kept []email
+	for _, e := range emails {
+		if rs.match(e) != ruleHide {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// prioritize moves the messages a priority rule matches to the front,
+// keeping the order within each part.
+func (rs rules) prioritize(emails []email) {
+	if len(rs) == 0 {
+		return
+	}
+	first := make([]email, 0, len(emails))
+	var rest []email
+	for _, e := range emails {
+		if rs.match(e) == rulePriority {
+			first = append(first, e)
+		} else {
+			rest = append(rest, e)
+		}
+	}
+	copy(emails, append(first, rest...))
+}
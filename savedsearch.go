@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// savedSearchFile holds the searches saved from the search view. It's in
+// the state directory, so they follow the rest of the state to other
+// machines.
+const savedSearchFile = "searches.json"
+
+// maxSavedSearchKeys is how many saved searches get a number key in the
+// list, 1 through 9.
+const maxSavedSearchKeys = 9
+
+type savedSearch struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// savedSearches are in the order they were saved, which is the order of
+// their number keys.
+type savedSearches []savedSearch
+
+func loadSavedSearches(dir string) (savedSearches, error) {
+	return loadStateFile[savedSearches](dir, savedSearchFile)
+}
+
+// save writes s to dir through a temporary file.
+func (s savedSearches) save(dir string) error {
+	return writeStateFile(dir, savedSearchFile, s)
+}
+
+// with returns s with search added, or replacing the one of the same name
+// in its place, and its position.
+func (s savedSearches) with(search savedSearch) (savedSearches, int) {
+	if i := slices.IndexFunc(s, func(o savedSearch) bool { return strings.EqualFold(o.Name, search.Name) }); i >= 0 {
+		s = slices.Clone(s)
+		s[i] = search
+		return s, i
+	}
+	return append(slices.Clone(s), search), len(s)
+}
+
+func newSearchNameInput() textinput.Model {
+	in := textinput.New()
+	in.Prompt = "Save as: "
+	in.Placeholder = "a name for this search"
+	in.CharLimit = 40
+	return in
+}
+
+// startSaving asks for a name to save the shown search under.
+func (s *searchScreen) startSaving() tea.Cmd {
+	if s.query == "" {
+		return nil
+	}
+	s.naming.SetValue("")
+	for _, saved := range s.saved {
+		if saved.Query == s.query {
+			s.naming.SetValue(saved.Name)
+		}
+	}
+	s.naming.CursorEnd()
+	return s.naming.Focus()
+}
+
+// searchNameKeys handles keys while a search is being named: enter saves
+// it and esc gives up.
+func (m *model) searchNameKeys(key string) (tea.Cmd, bool) {
+	switch key {
+	case "esc":
+		m.search.naming.Blur()
+		return nil, true
+	case "enter":
+		name := strings.TrimSpace(m.search.naming.Value())
+		if name == "" {
+			return nil, true
+		}
+		// Saved on top of what's on file, for searches saved elsewhere.
+		saved, err := loadSavedSearches(m.cfg.State.dir())
+		i := 0
+		if err == nil {
+			saved, i = saved.with(savedSearch{Name: name, Query: m.search.query})
+			err = saved.save(m.cfg.State.dir())
+		}
+		if err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't save the search: %v", err))
+			return nil, true
+		}
+		m.search.saved = saved
+		m.search.naming.Blur()
+		if i < maxSavedSearchKeys {
+			m.setNotice(fmt.Sprintf("Saved %q; %d in the list runs it", name, i+1))
+		} else {
+			m.setNotice(fmt.Sprintf("Saved %q; only the first %d get a number key", name, maxSavedSearchKeys))
+		}
+		return nil, true
+	}
+	return nil, false
+}
+
+// runSavedSearch runs the saved search with number key n.
+func (m *model) runSavedSearch(n int) tea.Cmd {
+	if n > len(m.search.saved) {
+		m.setNotice(fmt.Sprintf("No saved search on %d; save one with S in search results", n))
+		return nil
+	}
+	m.mode = searchView
+	m.search.input.SetValue(m.search.saved[n-1].Query)
+	m.search.input.Blur()
+	return m.runSearch(m.search.saved[n-1].Query)
+}
+
+// savedLine lists the saved searches with their number keys.
+func (s searchScreen) savedLine() string {
+	var parts []string
+	for i, saved := range s.saved[:min(len(s.saved), maxSavedSearchKeys)] {
+		parts = append(parts, senderStyle.Render(fmt.Sprint(i+1))+" "+metaStyle.Render(saved.Name))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return metaStyle.Render("Saved, from the list: ") + strings.Join(parts, metaStyle.Render(" • "))
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule restricts automatic polling to a set of weekly windows. An empty
+// schedule allows polling at any time.
+type schedule []pollWindow
+
+// pollWindow is a daily time range on some days of the week, in minutes
+// since midnight. A window whose end is not after its start runs past
+// midnight into the next day.
+type pollWindow struct {
+	days       [7]bool
+	start, end int
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseSchedule parses windows like "mon-fri 09:00-18:00", "sat,sun 10-14"
+// or "08:30-17:00" (every day).
+func parseSchedule(windows []string) (schedule, error) {
+	var s schedule
+	for _, spec := range windows {
+		w, err := parsePollWindow(spec)
+		if err != nil {
+			return nil, fmt.Errorf("window %q: %w", spec, err)
+		}
+		s = append(s, w)
+	}
+	return s, nil
+}
+
+func parsePollWindow(spec string) (pollWindow, error) {
+	var w pollWindow
+	fields := strings.Fields(strings.ToLower(spec))
+	switch len(fields) {
+	case 1:
+		for d := range w.days {
+			w.days[d] = true
+		}
+	case 2:
+		if err := parseDays(fields[0], &w.days); err != nil {
+			return w, err
+		}
+		fields = fields[1:]
+	default:
+		return w, fmt.Errorf("want [days] start-end")
+	}
+
+	from, to, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return w, fmt.Errorf("time range %q needs a start and an end", fields[0])
+	}
+	var err error
+	if w.start, err = parseClock(from); err != nil {
+		return w, err
+	}
+	if w.end, err = parseClock(to); err != nil {
+		return w, err
+	}
+	return w, nil
+}
+
+// parseDays sets days from a comma-separated list of names and ranges such
+// as "mon-fri,sun". Ranges may wrap around the weekend.
+func parseDays(spec string, days *[7]bool) error {
+	for _, part := range strings.Split(spec, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdayNames[from]
+		if !ok {
+			return fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdayNames[to]; !ok {
+				return fmt.Errorf("unknown day %q", to)
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseClock parses "9", "09:30" or "24:00" into minutes since midnight.
+func parseClock(s string) (int, error) {
+	hh, mm, hasMinutes := strings.Cut(s, ":")
+	h, err := strconv.Atoi(hh)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	m := 0
+	if hasMinutes {
+		if m, err = strconv.Atoi(mm); err != nil || len(mm) != 2 {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+	}
+	if h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return h*60 + m, nil
+}
+
+// allows reports whether t falls inside one of the windows.
+func (s schedule) allows(t time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+	min := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	yesterday := (day + 6) % 7
+	for _, w := range s {
+		if w.start < w.end {
+			if w.days[day] && min >= w.start && min < w.end {
+				return true
+			}
+			continue
+		}
+		if (w.days[day] && min >= w.start) || (w.days[yesterday] && min < w.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextOpen returns the start of the next window after t, or the zero time
+// when no window opens within a week.
+func (s schedule) nextOpen(t time.Time) time.Time {
+	t = t.Truncate(time.Minute)
+	for i := 1; i <= 7*24*60; i++ {
+		next := t.Add(time.Duration(i) * time.Minute)
+		if s.allows(next) {
+			return next
+		}
+	}
+	return time.Time{}
+}
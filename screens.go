@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// screen routes one view of the UI. Update handles the overlays, the
+// spinner and ctrl+c itself and routes the other keys, messages and drawing
+// to the current mode's screen, so a new view is a new entry in screens
+// rather than more cases in Update. Each screen keeps its state in a
+// struct of its own, such as composer, searchScreen or watchScreen, which
+// updates and draws what's its own; the screen's functions here work on the
+// whole model, for the keys that reach the mail, the notice or another
+// view. Views share the mail they show, and a screen switches to another
+// by setting mode.
+type screen struct {
+	// keys handles a key, already translated to its default binding by the
+	// [keys] table. It reports false to pass the key on to update.
+	keys func(m *model, key string) (tea.Cmd, bool)
+	// update hands any other message to the screen's list, viewport or
+	// input.
+	update func(m *model, msg tea.Msg) tea.Cmd
+	// view draws the screen; status is the notice line, for the screens
+	// that don't show the notice in their own status line.
+	view func(m model, status string) string
+}
+
+var screens = map[viewMode]screen{
+	listView:     {(*model).inboxKeys, (*model).updateInbox, model.viewInbox},
+	detailView:   {(*model).readerKeys, (*model).updateReader, model.viewReader},
+	draftsView:   {(*model).draftListKeys, (*model).updateDraftList, model.viewDraftList},
+	composeView:  {(*model).composerKeys, (*model).updateComposer, model.viewComposer},
+	mailboxView:  {(*model).mailboxPickerKeys, (*model).updateMailboxPicker, model.viewMailboxPicker},
+	searchView:   {(*model).searchKeys, (*model).updateSearch, model.viewSearch},
+	settingsView: {(*model).settingsKeys, (*model).updateSettings, model.viewSettings},
+	accountsView: {(*model).accountsKeys, (*model).updateAccounts, model.viewAccounts},
+	linksView:    {(*model).linksKeys, (*model).updateLinks, model.viewLinks},
+	snoozeView:   {(*model).snoozeKeys, (*model).updateSnooze, model.viewSnooze},
+	checksView:   {(*model).checksKeys, (*model).updateChecks, model.viewChecks},
+	logView:      {(*model).logKeys, (*model).updateLog, model.viewLog},
+	stagingView:  {(*model).stagingKeys, (*model).updateStaging, model.viewStaging},
+	senderView:   {(*model).senderKeys, (*model).updateSender, model.viewSender},
+	watchView:    {(*model).watchKeys, (*model).updateWatches, model.viewWatches},
+	flagView:     {(*model).flagKeys, (*model).updateFlags, model.viewFlags},
+	labelView:    {(*model).labelKeys, (*model).updateLabels, model.viewLabels},
+}
+
+// commonKeys handles the keys the list, the detail view and the drafts
+// share: key help, about, compose, undo, and the actions on the selected or
+// open message.
+func (m *model) commonKeys(key string) (tea.Cmd, bool) {
+	switch key {
+	case "?":
+		m.overlay = helpOverlay
+		return nil, true
+	case "E":
+		m.openLog()
+		return nil, true
+	case "i":
+		m.overlay = aboutOverlay
+		m.about = nil
+		return tea.Batch(fetchDiagnostics(m.cfg), m.spinner.Tick), true
+	case "c":
+		if m.draftStore() == nil {
+			m.setNotice("Composing isn't supported by " + m.provider.name())
+			return nil, true
+		}
+		m.openComposer(draft{}, "")
+		return textarea.Blink, true
+	case "u":
+		if e, ok := m.actionTarget(); ok {
+			p := m.mail()
+			if m.mode == detailView || e.read {
+				m.leaveDetail()
+				return m.markUnread([]email{e}), true
+			}
+			if !m.confirmed("toggle_read", "u", emailKey(e), 1, fmt.Sprintf("mark %q read", e.subject)) {
+				return nil, true
+			}
+			m.lastRead = []email{e}
+			return tea.Batch(m.dropEmail(e), m.track(actOnMessage("mark read", "Marked read", func() error { return p.markRead([]email{e}) }))), true
+		}
+	case "U":
+		if len(m.lastRead) == 0 {
+			m.setNotice("Nothing marked read to undo")
+			return nil, true
+		}
+		emails := m.lastRead
+		m.lastRead = nil
+		return m.markUnread(emails), true
+	case "*":
+		if e, ok := m.actionTarget(); ok {
+			return m.openFlags(e), true
+		}
+	case "K":
+		if e, ok := m.actionTarget(); ok {
+			return m.toggleThreadMute(e), true
+		}
+	case "#":
+		if e, ok := m.actionTarget(); ok {
+			return m.openLabels(e), true
+		}
+	case "O":
+		if e, ok := m.actionTarget(); ok {
+			if !canOpenInMail(e) {
+				m.setNotice("Only messages with a Message-ID open in Mail.app, on macOS")
+				return nil, true
+			}
+			return openInMail(e), true
+		}
+	case "d", "e":
+		if e, ok := m.actionTarget(); ok {
+			if _, ok := m.mail().(messageEditor); !ok {
+				m.setNotice("Messages can't be moved in " + m.provider.name())
+				return nil, true
+			}
+			action, what := "delete", fmt.Sprintf("move %q to Trash", e.subject)
+			if key == "e" {
+				action, what = "archive", fmt.Sprintf("archive %q", e.subject)
+			}
+			if !m.confirmed(action, key, emailKey(e), 1, what) {
+				return nil, true
+			}
+			m.leaveDetail()
+			return m.stage([]email{e}, key == "e"), true
+		}
+	}
+	return nil, false
+}
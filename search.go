@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// searchResultsMsg carries the messages a mailbox search found.
+type searchResultsMsg struct {
+	query  string
+	emails []email
+	err    error
+}
+
+// mailSearch is a search query parsed into its operators. text is what's
+// left, which the sender, subject or body may contain. after and before
+// bound the date received, after inclusive and before not, at midnight
+// local time.
+type mailSearch struct {
+	text          string
+	from          string
+	to            string
+	subject       string
+	after         time.Time
+	before        time.Time
+	hasAttachment bool
+}
+
+// searchOperators are the operators a search understands, with an example
+// and what it matches, for the search view's help.
+var searchOperators = [][]string{
+	{"from:ann", "the sender contains ann"},
+	{"to:support@", "a To or Cc address contains support@"},
+	{`subject:"q3 report"`, "the subject contains q3 report"},
+	{"after:2026-01-31", "received on the 31st or later"},
+	{"before:2026-02-01", "received before the 1st"},
+	{"has:attachment", "has attachments"},
+}
+
+var searchPrefixes = map[string]bool{"from": true, "to": true, "subject": true, "subj": true, "after": true, "before": true, "has": true}
+
+// parseMailSearch splits query into operators and text. Double quotes group
+// words into one value, and a word with an unknown prefix is text.
+func parseMailSearch(query string) (mailSearch, error) {
+	var s mailSearch
+	var text []string
+	for _, tok := range tokenizeQuery(query) {
+		name, value, ok := strings.Cut(tok, ":")
+		name = strings.ToLower(name)
+		if ok && value == "" && searchPrefixes[name] {
+			return s, fmt.Errorf("%s: wants a value", tok)
+		}
+		var err error
+		switch name {
+		case "from":
+			s.from = value
+		case "to":
+			s.to = value
+		case "subject", "subj":
+			s.subject = value
+		case "after":
+			s.after, err = time.ParseInLocation(time.DateOnly, value, time.Local)
+		case "before":
+			s.before, err = time.ParseInLocation(time.DateOnly, value, time.Local)
+		case "has":
+			if value != "attachment" && value != "attachments" {
+				return s, fmt.Errorf("has:%s: only has:attachment is supported", value)
+			}
+			s.hasAttachment = true
+		default:
+			text = append(text, tok)
+			continue
+		}
+		if err != nil {
+			return s, fmt.Errorf("%s: want a date like 2026-01-31", tok)
+		}
+	}
+	s.text = strings.Join(text, " ")
+	if s == (mailSearch{}) {
+		return s, fmt.Errorf("nothing to search for")
+	}
+	return s, nil
+}
+
+func searchMail(s searcher, query string, q mailSearch) tea.Cmd {
+	return func() tea.Msg {
+		emails, err := s.search(q)
+		return searchResultsMsg{query: query, emails: emails, err: err}
+	}
+}
+
+func newSearchInput() textinput.Model {
+	in := textinput.New()
+	in.Prompt = "Search: "
+	in.Placeholder = "sender, subject or text in the message • ? for operators"
+	return in
+}
+
+// searchScreen is the search view's state: the query input, with the
+// operators shown under it while help is on; naming, which takes the name
+// of the search being saved, and the searches saved, for their number keys;
+// and the results of the last search, for query.
+type searchScreen struct {
+	input   textinput.Model
+	help    bool
+	naming  textinput.Model
+	saved   savedSearches
+	results list.Model
+	query   string
+}
+
+// show fills the results list from a finished search.
+func (s *searchScreen) show(msg searchResultsMsg) tea.Cmd {
+	s.query = msg.query
+	items := make([]list.Item, 0, len(msg.emails))
+	for _, e := range msg.emails {
+		items = append(items, e)
+	}
+	s.results.Title = fmt.Sprintf("Search: %s (%d)", msg.query, len(items))
+	if len(items) == maxSearchResults {
+		s.results.Title = fmt.Sprintf("Search: %s (first %d)", msg.query, len(items))
+	}
+	s.results.ResetFilter()
+	return s.results.SetItems(items)
+}
+
+// update types keys into whichever input has focus, and browses the
+// results with them otherwise.
+func (s *searchScreen) update(msg tea.Msg) tea.Cmd {
+	var inputCmd, listCmd tea.Cmd
+	_, isKey := msg.(tea.KeyMsg)
+	if s.naming.Focused() {
+		s.naming, inputCmd = s.naming.Update(msg)
+		return inputCmd
+	}
+	if !isKey || s.input.Focused() {
+		s.input, inputCmd = s.input.Update(msg)
+	}
+	if !isKey || !s.input.Focused() {
+		s.results, listCmd = s.results.Update(msg)
+	}
+	return tea.Batch(listCmd, inputCmd)
+}
+
+// view draws the screen down to the status line.
+func (s searchScreen) view() string {
+	second := s.savedLine()
+	if s.naming.Focused() {
+		second = s.naming.View()
+	}
+	results := s.results.View()
+	if s.help && s.input.Focused() {
+		results = lipgloss.Place(s.results.Width(), lipgloss.Height(results), lipgloss.Left, lipgloss.Top, viewSearchHelp())
+	}
+	return s.input.View() + "\n" + second + "\n" + results
+}
+
+// bindings are the help bar's keys for what has focus.
+func (s searchScreen) bindings() [][]string {
+	switch {
+	case s.naming.Focused():
+		return [][]string{{"enter", "save"}, {"esc", "cancel"}}
+	case !s.input.Focused():
+		return [][]string{{"enter", "read"}, {"f", "new search"}, {"S", "save search"}, {"/", "filter"}, {"q", "back"}}
+	}
+	return [][]string{{"enter", "search"}, {"?", "operators"}, {"esc", "cancel"}}
+}
+
+// openSearch switches to the search view with the query input focused.
+func (m *model) openSearch() tea.Cmd {
+	m.mode = searchView
+	m.search.input.SetValue(m.search.query)
+	m.search.input.CursorEnd()
+	return m.search.input.Focus()
+}
+
+// showResults shows a finished search's results, and why it failed when
+// it did.
+func (m *model) showResults(msg searchResultsMsg) tea.Cmd {
+	if msg.err != nil {
+		m.setNotice(fmt.Sprintf("Search failed: %v", msg.err))
+	}
+	return m.search.show(msg)
+}
+
+// searchKeys handles keys in the search view: typing a query while the
+// input has focus, and browsing the results otherwise. Results open in the
+// detail view like unread mail.
+func (m *model) searchKeys(key string) (tea.Cmd, bool) {
+	if m.search.naming.Focused() {
+		return m.searchNameKeys(key)
+	}
+	if m.search.input.Focused() {
+		switch key {
+		case "esc":
+			m.search.input.Blur()
+			if m.search.query == "" {
+				m.mode = listView
+			}
+			return nil, true
+		case "enter":
+			query := strings.TrimSpace(m.search.input.Value())
+			if query == "" {
+				return nil, true
+			}
+			return m.runSearch(query), true
+		case "?":
+			if m.search.input.Value() == "" {
+				m.search.help = !m.search.help
+				return nil, true
+			}
+		}
+		return nil, false
+	}
+
+	if m.search.results.FilterState() != list.Filtering {
+		switch key {
+		case "q":
+			m.mode = listView
+			return nil, true
+		case "esc":
+			// With a filter applied, esc goes to the results list, which
+			// clears it.
+			if m.search.results.FilterState() == list.Unfiltered {
+				m.mode = listView
+				return nil, true
+			}
+		case "f":
+			return m.openSearch(), true
+		case "S":
+			return m.search.startSaving(), true
+		case "enter":
+			if item, ok := m.search.results.SelectedItem().(email); ok {
+				if !m.downloadOK(item) {
+					return nil, true
+				}
+				m.currentEmail = &item
+				m.detailFrom = searchView
+				return tea.Batch(m.track(fetchEmailContent(m.mail(), item, m.cfg.Read.markOnOpen())), m.begin(openingMessage)), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// runSearch searches the mailboxes for query, leaving the input untouched
+// when it doesn't parse.
+func (m *model) runSearch(query string) tea.Cmd {
+	s, ok := m.mail().(searcher)
+	if !ok {
+		m.setNotice(m.provider.name() + " can't search mailboxes")
+		return nil
+	}
+	q, err := parseMailSearch(query)
+	if err != nil {
+		m.setNotice(fmt.Sprintf("Search: %v", err))
+		return nil
+	}
+	m.search.input.Blur()
+	m.search.help = false
+	return tea.Batch(searchMail(s, query, q), m.begin(searching))
+}
+
+func (m *model) updateSearch(msg tea.Msg) tea.Cmd {
+	return m.search.update(msg)
+}
+
+func (m model) viewSearch(status string) string {
+	return m.search.view() + "\n" + status + renderHelpBar(m.width, m.search.bindings())
+}
+
+// viewSearchHelp is the popover listing the search operators.
+func viewSearchHelp() string {
+	var lines []string
+	for _, op := range searchOperators {
+		lines = append(lines, senderStyle.Render(fmt.Sprintf("%-20s", op[0]))+metaStyle.Render(op[1]))
+	}
+	lines = append(lines, "", metaStyle.Render("Other words match the sender, subject or body. Combine them freely."))
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(accentColor).
+		Padding(0, 1).
+		Render(headerStyle.Render("Search operators") + "\n\n" + strings.Join(lines, "\n"))
+}
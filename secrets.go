@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Config values may reference a secret instead of containing it:
+//
+//	keychain:<service>  generic password from the macOS login keychain
+//	cmd:<command>       first line of a shell command's output
+//	env:<name>          environment variable
+//
+// Anything else is used literally. References are resolved on first use and
+// cached for the life of the process, so a password manager prompts once.
+var secretCache = struct {
+	sync.Mutex
+	values map[string]string
+}{values: map[string]string{}}
+
+func resolveSecret(ref string) (string, error) {
+	kind, arg, ok := strings.Cut(ref, ":")
+	if !ok || (kind != "keychain" && kind != "cmd" && kind != "env") {
+		return ref, nil
+	}
+
+	secretCache.Lock()
+	defer secretCache.Unlock()
+	if v, ok := secretCache.values[ref]; ok {
+		return v, nil
+	}
+
+	var (
+		v   string
+		err error
+	)
+	switch kind {
+	case "keychain":
+		v, err = runSecretCommand(exec.Command("security", "find-generic-password", "-s", arg, "-w"))
+	case "cmd":
+		v, err = runSecretCommand(exec.Command("sh", "-c", arg))
+	case "env":
+		var set bool
+		if v, set = os.LookupEnv(arg); !set {
+			err = fmt.Errorf("environment variable %s is not set", arg)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolving %s secret: %w", kind, err)
+	}
+	secretCache.values[ref] = v
+	return v, nil
+}
+
+func runSecretCommand(cmd *exec.Cmd) (string, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	return strings.TrimSpace(line), nil
+}
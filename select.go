@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// listMarks are the messages picked in the list for a batch action, by
+// emailKey, and the row a range picked with v starts at, or -1. The list's
+// delegate shares it to draw them.
+type listMarks struct {
+	keys   map[string]bool
+	anchor int
+}
+
+func newListMarks() *listMarks {
+	return &listMarks{keys: map[string]bool{}, anchor: -1}
+}
+
+// has reports whether the row at index, holding e, is picked: marked, or
+// in the range open between the anchor and the cursor.
+func (l *listMarks) has(e email, index, cursor int) bool {
+	if l.keys[emailKey(e)] {
+		return true
+	}
+	return l.anchor >= 0 && index >= min(l.anchor, cursor) && index <= max(l.anchor, cursor)
+}
+
+func (l *listMarks) clear() {
+	clear(l.keys)
+	l.anchor = -1
+}
+
+// marked returns the picked messages, in list order.
+func (m model) marked() []email {
+	var out []email
+	for _, item := range m.list.Items() {
+		if e, ok := item.(email); ok && m.marks.keys[emailKey(e)] {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// markKeys handles picking messages in the list, and u, d and e while some
+// are picked, which act on all of them instead of the selected one.
+func (m *model) markKeys(key string) (tea.Cmd, bool) {
+	switch key {
+	case " ":
+		if e, ok := m.list.SelectedItem().(email); ok {
+			k := emailKey(e)
+			if m.marks.keys[k] {
+				delete(m.marks.keys, k)
+			} else {
+				m.marks.keys[k] = true
+			}
+			m.list.CursorDown()
+		}
+		return nil, true
+	case "v":
+		if m.marks.anchor < 0 {
+			m.marks.anchor = m.list.Index()
+			m.setNotice("Move to the end of the range, then v")
+			return nil, true
+		}
+		visible := m.list.VisibleItems()
+		from, to := min(m.marks.anchor, m.list.Index()), max(m.marks.anchor, m.list.Index())
+		for i := from; i <= to && i < len(visible); i++ {
+			if e, ok := visible[i].(email); ok {
+				m.marks.keys[emailKey(e)] = true
+			}
+		}
+		m.marks.anchor = -1
+		return nil, true
+	}
+	if len(m.marks.keys) == 0 && m.marks.anchor < 0 {
+		return nil, false
+	}
+	switch key {
+	case "esc":
+		m.marks.clear()
+		return nil, true
+	case "u", "d", "e":
+		if m.marks.anchor >= 0 {
+			// An open range counts as picked.
+			m.markKeys("v")
+		}
+		emails := m.marked()
+		if len(emails) == 0 {
+			return nil, false
+		}
+		action, what := "toggle_read", "mark "+countOf(len(emails))+" read"
+		switch key {
+		case "u":
+			if !slices.ContainsFunc(emails, func(e email) bool { return !e.read }) {
+				what = "mark " + countOf(len(emails)) + " unread"
+			}
+		case "d":
+			action, what = "delete", "move "+countOf(len(emails))+" to Trash"
+		case "e":
+			action, what = "archive", "archive "+countOf(len(emails))
+		}
+		keys := make([]string, len(emails))
+		for i, e := range emails {
+			keys[i] = emailKey(e)
+		}
+		if !m.confirmed(action, key, strings.Join(keys, "\n"), len(emails), what) {
+			return nil, true
+		}
+		m.marks.clear()
+		if key == "u" {
+			return m.markReadAll(emails), true
+		}
+		return m.moveAll(emails, key == "e"), true
+	}
+	return nil, false
+}
+
+// markReadAll marks emails read in one call to the backend. When they're all
+// read already, listed from this session, it marks them unread instead, as
+// u does for one.
+func (m *model) markReadAll(emails []email) tea.Cmd {
+	var unread []email
+	for _, e := range emails {
+		if !e.read {
+			unread = append(unread, e)
+		}
+	}
+	if len(unread) == 0 {
+		return m.markUnread(emails)
+	}
+	p := m.mail()
+	m.lastRead = unread
+	return tea.Batch(m.dropEmails(unread), m.track(actOnMessage("mark read", fmt.Sprintf("Marked %d read", len(unread)), func() error { return p.markRead(unread) })))
+}
+
+// moveAll moves emails to the Trash, or the archive, once [staging] delay
+// is up.
+func (m *model) moveAll(emails []email, archive bool) tea.Cmd {
+	if _, ok := m.mail().(messageEditor); !ok {
+		m.setNotice("Messages can't be moved in " + m.provider.name())
+		return nil
+	}
+	return m.stage(emails, archive)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// senderAction is an entry of the sender menu.
+type senderAction struct {
+	label string
+	// off says why the action doesn't apply, when it doesn't.
+	off string
+	run func(m *model, e email) tea.Cmd
+}
+
+// senderActions are the sender menu's entries for the sender of e.
+func (m model) senderActions(e email) []senderAction {
+	rec := m.senders.senders[normalizeAddress(e.sender)]
+	contacts := senderAction{label: "Add to Contacts", run: (*model).addToContacts}
+	if runtime.GOOS != "darwin" {
+		contacts.off = "macOS only"
+	}
+	vip := senderAction{label: "Add to VIPs, listed first", run: (*model).toggleVIP}
+	if rec.VIP {
+		vip.label = "Remove from VIPs"
+	}
+	mute := senderAction{label: "Mute, listed but never announced", run: (*model).toggleSenderMute}
+	if rec.Muted {
+		mute.label = "Unmute"
+	}
+	return []senderAction{
+		contacts,
+		{label: "Copy the address", run: (*model).copyAddress},
+		{label: "Show mail from them", run: (*model).showMailFrom},
+		vip,
+		mute,
+	}
+}
+
+// senderMenu is the sender menu's state: the selected action.
+type senderMenu struct {
+	cursor int
+}
+
+// move moves the selection by delta actions of n, wrapping around.
+func (s *senderMenu) move(delta, n int) {
+	s.cursor = (s.cursor + delta + n) % n
+}
+
+// view draws the menu of actions on e's sender.
+func (s senderMenu) view(e email, actions []senderAction) string {
+	var lines []string
+	for i, a := range actions {
+		cursor := "  "
+		style := bodyStyle
+		if i == s.cursor {
+			cursor = senderStyle.Render("▸ ")
+			style = senderStyle
+		}
+		line := cursor + metaStyle.Render(fmt.Sprintf("%d ", i+1)) + style.Render(a.label)
+		if a.off != "" {
+			line += metaStyle.Render(" • " + a.off)
+		}
+		lines = append(lines, line)
+	}
+	title := headerStyle.Render(displayName(e.sender)) + "\n" + metaStyle.Render(normalizeAddress(e.sender))
+	return lipgloss.NewStyle().Padding(1, 2).Render(title + "\n\n" + strings.Join(lines, "\n"))
+}
+
+// openSenderMenu shows the actions on the open message's sender.
+func (m *model) openSenderMenu() {
+	if m.currentEmail == nil || normalizeAddress(m.currentEmail.sender) == "" {
+		return
+	}
+	m.senderMenu = senderMenu{}
+	m.mode = senderView
+}
+
+// senderKeys handles keys in the sender menu: a number or enter runs an
+// action, and esc goes back to the message.
+func (m *model) senderKeys(key string) (tea.Cmd, bool) {
+	e := *m.currentEmail
+	actions := m.senderActions(e)
+	switch key {
+	case "up", "k":
+		m.senderMenu.move(-1, len(actions))
+	case "down", "j":
+		m.senderMenu.move(1, len(actions))
+	case "esc", "q":
+		m.mode = detailView
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		i := int(key[0] - '1')
+		if i >= len(actions) {
+			return nil, true
+		}
+		m.senderMenu.cursor = i
+		return m.senderKeys("enter")
+	case "enter":
+		a := actions[m.senderMenu.cursor]
+		if a.off != "" {
+			m.setNotice(a.label + ": " + a.off)
+			return nil, true
+		}
+		m.mode = detailView
+		return a.run(m, e), true
+	}
+	return nil, true
+}
+
+func (m *model) updateSender(tea.Msg) tea.Cmd {
+	return nil
+}
+
+func (m *model) addToContacts(e email) tea.Cmd {
+	addr, name := normalizeAddress(e.sender), displayName(e.sender)
+	if name == e.sender {
+		name = ""
+	}
+	return m.track(actOnMessage("add to Contacts", "Added "+addr+" to Contacts", func() error {
+		return addContact(name, addr)
+	}))
+}
+
+// addContact creates a Contacts.app card for addr, named name when it's
+// known.
+func addContact(name, addr string) error {
+	first, last, _ := strings.Cut(name, " ")
+	script := `
+on run argv
+	tell application "Contacts"
+		set p to make new person with properties {first name:(item 1 of argv), last name:(item 2 of argv)}
+		make new email at end of emails of p with properties {label:"email", value:(item 3 of argv)}
+		save
+	end tell
+end run
+`
+	if first == "" {
+		first = addr
+	}
+	out, err := exec.Command("osascript", "-e", script, first, last, addr).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(out), "-1743") {
+			return errors.New("allow your terminal to control Contacts in System Settings → Privacy & Security → Automation")
+		}
+		return errors.New(strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (m *model) copyAddress(e email) tea.Cmd {
+	addr := normalizeAddress(e.sender)
+	return m.track(actOnMessage("copy the address", "Copied "+addr, func() error {
+		return copyText(addr)
+	}))
+}
+
+// clipboardCommands copy standard input to the clipboard, in the order
+// they're tried.
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+}
+
+func copyText(s string) error {
+	for _, args := range clipboardCommands {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = strings.NewReader(s)
+		return cmd.Run()
+	}
+	return errors.New("no clipboard command found (pbcopy, wl-copy, xclip or xsel)")
+}
+
+// showMailFrom lists the mail from e's sender this session knows of: the
+// unread list, what was read here and the cached list, newest first.
+func (m *model) showMailFrom(e email) tea.Cmd {
+	addr := normalizeAddress(e.sender)
+	seen := map[string]bool{}
+	var from []email
+	for _, list := range [][]email{m.emails, m.readHere, m.cache.emails(), {e}} {
+		for _, other := range list {
+			if normalizeAddress(other.sender) == addr && !seen[emailKey(other)] {
+				seen[emailKey(other)] = true
+				from = append(from, other)
+			}
+		}
+	}
+	slices.SortStableFunc(from, func(a, b email) int {
+		ta, _ := parseMailDate(a.date)
+		tb, _ := parseMailDate(b.date)
+		return tb.Compare(ta)
+	})
+	m.leaveDetail()
+	m.mode = searchView
+	m.search.input.SetValue(addr)
+	m.search.input.Blur()
+	return m.showResults(searchResultsMsg{query: addr, emails: from})
+}
+
+// toggleVIP makes e's sender a VIP, or stops them being one.
+func (m *model) toggleVIP(e email) tea.Cmd {
+	vip := !m.senders.vip(e)
+	if err := m.senders.update(e, func(r *senderRecord) { r.VIP = vip }); err != nil {
+		m.setNotice(fmt.Sprintf("Couldn't change the VIPs: %v", err))
+		return nil
+	}
+	if vip {
+		m.setNotice(normalizeAddress(e.sender) + " is a VIP")
+	} else {
+		m.setNotice(normalizeAddress(e.sender) + " is no longer a VIP")
+	}
+	return m.applyEmails(emailsMsg{emails: m.emails, err: m.err, total: m.total})
+}
+
+// toggleSenderMute mutes e's sender, or unmutes them.
+func (m *model) toggleSenderMute(e email) tea.Cmd {
+	muted := !m.senders.muted(e)
+	if err := m.senders.update(e, func(r *senderRecord) { r.Muted = muted }); err != nil {
+		m.setNotice(fmt.Sprintf("Couldn't change the mute: %v", err))
+		return nil
+	}
+	if muted {
+		m.setNotice("Muted " + normalizeAddress(e.sender))
+	} else {
+		m.setNotice("Unmuted " + normalizeAddress(e.sender))
+	}
+	return nil
+}
+
+func (m model) viewSender(status string) string {
+	e := *m.currentEmail
+	bindings := [][]string{{"↑/↓", "select"}, {"enter", "run"}, {"esc", "back"}}
+	body := m.senderMenu.view(e, m.senderActions(e))
+	gap := max(m.height-lipgloss.Height(body)-lipgloss.Height(status)-1, 0)
+	return body + strings.Repeat("\n", gap) + "\n" + status + renderHelpBar(m.width, bindings)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// senderRecord is what's remembered about one sender address.
+type senderRecord struct {
+	// First is the emailKey of the first message seen from the sender.
+	First   string    `json:"first"`
+	Seen    time.Time `json:"seen"`
+	Trusted bool      `json:"trusted,omitempty"`
+	Blocked bool      `json:"blocked,omitempty"`
+	// VIP senders are listed first; Muted ones are listed but never
+	// announced.
+	VIP   bool `json:"vip,omitempty"`
+	Muted bool `json:"muted,omitempty"`
+	// Changed is when Trusted, Blocked, VIP or Muted were last set, so the
+	// latest change wins when journals are merged. Records from before it
+	// was kept have none.
+	Changed time.Time `json:"changed,omitzero"`
+}
+
+// senderEntry is one line of a sender journal.
+type senderEntry struct {
+	Addr string `json:"addr"`
+	senderRecord
+}
+
+// senderHistory remembers every address mail has arrived from, so the first
+// message from a sender can be flagged.
+//
+// It's stored so the state directory can live in a synced folder: each
+// machine only appends to its own senders-<machine>.jsonl journal, and
+// loading merges every machine's journal. No two machines write the same
+// file, and a line cut short mid-sync is skipped.
+type senderHistory struct {
+	dir     string
+	senders map[string]senderRecord
+	// trusted are address globs from the config, such as "*@example.com",
+	// that are never flagged.
+	trusted []string
+}
+
+// legacySenderFile is the single-file history older versions wrote. It's
+// still read, but never written.
+const legacySenderFile = "senders.json"
+
+// isSenderJournal reports whether name is any machine's sender journal.
+func isSenderJournal(name string) bool {
+	return strings.HasPrefix(name, "senders-") && strings.HasSuffix(name, ".jsonl")
+}
+
+// senderJournal is this machine's journal file name.
+func senderJournal() string {
+	return "senders-" + machineName() + ".jsonl"
+}
+
+// machineName is the short host name, made safe for a file name.
+func machineName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "local"
+	}
+	host, _, _ = strings.Cut(strings.ToLower(host), ".")
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '-'
+		}
+		return r
+	}, host)
+}
+
+// loadSenderHistory reads every sender journal in dir. Missing or
+// unreadable files start an empty history rather than failing.
+func loadSenderHistory(dir string, trusted []string) *senderHistory {
+	h := &senderHistory{dir: dir, senders: map[string]senderRecord{}, trusted: trusted}
+	h.reload()
+	return h
+}
+
+// reload re-reads the journals, picking up what other machines appended.
+func (h *senderHistory) reload() {
+	if h.dir == "" {
+		return
+	}
+	h.senders = map[string]senderRecord{}
+	if data, err := os.ReadFile(filepath.Join(h.dir, legacySenderFile)); err == nil {
+		json.Unmarshal(data, &h.senders)
+	}
+	entries, _ := os.ReadDir(h.dir)
+	for _, e := range entries {
+		if !isSenderJournal(e.Name()) {
+			continue
+		}
+		if data, err := os.ReadFile(filepath.Join(h.dir, e.Name())); err == nil {
+			h.merge(parseSenderJournal(data))
+		}
+	}
+}
+
+// parseSenderJournal reads journal lines, skipping any that don't parse.
+func parseSenderJournal(data []byte) map[string]senderRecord {
+	records := map[string]senderRecord{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		var e senderEntry
+		if json.Unmarshal(sc.Bytes(), &e) != nil || e.Addr == "" {
+			continue
+		}
+		if cur, ok := records[e.Addr]; ok {
+			e.senderRecord = mergeSenderRecords(cur, e.senderRecord)
+		}
+		records[e.Addr] = e.senderRecord
+	}
+	return records
+}
+
+// observe records the senders of emails, appending any new ones to the
+// journal. Other machines' additions are picked up first.
+func (h *senderHistory) observe(emails []email) error {
+	h.reload()
+	fresh := map[string]senderRecord{}
+	for _, e := range emails {
+		addr := normalizeAddress(e.sender)
+		if addr == "" {
+			continue
+		}
+		if _, ok := h.senders[addr]; !ok {
+			rec := senderRecord{First: emailKey(e), Seen: time.Now()}
+			h.senders[addr] = rec
+			fresh[addr] = rec
+		}
+	}
+	return h.append(fresh)
+}
+
+// firstContact reports whether e is the first message from a sender that
+// isn't trusted.
+func (h *senderHistory) firstContact(e email) bool {
+	if h == nil {
+		return false
+	}
+	addr := normalizeAddress(e.sender)
+	rec, ok := h.senders[addr]
+	if !ok || rec.Trusted || rec.First != emailKey(e) {
+		return false
+	}
+	for _, glob := range h.trusted {
+		if ok, _ := path.Match(normalizeAddress(glob), addr); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// trust stops flagging e's sender.
+func (h *senderHistory) trust(e email) error {
+	return h.update(e, func(r *senderRecord) { r.Trusted = true })
+}
+
+// block hides mail from e's sender from now on.
+func (h *senderHistory) block(e email) error {
+	return h.update(e, func(r *senderRecord) { r.Blocked = true })
+}
+
+func (h *senderHistory) update(e email, change func(*senderRecord)) error {
+	addr := normalizeAddress(e.sender)
+	rec, ok := h.senders[addr]
+	if !ok {
+		rec = senderRecord{First: emailKey(e), Seen: time.Now()}
+	}
+	change(&rec)
+	rec.Changed = time.Now()
+	h.senders[addr] = rec
+	return h.append(map[string]senderRecord{addr: rec})
+}
+
+// blocked reports whether e's sender has been blocked.
+func (h *senderHistory) blocked(e email) bool {
+	return h != nil && h.senders[normalizeAddress(e.sender)].Blocked
+}
+
+// vip reports whether e's sender was made a VIP.
+func (h *senderHistory) vip(e email) bool {
+	return h != nil && h.senders[normalizeAddress(e.sender)].VIP
+}
+
+// prioritize moves the messages from VIPs to the front, ahead of what
+// priority rules moved there, keeping the order within each part.
+func (h *senderHistory) prioritize(emails []email) {
+	first := make([]email, 0, len(emails))
+	var rest []email
+	for _, e := range emails {
+		if h.vip(e) {
+			first = append(first, e)
+		} else {
+			rest = append(rest, e)
+		}
+	}
+	copy(emails, append(first, rest...))
+}
+
+// muted reports whether e's sender was muted.
+func (h *senderHistory) muted(e email) bool {
+	return h != nil && h.senders[normalizeAddress(e.sender)].Muted
+}
+
+// merge folds records from another machine's history into h.
+func (h *senderHistory) merge(records map[string]senderRecord) {
+	for addr, r := range records {
+		if cur, ok := h.senders[addr]; ok {
+			r = mergeSenderRecords(cur, r)
+		}
+		h.senders[addr] = r
+	}
+}
+
+// importRecords merges records into the history, appending the ones that
+// change it to this machine's journal. It returns how many did.
+func (h *senderHistory) importRecords(records map[string]senderRecord) (int, error) {
+	changed := map[string]senderRecord{}
+	for addr, r := range records {
+		cur, ok := h.senders[addr]
+		if ok {
+			r = mergeSenderRecords(cur, r)
+		}
+		if !ok || r != cur {
+			changed[addr] = r
+			h.senders[addr] = r
+		}
+	}
+	return len(changed), h.append(changed)
+}
+
+// mergeSenderRecords combines two records of one sender: it keeps whichever
+// first message was seen earlier, and the trusted, blocked, VIP and muted
+// flags of whichever was changed later. Between records changed at the same
+// time, as those from before Changed was kept are, a flag set on either
+// side stays set. The result doesn't depend on the order journals are read.
+func mergeSenderRecords(a, b senderRecord) senderRecord {
+	if b.Seen.Before(a.Seen) {
+		a.First, a.Seen = b.First, b.Seen
+	}
+	switch {
+	case b.Changed.After(a.Changed):
+		a.Trusted, a.Blocked, a.VIP, a.Muted, a.Changed = b.Trusted, b.Blocked, b.VIP, b.Muted, b.Changed
+	case b.Changed.Equal(a.Changed):
+		a.Trusted = a.Trusted || b.Trusted
+		a.Blocked = a.Blocked || b.Blocked
+		a.VIP = a.VIP || b.VIP
+		a.Muted = a.Muted || b.Muted
+	}
+	return a
+}
+
+// append adds records to the end of this machine's journal.
+func (h *senderHistory) append(records map[string]senderRecord) error {
+	if h.dir == "" || len(records) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for addr, rec := range records {
+		line, err := json.Marshal(senderEntry{Addr: addr, senderRecord: rec})
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := os.MkdirAll(h.dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(h.dir, senderJournal()), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
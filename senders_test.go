@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeSenderRecords(t *testing.T) {
+	t0 := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	seen := senderRecord{First: "a", Seen: t0}
+	muted := senderRecord{First: "b", Seen: t0.Add(time.Hour), Muted: true, VIP: true, Changed: t0.Add(2 * time.Hour)}
+	unmuted := senderRecord{First: "b", Seen: t0.Add(time.Hour), VIP: true, Changed: t0.Add(3 * time.Hour)}
+	legacy := senderRecord{First: "c", Seen: t0.Add(time.Hour), Trusted: true}
+	for _, tt := range []struct {
+		name string
+		a, b senderRecord
+		want senderRecord
+	}{
+		{"unmute after mute", muted, unmuted, unmuted},
+		{"change over a first sighting", seen, muted, senderRecord{First: "a", Seen: t0, Muted: true, VIP: true, Changed: t0.Add(2 * time.Hour)}},
+		{"legacy flags add up", seen, legacy, senderRecord{First: "a", Seen: t0, Trusted: true}},
+	} {
+		for _, order := range [][2]senderRecord{{tt.a, tt.b}, {tt.b, tt.a}} {
+			if got := mergeSenderRecords(order[0], order[1]); got != tt.want {
+				t.Errorf("%s: merged %+v, want %+v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestSenderMuteToggles(t *testing.T) {
+	dir := t.TempDir()
+	e := email{sender: "Ann <ann@example.com>", subject: "Hi", id: "1"}
+	h := loadSenderHistory(dir, nil)
+	if err := h.update(e, func(r *senderRecord) { r.Muted = true }); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if err := h.update(e, func(r *senderRecord) { r.Muted = false }); err != nil {
+		t.Fatal(err)
+	}
+	if loadSenderHistory(dir, nil).muted(e) {
+		t.Error("an unmuted sender is muted again once the journal is read back")
+	}
+}
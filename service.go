@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+const serviceName = "mailnotify.service"
+
+// The unit is sandboxed: the daemon only needs the network and its own
+// state directories, so the rest of the filesystem is read-only to it.
+var serviceUnit = template.Must(template.New("unit").Parse(`[Unit]
+Description=mailnotify unread mail daemon
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+ExecStart={{.ExecStart}}
+Restart=on-failure
+RestartSec=30
+
+NoNewPrivileges=yes
+PrivateTmp=yes
+ProtectSystem=strict
+ProtectHome=read-only
+ReadWritePaths={{.ReadWrite}}
+ProtectKernelTunables=yes
+ProtectKernelModules=yes
+ProtectControlGroups=yes
+RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6
+RestrictNamespaces=yes
+RestrictRealtime=yes
+RestrictSUIDSGID=yes
+LockPersonality=yes
+MemoryDenyWriteExecute=yes
+SystemCallArchitectures=native
+
+[Install]
+WantedBy=default.target
+`))
+
+func servicePath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "systemd", "user", serviceName), nil
+}
+
+// installService writes a systemd user unit for cfg's daemon and enables
+// it.
+func installService(cfg config) error {
+	unitPath, err := servicePath()
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+
+	// systemd won't start a unit whose ReadWritePaths are missing.
+	for _, dir := range append(daemonStateDirs(), cfg.State.dir(), cfg.State.cacheDir()) {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	var readWrite []string
+	for _, dir := range append(daemonStateDirs(), cfg.writePaths()...) {
+		readWrite = append(readWrite, systemdQuote(dir))
+	}
+
+	var buf bytes.Buffer
+	err = serviceUnit.Execute(&buf, struct {
+		ExecStart string
+		ReadWrite string
+	}{
+		ExecStart: strings.Join([]string{systemdQuote(exe), "-daemon", "-config", systemdQuote(cfg.path)}, " "),
+		ReadWrite: strings.Join(readWrite, " "),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(unitPath), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(unitPath, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	if err := systemctl("enable", "--now", serviceName); err != nil {
+		return err
+	}
+	// Pick up a changed unit if the service was already running.
+	if err := systemctl("restart", serviceName); err != nil {
+		return err
+	}
+	fmt.Printf("Installed %s\nLogs: journalctl --user -u %s\n", unitPath, serviceName)
+	return nil
+}
+
+// uninstallService disables the unit and removes it.
+func uninstallService() error {
+	unitPath, err := servicePath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(unitPath); os.IsNotExist(err) {
+		return fmt.Errorf("no service installed at %s", unitPath)
+	}
+	_ = systemctl("disable", "--now", serviceName)
+	if err := os.Remove(unitPath); err != nil {
+		return err
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s\n", unitPath)
+	return nil
+}
+
+// daemonStateDirs are the directories the daemon may write to.
+func daemonStateDirs() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	cache := os.Getenv("XDG_CACHE_HOME")
+	if cache == "" {
+		cache = filepath.Join(home, ".cache")
+	}
+	state := os.Getenv("XDG_STATE_HOME")
+	if state == "" {
+		state = filepath.Join(home, ".local", "state")
+	}
+	return []string{filepath.Join(cache, "mailnotify"), filepath.Join(state, "mailnotify")}
+}
+
+// writePaths are the directories outside daemonStateDirs that cfg has the
+// daemon write to: a [state] dir or cache_dir moved elsewhere, and each
+// Maildir tree, whose flags are in its file names.
+func (cfg config) writePaths() []string {
+	defaults := daemonStateDirs()
+	var paths []string
+	add := func(dir string) {
+		if dir != "" && !slices.Contains(defaults, dir) && !slices.Contains(paths, dir) {
+			paths = append(paths, dir)
+		}
+	}
+	add(cfg.State.dir())
+	add(cfg.State.cacheDir())
+	for _, b := range append([]backendConfig{cfg.Backend}, cfg.Accounts...) {
+		if b.Type == "maildir" && b.Path != "" {
+			add(expandHome(b.Path))
+		}
+	}
+	return paths
+}
+
+// defaultStateDir is where mailnotify keeps local history such as the
+// sender journals, unless [state] dir moves it.
+func defaultStateDir() string {
+	dirs := daemonStateDirs()
+	if len(dirs) < 2 {
+		return ""
+	}
+	return dirs[1]
+}
+
+// systemdQuote quotes s for use as a single word in a unit file.
+func systemdQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\"'\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func systemctl(args ...string) error {
+	out, err := exec.Command("systemctl", append([]string{"--user"}, args...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s: %v: %s", args[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,556 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// setting is one config value the settings screen edits. It's written back
+// as key in [section] of the config file. A setting with choices cycles
+// through them; any other is typed, and check rejects a bad value.
+type setting struct {
+	label   string
+	section string
+	key     string
+	value   string
+	choices []string
+	check   func(string) error
+	// literal writes the value unquoted, for numbers and booleans.
+	literal bool
+	// rule marks a [[rules]] entry, whose value is its ruleSpec; the rules
+	// are written back together, and one left empty is removed.
+	rule    bool
+	changed bool
+}
+
+var onOff = []string{"true", "false"}
+
+// newSettings lists the settings the screen edits, with cfg's values.
+func newSettings(cfg config) []setting {
+	palette := cfg.Theme.Palette
+	switch palette {
+	case "":
+		palette = "auto"
+	case "default":
+		palette = "dark"
+	}
+	profile := cfg.Theme.ColorProfile
+	if profile == "" {
+		profile = "auto"
+	}
+	tool := cfg.Notify.Tool
+	if tool == "" {
+		tool = "auto"
+	}
+	batch := cfg.Poll.Batch
+	if batch == "" {
+		batch = "off"
+	}
+	dates := cfg.Dates.Style
+	if dates == "" {
+		dates = "coarse"
+	}
+	items := []setting{
+		{label: "Poll interval", section: "poll", key: "interval", value: formatInterval(cfg.Poll.interval()), check: checkInterval},
+		{label: "Messages per poll", section: "poll", key: "max", value: strconv.Itoa(cfg.Poll.max()), check: checkPositive, literal: true},
+		{label: "Batch mode", section: "poll", key: "batch", value: batch, choices: []string{"off", "manual", "30m", "1h", "2h"}, check: checkBatch},
+		{label: "Group by conversation", section: "poll", key: "threads", value: strconv.FormatBool(cfg.Poll.Threads), choices: onOff, literal: true},
+		{label: "Palette", section: "theme", key: "palette", value: palette, choices: paletteNames()},
+		{label: "Color profile", section: "theme", key: "color_profile", value: profile, choices: []string{"auto", "truecolor", "256", "16", "none"}},
+		{label: "Notifications", section: "notify", key: "enabled", value: strconv.FormatBool(cfg.Notify.enabled()), choices: onOff, literal: true},
+		{label: "Notification tool", section: "notify", key: "tool", value: tool, choices: []string{"auto", "terminal-notifier", "osascript", "notify-send"}},
+		{label: "Open on new mail", section: "notify", key: "launch", value: strconv.FormatBool(cfg.Notify.Launch), choices: onOff, literal: true},
+		{label: "Focus filter", section: "filter", key: "default", value: cfg.Filter.Default},
+		{label: "Mark read on open", section: "read", key: "mark_on_open", value: strconv.FormatBool(cfg.Read.markOnOpen()), choices: onOff, literal: true},
+		{label: "Summary title", section: "list", key: "summary", value: strconv.FormatBool(cfg.List.Summary), choices: onOff, literal: true},
+		{label: "Relative times", section: "dates", key: "style", value: dates, choices: []string{"coarse", "precise"}},
+	}
+	for i, r := range cfg.Rules {
+		items = append(items, setting{label: fmt.Sprintf("Rule %d", i+1), value: ruleSpec(r), check: checkRule, rule: true})
+	}
+	return append(items, newRuleSetting())
+}
+
+// newRuleSetting is the settings screen's last row, which adds a rule.
+func newRuleSetting() setting {
+	return setting{label: "Add a rule", check: checkRule, rule: true}
+}
+
+// ruleFields are the fields of a [[rules]] entry other than its action, in
+// the order they're shown and written.
+var ruleFields = []string{"sender", "domain", "subject", "to", "reply"}
+
+func ruleField(c *ruleConfig, name string) *string {
+	switch name {
+	case "sender":
+		return &c.Sender
+	case "domain":
+		return &c.Domain
+	case "subject":
+		return &c.Subject
+	case "to":
+		return &c.To
+	case "reply":
+		return &c.Reply
+	}
+	return nil
+}
+
+// ruleSpec is a rule as the settings screen shows it: its action, then each
+// field it matches on as name=value, quoted when the value needs it, as in
+// `hide domain=*.example.com subject="weekly digest"`.
+func ruleSpec(c ruleConfig) string {
+	parts := []string{c.Action}
+	for _, name := range ruleFields {
+		v := *ruleField(&c, name)
+		if v == "" {
+			continue
+		}
+		if strings.ContainsAny(v, " \t\n\"\\") {
+			v = strconv.Quote(v)
+		}
+		parts = append(parts, name+"="+v)
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseRuleSpec reads a rule written as ruleSpec writes it.
+func parseRuleSpec(s string) (ruleConfig, error) {
+	var c ruleConfig
+	action, rest, _ := strings.Cut(strings.TrimSpace(s), " ")
+	c.Action = action
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		name, value, ok := strings.Cut(rest, "=")
+		field := ruleField(&c, name)
+		if !ok || strings.ContainsAny(name, " \t") || field == nil {
+			return c, fmt.Errorf("expected sender=, domain=, subject=, to= or reply= after the action")
+		}
+		if strings.HasPrefix(value, `"`) {
+			quoted, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return c, fmt.Errorf("%s: unterminated quote", name)
+			}
+			*field, _ = strconv.Unquote(quoted)
+			rest = value[len(quoted):]
+			continue
+		}
+		*field, rest, _ = strings.Cut(value, " ")
+	}
+	return c, nil
+}
+
+// checkRule checks a rule the way loading the config would. An empty one
+// is fine: it removes the rule.
+func checkRule(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	c, err := parseRuleSpec(s)
+	if err != nil {
+		return err
+	}
+	if _, err := parseRules([]ruleConfig{c}); err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "rule 1: "))
+	}
+	return nil
+}
+
+func checkInterval(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("not a duration such as 30s or 5m")
+	}
+	if d < time.Second {
+		return fmt.Errorf("must be at least 1s")
+	}
+	return nil
+}
+
+func checkBatch(s string) error {
+	_, err := parseBatch(s)
+	return err
+}
+
+func checkPositive(s string) error {
+	if n, err := strconv.Atoi(s); err != nil || n <= 0 {
+		return fmt.Errorf("must be a whole number above 0")
+	}
+	return nil
+}
+
+// toml is the value as the config file spells it. "auto" stands for an
+// unset choice.
+func (s setting) toml() string {
+	switch {
+	case s.literal:
+		return s.value
+	case s.value == "auto" && s.choices != nil:
+		return `""`
+	}
+	return tomlString(s.value)
+}
+
+// tomlString quotes s as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\u%04X", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// setTOMLValue sets key in [section] of a TOML document to value, an
+// already encoded TOML value, keeping the rest of the file, comments
+// included, as it was. A missing key is added at the end of its table, and
+// a missing table at the end of the file.
+func setTOMLValue(data []byte, section, key, value string) []byte {
+	lines := strings.Split(string(data), "\n")
+	current, last, found := "", -1, false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			current = strings.TrimSpace(strings.Trim(trimmed[:strings.IndexByte(trimmed+"]", ']')+1], "[]"))
+			if strings.HasPrefix(trimmed, "[[") {
+				current = ""
+			}
+			if current == section {
+				found, last = true, i
+			}
+			continue
+		}
+		if current != section {
+			continue
+		}
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			last = i
+		}
+		name, rest, ok := strings.Cut(trimmed, "=")
+		if !ok || strings.Trim(strings.TrimSpace(name), `"'`) != key {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		lines[i] = indent + key + " = " + value + trailingComment(rest)
+		return []byte(strings.Join(lines, "\n"))
+	}
+	if !found {
+		text := strings.TrimRight(string(data), "\n")
+		if text != "" {
+			text += "\n\n"
+		}
+		return []byte(text + "[" + section + "]\n" + key + " = " + value + "\n")
+	}
+	lines = slices.Insert(lines, last+1, key+" = "+value)
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// setTOMLRules replaces the [[rules]] tables of a TOML document with rules,
+// where the first of them was, or at the end of the file. The rest of the
+// file is kept as it was, as are comments and blank lines between the last
+// of a table's keys and the next table; comments among a rule's keys go with
+// it.
+func setTOMLRules(data []byte, rules []ruleConfig) []byte {
+	var tables []string
+	for _, c := range rules {
+		lines := []string{"[[rules]]"}
+		names := append([]string{"action"}, ruleFields...)
+		for _, name := range names {
+			v := c.Action
+			if name != "action" {
+				v = *ruleField(&c, name)
+			}
+			if v != "" {
+				lines = append(lines, name+" = "+tomlString(v))
+			}
+		}
+		tables = append(tables, strings.Join(lines, "\n"))
+	}
+
+	lines := strings.Split(string(data), "\n")
+	var out []string
+	at := -1
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(trimmed, "[[") || strings.TrimSpace(strings.Trim(trimmed[:strings.IndexByte(trimmed+"]", ']')], "[")) != "rules" {
+			out = append(out, lines[i])
+			continue
+		}
+		if at < 0 {
+			at = len(out)
+		}
+		end := i + 1
+		for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), "[") {
+			end++
+		}
+		keep := end
+		for keep > i+1 && (strings.TrimSpace(lines[keep-1]) == "" || strings.HasPrefix(strings.TrimSpace(lines[keep-1]), "#")) {
+			keep--
+		}
+		out = append(out, lines[keep:end]...)
+		i = end - 1
+	}
+	block := strings.Join(tables, "\n\n")
+	if at < 0 {
+		text := strings.TrimRight(strings.Join(out, "\n"), "\n")
+		if block == "" {
+			return []byte(text + "\n")
+		}
+		if text != "" {
+			text += "\n\n"
+		}
+		return []byte(text + block + "\n")
+	}
+	if block != "" {
+		if at < len(out) && strings.TrimSpace(out[at]) != "" {
+			block += "\n"
+		}
+		out = slices.Insert(out, at, block)
+	}
+	return []byte(strings.TrimRight(strings.Join(out, "\n"), "\n") + "\n")
+}
+
+// trailingComment returns the comment after a TOML value, with the space
+// before it, if there is one.
+func trailingComment(value string) string {
+	quote := byte(0)
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0 && c == '\\' && quote == '"':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			for i > 0 && (value[i-1] == ' ' || value[i-1] == '\t') {
+				i--
+			}
+			return value[i:]
+		}
+	}
+	return ""
+}
+
+// settingsForm is the settings screen: the settings, the one selected, and
+// the input while one is being typed.
+type settingsForm struct {
+	items   []setting
+	cursor  int
+	editing bool
+	input   textinput.Model
+	// discard is set after esc with unsaved changes; a second esc drops
+	// them.
+	discard bool
+}
+
+func (m *model) openSettings() {
+	in := textinput.New()
+	in.Prompt = ""
+	m.settings = settingsForm{items: newSettings(m.cfg), input: in}
+	m.mode = settingsView
+}
+
+func (f settingsForm) dirty() bool {
+	for _, s := range f.items {
+		if s.changed {
+			return true
+		}
+	}
+	return false
+}
+
+// settingsKeys handles keys in the settings screen. ctrl+s writes the
+// changes to the config file and reloads it.
+func (m *model) settingsKeys(key string) (tea.Cmd, bool) {
+	f := &m.settings
+	s := &f.items[f.cursor]
+	if f.editing {
+		switch key {
+		case "enter":
+			value := strings.TrimSpace(f.input.Value())
+			if s.check != nil {
+				if err := s.check(value); err != nil {
+					m.setNotice(s.label + ": " + err.Error())
+					return nil, true
+				}
+			}
+			if value != s.value {
+				s.value, s.changed = value, true
+			}
+			f.editing = false
+			f.input.Blur()
+			if f.cursor == len(f.items)-1 && s.rule && value != "" {
+				rules := 0
+				for _, item := range f.items {
+					if item.rule {
+						rules++
+					}
+				}
+				s.label = fmt.Sprintf("Rule %d", rules)
+				f.items = append(f.items, newRuleSetting())
+			}
+			return nil, true
+		case "esc":
+			f.editing = false
+			f.input.Blur()
+			return nil, true
+		}
+		return nil, false
+	}
+	if key != "esc" {
+		f.discard = false
+	}
+	switch key {
+	case "up", "k":
+		f.cursor = (f.cursor + len(f.items) - 1) % len(f.items)
+		return nil, true
+	case "down", "j", "tab":
+		f.cursor = (f.cursor + 1) % len(f.items)
+		return nil, true
+	case "enter", " ":
+		if s.choices != nil {
+			i := slices.Index(s.choices, s.value)
+			s.value = s.choices[(i+1)%len(s.choices)]
+			s.changed = true
+			return nil, true
+		}
+		f.editing = true
+		f.input.SetValue(s.value)
+		f.input.CursorEnd()
+		return f.input.Focus(), true
+	case "ctrl+s":
+		if err := m.saveSettings(); err != nil {
+			m.setNotice(fmt.Sprintf("Settings not saved: %v", err))
+			return nil, true
+		}
+		m.mode = listView
+		m.setNotice("Settings saved to " + m.cfg.path)
+		return m.applyEmails(emailsMsg{emails: m.emails, err: m.err, total: m.total}), true
+	case "esc", "q":
+		if f.dirty() && !f.discard {
+			f.discard = true
+			m.setNotice("Unsaved changes: ctrl+s saves, esc again discards them")
+			return nil, true
+		}
+		m.mode = listView
+		return nil, true
+	}
+	return nil, true
+}
+
+// saveSettings writes the changed settings into the config file, checking
+// that the result still loads before replacing it, and applies it.
+func (m *model) saveSettings() error {
+	path := m.cfg.path
+	if path == "" {
+		return fmt.Errorf("no config file; start with -config")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var rules []ruleConfig
+	rulesChanged := false
+	for _, s := range m.settings.items {
+		switch {
+		case s.rule:
+			rulesChanged = rulesChanged || s.changed
+			if c, err := parseRuleSpec(s.value); err == nil && s.value != "" {
+				rules = append(rules, c)
+			}
+		case s.changed:
+			data = setTOMLValue(data, s.section, s.key, s.toml())
+		}
+	}
+	if rulesChanged {
+		data = setTOMLRules(data, rules)
+	}
+	if _, err := parseConfig(path, data); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return m.reloadConfig()
+}
+
+func (m *model) updateSettings(msg tea.Msg) tea.Cmd {
+	if !m.settings.editing {
+		return nil
+	}
+	var cmd tea.Cmd
+	m.settings.input, cmd = m.settings.input.Update(msg)
+	return cmd
+}
+
+func (m model) viewSettings(status string) string {
+	f := m.settings
+	width := 0
+	for _, s := range f.items {
+		width = max(width, lipgloss.Width(s.label))
+	}
+	var lines []string
+	for i, s := range f.items {
+		label := metaStyle.Render(fmt.Sprintf("%-*s", width, s.label))
+		value := bodyStyle.Render(s.value)
+		switch {
+		case i == f.cursor && f.editing:
+			value = f.input.View()
+		case s.rule && s.value == "" && s.changed:
+			value = metaStyle.Render("(removed)")
+		case s.rule && s.value == "":
+			value = metaStyle.Render("action and fields, such as: hide domain=*.example.com")
+		case s.value == "":
+			value = metaStyle.Render("(none)")
+		}
+		if s.changed {
+			value += statusStyle.Render(" •")
+		}
+		cursor := "  "
+		if i == f.cursor {
+			cursor = senderStyle.Render("▸ ")
+		}
+		lines = append(lines, cursor+label+"  "+value)
+	}
+	title := headerStyle.Render("Settings") + metaStyle.Render("  "+m.cfg.path)
+
+	bindings := [][]string{{"↑/↓", "select"}, {"enter", "change"}, {"ctrl+s", "save"}, {"esc", "back"}}
+	if f.editing {
+		bindings = [][]string{{"enter", "done"}, {"esc", "cancel"}}
+	}
+	body := lipgloss.NewStyle().Padding(1, 2).Render(title + "\n\n" + strings.Join(lines, "\n"))
+	gap := max(m.height-lipgloss.Height(body)-lipgloss.Height(status)-1, 0)
+	return body + strings.Repeat("\n", gap) + "\n" + status + renderHelpBar(m.width, bindings)
+}
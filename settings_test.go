@@ -0,0 +1,128 @@
+package main
+
+import "testing"
+
+func TestSetTOMLValue(t *testing.T) {
+	for _, tt := range []struct {
+		name, in, section, key, value, want string
+	}{
+		{
+			"replaces the value",
+			"[poll]\ninterval = \"1m\"\n",
+			"poll", "interval", `"5m"`,
+			"[poll]\ninterval = \"5m\"\n",
+		},
+		{
+			"keeps the trailing comment and indent",
+			"[poll]\n  interval = \"1m\" # how often\n",
+			"poll", "interval", `"5m"`,
+			"[poll]\n  interval = \"5m\" # how often\n",
+		},
+		{
+			"a # inside quotes isn't a comment",
+			"[filter]\ndefault = \"#work\" # tag\n",
+			"filter", "default", `"#home"`,
+			"[filter]\ndefault = \"#home\" # tag\n",
+		},
+		{
+			"quoted key",
+			"[poll]\n\"max\" = 10\n",
+			"poll", "max", "30",
+			"[poll]\nmax = 30\n",
+		},
+		{
+			"same key in another table is left alone",
+			"[notify]\nenabled = false\n[poll]\nmax = 10\n",
+			"poll", "enabled", "true",
+			"[notify]\nenabled = false\n[poll]\nmax = 10\nenabled = true\n",
+		},
+		{
+			"missing key goes after the table's last key, before its trailing comments",
+			"[poll]\nmax = 10\n\n# theme\n[theme]\npalette = \"dark\"\n",
+			"poll", "interval", `"5m"`,
+			"[poll]\nmax = 10\ninterval = \"5m\"\n\n# theme\n[theme]\npalette = \"dark\"\n",
+		},
+		{
+			"missing table is added at the end",
+			"[poll]\nmax = 10\n",
+			"theme", "palette", `"light"`,
+			"[poll]\nmax = 10\n\n[theme]\npalette = \"light\"\n",
+		},
+		{
+			"empty file",
+			"",
+			"poll", "max", "10",
+			"[poll]\nmax = 10\n",
+		},
+		{
+			"keys of an array of tables aren't the section's",
+			"[[rules]]\naction = \"hide\"\n",
+			"rules", "action", `"x"`,
+			"[[rules]]\naction = \"hide\"\n\n[rules]\naction = \"x\"\n",
+		},
+		{
+			"a commented-out key isn't matched",
+			"[poll]\n# max = 10\n",
+			"poll", "max", "30",
+			"[poll]\nmax = 30\n# max = 10\n",
+		},
+	} {
+		if got := string(setTOMLValue([]byte(tt.in), tt.section, tt.key, tt.value)); got != tt.want {
+			t.Errorf("%s:\ngot\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTOMLString(t *testing.T) {
+	for in, want := range map[string]string{
+		`plain`:        `"plain"`,
+		`say "hi"`:     `"say \"hi\""`,
+		`C:\mail`:      `"C:\\mail"`,
+		"two\nlines":   `"two\u000Alines"`,
+		"tab\tstop":    `"tab\u0009stop"`,
+		"naïve résumé": `"naïve résumé"`,
+	} {
+		if got := tomlString(in); got != want {
+			t.Errorf("tomlString(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestRuleSpec(t *testing.T) {
+	rules := []ruleConfig{
+		{Action: "hide", Domain: "*.example.com"},
+		{Action: "highlight", Sender: "boss@example.com", Subject: "weekly digest"},
+		{Action: "reply", To: "support@", Reply: "Hi {{.Name}},\n\"Thanks\"\n"},
+	}
+	for _, c := range rules {
+		spec := ruleSpec(c)
+		got, err := parseRuleSpec(spec)
+		if err != nil || got != c {
+			t.Errorf("parseRuleSpec(%q) = %+v, %v; want %+v", spec, got, err, c)
+		}
+		if err := checkRule(spec); err != nil {
+			t.Errorf("checkRule(%q) = %v", spec, err)
+		}
+	}
+	for _, bad := range []string{"hide", "hide from=ann", "discard sender=ann", `hide subject="open`, "reply sender=ann"} {
+		if checkRule(bad) == nil {
+			t.Errorf("checkRule(%q) took it", bad)
+		}
+	}
+}
+
+func TestSetTOMLRules(t *testing.T) {
+	in := "[poll]\nmax = 10\n\n[[rules]]\n# noisy\nsender = \"news@\"\naction = \"hide\"\n\n# keys\n[keys]\nquit = \"Q\"\n\n[[rules]]\ndomain = \"x.com\"\naction = \"priority\"\n"
+	got := string(setTOMLRules([]byte(in), []ruleConfig{{Action: "highlight", Subject: "invoice"}}))
+	want := "[poll]\nmax = 10\n\n[[rules]]\naction = \"highlight\"\nsubject = \"invoice\"\n\n# keys\n[keys]\nquit = \"Q\"\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	cfg, err := parseConfig("test.toml", []byte(got))
+	if err != nil || len(cfg.Rules) != 1 || cfg.Rules[0].Subject != "invoice" {
+		t.Errorf("rewritten config loads as %+v, %v", cfg.Rules, err)
+	}
+	if got := string(setTOMLRules([]byte("[poll]\nmax = 10\n"), []ruleConfig{{Action: "hide", Domain: "x.com"}})); got != "[poll]\nmax = 10\n\n[[rules]]\naction = \"hide\"\ndomain = \"x.com\"\n" {
+		t.Errorf("rules added to a file without any:\n%s", got)
+	}
+}
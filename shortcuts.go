@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// runURL carries out a mailnotify x-callback-url, for Shortcuts and the
+// other apps that chain actions through URLs. The action is the URL's path
+// and its arguments are query parameters:
+//
+//	mailnotify://x-callback-url/unread?q=<filter query>
+//	mailnotify://x-callback-url/read?id=<id>[&account=][&mailbox=]
+//	mailnotify://x-callback-url/archive?id=<id>
+//	mailnotify://x-callback-url/open?id=<id>
+//
+// When it's done x-success is opened with the result, the list's JSON or
+// the message id, as its result parameter; on failure x-error is opened
+// with errorCode and errorMessage. Without them the result is printed and
+// a failure is an error, as `list` and `act` do.
+//
+//	mailnotify url <x-callback-url>
+func runURL(cfg config, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: mailnotify url 'mailnotify://x-callback-url/<action>?…'")
+	}
+	u, err := url.Parse(args[0])
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "mailnotify" || u.Host != "x-callback-url" {
+		return fmt.Errorf("%s: want a mailnotify://x-callback-url/ URL", args[0])
+	}
+	q := u.Query()
+	// Any page could open a registered mailnotify: URL, so the callbacks
+	// are checked before the action runs and the list goes nowhere else.
+	for _, param := range []string{"x-success", "x-error"} {
+		if target := q.Get(param); target != "" {
+			if err := cfg.Shortcuts.allowed(target); err != nil {
+				return fmt.Errorf("%s: %w", param, err)
+			}
+		}
+	}
+	result, err := callbackAction(cfg, strings.Trim(u.Path, "/"), q)
+	if err != nil {
+		if q.Get("x-error") == "" {
+			return err
+		}
+		return openCallback(q.Get("x-error"), url.Values{"errorCode": {"1"}, "errorMessage": {err.Error()}})
+	}
+	if q.Get("x-success") == "" {
+		fmt.Println(result)
+		return nil
+	}
+	return openCallback(q.Get("x-success"), url.Values{"result": {result}})
+}
+
+// callbackAction runs a URL's action and returns its result.
+func callbackAction(cfg config, action string, q url.Values) (string, error) {
+	switch action {
+	case "unread":
+		list, err := fetchList(cfg, q.Get("q"))
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(list)
+		return string(data), err
+	case "read", "archive", "open":
+		id := q.Get("id")
+		if id == "" {
+			return "", fmt.Errorf("%s: wants an id", action)
+		}
+		return id, runAct(cfg, []string{"-account", q.Get("account"), "-mailbox", q.Get("mailbox"), id, action})
+	}
+	return "", fmt.Errorf("unknown action %q", action)
+}
+
+// defaultCallbackSchemes are the schemes a callback may use unless
+// [shortcuts] callback_schemes says otherwise.
+var defaultCallbackSchemes = []string{"shortcuts"}
+
+// allowed reports whether target may be opened as a callback: its scheme
+// has to be one of the configured ones.
+func (s shortcutsConfig) allowed(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	schemes := s.CallbackSchemes
+	if schemes == nil {
+		schemes = defaultCallbackSchemes
+	}
+	scheme := strings.ToLower(u.Scheme)
+	for _, allowed := range schemes {
+		if strings.EqualFold(allowed, scheme) {
+			return nil
+		}
+	}
+	return fmt.Errorf("callbacks to %s: URLs aren't allowed; add %q to [shortcuts] callback_schemes to allow them", scheme, scheme)
+}
+
+// openCallback opens the callback URL target with params added to its
+// query.
+func openCallback(target string, params url.Values) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("callback: %w", err)
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	// Shortcuts reads + as itself rather than a space.
+	u.RawQuery = strings.ReplaceAll(q.Encode(), "+", "%20")
+	return exec.Command(systemOpener(), u.String()).Run()
+}
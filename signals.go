@@ -0,0 +1,17 @@
+package main
+
+// signalAction is what a process signal asks a running instance to do.
+//
+//	SIGUSR1          poll now
+//	SIGHUP           reload the config file
+//	SIGTERM, SIGINT  shut down gracefully
+type signalAction int
+
+const (
+	signalRefresh signalAction = iota
+	signalReload
+	signalShutdown
+)
+
+// signalMsg delivers a signalAction to the TUI.
+type signalMsg signalAction
@@ -0,0 +1,32 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+)
+
+func pauseProcess(*os.Process) error {
+	return errors.New("pausing isn't supported on this platform")
+}
+
+func resumeProcess(*os.Process) error {
+	return errors.New("pausing isn't supported on this platform")
+}
+
+// watchSignals only supports interrupts on platforms without SIGUSR1 and
+// SIGHUP.
+func watchSignals() <-chan signalAction {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+
+	actions := make(chan signalAction)
+	go func() {
+		for range sigs {
+			actions <- signalShutdown
+		}
+	}()
+	return actions
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// pauseProcess stops p until resumeProcess continues it.
+func pauseProcess(p *os.Process) error {
+	return p.Signal(syscall.SIGSTOP)
+}
+
+func resumeProcess(p *os.Process) error {
+	return p.Signal(syscall.SIGCONT)
+}
+
+// watchSignals translates incoming signals into actions until the process
+// exits.
+func watchSignals() <-chan signalAction {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGINT)
+
+	actions := make(chan signalAction)
+	go func() {
+		for sig := range sigs {
+			switch sig {
+			case syscall.SIGUSR1:
+				actions <- signalRefresh
+			case syscall.SIGHUP:
+				actions <- signalReload
+			default:
+				actions <- signalShutdown
+			}
+		}
+	}()
+	return actions
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// snoozeFile is in the state directory, so the TUI and the daemon, and
+// machines sharing state, see the same snoozes.
+const snoozeFile = "snoozed.json"
+
+// snoozeKept is how long a snooze that's over stays on file for a daemon
+// to announce it, when none is running.
+const snoozeKept = 24 * time.Hour
+
+// snooze is one snoozed message. The sender and subject are kept for the
+// notification when it's over.
+type snooze struct {
+	Until   time.Time `json:"until"`
+	Sender  string    `json:"sender"`
+	Subject string    `json:"subject"`
+}
+
+// snoozes are the snoozed messages by emailKey.
+type snoozes map[string]snooze
+
+func loadSnoozes(dir string) (snoozes, error) {
+	s, err := loadStateFile[snoozes](dir, snoozeFile)
+	if s == nil {
+		s = snoozes{}
+	}
+	return s, err
+}
+
+// save writes s to dir, dropping the snoozes that ended over snoozeKept
+// ago. It's written to a temporary file first so a reader never sees half
+// of it.
+func (s snoozes) save(dir string, now time.Time) error {
+	for k, z := range s {
+		if now.Sub(z.Until) > snoozeKept {
+			delete(s, k)
+		}
+	}
+	return writeStateFile(dir, snoozeFile, s)
+}
+
+// hides reports whether e is snoozed at now.
+func (s snoozes) hides(e email, now time.Time) bool {
+	z, ok := s[emailKey(e)]
+	return ok && now.Before(z.Until)
+}
+
+// next returns when the first snooze still running ends, or the zero time.
+func (s snoozes) next(now time.Time) time.Time {
+	var next time.Time
+	for _, z := range s {
+		if now.Before(z.Until) && (next.IsZero() || z.Until.Before(next)) {
+			next = z.Until
+		}
+	}
+	return next
+}
+
+// wake removes the snoozes that are over and returns those of emails whose
+// snooze that was, for announcing them.
+func (s snoozes) wake(emails []email, now time.Time) []email {
+	var woken []email
+	for _, e := range emails {
+		if z, ok := s[emailKey(e)]; ok && !now.Before(z.Until) {
+			woken = append(woken, e)
+		}
+	}
+	for k, z := range s {
+		if !now.Before(z.Until) {
+			delete(s, k)
+		}
+	}
+	return woken
+}
+
+// snoozeChoice is an entry of the snooze picker.
+type snoozeChoice struct {
+	label string
+	until time.Time
+}
+
+// snoozeChoices are the times a message can be snoozed until, from now.
+// This evening is only offered while it's some hours off.
+func snoozeChoices(now time.Time) []snoozeChoice {
+	at := func(days, hour int) time.Time {
+		d := now.AddDate(0, 0, days)
+		return time.Date(d.Year(), d.Month(), d.Day(), hour, 0, 0, 0, now.Location())
+	}
+	choices := []snoozeChoice{
+		{"1 hour", now.Add(time.Hour)},
+		{"3 hours", now.Add(3 * time.Hour)},
+	}
+	if evening := at(0, 18); evening.Sub(now) > 3*time.Hour {
+		choices = append(choices, snoozeChoice{"This evening, 18:00", evening})
+	}
+	choices = append(choices, snoozeChoice{"Tomorrow, 9:00", at(1, 9)})
+	days := (int(time.Monday) - int(now.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	if days > 1 {
+		choices = append(choices, snoozeChoice{"Next Monday, 9:00", at(days, 9)})
+	}
+	return choices
+}
+
+// snoozeDueMsg is sent when the earliest snooze ends.
+type snoozeDueMsg struct{}
+
+// wakeOnSnooze schedules a snoozeDueMsg for when the next snooze ends,
+// unless one is already set for then.
+func (m *model) wakeOnSnooze() tea.Cmd {
+	next := m.snoozed.next(time.Now())
+	if next.IsZero() || next.Equal(m.snoozeWake) {
+		return nil
+	}
+	m.snoozeWake = next
+	return tea.Tick(time.Until(next), func(time.Time) tea.Msg { return snoozeDueMsg{} })
+}
+
+// applySnoozeDue puts the messages whose snooze just ended back in the
+// list.
+func (m *model) applySnoozeDue() tea.Cmd {
+	m.snoozeWake = time.Time{}
+	now := time.Now()
+	before := m.snoozed
+	if s, err := loadSnoozes(m.cfg.State.dir()); err == nil {
+		m.snoozed = s
+	}
+	var back []email
+	for _, e := range m.emails {
+		if z, ok := before[emailKey(e)]; ok && !now.Before(z.Until) {
+			back = append(back, e)
+		}
+	}
+	switch {
+	case len(back) == 1:
+		m.setNotice("Back from snooze: " + back[0].subject)
+	case len(back) > 1:
+		m.setNotice(fmt.Sprintf("%d messages back from snooze", len(back)))
+	}
+	return tea.Batch(m.applyEmails(emailsMsg{emails: m.emails, err: m.err, total: m.total}), m.wakeOnSnooze())
+}
+
+// snoozePicker is the snooze screen's state: the message being snoozed and
+// the selected choice.
+type snoozePicker struct {
+	target email
+	cursor int
+}
+
+// move moves the selection by delta choices of n, wrapping around.
+func (s *snoozePicker) move(delta, n int) {
+	s.cursor = (s.cursor + delta + n) % n
+}
+
+// view draws the screen's title and choices.
+func (s snoozePicker) view(choices []snoozeChoice) string {
+	var lines []string
+	for i, c := range choices {
+		cursor := "  "
+		style := bodyStyle
+		if i == s.cursor {
+			cursor = senderStyle.Render("▸ ")
+			style = senderStyle
+		}
+		lines = append(lines, cursor+metaStyle.Render(fmt.Sprintf("%d ", i+1))+style.Render(c.label)+metaStyle.Render(" • "+c.until.Format("Mon 2 Jan 15:04")))
+	}
+	title := headerStyle.Render("Snooze until…") + "\n" + metaStyle.Render(s.target.sender+" • "+s.target.subject)
+	return lipgloss.NewStyle().Padding(1, 2).Render(title + "\n\n" + strings.Join(lines, "\n"))
+}
+
+// openSnooze switches to the snooze picker for e. A message that's still
+// snoozed, listed because F shows everything, is woken instead.
+func (m *model) openSnooze(e email) tea.Cmd {
+	if m.snoozed.hides(e, time.Now()) {
+		return m.setSnooze(e, time.Time{})
+	}
+	m.snoozing = snoozePicker{target: e}
+	m.mode = snoozeView
+	return nil
+}
+
+// setSnooze snoozes e until then, or ends its snooze when then is zero, and
+// saves the change on top of any the daemon or another machine made.
+func (m *model) setSnooze(e email, then time.Time) tea.Cmd {
+	dir := m.cfg.State.dir()
+	s, err := loadSnoozes(dir)
+	if err != nil {
+		m.setNotice(fmt.Sprintf("Couldn't snooze: %v", err))
+		return nil
+	}
+	if then.IsZero() {
+		delete(s, emailKey(e))
+	} else {
+		s[emailKey(e)] = snooze{Until: then, Sender: e.sender, Subject: e.subject}
+	}
+	if err := s.save(dir, time.Now()); err != nil {
+		m.setNotice(fmt.Sprintf("Couldn't snooze: %v", err))
+		return nil
+	}
+	m.snoozed = s
+	if then.IsZero() {
+		m.setNotice("No longer snoozed")
+	} else {
+		m.setNotice("Snoozed until " + then.Format("Mon 15:04"))
+	}
+	return tea.Batch(m.applyEmails(emailsMsg{emails: m.emails, err: m.err, total: m.total}), m.wakeOnSnooze())
+}
+
+// snoozeKeys handles keys in the snooze picker. A digit picks that choice.
+func (m *model) snoozeKeys(key string) (tea.Cmd, bool) {
+	choices := snoozeChoices(time.Now())
+	switch key {
+	case "up", "k":
+		m.snoozing.move(-1, len(choices))
+	case "down", "j":
+		m.snoozing.move(1, len(choices))
+	case "enter":
+		m.mode = listView
+		return m.setSnooze(m.snoozing.target, choices[min(m.snoozing.cursor, len(choices)-1)].until), true
+	case "esc", "q":
+		m.mode = listView
+	default:
+		if n, err := strconv.Atoi(key); err == nil && n >= 1 && n <= len(choices) {
+			m.mode = listView
+			return m.setSnooze(m.snoozing.target, choices[n-1].until), true
+		}
+	}
+	return nil, true
+}
+
+func (m *model) updateSnooze(tea.Msg) tea.Cmd {
+	return nil
+}
+
+func (m model) viewSnooze(status string) string {
+	bindings := [][]string{{"↑/↓", "select"}, {"enter", "snooze"}, {"esc", "back"}}
+	body := m.snoozing.view(snoozeChoices(time.Now()))
+	gap := max(m.height-lipgloss.Height(body)-lipgloss.Height(status)-1, 0)
+	return body + strings.Repeat("\n", gap) + "\n" + status + renderHelpBar(m.width, bindings)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// speaker is a message being read aloud: the speech command running, and
+// whether it's stopped for a pause.
+type speaker struct {
+	cmd     *exec.Cmd
+	id      string
+	paused  bool
+	stopped bool
+}
+
+// speechCommand is the text-to-speech command for this platform, reading
+// the text on stdin: say on macOS, espeak-ng or espeak elsewhere.
+func speechCommand(cfg speechConfig) (*exec.Cmd, error) {
+	if runtime.GOOS == "darwin" {
+		args := []string{"-f", "-"}
+		if cfg.Voice != "" {
+			args = append(args, "-v", cfg.Voice)
+		}
+		if cfg.Rate > 0 {
+			args = append(args, "-r", strconv.Itoa(cfg.Rate))
+		}
+		return exec.Command("say", args...), nil
+	}
+	for _, name := range []string{"espeak-ng", "espeak"} {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		args := []string{"--stdin"}
+		if cfg.Voice != "" {
+			args = append(args, "-v", cfg.Voice)
+		}
+		if cfg.Rate > 0 {
+			args = append(args, "-s", strconv.Itoa(cfg.Rate))
+		}
+		return exec.Command(path, args...), nil
+	}
+	return nil, fmt.Errorf("reading aloud needs espeak-ng or espeak")
+}
+
+// speak starts reading text aloud.
+func speak(cfg speechConfig, id, text string) (*speaker, error) {
+	cmd, err := speechCommand(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &speaker{cmd: cmd, id: id}, nil
+}
+
+// speechDoneMsg is sent when s finishes or is stopped.
+type speechDoneMsg struct {
+	s   *speaker
+	err error
+}
+
+func waitSpeech(s *speaker) tea.Cmd {
+	return func() tea.Msg {
+		return speechDoneMsg{s: s, err: s.cmd.Wait()}
+	}
+}
+
+// toggle pauses or resumes s.
+func (s *speaker) toggle() error {
+	if s.paused {
+		if err := resumeProcess(s.cmd.Process); err != nil {
+			return err
+		}
+	} else if err := pauseProcess(s.cmd.Process); err != nil {
+		return err
+	}
+	s.paused = !s.paused
+	return nil
+}
+
+// stop ends the speech. It's safe on a nil speaker.
+func (s *speaker) stop() {
+	if s == nil || s.stopped {
+		return
+	}
+	s.stopped = true
+	// A stopped process only dies once it's continued.
+	if s.paused {
+		resumeProcess(s.cmd.Process)
+	}
+	s.cmd.Process.Kill()
+}
+
+// speechText is what reading the open message aloud says: who it's from,
+// the subject, then the body without quoted replies or the footnoted
+// links, which are tedious to listen to.
+func (m model) speechText() string {
+	e := m.currentEmail
+	var lines []string
+	for _, line := range strings.Split(m.emailBody, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		lines = append(lines, bareURL.ReplaceAllString(line, "link"))
+	}
+	body, _ := splitFootnotes(strings.Join(lines, "\n"))
+	return fmt.Sprintf("From %s. %s.\n\n%s", displayName(e.sender), e.subject, body)
+}
+
+// displayName is the name part of an address, or the address.
+func displayName(addr string) string {
+	if i := strings.Index(addr, " <"); i > 0 {
+		return strings.Trim(addr[:i], `"`)
+	}
+	return addr
+}
+
+// readAloud starts reading the open message, or stops a reading in
+// progress.
+func (m *model) readAloud() tea.Cmd {
+	if m.speech != nil {
+		m.stopSpeech()
+		m.setNotice("Stopped reading")
+		return nil
+	}
+	s, err := speak(m.cfg.Speech, m.currentEmail.id, m.speechText())
+	if err != nil {
+		m.setNotice(fmt.Sprintf("Couldn't read aloud: %v", err))
+		return nil
+	}
+	m.speech = s
+	m.setNotice("Reading aloud • p to pause, P to stop")
+	return waitSpeech(s)
+}
+
+func (m *model) stopSpeech() {
+	m.speech.stop()
+	m.speech = nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// stagedMove is a delete or archive held back for [staging] delay, so it
+// can still be taken back: the messages, the provider they were listed
+// through and when the move is made.
+type stagedMove struct {
+	emails  []email
+	p       mailProvider
+	archive bool
+	due     time.Time
+}
+
+func (s stagedMove) verb() string {
+	if s.archive {
+		return "archive"
+	}
+	return "delete"
+}
+
+// done is the notice for the move once it's made.
+func (s stagedMove) done() string {
+	switch {
+	case s.archive && len(s.emails) == 1:
+		return "Archived"
+	case s.archive:
+		return fmt.Sprintf("Archived %d", len(s.emails))
+	case len(s.emails) == 1:
+		return "Moved to Trash"
+	}
+	return fmt.Sprintf("Moved %d to Trash", len(s.emails))
+}
+
+// staging are the moves waiting to be made, oldest first.
+type staging []stagedMove
+
+// has reports whether e is waiting to be moved, so a poll doesn't list it
+// again meanwhile.
+func (s staging) has(e email) bool {
+	key := emailKey(e)
+	for _, m := range s {
+		if slices.ContainsFunc(m.emails, func(other email) bool { return emailKey(other) == key }) {
+			return true
+		}
+	}
+	return false
+}
+
+// count is how many messages are waiting.
+func (s staging) count() int {
+	n := 0
+	for _, m := range s {
+		n += len(m.emails)
+	}
+	return n
+}
+
+// move makes s through the backend, in one call when it can move several
+// at once.
+func (s stagedMove) move() tea.Cmd {
+	editor, _ := s.p.(messageEditor)
+	bulk, _ := s.p.(bulkEditor)
+	one, all := editor.trash, (bulkEditor).trashAll
+	if s.archive {
+		one, all = editor.archive, (bulkEditor).archiveAll
+	}
+	return actOnMessage(s.verb(), s.done(), func() error {
+		if bulk != nil {
+			return all(bulk, s.emails)
+		}
+		for _, e := range s.emails {
+			if err := one(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// stage takes emails out of the list and holds their move for the delay,
+// or makes it straight away without one.
+func (m *model) stage(emails []email, archive bool) tea.Cmd {
+	s := stagedMove{emails: emails, p: m.mail(), archive: archive, due: time.Now().Add(m.cfg.Staging.delay())}
+	drop := m.dropEmails(emails)
+	if m.cfg.Staging.delay() <= 0 {
+		return tea.Batch(drop, m.track(s.move()))
+	}
+	m.staged = append(m.staged, s)
+	what := fmt.Sprintf("Moving %s to Trash", countOf(len(emails)))
+	if archive {
+		what = "Archiving " + countOf(len(emails))
+	}
+	m.setNotice(fmt.Sprintf("%s in %s (%s to take back)", what, formatInterval(m.cfg.Staging.delay()), m.cfg.keys.label(listView, "X")))
+	return drop
+}
+
+// flushStaged makes the moves due by now, or all of them.
+func (m *model) flushStaged(now time.Time, all bool) tea.Cmd {
+	var cmds []tea.Cmd
+	m.staged = slices.DeleteFunc(m.staged, func(s stagedMove) bool {
+		if !all && now.Before(s.due) {
+			return false
+		}
+		cmds = append(cmds, m.track(s.move()))
+		return true
+	})
+	return tea.Batch(cmds...)
+}
+
+// unstage takes back the move of e, listing it again.
+func (m *model) unstage(e email) tea.Cmd {
+	key := emailKey(e)
+	for i := range m.staged {
+		m.staged[i].emails = slices.DeleteFunc(m.staged[i].emails, func(other email) bool { return emailKey(other) == key })
+	}
+	m.staged = slices.DeleteFunc(m.staged, func(s stagedMove) bool { return len(s.emails) == 0 })
+	m.stagingScreen.move(0, m.staged.count())
+	emails, total := m.emails, m.total
+	if !slices.ContainsFunc(emails, func(other email) bool { return emailKey(other) == key }) {
+		emails, total = append(slices.Clone(emails), e), total+1
+	}
+	return m.applyEmails(emailsMsg{emails: emails, err: m.err, total: total})
+}
+
+// stagedRow is a message of the staging screen and the move it waits for.
+type stagedRow struct {
+	e    email
+	move stagedMove
+}
+
+func (s staging) rows() []stagedRow {
+	var rows []stagedRow
+	for _, m := range s {
+		for _, e := range m.emails {
+			rows = append(rows, stagedRow{e, m})
+		}
+	}
+	return rows
+}
+
+// stagingScreen is the staging screen's state: the selected row.
+type stagingScreen struct {
+	cursor int
+}
+
+// move moves the selection by delta rows of n, stopping at the ends.
+func (s *stagingScreen) move(delta, n int) {
+	s.cursor = min(max(s.cursor+delta, 0), max(n-1, 0))
+}
+
+// view draws the screen's title and the moves waiting in rows, as of now.
+func (s stagingScreen) view(rows []stagedRow, now time.Time) string {
+	var lines []string
+	for i, r := range rows {
+		cursor := "  "
+		style := bodyStyle
+		if i == s.cursor {
+			cursor = senderStyle.Render("▸ ")
+			style = senderStyle
+		}
+		when := "now"
+		if left := r.move.due.Sub(now).Round(time.Second); left > 0 {
+			when = "in " + formatInterval(left)
+		}
+		lines = append(lines, cursor+style.Render(r.e.subject)+metaStyle.Render(" • "+displayName(r.e.sender)+" • "+r.move.verb()+" "+when))
+	}
+	title := headerStyle.Render("Waiting to be moved") + "\n" + metaStyle.Render("Deletes and archives are made once their time's up")
+	return lipgloss.NewStyle().Padding(1, 2).Render(title + "\n\n" + strings.Join(lines, "\n"))
+}
+
+func (m *model) openStaging() {
+	if len(m.staged) == 0 {
+		m.setNotice("Nothing is waiting to be deleted or archived")
+		return
+	}
+	m.stagingScreen = stagingScreen{}
+	m.mode = stagingView
+}
+
+// stagingKeys handles keys on the staging screen: u takes the selected
+// message back, and f makes every move now.
+func (m *model) stagingKeys(key string) (tea.Cmd, bool) {
+	rows := m.staged.rows()
+	switch key {
+	case "up", "k":
+		m.stagingScreen.move(-1, len(rows))
+	case "down", "j":
+		m.stagingScreen.move(1, len(rows))
+	case "u", "enter":
+		if i := m.stagingScreen.cursor; i < len(rows) {
+			cmd := m.unstage(rows[i].e)
+			m.setNotice("Taken back: " + rows[i].e.subject)
+			if len(m.staged) == 0 {
+				m.mode = listView
+			}
+			return cmd, true
+		}
+	case "f":
+		m.mode = listView
+		return m.flushStaged(time.Now(), true), true
+	case "esc", "q", "X":
+		m.mode = listView
+	}
+	return nil, true
+}
+
+func (m *model) updateStaging(tea.Msg) tea.Cmd {
+	return nil
+}
+
+func (m model) viewStaging(status string) string {
+	bindings := [][]string{{"↑/↓", "select"}, {"u", "take back"}, {"f", "move all now"}, {"esc", "back"}}
+	body := m.stagingScreen.view(m.staged.rows(), time.Now())
+	gap := max(m.height-lipgloss.Height(body)-lipgloss.Height(status)-1, 0)
+	return body + strings.Repeat("\n", gap) + "\n" + status + renderHelpBar(m.width, bindings)
+}
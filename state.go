@@ -0,0 +1,115 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The UI's state is three independent parts:
+//
+//   - mode picks the screen keys go to and View draws: the list, a message,
+//     the drafts, the composer, the mailbox picker or search. Each has its
+//     own keys, update and view, routed through screens; its state is on
+//     the model.
+//   - activity is what the user is waiting on, if anything. While it isn't
+//     idle View shows a spinner in place of the view and keys don't reach
+//     it, so nothing acts on a screen that isn't shown; esc gives up.
+//   - overlay is a screen drawn over everything else: the about box, the
+//     key help, the release notes or the shutdown screen.
+//
+// Work happens in tea.Cmds, which run on their own goroutines. A Cmd must
+// only use the values it was built with, never the model, and reports back
+// with a *Msg that Update applies. A Cmd the user waits on is started with
+// begin, which returns the spinner's tick; its Msg's handler calls finish
+// with the same activity, which only ends the wait it belongs to. A
+// background poll that lands while a message is opening therefore doesn't
+// drop the spinner, and a result that arrives after esc gave up on it is
+// discarded rather than switching the view under the user. Cmds that change
+// mail are also counted with track and opDone, so quitting waits for them.
+
+// activity is what the user is waiting on.
+type activity int
+
+const (
+	idle activity = iota
+	polling
+	openingMessage
+	listingMailboxes
+	loadingDrafts
+	openingDraft
+	savingDraft
+	searching
+	markingRead
+	loadingMore
+	checkingSetup
+	loadingLabels
+)
+
+// String is the spinner's caption.
+func (a activity) String() string {
+	switch a {
+	case polling:
+		return "Checking mail…"
+	case openingMessage:
+		return "Opening message…"
+	case listingMailboxes:
+		return "Listing mailboxes…"
+	case loadingDrafts:
+		return "Loading drafts…"
+	case openingDraft:
+		return "Opening draft…"
+	case savingDraft:
+		return "Saving…"
+	case searching:
+		return "Searching…"
+	case markingRead:
+		return "Marking all read…"
+	case loadingMore:
+		return "Loading more…"
+	case checkingSetup:
+		return "Checking setup…"
+	case loadingLabels:
+		return "Reading labels…"
+	}
+	return ""
+}
+
+// overlay is a screen shown over the current view.
+type overlay int
+
+const (
+	noOverlay overlay = iota
+	aboutOverlay
+	helpOverlay
+	// whatsNewOverlay shows the release notes after an upgrade.
+	whatsNewOverlay
+	// quitOverlay waits for tracked operations before exiting.
+	quitOverlay
+)
+
+// begin starts waiting on a and returns the spinner's tick to batch with
+// the Cmd doing the work.
+func (m *model) begin(a activity) tea.Cmd {
+	m.activity = a
+	return m.spinner.Tick
+}
+
+// finish ends the wait on a and reports whether it was still in progress:
+// false means the user gave up on it or another wait replaced it.
+func (m *model) finish(a activity) bool {
+	if m.activity != a {
+		return false
+	}
+	m.activity = idle
+	return true
+}
+
+// busy reports whether the user is waiting on something.
+func (m model) busy() bool {
+	return m.activity != idle
+}
+
+// spinning reports whether anything on screen shows the spinner, so its
+// ticks should keep coming.
+func (m model) spinning() bool {
+	return m.busy() || m.overlay == quitOverlay || m.overlay == aboutOverlay && m.about == nil
+}
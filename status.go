@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// defaultStatusTemplate is what `mailnotify status` prints unless [status]
+// template or -template replaces it.
+const defaultStatusTemplate = "✉ {{.Count}}"
+
+// statusData is what the status template is executed against.
+type statusData struct {
+	Count    int            `json:"count"`
+	High     int            `json:"high"`
+	Accounts map[string]int `json:"accounts"`
+	Emails   []apiEmail     `json:"emails"`
+	// Latest is the first message of the list, if there is one.
+	Latest *apiEmail `json:"latest,omitempty"`
+	// Error is set when the daemon's last poll failed; the counts are then
+	// from the last poll that worked.
+	Error string `json:"error,omitempty"`
+}
+
+// runStatus prints a one-line unread summary for status bars and prompts,
+// and returns the exit code: [status] exit_mail when there is unread mail,
+// exit_none when there isn't, exit_error when the poll failed.
+//
+//	mailnotify status [-format text|json] [-template tmpl] [-q query]
+func runStatus(cfg config, args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	format := fs.String("format", "text", "output format: text or json")
+	tmplText := fs.String("template", cfg.Status.template(), "text/template for the text format")
+	query := fs.String("q", cfg.Filter.Default, "only count messages matching a filter query")
+	if err := fs.Parse(args); err != nil {
+		return cfg.Status.exitError()
+	}
+
+	list, err := fetchList(cfg, *query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return cfg.Status.exitError()
+	}
+	data := statusData{Count: len(list.Emails), Accounts: map[string]int{}, Emails: list.Emails, Error: list.Error}
+	for i, e := range list.Emails {
+		if e.Priority == priorityHigh.String() {
+			data.High++
+		}
+		if e.Account != "" {
+			data.Accounts[e.Account]++
+		}
+		if i == 0 {
+			data.Latest = &list.Emails[i]
+		}
+	}
+
+	switch *format {
+	case "text":
+		tmpl, err := template.New("status").Funcs(ticketFuncs).Parse(*tmplText)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: status template: %v\n", err)
+			return cfg.Status.exitError()
+		}
+		var out strings.Builder
+		if err := tmpl.Execute(&out, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: status template: %v\n", err)
+			return cfg.Status.exitError()
+		}
+		fmt.Println(strings.TrimRight(out.String(), "\n"))
+	case "json":
+		json.NewEncoder(os.Stdout).Encode(data)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *format)
+		return cfg.Status.exitError()
+	}
+
+	switch {
+	case data.Error != "":
+		fmt.Fprintf(os.Stderr, "Error: last poll failed: %s\n", data.Error)
+		return cfg.Status.exitError()
+	case data.Count > 0:
+		return cfg.Status.exitMail()
+	default:
+		return cfg.Status.exitNone()
+	}
+}
+
+// runCheck reports through its exit code whether there is unread mail, for
+// shell scripts and cron jobs to branch on: 0 when there is, 1 when there
+// isn't, and 2 when it couldn't tell. Messages a hide rule matches don't
+// count, and -rule counts only what a highlight or priority rule matches.
+//
+//	mailnotify check [-quiet] [-q query] [-rule highlight|priority]
+func runCheck(cfg config, args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	quiet := fs.Bool("quiet", false, "print nothing, only set the exit code")
+	query := fs.String("q", cfg.Filter.Default, "only count messages matching a filter query")
+	ruleName := fs.String("rule", "", "only count messages a highlight or priority rule matches")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	want := ruleNone
+	if *ruleName != "" {
+		var ok bool
+		if want, ok = ruleActions[*ruleName]; !ok || want == ruleHide {
+			fmt.Fprintf(os.Stderr, "Error: -rule must be highlight or priority, not %q\n", *ruleName)
+			return 2
+		}
+	}
+
+	list, err := fetchList(cfg, *query)
+	if err == nil && list.Error != "" {
+		err = fmt.Errorf("last poll failed: %s", list.Error)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	n := 0
+	for _, e := range list.Emails {
+		action := cfg.rules.match(e.email())
+		if action != ruleHide && (want == ruleNone || action == want) {
+			n++
+		}
+	}
+	if !*quiet {
+		fmt.Printf("%d unread\n", n)
+	}
+	if n == 0 {
+		return 1
+	}
+	return 0
+}
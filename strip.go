@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// stripper is a [[strip]] entry parsed.
+type stripper struct {
+	pattern *regexp.Regexp
+	// account is the only account the entry applies to, or "" for all.
+	account string
+}
+
+type strippers []stripper
+
+// parseStrippers compiles the [[strip]] entries.
+func parseStrippers(entries []stripConfig) (strippers, error) {
+	var ss strippers
+	for i, c := range entries {
+		if c.Pattern == "" {
+			return nil, fmt.Errorf("entry %d: needs a pattern", i+1)
+		}
+		// Compiled alone first, so an error quotes the pattern as written.
+		if _, err := regexp.Compile(c.Pattern); err != nil {
+			return nil, fmt.Errorf("entry %d: pattern: %w", i+1, err)
+		}
+		ss = append(ss, stripper{pattern: regexp.MustCompile("(?mi)" + c.Pattern), account: c.Account})
+	}
+	return ss, nil
+}
+
+// blankRun is a run of blank lines a cut can leave behind.
+var blankRun = regexp.MustCompile(`\n[ \t]*\n(?:[ \t]*\n)+`)
+
+// apply cuts what the entries for account match out of text, and reports
+// whether any of them did.
+func (ss strippers) apply(text, account string) (string, bool) {
+	stripped := false
+	for _, s := range ss {
+		if s.account != "" && !strings.EqualFold(s.account, account) {
+			continue
+		}
+		if out := s.pattern.ReplaceAllString(text, ""); out != text {
+			text, stripped = out, true
+		}
+	}
+	if !stripped {
+		return text, false
+	}
+	return strings.TrimSpace(blankRun.ReplaceAllString(text, "\n\n")), true
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// paletteColor is a true-color value with hand-picked fallbacks for
+// terminals limited to the 256-color or 16-color ANSI palettes. Empty
+// fallbacks are approximated from TrueColor.
+type paletteColor struct {
+	TrueColor string
+	ANSI256   string
+	ANSI      string
+}
+
+func (c paletteColor) terminalColor() lipgloss.TerminalColor {
+	if c.ANSI256 == "" || c.ANSI == "" {
+		return lipgloss.Color(c.TrueColor)
+	}
+	return lipgloss.CompleteColor{TrueColor: c.TrueColor, ANSI256: c.ANSI256, ANSI: c.ANSI}
+}
+
+// palette holds every color the UI is drawn with. Background is not painted
+// anywhere; it describes the terminal background the palette is designed for
+// and is only used for contrast checking.
+type palette struct {
+	Background paletteColor
+	Accent     paletteColor
+	Subtle     paletteColor
+	Sender     paletteColor
+	Date       paletteColor
+	Text       paletteColor
+	Dim        paletteColor
+	Success    paletteColor
+	Error      paletteColor
+	TitleText  paletteColor
+	KeyText    paletteColor
+	KeyBg      paletteColor
+	BarText    paletteColor
+	BarBg      paletteColor
+}
+
+var palettes = map[string]palette{
+	"dark": {
+		Background: paletteColor{"#111827", "234", "0"},
+		Accent:     paletteColor{"#2563EB", "26", "4"},
+		Subtle:     paletteColor{"#6B7280", "243", "8"},
+		Sender:     paletteColor{"#60A5FA", "75", "12"},
+		Date:       paletteColor{"#93C5FD", "117", "14"},
+		Text:       paletteColor{"#E5E7EB", "254", "7"},
+		Dim:        paletteColor{"#4B5563", "240", "8"},
+		Success:    paletteColor{"#34D399", "78", "10"},
+		Error:      paletteColor{"#FF6B6B", "203", "9"},
+		TitleText:  paletteColor{"#FFFFFF", "231", "15"},
+		KeyText:    paletteColor{"#FFFFFF", "231", "15"},
+		KeyBg:      paletteColor{"#3F3F46", "238", "8"},
+		BarText:    paletteColor{"#A1A1AA", "248", "7"},
+		BarBg:      paletteColor{"#27272A", "235", "0"},
+	},
+	"light": {
+		Background: paletteColor{"#FFFFFF", "231", "15"},
+		Accent:     paletteColor{"#1D4ED8", "26", "4"},
+		Subtle:     paletteColor{"#4B5563", "240", "8"},
+		Sender:     paletteColor{"#1E40AF", "25", "4"},
+		Date:       paletteColor{"#0369A1", "24", "6"},
+		Text:       paletteColor{"#111827", "234", "0"},
+		Dim:        paletteColor{"#9CA3AF", "248", "7"},
+		Success:    paletteColor{"#047857", "29", "2"},
+		Error:      paletteColor{"#B91C1C", "124", "1"},
+		TitleText:  paletteColor{"#FFFFFF", "231", "15"},
+		KeyText:    paletteColor{"#111827", "234", "0"},
+		KeyBg:      paletteColor{"#E5E7EB", "254", "7"},
+		BarText:    paletteColor{"#374151", "238", "0"},
+		BarBg:      paletteColor{"#F3F4F6", "255", "7"},
+	},
+	// The solarized palettes use Ethan Schoonover's colors as they are,
+	// except where one falls short of the contrast checks.
+	"solarized-dark": {
+		Background: paletteColor{"#002B36", "234", "0"},
+		Accent:     paletteColor{"#268BD2", "33", "4"},
+		Subtle:     paletteColor{"#657B83", "241", "8"},
+		Sender:     paletteColor{"#2AA198", "37", "6"},
+		Date:       paletteColor{"#6C71C4", "61", "5"},
+		Text:       paletteColor{"#93A1A1", "245", "7"},
+		Dim:        paletteColor{"#586E75", "240", "8"},
+		Success:    paletteColor{"#859900", "64", "2"},
+		Error:      paletteColor{"#DC322F", "160", "1"},
+		TitleText:  paletteColor{"#FDF6E3", "230", "15"},
+		KeyText:    paletteColor{"#FDF6E3", "230", "15"},
+		KeyBg:      paletteColor{"#073642", "235", "0"},
+		BarText:    paletteColor{"#93A1A1", "245", "7"},
+		BarBg:      paletteColor{"#073642", "235", "0"},
+	},
+	"solarized-light": {
+		Background: paletteColor{"#FDF6E3", "230", "15"},
+		Accent:     paletteColor{"#268BD2", "33", "4"},
+		Subtle:     paletteColor{"#657B83", "241", "8"},
+		Sender:     paletteColor{"#6C71C4", "61", "5"},
+		Date:       paletteColor{"#CB4B16", "166", "3"},
+		Text:       paletteColor{"#586E75", "240", "0"},
+		Dim:        paletteColor{"#93A1A1", "245", "7"},
+		Success:    paletteColor{"#6B7F00", "64", "2"},
+		Error:      paletteColor{"#DC322F", "160", "1"},
+		TitleText:  paletteColor{"#FDF6E3", "230", "15"},
+		KeyText:    paletteColor{"#073642", "235", "0"},
+		KeyBg:      paletteColor{"#EEE8D5", "254", "7"},
+		BarText:    paletteColor{"#073642", "235", "0"},
+		BarBg:      paletteColor{"#EEE8D5", "254", "7"},
+	},
+	// Deuteranopia and protanopia both collapse the red/green axis, so these
+	// palettes carry meaning on the blue/orange axis and through lightness.
+	"deuteranopia": {
+		Background: paletteColor{"#111827", "234", "0"},
+		Accent:     paletteColor{"#0072B2", "25", "4"},
+		Subtle:     paletteColor{"#8C8C8C", "245", "8"},
+		Sender:     paletteColor{"#56B4E9", "74", "12"},
+		Date:       paletteColor{"#9AD0F5", "117", "14"},
+		Text:       paletteColor{"#EDEDED", "255", "7"},
+		Dim:        paletteColor{"#5A5A5A", "240", "8"},
+		Success:    paletteColor{"#56B4E9", "74", "14"},
+		Error:      paletteColor{"#E69F00", "178", "3"},
+		TitleText:  paletteColor{"#FFFFFF", "231", "15"},
+		KeyText:    paletteColor{"#FFFFFF", "231", "15"},
+		KeyBg:      paletteColor{"#3F3F46", "238", "8"},
+		BarText:    paletteColor{"#B4B4B4", "249", "7"},
+		BarBg:      paletteColor{"#27272A", "235", "0"},
+	},
+	"protanopia": {
+		Background: paletteColor{"#111827", "234", "0"},
+		Accent:     paletteColor{"#1F77D0", "32", "4"},
+		Subtle:     paletteColor{"#8C8C8C", "245", "8"},
+		Sender:     paletteColor{"#64B5F6", "75", "12"},
+		Date:       paletteColor{"#A7C7E7", "152", "14"},
+		Text:       paletteColor{"#EDEDED", "255", "7"},
+		Dim:        paletteColor{"#5A5A5A", "240", "8"},
+		Success:    paletteColor{"#64B5F6", "75", "14"},
+		Error:      paletteColor{"#FFC20A", "214", "11"},
+		TitleText:  paletteColor{"#FFFFFF", "231", "15"},
+		KeyText:    paletteColor{"#FFFFFF", "231", "15"},
+		KeyBg:      paletteColor{"#3F3F46", "238", "8"},
+		BarText:    paletteColor{"#B4B4B4", "249", "7"},
+		BarBg:      paletteColor{"#27272A", "235", "0"},
+	},
+}
+
+// Minimum WCAG contrast ratios. Body text gets the AA threshold for normal
+// text, secondary text the threshold for large text and UI components.
+const (
+	minTextContrast      = 4.5
+	minSecondaryContrast = 3.0
+)
+
+// adaptivePalettes are the palette names that pick a dark or light palette
+// to suit the terminal's background, with the pair they choose from.
+var adaptivePalettes = map[string][2]string{
+	"auto":      {"dark", "light"},
+	"solarized": {"solarized-dark", "solarized-light"},
+}
+
+// paletteNames are the names [theme] palette accepts, sorted.
+func paletteNames() []string {
+	names := make([]string, 0, len(palettes)+len(adaptivePalettes))
+	for name := range palettes {
+		names = append(names, name)
+	}
+	for name := range adaptivePalettes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// resolvePalette turns a palette name into a built-in palette's. Unset is
+// auto, and "default" is the dark palette it used to name.
+func resolvePalette(name string, darkBackground func() bool) string {
+	switch name {
+	case "":
+		name = "auto"
+	case "default":
+		return "dark"
+	}
+	if pair, ok := adaptivePalettes[name]; ok {
+		if darkBackground() {
+			return pair[0]
+		}
+		return pair[1]
+	}
+	return name
+}
+
+// loadTheme resolves the palette named in cfg, applies any per-color
+// overrides and returns it along with readability warnings. Adaptive
+// palettes ask the terminal for its background color.
+func loadTheme(cfg themeConfig) (palette, []string, error) {
+	name := resolvePalette(cfg.Palette, lipgloss.HasDarkBackground)
+	p, ok := palettes[name]
+	if !ok {
+		return palette{}, nil, fmt.Errorf("unknown palette %q", name)
+	}
+
+	overrides := map[string]*paletteColor{
+		"background": &p.Background,
+		"accent":     &p.Accent,
+		"subtle":     &p.Subtle,
+		"sender":     &p.Sender,
+		"date":       &p.Date,
+		"text":       &p.Text,
+		"dim":        &p.Dim,
+		"success":    &p.Success,
+		"error":      &p.Error,
+		"title_text": &p.TitleText,
+		"key_text":   &p.KeyText,
+		"key_bg":     &p.KeyBg,
+		"bar_text":   &p.BarText,
+		"bar_bg":     &p.BarBg,
+	}
+	for k, v := range cfg.Colors {
+		dst, ok := overrides[k]
+		if !ok {
+			return palette{}, nil, fmt.Errorf("unknown theme color %q", k)
+		}
+		if _, err := parseHex(v); err != nil {
+			return palette{}, nil, fmt.Errorf("theme color %s: %w", k, err)
+		}
+		*dst = paletteColor{TrueColor: v}
+	}
+
+	return p, checkContrast(p), nil
+}
+
+// checkContrast reports every foreground/background pair in p that falls
+// below its readability threshold. Dim is decorative and is not checked.
+func checkContrast(p palette) []string {
+	pairs := []struct {
+		name   string
+		fg, bg string
+		min    float64
+	}{
+		{"text on background", p.Text.TrueColor, p.Background.TrueColor, minTextContrast},
+		{"sender on background", p.Sender.TrueColor, p.Background.TrueColor, minSecondaryContrast},
+		{"date on background", p.Date.TrueColor, p.Background.TrueColor, minSecondaryContrast},
+		{"subtle on background", p.Subtle.TrueColor, p.Background.TrueColor, minSecondaryContrast},
+		{"accent on background", p.Accent.TrueColor, p.Background.TrueColor, minSecondaryContrast},
+		{"success on background", p.Success.TrueColor, p.Background.TrueColor, minSecondaryContrast},
+		{"error on background", p.Error.TrueColor, p.Background.TrueColor, minSecondaryContrast},
+		{"title text on accent", p.TitleText.TrueColor, p.Accent.TrueColor, minSecondaryContrast},
+		{"key text on key background", p.KeyText.TrueColor, p.KeyBg.TrueColor, minTextContrast},
+		{"help text on help bar", p.BarText.TrueColor, p.BarBg.TrueColor, minTextContrast},
+	}
+
+	var warnings []string
+	for _, pr := range pairs {
+		ratio, err := contrastRatio(pr.fg, pr.bg)
+		if err != nil {
+			continue
+		}
+		if ratio < pr.min {
+			warnings = append(warnings, fmt.Sprintf("low contrast: %s is %.1f:1 (want %.1f:1)", pr.name, ratio, pr.min))
+		}
+	}
+	return warnings
+}
+
+// contrastRatio returns the WCAG 2 contrast ratio between two hex colors.
+func contrastRatio(a, b string) (float64, error) {
+	la, err := luminance(a)
+	if err != nil {
+		return 0, err
+	}
+	lb, err := luminance(b)
+	if err != nil {
+		return 0, err
+	}
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05), nil
+}
+
+func luminance(hex string) (float64, error) {
+	rgb, err := parseHex(hex)
+	if err != nil {
+		return 0, err
+	}
+	channel := func(c uint8) float64 {
+		v := float64(c) / 255
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(rgb[0]) + 0.7152*channel(rgb[1]) + 0.0722*channel(rgb[2]), nil
+}
+
+func parseHex(hex string) ([3]uint8, error) {
+	s := strings.TrimPrefix(hex, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return [3]uint8{}, fmt.Errorf("invalid hex color %q", hex)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return [3]uint8{}, fmt.Errorf("invalid hex color %q", hex)
+	}
+	return [3]uint8{uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+// setupTheme applies the color profile and palette from cfg, returning any
+// contrast warnings.
+func setupTheme(cfg themeConfig) ([]string, error) {
+	if err := applyColorProfile(cfg.ColorProfile); err != nil {
+		return nil, err
+	}
+	p, warnings, err := loadTheme(cfg)
+	if err != nil {
+		return nil, err
+	}
+	applyTheme(p)
+	return warnings, nil
+}
+
+// applyColorProfile forces the renderer's color profile. "auto" (or empty)
+// keeps lipgloss's detection, which honors COLORTERM and TERM.
+func applyColorProfile(name string) error {
+	switch name {
+	case "", "auto":
+	case "truecolor":
+		lipgloss.SetColorProfile(termenv.TrueColor)
+	case "256":
+		lipgloss.SetColorProfile(termenv.ANSI256)
+	case "16":
+		lipgloss.SetColorProfile(termenv.ANSI)
+	case "none":
+		lipgloss.SetColorProfile(termenv.Ascii)
+	default:
+		return fmt.Errorf("unknown color profile %q", name)
+	}
+	return nil
+}
+
+// applyTheme rebuilds the package-level colors and styles from p.
+func applyTheme(p palette) {
+	accentColor = p.Accent.terminalColor()
+	subtleColor = p.Subtle.terminalColor()
+	senderColor = p.Sender.terminalColor()
+	dateColor = p.Date.terminalColor()
+	textColor = p.Text.terminalColor()
+	dimColor = p.Dim.terminalColor()
+	successColor = p.Success.terminalColor()
+	errorColor = p.Error.terminalColor()
+	titleTextColor = p.TitleText.terminalColor()
+	keyTextColor = p.KeyText.terminalColor()
+	keyBgColor = p.KeyBg.terminalColor()
+	barTextColor = p.BarText.terminalColor()
+	barBgColor = p.BarBg.terminalColor()
+
+	titleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(titleTextColor).
+		Background(accentColor).
+		Padding(0, 1)
+
+	statusStyle = lipgloss.NewStyle().
+		Foreground(subtleColor).
+		Italic(true)
+
+	headerStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(accentColor).
+		MarginBottom(1)
+
+	metaStyle = lipgloss.NewStyle().
+		Foreground(subtleColor)
+
+	senderStyle = lipgloss.NewStyle().
+		Foreground(senderColor)
+
+	dateStyle = lipgloss.NewStyle().
+		Foreground(dateColor)
+
+	bodyStyle = lipgloss.NewStyle().
+		Foreground(textColor)
+
+	dividerStyle = lipgloss.NewStyle().
+		Foreground(dimColor)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// mutedThreadFile holds the muted conversations. It's in the state
+// directory, so the daemon keeps quiet about them too.
+const mutedThreadFile = "muted-threads.json"
+
+// mutedThreads are the muted conversations, by muteKey, with when each was
+// muted. Files from before muteKey are keyed by threadKey, which still
+// mutes. Their messages are listed, dimmed, but never announced; other
+// mail from the same people is left alone. The list's delegate shares it
+// to draw them.
+type mutedThreads struct {
+	keys map[string]time.Time
+}
+
+func loadMutedThreads(dir string) (*mutedThreads, error) {
+	keys, err := loadStateFile[map[string]time.Time](dir, mutedThreadFile)
+	if keys == nil {
+		keys = map[string]time.Time{}
+	}
+	return &mutedThreads{keys: keys}, err
+}
+
+// save writes t to dir through a temporary file.
+func (t *mutedThreads) save(dir string) error {
+	return writeStateFile(dir, mutedThreadFile, t.keys)
+}
+
+// muteKey is the conversation e is in, for muting: the Message-ID it
+// started with, or for mail whose headers don't say, its subject and the
+// people on it. It's empty without either.
+func muteKey(e email) string {
+	if e.threadRoot != "" {
+		return "id:" + e.threadRoot
+	}
+	subject := threadKey(e.subject)
+	if subject == "" {
+		return ""
+	}
+	var people []string
+	for _, a := range append([]string{e.sender}, append(e.to, e.cc...)...) {
+		if addr := normalizeAddress(a); addr != "" && !slices.Contains(people, addr) {
+			people = append(people, addr)
+		}
+	}
+	slices.Sort(people)
+	return "subject:" + subject + " " + strings.Join(people, ",")
+}
+
+// has reports whether e's conversation is muted, by its muteKey or by the
+// threadKey an older file muted it by.
+func (t *mutedThreads) has(e email) bool {
+	if t == nil {
+		return false
+	}
+	for _, k := range []string{muteKey(e), threadKey(e.subject)} {
+		if _, ok := t.keys[k]; ok && k != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// reload replaces t with what's on file in dir, for the changes the daemon
+// or another machine made. t is left as it was when the file is damaged.
+func (t *mutedThreads) reload(dir string) error {
+	on, err := loadMutedThreads(dir)
+	if err == nil {
+		t.keys = on.keys
+	}
+	return err
+}
+
+// toggleThreadMute mutes e's conversation, or unmutes it, on top of what's
+// on file.
+func (m *model) toggleThreadMute(e email) tea.Cmd {
+	key := muteKey(e)
+	if key == "" {
+		m.setNotice("A message without a Message-ID or a subject has no conversation to mute")
+		return nil
+	}
+	dir := m.cfg.State.dir()
+	if err := m.muted.reload(dir); err != nil {
+		m.setNotice(fmt.Sprintf("Couldn't mute the conversation: %v", err))
+		return nil
+	}
+	muted := m.muted.has(e)
+	if muted {
+		delete(m.muted.keys, key)
+		delete(m.muted.keys, threadKey(e.subject))
+	} else {
+		m.muted.keys[key] = time.Now()
+	}
+	if err := m.muted.save(dir); err != nil {
+		m.muted.reload(dir)
+		m.setNotice(fmt.Sprintf("Couldn't mute the conversation: %v", err))
+		return nil
+	}
+	if muted {
+		m.setNotice("Unmuted the conversation " + e.subject)
+	} else {
+		m.setNotice("Muted the conversation " + e.subject + "; its new messages won't be announced")
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMutedThreads(t *testing.T) {
+	parse := func(headers string) email { return parseHeaderEmail([]byte(headers + "\r\n")) }
+	root := parse("From: ann@example.com\r\nTo: me@example.com\r\nSubject: Offsite\r\nMessage-Id: <a1@example.com>\r\n")
+	reply := parse("From: bob@example.com\r\nTo: ann@example.com\r\nCc: me@example.com\r\nSubject: Re: Offsite\r\nMessage-Id: <b1@example.com>\r\nIn-Reply-To: <a1@example.com>\r\nReferences: <a1@example.com>\r\n")
+	later := parse("From: cat@example.com\r\nTo: bob@example.com\r\nSubject: Re: Re: Offsite\r\nMessage-Id: <c1@example.com>\r\nReferences: <a1@example.com> <b1@example.com>\r\n")
+	other := parse("From: ann@example.com\r\nTo: me@example.com\r\nSubject: Offsite\r\nMessage-Id: <a2@example.com>\r\n")
+	bare := email{sender: "Dee <dee@example.com>", to: []string{"me@example.com"}, subject: "Lunch"}
+	bareReply := email{sender: "me@example.com", to: []string{"dee@example.com"}, subject: "Re: lunch"}
+
+	muted := &mutedThreads{keys: map[string]time.Time{muteKey(reply): time.Now(), muteKey(bare): time.Now()}}
+	for _, tt := range []struct {
+		name string
+		e    email
+		want bool
+	}{
+		{"root", root, true},
+		{"reply", reply, true},
+		{"later reply", later, true},
+		{"same subject, other thread", other, false},
+		{"no message id", bare, true},
+		{"no message id, reply", bareReply, true},
+	} {
+		if got := muted.has(tt.e); got != tt.want {
+			t.Errorf("%s: has = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	legacy := &mutedThreads{keys: map[string]time.Time{threadKey(root.subject): time.Now()}}
+	if !legacy.has(reply) {
+		t.Error("a conversation muted by subject in an older file isn't muted")
+	}
+}
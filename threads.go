@@ -0,0 +1,83 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// threadFetchFactor is how many more messages a poll fetches when threads
+// are grouped, since [poll] max then counts conversations rather than
+// messages.
+const threadFetchFactor = 5
+
+// replyPrefix matches the reply and forward markers mail clients put in
+// front of a subject, in the common languages, with an optional count such
+// as "Re[2]:".
+var replyPrefix = regexp.MustCompile(`(?i)^\s*(re|fw|fwd|aw|wg|sv|vs|antw|rif|tr)(\[\d+\]|\(\d+\))?\s*[:：]\s*`)
+
+// threadKey is the conversation a message belongs to: its subject without
+// reply markers, case or extra spaces.
+func threadKey(subject string) string {
+	s := subject
+	for {
+		loc := replyPrefix.FindStringIndex(s)
+		if loc == nil {
+			break
+		}
+		s = s[loc[1]:]
+	}
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// rootMessageID is the Message-ID of the message that started a
+// conversation, from a message's References, In-Reply-To and own
+// Message-ID headers: the first of its References, else what it replies
+// to, else its own, as it may have started it. It's empty without any.
+func rootMessageID(references, inReplyTo, messageID string) string {
+	for _, h := range []string{references, inReplyTo} {
+		if id, _, ok := strings.Cut(h, ">"); ok {
+			if _, id, ok := strings.Cut(id, "<"); ok && strings.TrimSpace(id) != "" {
+				return strings.TrimSpace(id)
+			}
+		}
+	}
+	return strings.Trim(messageID, "<> ")
+}
+
+// groupThreads folds sorted into conversations, keeping the order of each
+// conversation's first message. A conversation of several messages gets a
+// heading row, a copy of that message with threadSize set; when its key is
+// in expanded, all its messages follow, marked inThread. limit caps how many
+// conversations are kept, if it's positive.
+func groupThreads(sorted []email, expanded map[string]bool, limit int) []email {
+	var order []string
+	members := map[string][]email{}
+	for _, e := range sorted {
+		key := threadKey(e.subject)
+		if _, ok := members[key]; !ok {
+			if limit > 0 && len(order) == limit {
+				continue
+			}
+			order = append(order, key)
+		}
+		members[key] = append(members[key], e)
+	}
+
+	out := make([]email, 0, len(order))
+	for _, key := range order {
+		thread := members[key]
+		head := thread[0]
+		if len(thread) > 1 {
+			head.threadSize = len(thread)
+		}
+		out = append(out, head)
+		if !expanded[key] {
+			continue
+		}
+		for _, e := range thread {
+			e.inThread = true
+			out = append(out, e)
+		}
+	}
+	return out
+}
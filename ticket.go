@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const ticketTimeout = 15 * time.Second
+
+type ticketCreatedMsg struct {
+	url string
+	err error
+}
+
+// ticketData is what the payload template is executed against.
+type ticketData struct {
+	Subject string
+	Sender  string
+	Date    string
+	Body    string
+	Link    string
+}
+
+var ticketFuncs = template.FuncMap{
+	// json renders v as a JSON value, so templates can embed arbitrary
+	// text safely: {"title": {{json .Subject}}}.
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// createTicket renders the configured payload template for e and sends it
+// to the ticket endpoint, returning the URL of the new ticket.
+func createTicket(cfg ticketConfig, e email, body string) tea.Cmd {
+	return func() tea.Msg {
+		url, err := postTicket(cfg, e, body)
+		return ticketCreatedMsg{url: url, err: err}
+	}
+}
+
+func postTicket(cfg ticketConfig, e email, body string) (string, error) {
+	if cfg.Endpoint == "" {
+		return "", fmt.Errorf("no ticket endpoint configured")
+	}
+	tmpl, err := template.New("ticket").Funcs(ticketFuncs).Parse(cfg.Template)
+	if err != nil {
+		return "", fmt.Errorf("ticket template: %w", err)
+	}
+	var payload bytes.Buffer
+	err = tmpl.Execute(&payload, ticketData{
+		Subject: e.subject,
+		Sender:  e.sender,
+		Date:    e.date,
+		Body:    body,
+		Link:    messageURL(e.messageID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("ticket template: %w", err)
+	}
+
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	endpoint, err := resolveSecret(cfg.Endpoint)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(method, endpoint, &payload)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range cfg.Headers {
+		v, err := resolveSecret(v)
+		if err != nil {
+			return "", fmt.Errorf("ticket header %s: %w", k, err)
+		}
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{Timeout: ticketTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("ticket endpoint returned %s", resp.Status)
+	}
+
+	field := cfg.URLField
+	if field == "" {
+		field = "url"
+	}
+	var decoded any
+	if json.Unmarshal(respBody, &decoded) == nil {
+		if url, ok := lookupJSONPath(decoded, field).(string); ok && url != "" {
+			return url, nil
+		}
+	}
+	if loc := resp.Header.Get("Location"); loc != "" {
+		return loc, nil
+	}
+	return "", fmt.Errorf("ticket created, but the response had no %q field", field)
+}
+
+// lookupJSONPath walks a dotted path such as "data.issue.url" or
+// "items.0.url" through decoded JSON.
+func lookupJSONPath(v any, path string) any {
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil
+			}
+			v = node[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
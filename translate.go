@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// languageNames are the languages detectLanguage tells apart, by ISO 639-1
+// code.
+var languageNames = map[string]string{
+	"en": "English", "de": "German", "fr": "French", "es": "Spanish",
+	"it": "Italian", "nl": "Dutch", "pt": "Portuguese", "sv": "Swedish",
+	"da": "Danish", "pl": "Polish", "ru": "Russian", "uk": "Ukrainian",
+	"el": "Greek", "he": "Hebrew", "ar": "Arabic", "zh": "Chinese",
+	"ja": "Japanese", "ko": "Korean",
+}
+
+// stopwords are short, frequent words that mostly belong to one language
+// each, for telling the Latin-script languages apart.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "that", "for", "with", "this", "have", "will", "not", "be", "of", "to"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "sie", "mit", "wir", "ein", "eine", "zu", "dass", "für"},
+	"fr": {"le", "la", "les", "et", "est", "vous", "nous", "pour", "avec", "une", "des", "pas", "que", "dans", "je"},
+	"es": {"el", "los", "las", "y", "es", "que", "para", "con", "una", "por", "del", "nos", "usted", "está", "pero"},
+	"it": {"il", "gli", "e", "è", "che", "per", "con", "una", "non", "sono", "della", "di", "ci", "questo", "grazie"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "dat", "voor", "met", "wij", "jij", "zijn", "ook", "van", "maar"},
+	"pt": {"o", "os", "as", "e", "é", "que", "para", "com", "uma", "não", "do", "da", "você", "em", "obrigado"},
+	"sv": {"och", "att", "det", "är", "som", "för", "med", "inte", "jag", "vi", "på", "en", "till", "av", "tack"},
+	"da": {"og", "at", "det", "er", "som", "for", "med", "ikke", "jeg", "vi", "på", "en", "til", "af", "tak"},
+	"pl": {"i", "w", "nie", "się", "na", "że", "jest", "to", "z", "do", "dla", "jak", "ale", "czy", "dziękuję"},
+}
+
+// detectLanguage guesses the language of text, returning its code, or ""
+// when it can't tell. Scripts used by one language decide it outright;
+// otherwise the stopwords that occur most win, if they're a clear lead.
+func detectLanguage(text string) string {
+	text, _ = truncateBody(text, binarySample)
+	scripts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			scripts["ja"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["zh"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["ru"]++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				scripts["uk"]++
+			}
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	switch {
+	case scripts["ja"] > 0 && (scripts["ja"]+scripts["zh"])*2 > letters:
+		// Japanese mixes kana with Han characters.
+		return "ja"
+	case scripts["uk"] > 0 && scripts["ru"]*2 > letters:
+		return "uk"
+	}
+	for _, code := range []string{"ko", "zh", "ru", "el", "he", "ar"} {
+		if scripts[code]*2 > letters {
+			return code
+		}
+	}
+
+	counts := map[string]int{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		for code, words := range stopwords {
+			if slices.Contains(words, w) {
+				counts[code]++
+			}
+		}
+	}
+	best, second := "", 0
+	for code, n := range counts {
+		switch {
+		case n > counts[best]:
+			best, second = code, counts[best]
+		case n > second:
+			second = n
+		}
+	}
+	// A handful of hits could be names or a quoted phrase.
+	if counts[best] < 3 || counts[best] < second*3/2 {
+		return ""
+	}
+	return best
+}
+
+// foreign reports whether a message in language should be offered for
+// translation: it was recognized, and isn't one the user reads.
+func (c translateConfig) foreign(language string) bool {
+	return language != "" && !slices.Contains(c.languages(), language)
+}
+
+type translatedMsg struct {
+	id   string
+	text string
+	err  error
+}
+
+// translate pipes body through the translate command, with the detected
+// language in MAILNOTIFY_LANG. The command's output is the translation.
+func translate(cfg translateConfig, id, body, language string) tea.Cmd {
+	return func() tea.Msg {
+		cmd := exec.Command("sh", "-c", cfg.Command)
+		cmd.Stdin = strings.NewReader(body)
+		cmd.Env = append(os.Environ(), "MAILNOTIFY_LANG="+language)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			reason, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+			if reason == "" {
+				reason = fmt.Sprintf("exit status %d", exit.ExitCode())
+			}
+			return translatedMsg{id: id, err: fmt.Errorf("translate command: %s", reason)}
+		}
+		if err != nil {
+			return translatedMsg{id: id, err: fmt.Errorf("translate command: %w", err)}
+		}
+		return translatedMsg{id: id, text: strings.TrimSpace(string(out))}
+	}
+}
+
+// sideBySide puts the original and the translation in two columns, or one
+// above the other when the view is too narrow for two.
+func sideBySide(original, translation string, width int) string {
+	if width < 60 {
+		return original + "\n\n" + dividerStyle.Render(strings.Repeat("─", max(width-2, 10))) + "\n" + translation
+	}
+	col := (width - 3) / 2
+	left := lipgloss.NewStyle().Width(col).Render(original)
+	right := lipgloss.NewStyle().Width(col).Render(translation)
+	rule := dividerStyle.Render(strings.TrimSuffix(strings.Repeat(" │ \n", max(lipgloss.Height(left), lipgloss.Height(right))), "\n"))
+	return lipgloss.JoinHorizontal(lipgloss.Top, left, rule, right)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	releasesURL   = "https://api.github.com/repos/darkdenlion/mailnotify/releases/latest"
+	checksumsName = "checksums.txt"
+	updateTimeout = 5 * time.Minute
+)
+
+type release struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (r release) assetURL(name string) string {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL
+		}
+	}
+	return ""
+}
+
+// releaseAssetName is the binary published for this platform.
+func releaseAssetName() string {
+	return fmt.Sprintf("mailnotify_%s_%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func latestRelease(client *http.Client) (release, error) {
+	var r release
+	req, err := http.NewRequest(http.MethodGet, releasesURL, nil)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return r, fmt.Errorf("checking for releases: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return r, fmt.Errorf("checking for releases: %w", err)
+	}
+	return r, nil
+}
+
+// runUpdate replaces the running binary with the latest release after
+// checking its SHA-256 against the release's checksums file.
+func runUpdate(cfg updateConfig) error {
+	if !cfg.enabled() {
+		return fmt.Errorf("updates are disabled in the config ([update] check = false)")
+	}
+	if version == "dev" {
+		return fmt.Errorf("this is a development build; update it with git pull && go build")
+	}
+
+	client := &http.Client{Timeout: updateTimeout}
+	rel, err := latestRelease(client)
+	if err != nil {
+		return err
+	}
+	if compareVersions(rel.TagName, version) <= 0 {
+		fmt.Printf("mailnotify %s is up to date\n", version)
+		return nil
+	}
+
+	name := releaseAssetName()
+	binURL, sumsURL := rel.assetURL(name), rel.assetURL(checksumsName)
+	if binURL == "" {
+		return fmt.Errorf("release %s has no build for %s/%s", rel.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	if sumsURL == "" {
+		return fmt.Errorf("release %s has no %s; refusing to install an unverified binary", rel.TagName, checksumsName)
+	}
+
+	want, err := fetchChecksum(client, sumsURL, name)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+
+	// Download next to the binary so the final rename stays on one
+	// filesystem and is atomic.
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".mailnotify-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	fmt.Printf("Downloading mailnotify %s…\n", rel.TagName)
+	got, err := download(client, binURL, tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", name, got, want)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %s to %s\n", exe, rel.TagName)
+	return nil
+}
+
+func fetchChecksum(client *http.Client, url, name string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("downloading checksums: %s", resp.Status)
+	}
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s has no entry for %s", checksumsName, name)
+}
+
+// download writes url to w and returns the hex SHA-256 of what it wrote.
+func download(client *http.Client, url string, w io.Writer) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("downloading update: %s", resp.Status)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), resp.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// compareVersions compares "v1.2.3"-style versions numerically, returning
+// -1, 0 or 1. Missing or non-numeric components count as zero.
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x, _ = strconv.Atoi(strings.SplitN(pa[i], "-", 2)[0])
+		}
+		if i < len(pb) {
+			y, _ = strconv.Atoi(strings.SplitN(pb[i], "-", 2)[0])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+type updateAvailableMsg struct {
+	version string
+}
+
+// checkForUpdate looks up the latest release in the background and reports
+// it only if it's newer than the running build.
+func checkForUpdate(cfg updateConfig) tea.Cmd {
+	if !cfg.enabled() || version == "dev" {
+		return nil
+	}
+	return func() tea.Msg {
+		rel, err := latestRelease(&http.Client{Timeout: 5 * time.Second})
+		if err != nil || compareVersions(rel.TagName, version) <= 0 {
+			return nil
+		}
+		return updateAvailableMsg{version: rel.TagName}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import "runtime/debug"
+
+// version and commit are set at build time:
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse --short HEAD)"
+//
+// When commit isn't set it falls back to the VCS revision Go embeds in
+// builds made from a checkout.
+var (
+	version = "dev"
+	commit  = ""
+)
+
+func init() {
+	if commit != "" {
+		return
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && len(s.Value) >= 7 {
+			commit = s.Value[:7]
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// watchFile holds the messages being watched for. It's in the state
+// directory, so the daemon alerts on them too, and whichever sees the
+// message first ends the watch.
+const watchFile = "watches.json"
+
+// watchSound is played for watched-for mail when [notify] sets no sound, on
+// macOS, which has system sounds to pick from.
+const watchSound = "Sosumi"
+
+// watch is a message being waited on, as a search query: from:, to:,
+// subject: and words the sender or subject contains.
+type watch struct {
+	Query string    `json:"query"`
+	Added time.Time `json:"added"`
+}
+
+// watches are in the order they were added.
+type watches []watch
+
+func loadWatches(dir string) (watches, error) {
+	return loadStateFile[watches](dir, watchFile)
+}
+
+// save writes w to dir through a temporary file.
+func (w watches) save(dir string) error {
+	return writeStateFile(dir, watchFile, w)
+}
+
+// parseWatch parses a watch's query. Only what a new message's headers
+// show can be watched for, so dates and attachments are refused.
+func parseWatch(query string) (mailSearch, error) {
+	q, err := parseMailSearch(query)
+	if err != nil {
+		return q, err
+	}
+	if !q.after.IsZero() || !q.before.IsZero() || q.hasAttachment {
+		return q, fmt.Errorf("a watch takes from:, to:, subject: and words, not dates or attachments")
+	}
+	return q, nil
+}
+
+// matches reports whether e is what q watches for: every part of it has to
+// match, ignoring case.
+func (q mailSearch) matches(e email) bool {
+	has := func(s, part string) bool {
+		return strings.Contains(strings.ToLower(s), strings.ToLower(part))
+	}
+	return has(e.sender, q.from) && has(e.subject, q.subject) &&
+		has(strings.Join(slices.Concat(e.to, e.cc), ", "), q.to) &&
+		has(e.sender+" "+e.subject, q.text)
+}
+
+// arrive returns the watches left once the messages in arrived have ended
+// those they match, and a matching message for each that ended.
+func (w watches) arrive(arrived []email) (watches, []email) {
+	var left watches
+	var found []email
+	for _, wt := range w {
+		i := -1
+		if q, err := parseWatch(wt.Query); err == nil {
+			i = slices.IndexFunc(arrived, q.matches)
+		}
+		if i < 0 {
+			left = append(left, wt)
+			continue
+		}
+		found = append(found, arrived[i])
+	}
+	return left, found
+}
+
+// checkWatches ends the watches in dir that a message in arrived matches
+// and returns the messages they were waiting on.
+func checkWatches(dir string, arrived []email) ([]email, error) {
+	if len(arrived) == 0 {
+		return nil, nil
+	}
+	w, err := loadWatches(dir)
+	if err != nil {
+		return nil, err
+	}
+	left, found := w.arrive(arrived)
+	if len(found) == 0 {
+		return nil, nil
+	}
+	return found, left.save(dir)
+}
+
+// notifyWatched posts a notification for each message in found and plays
+// the sound, whatever [notify] enabled says: these the user asked for.
+func notifyWatched(n notifyConfig, configPath string, found []email) error {
+	err := notifyEmails(n, configPath, found, "The mail you were watching for", "%d messages you were watching for")
+	if n.Sound == "" && runtime.GOOS == "darwin" {
+		n.Sound = watchSound
+	}
+	if n.Sound == "" {
+		return err
+	}
+	cmd, soundErr := n.soundCommand()
+	if soundErr == nil {
+		soundErr = cmd.Run()
+	}
+	return errors.Join(err, soundErr)
+}
+
+type watchedMsg struct {
+	found []email
+	err   error
+}
+
+// watchFor ends the watches the messages in arrived fulfil and raises the
+// alarm for them: the bell, a notification and the sound.
+func watchFor(cfg config, arrived []email) tea.Cmd {
+	if len(arrived) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		found, err := checkWatches(cfg.State.dir(), arrived)
+		if len(found) == 0 {
+			return watchedMsg{err: err}
+		}
+		return watchedMsg{found: found, err: errors.Join(err, notifyWatched(cfg.Notify, cfg.path, found))}
+	}
+}
+
+// applyWatched shows what watchFor found, and rings the bell for it.
+func (m *model) applyWatched(msg watchedMsg) tea.Cmd {
+	if w, err := loadWatches(m.cfg.State.dir()); err == nil {
+		m.watch.set(w)
+	}
+	for _, e := range msg.found {
+		m.log.add("watched-for mail arrived: %s from %s", e.subject, e.sender)
+	}
+	switch {
+	case len(msg.found) == 1:
+		m.setNotice("Arrived, as watched for: " + msg.found[0].subject + " from " + displayName(msg.found[0].sender))
+	case len(msg.found) > 1:
+		m.setNotice(fmt.Sprintf("%d messages you were watching for arrived", len(msg.found)))
+	case msg.err != nil:
+		m.setNotice(fmt.Sprintf("Watching for mail: %v", msg.err))
+	}
+	if len(msg.found) == 0 {
+		return nil
+	}
+	return ringBell
+}
+
+// watchScreen is the watch screen's state: the watches, the input taking a
+// new one, and the selected row.
+type watchScreen struct {
+	watches watches
+	input   textinput.Model
+	cursor  int
+}
+
+// newWatchScreen is the watch screen over w, width wide, with the input
+// focused.
+func newWatchScreen(w watches, width int) (watchScreen, tea.Cmd) {
+	in := textinput.New()
+	in.Prompt = "Watch for: "
+	in.Placeholder = "from:, to:, subject: or words in the sender or subject"
+	in.Width = max(width-16, 20)
+	return watchScreen{watches: w, input: in}, in.Focus()
+}
+
+// set replaces the watches shown, keeping the selection on a row.
+func (w *watchScreen) set(list watches) {
+	w.watches = list
+	w.move(0)
+}
+
+// move moves the selection by delta rows, stopping at the ends.
+func (w *watchScreen) move(delta int) {
+	w.cursor = min(max(w.cursor+delta, 0), max(len(w.watches)-1, 0))
+}
+
+// selected is the selected watch's query, or "" with none.
+func (w watchScreen) selected() string {
+	if w.cursor >= len(w.watches) {
+		return ""
+	}
+	return w.watches[w.cursor].Query
+}
+
+func (w *watchScreen) update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	w.input, cmd = w.input.Update(msg)
+	return cmd
+}
+
+// view draws the screen's title, input and watches.
+func (w watchScreen) view() string {
+	var lines []string
+	for i, wt := range w.watches {
+		cursor := "  "
+		style := bodyStyle
+		if i == w.cursor {
+			cursor = senderStyle.Render("▸ ")
+			style = senderStyle
+		}
+		lines = append(lines, cursor+style.Render(wt.Query)+metaStyle.Render(" • since "+wt.Added.Format("Mon 2 Jan 15:04")))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, metaStyle.Render("Nothing is being watched for"))
+	}
+	title := headerStyle.Render("Watch for mail") + "\n" + metaStyle.Render("When a new message matches, mailnotify alerts loudly and stops watching")
+	return lipgloss.NewStyle().Padding(1, 2).Render(title + "\n\n" + w.input.View() + "\n\n" + strings.Join(lines, "\n"))
+}
+
+// openWatches switches to the watch screen with the input focused.
+func (m *model) openWatches() tea.Cmd {
+	w, err := loadWatches(m.cfg.State.dir())
+	if err != nil {
+		m.setNotice(fmt.Sprintf("Watches: %v", err))
+	}
+	var cmd tea.Cmd
+	m.watch, cmd = newWatchScreen(w, m.width)
+	m.mode = watchView
+	return cmd
+}
+
+// watchKeys handles keys on the watch screen: enter adds the query typed
+// as a watch, ctrl+x ends the selected one, and the rest go to the input.
+func (m *model) watchKeys(key string) (tea.Cmd, bool) {
+	dir := m.cfg.State.dir()
+	switch key {
+	case "esc":
+		m.mode = listView
+		return nil, true
+	case "up":
+		m.watch.move(-1)
+		return nil, true
+	case "down":
+		m.watch.move(1)
+		return nil, true
+	case "enter":
+		query := strings.TrimSpace(m.watch.input.Value())
+		if query == "" {
+			return nil, true
+		}
+		if _, err := parseWatch(query); err != nil {
+			m.setNotice(fmt.Sprintf("Watch: %v", err))
+			return nil, true
+		}
+		w, err := loadWatches(dir)
+		if err == nil {
+			w = append(w, watch{Query: query, Added: time.Now()})
+			err = w.save(dir)
+		}
+		if err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't save the watch: %v", err))
+			return nil, true
+		}
+		m.watch.set(w)
+		m.watch.input.SetValue("")
+		m.setNotice("Watching for " + query + "; you'll be alerted when it arrives")
+		return nil, true
+	case "ctrl+x":
+		query := m.watch.selected()
+		if query == "" {
+			return nil, true
+		}
+		w, err := loadWatches(dir)
+		if err == nil {
+			w = slices.DeleteFunc(w, func(wt watch) bool { return wt.Query == query })
+			err = w.save(dir)
+		}
+		if err != nil {
+			m.setNotice(fmt.Sprintf("Couldn't remove the watch: %v", err))
+			return nil, true
+		}
+		m.watch.set(w)
+		m.setNotice("Stopped watching for " + query)
+		return nil, true
+	}
+	return nil, false
+}
+
+func (m *model) updateWatches(msg tea.Msg) tea.Cmd {
+	return m.watch.update(msg)
+}
+
+func (m model) viewWatches(status string) string {
+	bindings := [][]string{{"enter", "watch"}, {"↑/↓", "select"}, {"ctrl+x", "stop watching"}, {"esc", "back"}}
+	body := m.watch.view()
+	gap := max(m.height-lipgloss.Height(body)-lipgloss.Height(status)-1, 0)
+	return body + strings.Repeat("\n", gap) + "\n" + status + renderHelpBar(m.width, bindings)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+//go:embed CHANGELOG.md
+var changelog string
+
+// releaseNote is one "## v1.2.0" section of CHANGELOG.md.
+type releaseNote struct {
+	version string
+	lines   []string
+}
+
+// parseChangelog splits a changelog into its sections, in file order,
+// which is newest first.
+func parseChangelog(s string) []releaseNote {
+	var notes []releaseNote
+	for _, line := range strings.Split(s, "\n") {
+		if v, ok := strings.CutPrefix(line, "## "); ok {
+			notes = append(notes, releaseNote{version: strings.TrimSpace(v)})
+			continue
+		}
+		if len(notes) == 0 || strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := &notes[len(notes)-1]
+		n.lines = append(n.lines, strings.TrimRight(line, " \t"))
+	}
+	return notes
+}
+
+// newReleases returns the notes of the releases after seen, up to and
+// including current. Sections that aren't versions, such as Unreleased,
+// are left out.
+func newReleases(notes []releaseNote, seen, current string) []releaseNote {
+	var out []releaseNote
+	for _, n := range notes {
+		if !strings.HasPrefix(n.version, "v") {
+			continue
+		}
+		if compareVersions(n.version, seen) > 0 && compareVersions(n.version, current) <= 0 {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func seenVersionPath(stateDir string) string {
+	return filepath.Join(stateDir, "seen-version")
+}
+
+// loadWhatsNew returns the release notes the user hasn't seen since
+// upgrading. A first run has nothing to catch up on, so it only records
+// the version; development builds never show notes.
+func loadWhatsNew(stateDir string) []releaseNote {
+	if version == "dev" {
+		return nil
+	}
+	data, err := os.ReadFile(seenVersionPath(stateDir))
+	if os.IsNotExist(err) {
+		markVersionSeen(stateDir)
+		return nil
+	}
+	seen := strings.TrimSpace(string(data))
+	if err != nil || seen == version {
+		return nil
+	}
+	notes := newReleases(parseChangelog(changelog), seen, version)
+	if len(notes) == 0 {
+		markVersionSeen(stateDir)
+	}
+	return notes
+}
+
+// markVersionSeen stops the notes up to the running version being shown.
+func markVersionSeen(stateDir string) error {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(seenVersionPath(stateDir), []byte(version+"\n"), 0o644)
+}
+
+func (m model) whatsNewView() string {
+	title := lipgloss.NewStyle().Bold(true).Foreground(accentColor).Render("What's new in mailnotify " + version)
+
+	room := max(m.height-12, 3)
+	var lines []string
+	for i, n := range m.whatsNew {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, metaStyle.Render(n.version))
+		for _, l := range n.lines {
+			lines = append(lines, bodyStyle.Render(l))
+		}
+	}
+	if len(lines) > room {
+		lines = append(lines[:room-1], metaStyle.Render(fmt.Sprintf("…and %d more lines in CHANGELOG.md", len(lines)-room+1)))
+	}
+	hint := statusStyle.Render("enter don't show again • any other key to close")
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(accentColor).
+		Padding(1, 2).
+		Width(min(m.width-4, 80)).
+		Render(title + "\n\n" + strings.Join(lines, "\n") + "\n\n" + hint)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
+}